@@ -0,0 +1,287 @@
+// Package art provides an adaptive radix tree for byte keys
+// ("The Adaptive Radix Tree: ARTful Indexing for Main-Memory Databases" - Leis, Kemper, Neumann 2013).
+package art
+
+import (
+	"bytes"
+
+	"github.com/bitsgofer/containers"
+)
+
+// tree is an ordered map of byte keys. Inner nodes pick the smallest layout fitting their children,
+// paths without branches are compressed into node prefixes, and leaves are only created where keys diverge.
+type tree struct {
+	root node
+	size int
+}
+
+// New returns an empty adaptive radix tree.
+func New() *tree {
+	return &tree{}
+}
+
+// Len returns the number of keys.
+func (t *tree) Len() int {
+	return t.size
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+
+	return n
+}
+
+// Get returns the value of key, and whether key is there.
+func (t *tree) Get(key []byte) (containers.Value, bool) {
+	n, depth := t.root, 0
+	for n != nil {
+		if l, ok := n.(*leaf); ok {
+			if !bytes.Equal(l.key, key) {
+				return nil, false
+			}
+			return l.value, true
+		}
+
+		in := n.(innerNode)
+		h := in.header()
+		if !bytes.HasPrefix(key[depth:], h.prefix) {
+			return nil, false
+		}
+		depth += len(h.prefix)
+		if depth == len(key) {
+			if h.end == nil {
+				return nil, false
+			}
+			return h.end.value, true
+		}
+
+		slot := in.child(key[depth])
+		if slot == nil {
+			return nil, false
+		}
+		n, depth = *slot, depth+1
+	}
+
+	return nil, false
+}
+
+// Insert sets the value of key, which is copied. It returns whether key was already there,
+// in which case its value is replaced.
+func (t *tree) Insert(key []byte, value containers.Value) bool {
+	l := &leaf{key: append([]byte(nil), key...), value: value}
+	if replaced := t.insert(&t.root, l, 0); replaced {
+		return true
+	}
+
+	t.size++
+	return false
+}
+
+// put adds l into n at depth, either as the key ending at n or as a child.
+func put(n innerNode, l *leaf, depth int) {
+	if depth == len(l.key) {
+		n.header().end = l
+		return
+	}
+
+	n.addChild(l.key[depth], l)
+}
+
+func (t *tree) insert(ref *node, l *leaf, depth int) bool {
+	if *ref == nil {
+		*ref = l
+		return false
+	}
+
+	if existing, ok := (*ref).(*leaf); ok {
+		if bytes.Equal(existing.key, l.key) {
+			existing.value = l.value
+			return true
+		}
+
+		// both keys share a path until they diverge, where a new node holds them
+		common := commonPrefix(existing.key[depth:], l.key[depth:])
+		n := &node4{h: header{prefix: l.key[depth : depth+common]}}
+		put(n, existing, depth+common)
+		put(n, l, depth+common)
+		*ref = n
+		return false
+	}
+
+	in := (*ref).(innerNode)
+	h := in.header()
+	common := commonPrefix(h.prefix, l.key[depth:])
+	if common < len(h.prefix) { // the key leaves the compressed path, which is split
+		n := &node4{h: header{prefix: h.prefix[:common]}}
+		n.addChild(h.prefix[common], in)
+		h.prefix = h.prefix[common+1:]
+		put(n, l, depth+common)
+		*ref = n
+		return false
+	}
+
+	depth += len(h.prefix)
+	if depth == len(l.key) {
+		if h.end != nil {
+			h.end.value = l.value
+			return true
+		}
+		h.end = l
+		return false
+	}
+
+	if slot := in.child(l.key[depth]); slot != nil {
+		return t.insert(slot, l, depth+1)
+	}
+	if in.full() {
+		in = in.grow()
+		*ref = in
+	}
+	in.addChild(l.key[depth], l)
+	return false
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (t *tree) Delete(key []byte) (containers.Value, bool) {
+	value, deleted := t.delete(&t.root, key, 0)
+	if deleted {
+		t.size--
+	}
+
+	return value, deleted
+}
+
+func (t *tree) delete(ref *node, key []byte, depth int) (containers.Value, bool) {
+	if *ref == nil {
+		return nil, false
+	}
+
+	if l, ok := (*ref).(*leaf); ok {
+		if !bytes.Equal(l.key, key) {
+			return nil, false
+		}
+		*ref = nil
+		return l.value, true
+	}
+
+	in := (*ref).(innerNode)
+	h := in.header()
+	if !bytes.HasPrefix(key[depth:], h.prefix) {
+		return nil, false
+	}
+	depth += len(h.prefix)
+
+	var value containers.Value
+	if depth == len(key) {
+		if h.end == nil {
+			return nil, false
+		}
+		value, h.end = h.end.value, nil
+	} else {
+		b := key[depth]
+		slot := in.child(b)
+		if slot == nil {
+			return nil, false
+		}
+
+		var deleted bool
+		if value, deleted = t.delete(slot, key, depth+1); !deleted {
+			return nil, false
+		}
+		if *slot == nil {
+			in.removeChild(b)
+		}
+	}
+
+	*ref = compact(in)
+	return value, true
+}
+
+// compact returns what should replace in after one of its keys was removed:
+// its only key, its only child with a longer prefix, or a smaller layout.
+func compact(in innerNode) node {
+	h := in.header()
+	switch {
+	case h.n == 0 && h.end != nil:
+		return h.end
+	case h.n == 0:
+		return nil
+	case h.n == 1 && h.end == nil:
+		var (
+			only node
+			b    byte
+		)
+		in.each(func(key byte, child node) bool {
+			only, b = child, key
+			return false
+		})
+		if child, ok := only.(innerNode); ok {
+			ch := child.header()
+			prefix := make([]byte, 0, len(h.prefix)+1+len(ch.prefix))
+			prefix = append(append(append(prefix, h.prefix...), b), ch.prefix...)
+			ch.prefix = prefix
+		}
+		return only
+	}
+
+	return in.shrink()
+}
+
+// Walk calls fn on all keys in order, until fn returns false. fn must not change the keys.
+func (t *tree) Walk(fn func(key []byte, value containers.Value) bool) {
+	walk(t.root, fn)
+}
+
+// walk calls fn on keys of the subtree at n in order. It returns false if fn did.
+func walk(n node, fn func(key []byte, value containers.Value) bool) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *leaf:
+		return fn(n.key, n.value)
+	}
+
+	in := n.(innerNode)
+	if end := in.header().end; end != nil && !fn(end.key, end.value) {
+		return false
+	}
+	return in.each(func(b byte, child node) bool {
+		return walk(child, fn)
+	})
+}
+
+// WalkPrefix calls fn on keys starting with prefix in order, until fn returns false. fn must not change the keys.
+func (t *tree) WalkPrefix(prefix []byte, fn func(key []byte, value containers.Value) bool) {
+	n, depth := t.root, 0
+	for n != nil {
+		if l, ok := n.(*leaf); ok {
+			if bytes.HasPrefix(l.key, prefix) {
+				fn(l.key, l.value)
+			}
+			return
+		}
+
+		in := n.(innerNode)
+		h := in.header()
+		rest := prefix[depth:]
+		common := commonPrefix(h.prefix, rest)
+		if common == len(rest) { // the prefix ends in or right after the compressed path
+			walk(n, fn)
+			return
+		}
+		if common < len(h.prefix) {
+			return
+		}
+
+		depth += len(h.prefix)
+		slot := in.child(prefix[depth])
+		if slot == nil {
+			return
+		}
+		n, depth = *slot, depth+1
+	}
+}
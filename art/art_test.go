@@ -0,0 +1,372 @@
+package art
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/btree"
+	"github.com/bitsgofer/containers/skiplist"
+)
+
+// walkKeys returns keys found by WalkPrefix(prefix) as strings.
+func walkKeys(t *tree, prefix string) []string {
+	var keys []string
+	t.WalkPrefix([]byte(prefix), func(key []byte, value containers.Value) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+
+	return keys
+}
+
+// kinds counts inner nodes by layout.
+func kinds(n node, counts map[string]int) {
+	switch n.(type) {
+	case nil, *leaf:
+		return
+	case *node4:
+		counts["node4"]++
+	case *node16:
+		counts["node16"]++
+	case *node48:
+		counts["node48"]++
+	case *node256:
+		counts["node256"]++
+	}
+
+	n.(innerNode).each(func(b byte, child node) bool {
+		kinds(child, counts)
+		return true
+	})
+}
+
+func TestInsertGetDelete(t *testing.T) {
+	tr := New()
+	keys := []string{"", "a", "ab", "abc", "abd", "b", "banana", "band", "bandana"}
+	for _, k := range keys {
+		if tr.Insert([]byte(k), k) {
+			t.Fatalf("insert %q: want new key", k)
+		}
+	}
+	if !tr.Insert([]byte("band"), "BAND") {
+		t.Fatalf("insert band again: want existing key")
+	}
+
+	var testCases = map[string]struct {
+		key   string
+		value containers.Value
+		found bool
+	}{
+		"empty":          {key: "", value: "", found: true},
+		"prefixOfOthers": {key: "ab", value: "ab", found: true},
+		"leaf":           {key: "abd", value: "abd", found: true},
+		"replaced":       {key: "band", value: "BAND", found: true},
+		"inPath":         {key: "ban"},
+		"longer":         {key: "bandanas"},
+		"missing":        {key: "c"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			value, found := tr.Get([]byte(tc.key))
+			if found != tc.found || value != tc.value {
+				t.Fatalf("want= %v, %v, got= %v, %v", tc.value, tc.found, value, found)
+			}
+		})
+	}
+
+	for i, k := range keys {
+		if _, ok := tr.Delete([]byte(k)); !ok {
+			t.Fatalf("delete %q: want found", k)
+		}
+		if _, ok := tr.Delete([]byte(k)); ok {
+			t.Fatalf("delete %q again: want missing", k)
+		}
+		for _, other := range keys[i+1:] {
+			if _, ok := tr.Get([]byte(other)); !ok {
+				t.Fatalf("after deleting %q: want %q found", k, other)
+			}
+		}
+	}
+	if tr.root != nil || tr.Len() != 0 {
+		t.Fatalf("want empty tree, got len= %d, root= %v", tr.Len(), tr.root)
+	}
+}
+
+func TestNodesGrowAndShrink(t *testing.T) {
+	var testCases = map[string]struct {
+		children int
+		kind     string
+	}{
+		"node4":   {children: 4, kind: "node4"},
+		"node16":  {children: 5, kind: "node16"},
+		"node48":  {children: 17, kind: "node48"},
+		"node256": {children: 49, kind: "node256"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tr := New()
+			for b := 0; b < tc.children; b++ {
+				tr.Insert([]byte{'k', byte(b * 5)}, b)
+			}
+			counts := map[string]int{}
+			kinds(tr.root, counts)
+			if want, got := map[string]int{tc.kind: 1}, counts; !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+
+			for b := 0; b < tc.children; b++ {
+				if value, ok := tr.Get([]byte{'k', byte(b * 5)}); !ok || value != b {
+					t.Fatalf("get %d: want found, got= %v, %v", b, value, ok)
+				}
+			}
+
+			// down to 2 children, the node shrinks back to node4
+			for b := 2; b < tc.children; b++ {
+				tr.Delete([]byte{'k', byte(b * 5)})
+			}
+			counts = map[string]int{}
+			kinds(tr.root, counts)
+			if want, got := map[string]int{"node4": 1}, counts; !cmp.Equal(want, got) {
+				t.Fatalf("after deletes: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestPathCompression(t *testing.T) {
+	tr := New()
+	tr.Insert([]byte("/api/v1/users"), 1)
+	tr.Insert([]byte("/api/v1/orders"), 2)
+	tr.Insert([]byte("/api/v2/users"), 3)
+
+	// root "/api/v" -> {'1': "" -> {users, orders}, '2': users}
+	root := tr.root.(innerNode).header()
+	if want, got := "/api/v", string(root.prefix); want != got {
+		t.Fatalf("root prefix: want= %q, got= %q", want, got)
+	}
+
+	tr.Delete([]byte("/api/v2/users")) // the root has one child left, which takes its path
+	if want, got := "/api/v1/", string(tr.root.(innerNode).header().prefix); want != got {
+		t.Fatalf("merged prefix: want= %q, got= %q", want, got)
+	}
+	if value, ok := tr.Get([]byte("/api/v1/orders")); !ok || value != 2 {
+		t.Fatalf("get: want 2, got= %v, %v", value, ok)
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+	tr := New()
+	for _, k := range []string{"romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus", "rom"} {
+		tr.Insert([]byte(k), nil)
+	}
+
+	var testCases = map[string]struct {
+		prefix string
+		keys   []string
+	}{
+		"all": {
+			prefix: "",
+			keys:   []string{"rom", "romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus"},
+		},
+		"endsAtNode": {
+			prefix: "rom",
+			keys:   []string{"rom", "romane", "romanus", "romulus"},
+		},
+		"endsInPath": {
+			prefix: "rubic",
+			keys:   []string{"rubicon", "rubicundus"},
+		},
+		"wholeKey": {
+			prefix: "ruber",
+			keys:   []string{"ruber"},
+		},
+		"diverges": {
+			prefix: "rubx",
+		},
+		"longerThanKeys": {
+			prefix: "rubiconia",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.keys, walkKeys(tr, tc.prefix); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestAgainstMap performs random operations and compares the tree with a map.
+func TestAgainstMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	randomKey := func() []byte {
+		key := make([]byte, rng.Intn(4))
+		for i := range key {
+			key[i] = byte(rng.Intn(64)) // small alphabet, for shared prefixes; big enough for node256
+		}
+		return key
+	}
+
+	tr := New()
+	naive := map[string]int{}
+	for step := 0; step < 20000; step++ {
+		key := randomKey()
+		if rng.Intn(3) == 0 {
+			value, ok := tr.Delete(key)
+			want, found := naive[string(key)]
+			if ok != found || (ok && value != want) {
+				t.Fatalf("step %d: delete %v: want= %v, %v, got= %v, %v", step, key, want, found, value, ok)
+			}
+			delete(naive, string(key))
+		} else {
+			_, found := naive[string(key)]
+			if existed := tr.Insert(key, step); existed != found {
+				t.Fatalf("step %d: insert %v: want= %v, got= %v", step, key, found, existed)
+			}
+			naive[string(key)] = step
+		}
+
+		probe := randomKey()
+		value, ok := tr.Get(probe)
+		want, found := naive[string(probe)]
+		if ok != found || (ok && value != want) {
+			t.Fatalf("step %d: get %v: want= %v, %v, got= %v, %v", step, probe, want, found, value, ok)
+		}
+	}
+
+	if want, got := len(naive), tr.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+	var want []string
+	for k := range naive {
+		want = append(want, k)
+	}
+	sort.Strings(want)
+	if got := walkKeys(tr, ""); !cmp.Equal(want, got) {
+		t.Fatalf("keys: want= %v, got= %v", want, got)
+	}
+}
+
+// keySets returns n 8-byte keys, random or sequential.
+func keySets(n int) map[string][][]byte {
+	rng := rand.New(rand.NewSource(1))
+	sets := map[string][][]byte{}
+	for i := 0; i < n; i++ {
+		random, sequential := make([]byte, 8), make([]byte, 8)
+		binary.BigEndian.PutUint64(random, rng.Uint64())
+		binary.BigEndian.PutUint64(sequential, uint64(i))
+		sets["random"] = append(sets["random"], random)
+		sets["sequential"] = append(sets["sequential"], sequential)
+	}
+
+	return sets
+}
+
+func bytesLess(a, b containers.Value) bool {
+	return bytes.Compare(a.([]byte), b.([]byte)) < 0
+}
+
+func stringLess(a, b containers.Value) bool {
+	return a.(string) < b.(string)
+}
+
+// BenchmarkInsert measures inserting n keys.
+func BenchmarkInsert(b *testing.B) {
+	const n = 10000
+	for name, keys := range keySets(n) {
+		b.Run(name+"/art", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr := New()
+				for _, k := range keys {
+					tr.Insert(k, nil)
+				}
+			}
+		})
+		b.Run(name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := map[string]containers.Value{}
+				for _, k := range keys {
+					m[string(k)] = nil
+				}
+			}
+		})
+		b.Run(name+"/skiplist", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s := skiplist.New(stringLess, rand.NewSource(1))
+				for _, k := range keys {
+					s.Insert(string(k), nil)
+				}
+			}
+		})
+		b.Run(name+"/avl", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				avl := btree.NewAVLTree(bytesLess)
+				for _, k := range keys {
+					avl.Insert(k, nil)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	const n = 10000
+	for name, keys := range keySets(n) {
+		tr := New()
+		m := map[string]containers.Value{}
+		s := skiplist.New(stringLess, rand.NewSource(1))
+		avl := btree.NewAVLTree(bytesLess)
+		for _, k := range keys {
+			tr.Insert(k, nil)
+			m[string(k)] = nil
+			s.Insert(string(k), nil)
+			avl.Insert(k, nil)
+		}
+
+		b.Run(name+"/art", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr.Get(keys[i%n])
+			}
+		})
+		b.Run(name+"/map", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = m[string(keys[i%n])]
+			}
+		})
+		b.Run(name+"/skiplist", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.Get(string(keys[i%n]))
+			}
+		})
+		b.Run(name+"/avl", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				avl.Get(keys[i%n])
+			}
+		})
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	const n = 10000
+	for name, keys := range keySets(n) {
+		tr := New()
+		for _, k := range keys {
+			tr.Insert(k, nil)
+		}
+
+		b.Run(fmt.Sprintf("%s/n=%d", name, n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tr.Walk(func(key []byte, value containers.Value) bool { return true })
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package art
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// node is a *leaf or an innerNode.
+type node interface{}
+
+// leaf holds a whole key, so it can sit anywhere below the point where its key stops sharing a prefix.
+type leaf struct {
+	key   []byte
+	value containers.Value
+}
+
+// header is shared by inner nodes.
+type header struct {
+	prefix []byte // compressed path, the bytes of keys between the parent's byte and this node's children
+	end    *leaf  // the key ending at this node, if any
+	n      int    // number of children
+}
+
+// innerNode is a node with children indexed by the next byte of the key.
+// Layouts grow with the number of children: node4, node16, node48 and node256.
+type innerNode interface {
+	header() *header
+	// child returns the slot of the child for b, or nil if there is none.
+	child(b byte) *node
+	full() bool
+	// addChild adds a child for b, which must not be there. The node must not be full.
+	addChild(b byte, child node)
+	// removeChild removes the child for b, which must be there.
+	removeChild(b byte)
+	// grow returns a copy of the node with a bigger layout.
+	grow() innerNode
+	// shrink returns a copy of the node with a smaller layout if it has few children, or the node itself.
+	shrink() innerNode
+	// each calls fn on children in order of their bytes, until fn returns false. It returns false if fn did.
+	each(fn func(b byte, child node) bool) bool
+}
+
+// node4 and node16 keep sorted keys next to their children.
+type node4 struct {
+	h        header
+	keys     [4]byte
+	children [4]node
+}
+
+func (n *node4) header() *header { return &n.h }
+
+func (n *node4) full() bool { return n.h.n == len(n.keys) }
+
+func (n *node4) child(b byte) *node {
+	for i := 0; i < n.h.n; i++ {
+		if n.keys[i] == b {
+			return &n.children[i]
+		}
+	}
+
+	return nil
+}
+
+func (n *node4) addChild(b byte, child node) {
+	n.h.n = insertSorted(n.keys[:], n.children[:], n.h.n, b, child)
+}
+
+func (n *node4) removeChild(b byte) {
+	n.h.n = removeSorted(n.keys[:], n.children[:], n.h.n, b)
+}
+
+func (n *node4) grow() innerNode {
+	bigger := &node16{h: n.h}
+	copy(bigger.keys[:], n.keys[:])
+	copy(bigger.children[:], n.children[:])
+	return bigger
+}
+
+func (n *node4) shrink() innerNode { return n }
+
+func (n *node4) each(fn func(b byte, child node) bool) bool {
+	for i := 0; i < n.h.n; i++ {
+		if !fn(n.keys[i], n.children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type node16 struct {
+	h        header
+	keys     [16]byte
+	children [16]node
+}
+
+func (n *node16) header() *header { return &n.h }
+
+func (n *node16) full() bool { return n.h.n == len(n.keys) }
+
+func (n *node16) child(b byte) *node {
+	// binary search, as there is no SIMD to compare all keys at once
+	lo, hi := 0, n.h.n
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case n.keys[mid] < b:
+			lo = mid + 1
+		case n.keys[mid] > b:
+			hi = mid
+		default:
+			return &n.children[mid]
+		}
+	}
+
+	return nil
+}
+
+func (n *node16) addChild(b byte, child node) {
+	n.h.n = insertSorted(n.keys[:], n.children[:], n.h.n, b, child)
+}
+
+func (n *node16) removeChild(b byte) {
+	n.h.n = removeSorted(n.keys[:], n.children[:], n.h.n, b)
+}
+
+func (n *node16) grow() innerNode {
+	bigger := &node48{h: n.h}
+	for i := 0; i < n.h.n; i++ {
+		bigger.index[n.keys[i]] = byte(i + 1)
+		bigger.children[i] = n.children[i]
+	}
+	return bigger
+}
+
+func (n *node16) shrink() innerNode {
+	if n.h.n > 3 {
+		return n
+	}
+
+	smaller := &node4{h: n.h}
+	copy(smaller.keys[:], n.keys[:n.h.n])
+	copy(smaller.children[:], n.children[:n.h.n])
+	return smaller
+}
+
+func (n *node16) each(fn func(b byte, child node) bool) bool {
+	for i := 0; i < n.h.n; i++ {
+		if !fn(n.keys[i], n.children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// insertSorted inserts b and child at their position among the first n keys, and returns the new count.
+func insertSorted(keys []byte, children []node, n int, b byte, child node) int {
+	i := 0
+	for i < n && keys[i] < b {
+		i++
+	}
+	copy(keys[i+1:n+1], keys[i:n])
+	copy(children[i+1:n+1], children[i:n])
+	keys[i], children[i] = b, child
+
+	return n + 1
+}
+
+// removeSorted removes b and its child from the first n keys, and returns the new count.
+func removeSorted(keys []byte, children []node, n int, b byte) int {
+	i := 0
+	for keys[i] != b {
+		i++
+	}
+	copy(keys[i:], keys[i+1:n])
+	copy(children[i:], children[i+1:n])
+	children[n-1] = nil
+
+	return n - 1
+}
+
+// node48 maps each byte to a slot in children, where 0 means no child and i means children[i-1].
+type node48 struct {
+	h        header
+	index    [256]byte
+	children [48]node
+}
+
+func (n *node48) header() *header { return &n.h }
+
+func (n *node48) full() bool { return n.h.n == len(n.children) }
+
+func (n *node48) child(b byte) *node {
+	if i := n.index[b]; i > 0 {
+		return &n.children[i-1]
+	}
+
+	return nil
+}
+
+func (n *node48) addChild(b byte, child node) {
+	i := 0
+	for n.children[i] != nil {
+		i++
+	}
+	n.children[i] = child
+	n.index[b] = byte(i + 1)
+	n.h.n++
+}
+
+func (n *node48) removeChild(b byte) {
+	n.children[n.index[b]-1] = nil
+	n.index[b] = 0
+	n.h.n--
+}
+
+func (n *node48) grow() innerNode {
+	bigger := &node256{h: n.h}
+	for b, i := range n.index {
+		if i > 0 {
+			bigger.children[b] = n.children[i-1]
+		}
+	}
+	return bigger
+}
+
+func (n *node48) shrink() innerNode {
+	if n.h.n > 12 {
+		return n
+	}
+
+	smaller := &node16{h: n.h}
+	j := 0
+	for b, i := range n.index {
+		if i > 0 {
+			smaller.keys[j], smaller.children[j] = byte(b), n.children[i-1]
+			j++
+		}
+	}
+	return smaller
+}
+
+func (n *node48) each(fn func(b byte, child node) bool) bool {
+	for b, i := range n.index {
+		if i > 0 && !fn(byte(b), n.children[i-1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// node256 has a slot for every byte.
+type node256 struct {
+	h        header
+	children [256]node
+}
+
+func (n *node256) header() *header { return &n.h }
+
+func (n *node256) full() bool { return false }
+
+func (n *node256) child(b byte) *node {
+	if n.children[b] == nil {
+		return nil
+	}
+
+	return &n.children[b]
+}
+
+func (n *node256) addChild(b byte, child node) {
+	n.children[b] = child
+	n.h.n++
+}
+
+func (n *node256) removeChild(b byte) {
+	n.children[b] = nil
+	n.h.n--
+}
+
+func (n *node256) grow() innerNode { return n }
+
+func (n *node256) shrink() innerNode {
+	if n.h.n > 36 {
+		return n
+	}
+
+	smaller := &node48{h: n.h}
+	smaller.h.n = 0 // counted again by addChild
+	for b, child := range n.children {
+		if child != nil {
+			smaller.addChild(byte(b), child)
+		}
+	}
+	return smaller
+}
+
+func (n *node256) each(fn func(b byte, child node) bool) bool {
+	for b, child := range n.children {
+		if child != nil && !fn(byte(b), child) {
+			return false
+		}
+	}
+
+	return true
+}
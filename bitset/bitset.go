@@ -0,0 +1,222 @@
+// Package bitset provides sets of integers: a dense bitset, and a compressed roaring bitmap for sparse
+// or clustered values.
+package bitset
+
+import (
+	"math/bits"
+)
+
+// bitSet is a dense set of non-negative integers, one bit per integer up to the largest one.
+// It grows as needed. It is not safe for concurrent use.
+type bitSet struct {
+	words []uint64
+}
+
+// New returns an empty bitset with room for integers in [0, n).
+func New(n uint) *bitSet {
+	return &bitSet{words: make([]uint64, (n+63)/64)}
+}
+
+func wordOf(i uint) (uint, uint64) {
+	return i / 64, 1 << (i % 64)
+}
+
+// grow makes room for the word w.
+func (s *bitSet) grow(w uint) {
+	if w < uint(len(s.words)) {
+		return
+	}
+
+	size := 2 * len(s.words)
+	if size <= int(w) {
+		size = int(w) + 1
+	}
+	words := make([]uint64, size)
+	copy(words, s.words)
+	s.words = words
+}
+
+// Set adds i to the set.
+func (s *bitSet) Set(i uint) {
+	w, mask := wordOf(i)
+	s.grow(w)
+	s.words[w] |= mask
+}
+
+// Clear removes i from the set.
+func (s *bitSet) Clear(i uint) {
+	if w, mask := wordOf(i); w < uint(len(s.words)) {
+		s.words[w] &^= mask
+	}
+}
+
+// Flip adds i to the set if it is not in it, and removes it otherwise.
+func (s *bitSet) Flip(i uint) {
+	w, mask := wordOf(i)
+	s.grow(w)
+	s.words[w] ^= mask
+}
+
+// Test returns whether i is in the set.
+func (s *bitSet) Test(i uint) bool {
+	w, mask := wordOf(i)
+	return w < uint(len(s.words)) && s.words[w]&mask != 0
+}
+
+// Count returns the number of integers in the set.
+func (s *bitSet) Count() int {
+	count := 0
+	for _, w := range s.words {
+		count += bits.OnesCount64(w)
+	}
+
+	return count
+}
+
+// Rank returns the number of integers in the set which are less than i.
+func (s *bitSet) Rank(i uint) int {
+	w, mask := wordOf(i)
+	if w >= uint(len(s.words)) {
+		return s.Count()
+	}
+
+	rank := bits.OnesCount64(s.words[w] & (mask - 1))
+	for _, word := range s.words[:w] {
+		rank += bits.OnesCount64(word)
+	}
+	return rank
+}
+
+// Select returns the k-th smallest integer in the set, counting from 0, and false if the set has k integers or less.
+func (s *bitSet) Select(k int) (uint, bool) {
+	if k < 0 {
+		return 0, false
+	}
+
+	for w, word := range s.words {
+		count := bits.OnesCount64(word)
+		if k >= count {
+			k -= count
+			continue
+		}
+
+		for ; k > 0; k-- {
+			word &= word - 1 // drop the lowest bit
+		}
+		return uint(w)*64 + uint(bits.TrailingZeros64(word)), true
+	}
+
+	return 0, false
+}
+
+// NextSet returns the smallest integer in the set which is i or more, and false if there is none.
+func (s *bitSet) NextSet(i uint) (uint, bool) {
+	w, _ := wordOf(i)
+	if w >= uint(len(s.words)) {
+		return 0, false
+	}
+
+	word := s.words[w] >> (i % 64) << (i % 64)
+	for {
+		if word != 0 {
+			return w*64 + uint(bits.TrailingZeros64(word)), true
+		}
+		w++
+		if w == uint(len(s.words)) {
+			return 0, false
+		}
+		word = s.words[w]
+	}
+}
+
+// ForEach calls f with the integers of the set in ascending order, until it returns false.
+func (s *bitSet) ForEach(f func(i uint) bool) {
+	for w, word := range s.words {
+		for word != 0 {
+			if !f(uint(w)*64 + uint(bits.TrailingZeros64(word))) {
+				return
+			}
+			word &= word - 1
+		}
+	}
+}
+
+// Clone returns a copy of the set.
+func (s *bitSet) Clone() *bitSet {
+	return &bitSet{words: append([]uint64{}, s.words...)}
+}
+
+// Equal returns whether both sets have the same integers.
+func (s *bitSet) Equal(other *bitSet) bool {
+	short, long := s.words, other.words
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+
+	for i, w := range short {
+		if w != long[i] {
+			return false
+		}
+	}
+	for _, w := range long[len(short):] {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// And returns the integers in both sets.
+func (s *bitSet) And(other *bitSet) *bitSet {
+	n := len(s.words)
+	if len(other.words) < n {
+		n = len(other.words)
+	}
+
+	result := &bitSet{words: make([]uint64, n)}
+	for i := range result.words {
+		result.words[i] = s.words[i] & other.words[i]
+	}
+	return result
+}
+
+// Or returns the integers in either set.
+func (s *bitSet) Or(other *bitSet) *bitSet {
+	return s.combine(other, func(a, b uint64) uint64 { return a | b })
+}
+
+// Xor returns the integers in exactly one of the sets.
+func (s *bitSet) Xor(other *bitSet) *bitSet {
+	return s.combine(other, func(a, b uint64) uint64 { return a ^ b })
+}
+
+// AndNot returns the integers in s which are not in other.
+func (s *bitSet) AndNot(other *bitSet) *bitSet {
+	result := s.Clone()
+	for i := 0; i < len(result.words) && i < len(other.words); i++ {
+		result.words[i] &^= other.words[i]
+	}
+
+	return result
+}
+
+// combine returns op of the words of both sets, where missing words are 0.
+func (s *bitSet) combine(other *bitSet, op func(a, b uint64) uint64) *bitSet {
+	n := len(s.words)
+	if len(other.words) > n {
+		n = len(other.words)
+	}
+
+	result := &bitSet{words: make([]uint64, n)}
+	for i := range result.words {
+		var a, b uint64
+		if i < len(s.words) {
+			a = s.words[i]
+		}
+		if i < len(other.words) {
+			b = other.words[i]
+		}
+		result.words[i] = op(a, b)
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package bitset
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// model is a set of integers to check bitsets against.
+type model map[uint]bool
+
+func (m model) sorted() []uint {
+	values := []uint{}
+	for v, ok := range m {
+		if ok {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	return values
+}
+
+func valuesOf(s *bitSet) []uint {
+	values := []uint{}
+	s.ForEach(func(i uint) bool {
+		values = append(values, i)
+		return true
+	})
+
+	return values
+}
+
+func TestBitSetOps(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := New(0)
+	m := model{}
+	for op := 0; op < 5000; op++ {
+		i := uint(rng.Intn(1000))
+		switch rng.Intn(3) {
+		case 0:
+			s.Set(i)
+			m[i] = true
+		case 1:
+			s.Clear(i)
+			m[i] = false
+		default:
+			s.Flip(i)
+			m[i] = !m[i]
+		}
+	}
+	s.Clear(100000) // beyond the words, a no-op
+
+	want := m.sorted()
+	if got := valuesOf(s); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if got := s.Count(); got != len(want) {
+		t.Fatalf("count: want= %v, got= %v", len(want), got)
+	}
+	for i := uint(0); i < 1100; i++ {
+		if s.Test(i) != m[i] {
+			t.Fatalf("test %d: want= %v, got= %v", i, m[i], s.Test(i))
+		}
+
+		rank := sort.Search(len(want), func(k int) bool { return want[k] >= i })
+		if got := s.Rank(i); got != rank {
+			t.Fatalf("rank %d: want= %v, got= %v", i, rank, got)
+		}
+
+		next, ok := s.NextSet(i)
+		if wantOK := rank < len(want); ok != wantOK || (ok && next != want[rank]) {
+			t.Fatalf("next set %d: want %v, got= %v, %v", i, wantOK, next, ok)
+		}
+	}
+	for k := range want {
+		if got, ok := s.Select(k); !ok || got != want[k] {
+			t.Fatalf("select %d: want= %v, got= %v, %v", k, want[k], got, ok)
+		}
+	}
+	if _, ok := s.Select(len(want)); ok {
+		t.Fatalf("want select beyond count to fail")
+	}
+	if _, ok := s.Select(-1); ok {
+		t.Fatalf("want select of a negative rank to fail")
+	}
+}
+
+func TestBitSetForEachStops(t *testing.T) {
+	s := New(10)
+	for _, i := range []uint{1, 5, 70, 200} {
+		s.Set(i)
+	}
+
+	var got []uint
+	s.ForEach(func(i uint) bool {
+		got = append(got, i)
+		return i < 70
+	})
+	if want := []uint{1, 5, 70}; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func TestBitSetSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	a, b := New(0), New(0)
+	ma, mb := model{}, model{}
+	for i := 0; i < 300; i++ {
+		x, y := uint(rng.Intn(500)), uint(rng.Intn(1000)) // b is longer than a
+		a.Set(x)
+		ma[x] = true
+		b.Set(y)
+		mb[y] = true
+	}
+
+	var testCases = map[string]struct {
+		got  *bitSet
+		keep func(inA, inB bool) bool
+	}{
+		"and":    {got: a.And(b), keep: func(inA, inB bool) bool { return inA && inB }},
+		"or":     {got: a.Or(b), keep: func(inA, inB bool) bool { return inA || inB }},
+		"xor":    {got: a.Xor(b), keep: func(inA, inB bool) bool { return inA != inB }},
+		"andNot": {got: a.AndNot(b), keep: func(inA, inB bool) bool { return inA && !inB }},
+		"notAnd": {got: b.AndNot(a), keep: func(inA, inB bool) bool { return inB && !inA }},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := model{}
+			for i := uint(0); i < 1000; i++ {
+				m[i] = tc.keep(ma[i], mb[i])
+			}
+
+			if want, got := m.sorted(), valuesOf(tc.got); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestBitSetEqualClone(t *testing.T) {
+	a := New(0)
+	a.Set(3)
+	b := New(1000) // longer, with zero words
+	b.Set(3)
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Fatalf("want sets with the same integers equal")
+	}
+
+	c := a.Clone()
+	c.Set(4)
+	if a.Equal(c) || a.Test(4) {
+		t.Fatalf("want clone independent")
+	}
+	b.Set(999)
+	if a.Equal(b) || b.Equal(a) {
+		t.Fatalf("want sets with different integers not equal")
+	}
+}
+
+// BenchmarkBitSetVersusMap compares a dense bitset to a map used as a set of ints.
+func BenchmarkBitSetVersusMap(b *testing.B) {
+	const n = 1 << 20
+	b.Run("bitset/set", func(b *testing.B) {
+		s := New(n)
+		for i := 0; i < b.N; i++ {
+			s.Set(uint(i % n))
+		}
+	})
+	b.Run("map/set", func(b *testing.B) {
+		m := map[int]struct{}{}
+		for i := 0; i < b.N; i++ {
+			m[i%n] = struct{}{}
+		}
+	})
+
+	s := New(n)
+	m := map[int]struct{}{}
+	for i := 0; i < n; i += 3 {
+		s.Set(uint(i))
+		m[i] = struct{}{}
+	}
+	b.Run("bitset/test", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.Test(uint(i % n))
+		}
+	})
+	b.Run("map/test", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = m[i%n]
+		}
+	})
+}
@@ -0,0 +1,521 @@
+package bitset
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	arrayMaxSize = 4096         // containers with more values are bitmaps, unless they are runs
+	bitmapWords  = 1 << 16 / 64 // words of a bitmap container
+)
+
+// container holds the low 16 bits of the values of a roaring bitmap which share their high 16 bits.
+// Updates return the container to use instead, which may be of another kind.
+// Containers which are not runs must be arrays up to arrayMaxSize values and bitmaps above,
+// as their serialization tells them apart by cardinality.
+type container interface {
+	cardinality() int
+	contains(x uint16) bool
+	add(x uint16) container
+	remove(x uint16) container
+	// rank returns the number of values less than x.
+	rank(x uint16) int
+	// selectAt returns the k-th smallest value, k must be less than the cardinality.
+	selectAt(k int) uint16
+	// forEach calls f with high | each value until it returns false, and returns false if f did.
+	forEach(high uint32, f func(x uint32) bool) bool
+	// toBitmap returns the values in a new bitmap container.
+	toBitmap() *bitmapContainer
+	// numRuns returns the number of runs of consecutive values.
+	numRuns() int
+	clone() container
+}
+
+// arrayContainer holds few values, sorted.
+type arrayContainer struct {
+	values []uint16
+}
+
+func (c *arrayContainer) cardinality() int {
+	return len(c.values)
+}
+
+// search returns the index of the first value which is x or more.
+func (c *arrayContainer) search(x uint16) int {
+	return sort.Search(len(c.values), func(i int) bool { return c.values[i] >= x })
+}
+
+func (c *arrayContainer) contains(x uint16) bool {
+	i := c.search(x)
+	return i < len(c.values) && c.values[i] == x
+}
+
+func (c *arrayContainer) add(x uint16) container {
+	i := c.search(x)
+	if i < len(c.values) && c.values[i] == x {
+		return c
+	}
+	if len(c.values) == arrayMaxSize {
+		b := c.toBitmap()
+		b.add(x)
+		return b
+	}
+
+	c.values = append(c.values, 0)
+	copy(c.values[i+1:], c.values[i:])
+	c.values[i] = x
+	return c
+}
+
+func (c *arrayContainer) remove(x uint16) container {
+	if i := c.search(x); i < len(c.values) && c.values[i] == x {
+		c.values = append(c.values[:i], c.values[i+1:]...)
+	}
+
+	return c
+}
+
+func (c *arrayContainer) rank(x uint16) int {
+	return c.search(x)
+}
+
+func (c *arrayContainer) selectAt(k int) uint16 {
+	return c.values[k]
+}
+
+func (c *arrayContainer) forEach(high uint32, f func(x uint32) bool) bool {
+	for _, v := range c.values {
+		if !f(high | uint32(v)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *arrayContainer) toBitmap() *bitmapContainer {
+	b := &bitmapContainer{}
+	for _, v := range c.values {
+		b.words[v/64] |= 1 << (v % 64)
+	}
+	b.card = len(c.values)
+	return b
+}
+
+func (c *arrayContainer) numRuns() int {
+	runs := 0
+	for i, v := range c.values {
+		if i == 0 || c.values[i-1]+1 != v {
+			runs++
+		}
+	}
+
+	return runs
+}
+
+func (c *arrayContainer) clone() container {
+	return &arrayContainer{values: append([]uint16{}, c.values...)}
+}
+
+// bitmapContainer holds many values, one bit per value.
+type bitmapContainer struct {
+	words [bitmapWords]uint64
+	card  int
+}
+
+func (c *bitmapContainer) cardinality() int {
+	return c.card
+}
+
+func (c *bitmapContainer) contains(x uint16) bool {
+	return c.words[x/64]&(1<<(x%64)) != 0
+}
+
+func (c *bitmapContainer) add(x uint16) container {
+	if !c.contains(x) {
+		c.words[x/64] |= 1 << (x % 64)
+		c.card++
+	}
+
+	return c
+}
+
+func (c *bitmapContainer) remove(x uint16) container {
+	if c.contains(x) {
+		c.words[x/64] &^= 1 << (x % 64)
+		c.card--
+	}
+
+	return c.normalize()
+}
+
+// normalize returns an array container if there are few enough values.
+func (c *bitmapContainer) normalize() container {
+	if c.card > arrayMaxSize {
+		return c
+	}
+
+	a := &arrayContainer{values: make([]uint16, 0, c.card)}
+	c.forEach(0, func(x uint32) bool {
+		a.values = append(a.values, uint16(x))
+		return true
+	})
+	return a
+}
+
+// recount updates the cardinality after changing words.
+func (c *bitmapContainer) recount() {
+	c.card = 0
+	for _, w := range c.words {
+		c.card += bits.OnesCount64(w)
+	}
+}
+
+func (c *bitmapContainer) rank(x uint16) int {
+	rank := bits.OnesCount64(c.words[x/64] & (1<<(x%64) - 1))
+	for _, w := range c.words[:x/64] {
+		rank += bits.OnesCount64(w)
+	}
+
+	return rank
+}
+
+func (c *bitmapContainer) selectAt(k int) uint16 {
+	for i, w := range c.words {
+		count := bits.OnesCount64(w)
+		if k >= count {
+			k -= count
+			continue
+		}
+
+		for ; k > 0; k-- {
+			w &= w - 1
+		}
+		return uint16(i*64 + bits.TrailingZeros64(w))
+	}
+
+	panic("select out of range")
+}
+
+func (c *bitmapContainer) forEach(high uint32, f func(x uint32) bool) bool {
+	for i, w := range c.words {
+		for w != 0 {
+			if !f(high | uint32(i*64+bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+
+	return true
+}
+
+func (c *bitmapContainer) toBitmap() *bitmapContainer {
+	b := *c
+	return &b
+}
+
+func (c *bitmapContainer) numRuns() int {
+	runs := 0
+	var carry uint64 // the last bit of the previous word
+	for _, w := range c.words {
+		runs += bits.OnesCount64(w &^ (w<<1 | carry)) // bits set whose previous bit is not
+		carry = w >> 63
+	}
+
+	return runs
+}
+
+func (c *bitmapContainer) clone() container {
+	return c.toBitmap()
+}
+
+// setRange adds the values in [lo, hi].
+func (c *bitmapContainer) setRange(lo, hi uint16) {
+	for w := lo / 64; w <= hi/64; w++ {
+		mask := ^uint64(0)
+		if w == lo/64 {
+			mask &= ^uint64(0) << (lo % 64)
+		}
+		if w == hi/64 {
+			mask &= ^uint64(0) >> (63 - hi%64)
+		}
+		c.words[w] |= mask
+	}
+	c.recount()
+}
+
+// interval is a run of consecutive values [start, last].
+type interval struct {
+	start, last uint16
+}
+
+// runContainer holds runs of consecutive values, sorted and not overlapping.
+type runContainer struct {
+	runs []interval
+}
+
+func (c *runContainer) cardinality() int {
+	card := 0
+	for _, r := range c.runs {
+		card += int(r.last-r.start) + 1
+	}
+
+	return card
+}
+
+// search returns the index of the first run which ends at x or after.
+func (c *runContainer) search(x uint16) int {
+	return sort.Search(len(c.runs), func(i int) bool { return c.runs[i].last >= x })
+}
+
+func (c *runContainer) contains(x uint16) bool {
+	i := c.search(x)
+	return i < len(c.runs) && c.runs[i].start <= x
+}
+
+func (c *runContainer) add(x uint16) container {
+	return c.addRange(x, x)
+}
+
+// addRange adds the values in [lo, hi], merging the runs it overlaps or touches.
+func (c *runContainer) addRange(lo, hi uint16) container {
+	// runs before i end before lo - 1, runs from j start after hi + 1
+	i := sort.Search(len(c.runs), func(i int) bool { return uint32(c.runs[i].last)+1 >= uint32(lo) })
+	j := sort.Search(len(c.runs), func(j int) bool { return uint32(c.runs[j].start) > uint32(hi)+1 })
+
+	merged := interval{start: lo, last: hi}
+	if i < j {
+		if c.runs[i].start < lo {
+			merged.start = c.runs[i].start
+		}
+		if c.runs[j-1].last > hi {
+			merged.last = c.runs[j-1].last
+		}
+	}
+
+	runs := make([]interval, 0, len(c.runs)-(j-i)+1)
+	runs = append(runs, c.runs[:i]...)
+	runs = append(runs, merged)
+	c.runs = append(runs, c.runs[j:]...)
+	return c
+}
+
+func (c *runContainer) remove(x uint16) container {
+	i := c.search(x)
+	if i == len(c.runs) || c.runs[i].start > x {
+		return c
+	}
+
+	switch r := c.runs[i]; {
+	case r.start == r.last:
+		c.runs = append(c.runs[:i], c.runs[i+1:]...)
+	case x == r.start:
+		c.runs[i].start++
+	case x == r.last:
+		c.runs[i].last--
+	default: // split the run
+		c.runs = append(c.runs, interval{})
+		copy(c.runs[i+1:], c.runs[i:])
+		c.runs[i].last = x - 1
+		c.runs[i+1].start = x + 1
+	}
+	return c
+}
+
+func (c *runContainer) rank(x uint16) int {
+	rank := 0
+	for _, r := range c.runs {
+		if r.start >= x {
+			break
+		}
+		if r.last >= x {
+			return rank + int(x-r.start)
+		}
+		rank += int(r.last-r.start) + 1
+	}
+
+	return rank
+}
+
+func (c *runContainer) selectAt(k int) uint16 {
+	for _, r := range c.runs {
+		if size := int(r.last-r.start) + 1; k >= size {
+			k -= size
+			continue
+		}
+
+		return r.start + uint16(k)
+	}
+
+	panic("select out of range")
+}
+
+func (c *runContainer) forEach(high uint32, f func(x uint32) bool) bool {
+	for _, r := range c.runs {
+		for x := uint32(r.start); x <= uint32(r.last); x++ {
+			if !f(high | x) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c *runContainer) toBitmap() *bitmapContainer {
+	b := &bitmapContainer{}
+	for _, r := range c.runs {
+		b.setRange(r.start, r.last)
+	}
+
+	return b
+}
+
+func (c *runContainer) numRuns() int {
+	return len(c.runs)
+}
+
+func (c *runContainer) clone() container {
+	return &runContainer{runs: append([]interval{}, c.runs...)}
+}
+
+// serialized sizes of containers in bytes, to pick the smallest kind
+func arraySize(card int) int { return 2 * card }
+
+func runSize(runs int) int { return 2 + 4*runs }
+
+const bitmapSize = 8 * bitmapWords
+
+// optimize returns the smallest kind of container for the values of c.
+func optimize(c container) container {
+	card, runs := c.cardinality(), c.numRuns()
+	smallest := bitmapSize
+	if card <= arrayMaxSize {
+		smallest = arraySize(card)
+	}
+	if runSize(runs) < smallest {
+		if r, ok := c.(*runContainer); ok {
+			return r
+		}
+		r := &runContainer{runs: make([]interval, 0, runs)}
+		c.forEach(0, func(x uint32) bool {
+			if n := len(r.runs); n > 0 && uint32(r.runs[n-1].last)+1 == x {
+				r.runs[n-1].last++
+			} else {
+				r.runs = append(r.runs, interval{start: uint16(x), last: uint16(x)})
+			}
+			return true
+		})
+		return r
+	}
+
+	if _, ok := c.(*runContainer); ok {
+		return c.toBitmap().normalize()
+	}
+	return c
+}
+
+// and returns the values in both containers, which may be nil if there are none.
+func and(a, b container) container {
+	if x, ok := a.(*arrayContainer); ok {
+		return x.filter(b, true)
+	}
+	if y, ok := b.(*arrayContainer); ok {
+		return y.filter(a, true)
+	}
+
+	return a.toBitmap().combine(b.toBitmap(), func(x, y uint64) uint64 { return x & y })
+}
+
+// or returns the values in either container.
+func or(a, b container) container {
+	x, xArray := a.(*arrayContainer)
+	y, yArray := b.(*arrayContainer)
+	if xArray && yArray && x.cardinality()+y.cardinality() <= arrayMaxSize {
+		return x.merge(y, func(inX, inY bool) bool { return true })
+	}
+
+	return a.toBitmap().combine(b.toBitmap(), func(x, y uint64) uint64 { return x | y })
+}
+
+// xor returns the values in exactly one of the containers, which may be nil if there are none.
+func xor(a, b container) container {
+	x, xArray := a.(*arrayContainer)
+	y, yArray := b.(*arrayContainer)
+	if xArray && yArray && x.cardinality()+y.cardinality() <= arrayMaxSize {
+		return x.merge(y, func(inX, inY bool) bool { return inX != inY })
+	}
+
+	return a.toBitmap().combine(b.toBitmap(), func(x, y uint64) uint64 { return x ^ y })
+}
+
+// andNot returns the values in a which are not in b, which may be nil if there are none.
+func andNot(a, b container) container {
+	if x, ok := a.(*arrayContainer); ok {
+		return x.filter(b, false)
+	}
+
+	return a.toBitmap().combine(b.toBitmap(), func(x, y uint64) uint64 { return x &^ y })
+}
+
+// filter returns the values of c which are in other if keep, or not in other otherwise.
+func (c *arrayContainer) filter(other container, keep bool) container {
+	result := &arrayContainer{}
+	for _, v := range c.values {
+		if other.contains(v) == keep {
+			result.values = append(result.values, v)
+		}
+	}
+
+	if len(result.values) == 0 {
+		return nil
+	}
+	return result
+}
+
+// merge returns the values of both arrays for which keep is true. The result must fit in an array.
+func (c *arrayContainer) merge(other *arrayContainer, keep func(inC, inOther bool) bool) container {
+	result := &arrayContainer{}
+	i, j := 0, 0
+	for i < len(c.values) || j < len(other.values) {
+		switch {
+		case j == len(other.values) || (i < len(c.values) && c.values[i] < other.values[j]):
+			if keep(true, false) {
+				result.values = append(result.values, c.values[i])
+			}
+			i++
+		case i == len(c.values) || other.values[j] < c.values[i]:
+			if keep(false, true) {
+				result.values = append(result.values, other.values[j])
+			}
+			j++
+		default:
+			if keep(true, true) {
+				result.values = append(result.values, c.values[i])
+			}
+			i++
+			j++
+		}
+	}
+
+	if len(result.values) == 0 {
+		return nil
+	}
+	return result
+}
+
+// combine returns op of the words of both bitmaps, which may be nil if there are no values.
+func (c *bitmapContainer) combine(other *bitmapContainer, op func(x, y uint64) uint64) container {
+	result := &bitmapContainer{}
+	for i := range result.words {
+		result.words[i] = op(c.words[i], other.words[i])
+	}
+	result.recount()
+
+	if result.card == 0 {
+		return nil
+	}
+	return result.normalize()
+}
@@ -0,0 +1,480 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// roaring is a compressed set of uint32 ("Better bitmap performance with Roaring bitmaps" - Chambi et al. 2016).
+// Values are grouped by their high 16 bits, and the low 16 bits of each group are kept in the smallest of
+// a sorted array, a bitmap or a list of runs. It is not safe for concurrent use.
+type roaring struct {
+	keys       []uint16 // sorted high 16 bits of the values
+	containers []container
+}
+
+// NewRoaring returns an empty roaring bitmap.
+func NewRoaring() *roaring {
+	return &roaring{}
+}
+
+func split(x uint32) (uint16, uint16) {
+	return uint16(x >> 16), uint16(x)
+}
+
+// search returns the index of the first key which is key or more.
+func (r *roaring) search(key uint16) int {
+	return sort.Search(len(r.keys), func(i int) bool { return r.keys[i] >= key })
+}
+
+// container returns the container of key, or nil.
+func (r *roaring) container(key uint16) container {
+	if i := r.search(key); i < len(r.keys) && r.keys[i] == key {
+		return r.containers[i]
+	}
+
+	return nil
+}
+
+// update replaces the container of key with update(the current container or nil),
+// and removes it if the result has no values.
+func (r *roaring) update(key uint16, update func(c container) container) {
+	i := r.search(key)
+	if i < len(r.keys) && r.keys[i] == key {
+		if c := update(r.containers[i]); c != nil && c.cardinality() > 0 {
+			r.containers[i] = c
+		} else {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			r.containers = append(r.containers[:i], r.containers[i+1:]...)
+		}
+		return
+	}
+
+	c := update(nil)
+	if c == nil || c.cardinality() == 0 {
+		return
+	}
+	r.keys = append(r.keys, 0)
+	copy(r.keys[i+1:], r.keys[i:])
+	r.keys[i] = key
+	r.containers = append(r.containers, nil)
+	copy(r.containers[i+1:], r.containers[i:])
+	r.containers[i] = c
+}
+
+// Add adds x to the set.
+func (r *roaring) Add(x uint32) {
+	high, low := split(x)
+	r.update(high, func(c container) container {
+		if c == nil {
+			return &arrayContainer{values: []uint16{low}}
+		}
+		return c.add(low)
+	})
+}
+
+// AddRange adds all values in [lo, hi].
+func (r *roaring) AddRange(lo, hi uint32) {
+	if lo > hi {
+		return
+	}
+
+	loHigh, loLow := split(lo)
+	hiHigh, hiLow := split(hi)
+	for high := uint32(loHigh); high <= uint32(hiHigh); high++ {
+		start, last := uint16(0), uint16(0xffff)
+		if high == uint32(loHigh) {
+			start = loLow
+		}
+		if high == uint32(hiHigh) {
+			last = hiLow
+		}
+
+		r.update(uint16(high), func(c container) container {
+			switch c := c.(type) {
+			case nil:
+				return &runContainer{runs: []interval{{start: start, last: last}}}
+			case *runContainer:
+				return c.addRange(start, last)
+			default:
+				b := c.toBitmap()
+				b.setRange(start, last)
+				return b.normalize()
+			}
+		})
+	}
+}
+
+// Remove removes x from the set.
+func (r *roaring) Remove(x uint32) {
+	high, low := split(x)
+	if r.container(high) == nil {
+		return
+	}
+
+	r.update(high, func(c container) container { return c.remove(low) })
+}
+
+// Contains returns whether x is in the set.
+func (r *roaring) Contains(x uint32) bool {
+	high, low := split(x)
+	c := r.container(high)
+	return c != nil && c.contains(low)
+}
+
+// Count returns the number of values in the set.
+func (r *roaring) Count() int {
+	count := 0
+	for _, c := range r.containers {
+		count += c.cardinality()
+	}
+
+	return count
+}
+
+// Rank returns the number of values in the set which are less than x.
+func (r *roaring) Rank(x uint32) int {
+	high, low := split(x)
+	rank := 0
+	for i, key := range r.keys {
+		if key > high {
+			break
+		}
+		if key == high {
+			return rank + r.containers[i].rank(low)
+		}
+		rank += r.containers[i].cardinality()
+	}
+
+	return rank
+}
+
+// Select returns the k-th smallest value in the set, counting from 0, and false if the set has k values or less.
+func (r *roaring) Select(k int) (uint32, bool) {
+	if k < 0 {
+		return 0, false
+	}
+
+	for i, c := range r.containers {
+		if card := c.cardinality(); k >= card {
+			k -= card
+			continue
+		}
+
+		return uint32(r.keys[i])<<16 | uint32(c.selectAt(k)), true
+	}
+	return 0, false
+}
+
+// ForEach calls f with the values of the set in ascending order, until it returns false.
+func (r *roaring) ForEach(f func(x uint32) bool) {
+	for i, c := range r.containers {
+		if !c.forEach(uint32(r.keys[i])<<16, f) {
+			return
+		}
+	}
+}
+
+// ToArray returns the values of the set in ascending order.
+func (r *roaring) ToArray() []uint32 {
+	values := make([]uint32, 0, r.Count())
+	r.ForEach(func(x uint32) bool {
+		values = append(values, x)
+		return true
+	})
+
+	return values
+}
+
+// Clone returns a copy of the set.
+func (r *roaring) Clone() *roaring {
+	clone := &roaring{
+		keys:       append([]uint16{}, r.keys...),
+		containers: make([]container, len(r.containers)),
+	}
+	for i, c := range r.containers {
+		clone.containers[i] = c.clone()
+	}
+
+	return clone
+}
+
+// RunOptimize converts each container to the smallest kind for its values,
+// e.g. runs for long sequences of consecutive values.
+func (r *roaring) RunOptimize() {
+	for i, c := range r.containers {
+		r.containers[i] = optimize(c)
+	}
+}
+
+// And returns the values in both sets.
+func (r *roaring) And(other *roaring) *roaring {
+	return r.combine(other, and, false, false)
+}
+
+// Or returns the values in either set.
+func (r *roaring) Or(other *roaring) *roaring {
+	return r.combine(other, or, true, true)
+}
+
+// Xor returns the values in exactly one of the sets.
+func (r *roaring) Xor(other *roaring) *roaring {
+	return r.combine(other, xor, true, true)
+}
+
+// AndNot returns the values in r which are not in other.
+func (r *roaring) AndNot(other *roaring) *roaring {
+	return r.combine(other, andNot, true, false)
+}
+
+// combine returns the set with op of containers with the same key in both sets,
+// and copies of the containers only in r or other if keepR or keepOther.
+func (r *roaring) combine(other *roaring, op func(a, b container) container, keepR, keepOther bool) *roaring {
+	result := &roaring{}
+	add := func(key uint16, c container) {
+		if c != nil {
+			result.keys = append(result.keys, key)
+			result.containers = append(result.containers, c)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(r.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || (i < len(r.keys) && r.keys[i] < other.keys[j]):
+			if keepR {
+				add(r.keys[i], r.containers[i].clone())
+			}
+			i++
+		case i == len(r.keys) || other.keys[j] < r.keys[i]:
+			if keepOther {
+				add(other.keys[j], other.containers[j].clone())
+			}
+			j++
+		default:
+			add(r.keys[i], op(r.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Cookies of the portable roaring format, telling whether there are run containers.
+const (
+	serialCookieNoRuns = 12346
+	serialCookie       = 12347
+	noOffsetThreshold  = 4 // offsets of containers are omitted below this many containers, if there are runs
+)
+
+// MarshalBinary encodes the set in the portable format shared by roaring implementations
+// (https://github.com/RoaringBitmap/RoaringFormatSpec).
+func (r *roaring) MarshalBinary() ([]byte, error) {
+	n := len(r.keys)
+	hasRuns := false
+	for _, c := range r.containers {
+		if _, ok := c.(*runContainer); ok {
+			hasRuns = true
+		}
+	}
+
+	// the header is a cookie, with a bitset of run containers if any, then the key and cardinality - 1
+	// of each container, then their offsets
+	var data []byte
+	if hasRuns {
+		data = make([]byte, 4+(n+7)/8)
+		binary.LittleEndian.PutUint32(data, serialCookie|uint32(n-1)<<16)
+		for i, c := range r.containers {
+			if _, ok := c.(*runContainer); ok {
+				data[4+i/8] |= 1 << uint(i%8)
+			}
+		}
+	} else {
+		data = make([]byte, 8)
+		binary.LittleEndian.PutUint32(data, serialCookieNoRuns)
+		binary.LittleEndian.PutUint32(data[4:], uint32(n))
+	}
+
+	var buf [8]byte
+	for i, c := range r.containers {
+		binary.LittleEndian.PutUint16(buf[0:], r.keys[i])
+		binary.LittleEndian.PutUint16(buf[2:], uint16(c.cardinality()-1))
+		data = append(data, buf[:4]...)
+	}
+
+	if !hasRuns || n >= noOffsetThreshold {
+		offset := len(data) + 4*n
+		for _, c := range r.containers {
+			binary.LittleEndian.PutUint32(buf[:], uint32(offset))
+			data = append(data, buf[:4]...)
+			offset += serializedSize(c)
+		}
+	}
+
+	for _, c := range r.containers {
+		switch c := c.(type) {
+		case *arrayContainer:
+			for _, v := range c.values {
+				binary.LittleEndian.PutUint16(buf[:], v)
+				data = append(data, buf[:2]...)
+			}
+		case *bitmapContainer:
+			for _, w := range c.words {
+				binary.LittleEndian.PutUint64(buf[:], w)
+				data = append(data, buf[:]...)
+			}
+		case *runContainer:
+			binary.LittleEndian.PutUint16(buf[:], uint16(len(c.runs)))
+			data = append(data, buf[:2]...)
+			for _, run := range c.runs {
+				binary.LittleEndian.PutUint16(buf[0:], run.start)
+				binary.LittleEndian.PutUint16(buf[2:], run.last-run.start)
+				data = append(data, buf[:4]...)
+			}
+		}
+	}
+	return data, nil
+}
+
+func serializedSize(c container) int {
+	switch c := c.(type) {
+	case *arrayContainer:
+		return arraySize(len(c.values))
+	case *runContainer:
+		return runSize(len(c.runs))
+	default:
+		return bitmapSize
+	}
+}
+
+// UnmarshalBinary replaces the set with one encoded by MarshalBinary, or another roaring implementation.
+func (r *roaring) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return errors.Errorf("data is too short: %d bytes", len(data))
+	}
+
+	cookie := binary.LittleEndian.Uint32(data)
+	var n int
+	var runFlags []byte
+	switch {
+	case cookie&0xffff == serialCookie:
+		n = int(cookie>>16) + 1
+		if len(data) < 4+(n+7)/8 {
+			return errors.New("cannot read run container flags")
+		}
+		runFlags = data[4 : 4+(n+7)/8]
+		data = data[4+(n+7)/8:]
+	case cookie == serialCookieNoRuns:
+		if len(data) < 8 {
+			return errors.New("cannot read number of containers")
+		}
+		n = int(binary.LittleEndian.Uint32(data[4:]))
+		data = data[8:]
+	default:
+		return errors.Errorf("unknown cookie %d", cookie)
+	}
+	if n > 1<<16 || len(data) < 4*n {
+		return errors.Errorf("cannot read headers of %d containers", n)
+	}
+
+	headers := data[:4*n]
+	data = data[4*n:]
+	if runFlags == nil || n >= noOffsetThreshold {
+		if len(data) < 4*n {
+			return errors.Errorf("cannot read offsets of %d containers", n)
+		}
+		data = data[4*n:] // containers are contiguous, so offsets are not needed
+	}
+
+	decoded := &roaring{keys: make([]uint16, n), containers: make([]container, n)}
+	for i := 0; i < n; i++ {
+		key, card := binary.LittleEndian.Uint16(headers[4*i:]), int(binary.LittleEndian.Uint16(headers[4*i+2:]))+1
+		if i > 0 && key <= decoded.keys[i-1] {
+			return errors.Errorf("container keys are not sorted: %d after %d", key, decoded.keys[i-1])
+		}
+
+		var c container
+		var err error
+		switch {
+		case runFlags != nil && runFlags[i/8]&(1<<uint(i%8)) != 0:
+			c, data, err = readRuns(data)
+		case card <= arrayMaxSize:
+			c, data, err = readArray(data, card)
+		default:
+			c, data, err = readBitmap(data)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "cannot read container %d", key)
+		}
+		if c.cardinality() != card {
+			return errors.Errorf("container %d: want %d values, got %d", key, card, c.cardinality())
+		}
+		decoded.keys[i], decoded.containers[i] = key, c
+	}
+	if len(data) != 0 {
+		return errors.Errorf("%d bytes left after containers", len(data))
+	}
+
+	*r = *decoded
+	return nil
+}
+
+func readArray(data []byte, card int) (container, []byte, error) {
+	if len(data) < arraySize(card) {
+		return nil, nil, errors.New("array is too short")
+	}
+
+	c := &arrayContainer{values: make([]uint16, card)}
+	for i := range c.values {
+		c.values[i] = binary.LittleEndian.Uint16(data[2*i:])
+		if i > 0 && c.values[i] <= c.values[i-1] {
+			return nil, nil, errors.New("array values are not sorted")
+		}
+	}
+	return c, data[arraySize(card):], nil
+}
+
+func readBitmap(data []byte) (container, []byte, error) {
+	if len(data) < bitmapSize {
+		return nil, nil, errors.New("bitmap is too short")
+	}
+
+	c := &bitmapContainer{}
+	for i := range c.words {
+		c.words[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+	c.recount()
+	return c, data[bitmapSize:], nil
+}
+
+func readRuns(data []byte) (container, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("cannot read number of runs")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	if len(data) < runSize(n) {
+		return nil, nil, errors.New("runs are too short")
+	}
+
+	c := &runContainer{runs: make([]interval, n)}
+	for i := range c.runs {
+		start, length := binary.LittleEndian.Uint16(data[2+4*i:]), binary.LittleEndian.Uint16(data[4+4*i:])
+		if uint32(start)+uint32(length) > 0xffff || (i > 0 && start <= c.runs[i-1].last) {
+			return nil, nil, errors.New("runs are not sorted")
+		}
+		c.runs[i] = interval{start: start, last: start + length}
+	}
+	return c, data[runSize(n):], nil
+}
+
+// RoaringFromBinary returns a set encoded by MarshalBinary, or another roaring implementation.
+func RoaringFromBinary(data []byte) (*roaring, error) {
+	r := NewRoaring()
+	if err := r.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
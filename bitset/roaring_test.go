@@ -0,0 +1,349 @@
+package bitset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// roaringModel is a set of uint32 to check roaring bitmaps against.
+type roaringModel map[uint32]bool
+
+func (m roaringModel) sorted() []uint32 {
+	values := []uint32{}
+	for v, ok := range m {
+		if ok {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	return values
+}
+
+// checkRoaring checks r against m, and the invariants of containers.
+func checkRoaring(t *testing.T, r *roaring, m roaringModel) {
+	t.Helper()
+
+	want := m.sorted()
+	if got := r.ToArray(); !cmp.Equal(want, got) {
+		t.Fatalf("want %d values, got %d, diff= %v", len(want), len(got), cmp.Diff(want, got))
+	}
+	if got := r.Count(); got != len(want) {
+		t.Fatalf("count: want= %v, got= %v", len(want), got)
+	}
+
+	for i, c := range r.containers {
+		if i > 0 && r.keys[i] <= r.keys[i-1] {
+			t.Fatalf("want sorted keys, got %d after %d", r.keys[i], r.keys[i-1])
+		}
+		switch c.(type) {
+		case *arrayContainer:
+			if c.cardinality() == 0 || c.cardinality() > arrayMaxSize {
+				t.Fatalf("want arrays of 1 to %d values, got %d", arrayMaxSize, c.cardinality())
+			}
+		case *bitmapContainer:
+			if c.cardinality() <= arrayMaxSize {
+				t.Fatalf("want bitmaps of more than %d values, got %d", arrayMaxSize, c.cardinality())
+			}
+		}
+	}
+
+	for k := 0; k < len(want); k += 1 + len(want)/500 {
+		if got, ok := r.Select(k); !ok || got != want[k] {
+			t.Fatalf("select %d: want= %v, got= %v, %v", k, want[k], got, ok)
+		}
+		if got := r.Rank(want[k]); got != k {
+			t.Fatalf("rank %d: want= %v, got= %v", want[k], k, got)
+		}
+		if !r.Contains(want[k]) {
+			t.Fatalf("want %d contained", want[k])
+		}
+		if got := r.Rank(want[k] + 1); got != k+1 {
+			t.Fatalf("rank %d: want= %v, got= %v", want[k]+1, k+1, got)
+		}
+	}
+	if _, ok := r.Select(len(want)); ok {
+		t.Fatalf("want select beyond count to fail")
+	}
+}
+
+// randomRoaring returns a bitmap and its model, with sparse, dense and run containers.
+func randomRoaring(rng *rand.Rand, optimize bool) (*roaring, roaringModel) {
+	r := NewRoaring()
+	m := roaringModel{}
+	for op := 0; op < 30000; op++ {
+		var x uint32
+		switch rng.Intn(4) {
+		case 0: // sparse, in arrays
+			x = uint32(rng.Intn(1 << 16))
+		case 1: // dense, in a bitmap
+			x = 1<<16 | uint32(rng.Intn(8000))
+		case 2: // around the top of the uint32s
+			x = ^uint32(0) - uint32(rng.Intn(100))
+		default:
+			if rng.Intn(100) == 0 { // ranges across containers, as runs
+				lo := 2<<16 + uint32(rng.Intn(3<<16))
+				hi := lo + uint32(rng.Intn(1<<16))
+				r.AddRange(lo, hi)
+				for x := lo; x <= hi; x++ {
+					m[x] = true
+				}
+			}
+			continue
+		}
+
+		if rng.Intn(3) == 0 {
+			r.Remove(x)
+			m[x] = false
+		} else {
+			r.Add(x)
+			m[x] = true
+		}
+	}
+	if optimize {
+		r.RunOptimize()
+	}
+
+	return r, m
+}
+
+func TestRoaringRandomOps(t *testing.T) {
+	for _, optimize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("optimize=%v", optimize), func(t *testing.T) {
+			r, m := randomRoaring(rand.New(rand.NewSource(1)), optimize)
+			checkRoaring(t, r, m)
+
+			// updates after RunOptimize change run containers
+			for x := uint32(2 << 16); x < 6<<16; x += 997 {
+				r.Remove(x)
+				m[x] = false
+				r.Add(x + 1)
+				m[x+1] = true
+			}
+			checkRoaring(t, r, m)
+		})
+	}
+}
+
+func TestRoaringContainerKinds(t *testing.T) {
+	r := NewRoaring()
+	m := roaringModel{}
+	for x := uint32(0); x < 2*arrayMaxSize; x += 2 {
+		r.Add(x)
+		m[x] = true
+	}
+	r.Add(2*arrayMaxSize + 1)
+	m[2*arrayMaxSize+1] = true
+	if _, ok := r.containers[0].(*bitmapContainer); !ok {
+		t.Fatalf("want a bitmap container, got %T", r.containers[0])
+	}
+	r.Remove(0)
+	m[0] = false
+	if _, ok := r.containers[0].(*arrayContainer); !ok {
+		t.Fatalf("want an array container, got %T", r.containers[0])
+	}
+	checkRoaring(t, r, m)
+
+	r.AddRange(100, 60000)
+	for x := uint32(100); x <= 60000; x++ {
+		m[x] = true
+	}
+	r.RunOptimize()
+	if _, ok := r.containers[0].(*runContainer); !ok {
+		t.Fatalf("want a run container, got %T", r.containers[0])
+	}
+	checkRoaring(t, r, m)
+
+	for x := uint32(0); x < 1<<16; x++ { // remove all but a few values, so an array is smaller
+		if x%1000 != 0 {
+			r.Remove(x)
+			m[x] = false
+		}
+	}
+	r.RunOptimize()
+	if _, ok := r.containers[0].(*arrayContainer); !ok {
+		t.Fatalf("want an array container, got %T", r.containers[0])
+	}
+	checkRoaring(t, r, m)
+
+	for x := uint32(0); x < 1<<16; x += 1000 {
+		r.Remove(x)
+	}
+	if want, got := 0, len(r.containers); want != got {
+		t.Fatalf("want empty containers removed, got %d", got)
+	}
+}
+
+func TestRoaringSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	a, ma := randomRoaring(rng, true)
+	b, mb := randomRoaring(rng, false)
+	aValues := a.ToArray()
+
+	var testCases = map[string]struct {
+		got  *roaring
+		keep func(inA, inB bool) bool
+	}{
+		"and":    {got: a.And(b), keep: func(inA, inB bool) bool { return inA && inB }},
+		"or":     {got: a.Or(b), keep: func(inA, inB bool) bool { return inA || inB }},
+		"xor":    {got: a.Xor(b), keep: func(inA, inB bool) bool { return inA != inB }},
+		"andNot": {got: a.AndNot(b), keep: func(inA, inB bool) bool { return inA && !inB }},
+		"notAnd": {got: b.AndNot(a), keep: func(inA, inB bool) bool { return inB && !inA }},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m := roaringModel{}
+			for x := range ma {
+				m[x] = tc.keep(ma[x], mb[x])
+			}
+			for x := range mb {
+				m[x] = tc.keep(ma[x], mb[x])
+			}
+
+			checkRoaring(t, tc.got, m)
+		})
+	}
+
+	if got := a.ToArray(); !cmp.Equal(aValues, got) {
+		t.Fatalf("want operands unchanged")
+	}
+	clone := a.Clone()
+	clone.Add(12345678)
+	if a.Contains(12345678) {
+		t.Fatalf("want clone independent")
+	}
+}
+
+func TestRoaringMarshalBinary(t *testing.T) {
+	// encodings from the portable format spec
+	small := NewRoaring()
+	small.Add(1)
+	small.Add(2)
+	small.Add(3)
+	runs := NewRoaring()
+	runs.AddRange(0, 99)
+	var vectors = map[string]struct {
+		r    *roaring
+		data []byte
+	}{
+		"array": {
+			r:    small,
+			data: []byte{0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 2, 0, 16, 0, 0, 0, 1, 0, 2, 0, 3, 0},
+		},
+		"run": {
+			r:    runs,
+			data: []byte{0x3b, 0x30, 0, 0, 1, 0, 0, 99, 0, 1, 0, 0, 0, 99, 0},
+		},
+		"empty": {
+			r:    NewRoaring(),
+			data: []byte{0x3a, 0x30, 0, 0, 0, 0, 0, 0},
+		},
+	}
+	for name, v := range vectors {
+		t.Run(name, func(t *testing.T) {
+			data, err := v.r.MarshalBinary()
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if !cmp.Equal(v.data, data) {
+				t.Fatalf("want= %v, got= %v", v.data, data)
+			}
+		})
+	}
+
+	for _, optimize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("optimize=%v", optimize), func(t *testing.T) {
+			r, m := randomRoaring(rand.New(rand.NewSource(3)), optimize)
+			data, err := r.MarshalBinary()
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+
+			decoded, err := RoaringFromBinary(data)
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			checkRoaring(t, decoded, m)
+		})
+	}
+
+	data, _ := small.MarshalBinary()
+	runData, _ := runs.MarshalBinary()
+	var testCases = map[string][]byte{
+		"empty":         nil,
+		"wrongCookie":   append([]byte{0x3c}, data[1:]...),
+		"truncated":     data[:len(data)-1],
+		"trailing":      append(append([]byte{}, data...), 0),
+		"unsorted":      append(append([]byte{}, data[:16]...), 3, 0, 2, 0, 1, 0),
+		"truncatedRuns": runData[:len(runData)-1],
+		"runOverflow":   append(append([]byte{}, runData[:11]...), 0xff, 0xff, 99, 0),
+		"wrongCount":    append(append([]byte{}, runData[:7]...), 98, 0, 1, 0, 0, 0, 99, 0),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := RoaringFromBinary(data); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+}
+
+// BenchmarkRoaringVersusMap compares a roaring bitmap to a map used as a set of ints, on clustered values.
+func BenchmarkRoaringVersusMap(b *testing.B) {
+	const n = 1 << 20
+	value := func(i int) int { return i % n * 7 } // clustered in bitmap containers
+
+	b.Run("roaring/add", func(b *testing.B) {
+		r := NewRoaring()
+		for i := 0; i < b.N; i++ {
+			r.Add(uint32(value(i)))
+		}
+	})
+	b.Run("map/add", func(b *testing.B) {
+		m := map[int]struct{}{}
+		for i := 0; i < b.N; i++ {
+			m[value(i)] = struct{}{}
+		}
+	})
+
+	r1, r2 := NewRoaring(), NewRoaring()
+	m1, m2 := map[int]struct{}{}, map[int]struct{}{}
+	for i := 0; i < n; i++ {
+		r1.Add(uint32(value(i)))
+		m1[value(i)] = struct{}{}
+		if i%2 == 0 {
+			r2.Add(uint32(value(i) + 7))
+			m2[value(i)+7] = struct{}{}
+		}
+	}
+	b.Run("roaring/contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r1.Contains(uint32(i))
+		}
+	})
+	b.Run("map/contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = m1[i]
+		}
+	})
+	b.Run("roaring/and", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r1.And(r2)
+		}
+	})
+	b.Run("map/and", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			and := map[int]struct{}{}
+			for v := range m2 {
+				if _, ok := m1[v]; ok {
+					and[v] = struct{}{}
+				}
+			}
+		}
+	})
+}
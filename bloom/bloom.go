@@ -0,0 +1,282 @@
+// Package bloom provides Bloom filters, which tell whether a containers.Value may have been added,
+// with false positives but without false negatives.
+package bloom
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// ErrIncompatible is returned when combining filters with different sizes or numbers of hashes.
+var ErrIncompatible = errors.New("filters are not compatible")
+
+// Filter provides probabilistic membership APIs.
+type Filter interface {
+	Add(v containers.Value)
+	// Contains returns false if v was never added, and true if it probably was.
+	Contains(v containers.Value) bool
+	// Len returns the number of values added.
+	Len() int
+	// FalsePositiveRate estimates the probability that Contains returns true for a value which was never added.
+	FalsePositiveRate() float64
+}
+
+// Optimal returns the number of bits and hashes of a filter holding n values with a false positive rate p:
+// m = -n ln(p) / ln(2)^2 and k = m/n ln(2).
+func Optimal(n int, p float64) (bits, hashes int, err error) {
+	if n <= 0 {
+		return 0, 0, errors.Errorf("expected items must be positive, got %d", n)
+	}
+	if p <= 0 || p >= 1 {
+		return 0, 0, errors.Errorf("false positive rate must be in (0, 1), got %v", p)
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	return int(m), int(math.Max(k, 1)), nil
+}
+
+// params are shared by filters: m positions are set by k hashes of each value.
+type params struct {
+	m    uint64
+	k    int
+	hash hasher.Func
+	n    int
+}
+
+// MaxHashes is the largest number of hashes of a filter.
+const MaxHashes = 1 << 10
+
+const maxInt = int(^uint(0) >> 1)
+
+func newParams(bits, hashes int, hash hasher.Func) (params, error) {
+	if bits <= 0 {
+		return params{}, errors.Errorf("bits must be positive, got %d", bits)
+	}
+	if hashes <= 0 || hashes > MaxHashes {
+		return params{}, errors.Errorf("hashes must be in [1, %d], got %d", MaxHashes, hashes)
+	}
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	return params{m: uint64(bits), k: hashes, hash: hash}, nil
+}
+
+// positions calls fn with the k positions of v, derived from one hash by double hashing
+// ("Less Hashing, Same Performance: Building a Better Bloom Filter" - Kirsch & Mitzenmacher 2006).
+// It stops early when fn returns false.
+func (p *params) positions(v containers.Value, fn func(i uint64) bool) {
+	h1 := p.hash(v)
+	h2 := hasher.Mix(h1) | 1
+	for i := 0; i < p.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % p.m) {
+			return
+		}
+	}
+}
+
+// Bits returns the number of positions of the filter.
+func (p *params) Bits() int {
+	return int(p.m)
+}
+
+// Hashes returns the number of positions set by each value.
+func (p *params) Hashes() int {
+	return p.k
+}
+
+// Len returns the number of values added.
+func (p *params) Len() int {
+	return p.n
+}
+
+func (p *params) compatible(other *params) bool {
+	return p.m == other.m && p.k == other.k
+}
+
+// estimateRate returns the false positive rate when a fraction of positions is set.
+func (p *params) estimateRate(set uint64) float64 {
+	return math.Pow(float64(set)/float64(p.m), float64(p.k))
+}
+
+const (
+	formatVersion = 1
+	kindStandard  = 1
+	kindCounting  = 2
+	headerSize    = 1 + 1 + 8 + 4 + 8 // version, kind, m, k, n
+)
+
+func (p *params) marshalHeader(kind byte, size int) []byte {
+	data := make([]byte, headerSize, headerSize+size)
+	data[0], data[1] = formatVersion, kind
+	binary.LittleEndian.PutUint64(data[2:], p.m)
+	binary.LittleEndian.PutUint32(data[10:], uint32(p.k))
+	binary.LittleEndian.PutUint64(data[14:], uint64(p.n))
+	return data
+}
+
+// unmarshalHeader reads params from data, keeping the hash function, and returns the rest of data.
+func (p *params) unmarshalHeader(kind byte, data []byte) ([]byte, error) {
+	if len(data) < headerSize {
+		return nil, errors.Errorf("data is too short: %d bytes", len(data))
+	}
+	if data[0] != formatVersion {
+		return nil, errors.Errorf("unknown format version %d", data[0])
+	}
+	if data[1] != kind {
+		return nil, errors.Errorf("want filter kind %d, got %d", kind, data[1])
+	}
+
+	m := binary.LittleEndian.Uint64(data[2:])
+	k := binary.LittleEndian.Uint32(data[10:])
+	if m == 0 || k == 0 || k > MaxHashes || uint64(k) > m {
+		return nil, errors.Errorf("invalid filter with %d bits and %d hashes", m, k)
+	}
+	n := binary.LittleEndian.Uint64(data[14:])
+	if n > uint64(maxInt) {
+		return nil, errors.Errorf("invalid filter with %d values", n)
+	}
+	p.m, p.k, p.n = m, int(k), int(n)
+	return data[headerSize:], nil
+}
+
+// filter is a concrete implementation of Filter on a bit array.
+type filter struct {
+	params
+	words []uint64
+}
+
+// New returns a Filter sized to hold expectedItems values with the target false positive rate.
+// hash can be nil to use hasher.Default. Filters combined or unmarshaled together must use the same hash.
+func New(expectedItems int, falsePositiveRate float64, hash hasher.Func) (*filter, error) {
+	bits, hashes, err := Optimal(expectedItems, falsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithSize(bits, hashes, hash)
+}
+
+// NewWithSize returns a Filter with the given number of bits and hashes.
+func NewWithSize(bits, hashes int, hash hasher.Func) (*filter, error) {
+	p, err := newParams(bits, hashes, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filter{params: p, words: make([]uint64, (bits+63)/64)}, nil
+}
+
+// Add sets the positions of v.
+func (f *filter) Add(v containers.Value) {
+	f.positions(v, func(i uint64) bool {
+		f.words[i/64] |= 1 << (i % 64)
+		return true
+	})
+	f.n++
+}
+
+// Contains returns whether all positions of v are set.
+func (f *filter) Contains(v containers.Value) bool {
+	found := true
+	f.positions(v, func(i uint64) bool {
+		found = f.words[i/64]&(1<<(i%64)) != 0
+		return found
+	})
+
+	return found
+}
+
+// FalsePositiveRate estimates the false positive rate from the fraction of set bits.
+func (f *filter) FalsePositiveRate() float64 {
+	var set int
+	for _, w := range f.words {
+		set += bits.OnesCount64(w)
+	}
+
+	return f.estimateRate(uint64(set))
+}
+
+// Union adds all values of other, which must have the same size and number of hashes.
+// The filter then contains values of either filter.
+func (f *filter) Union(other *filter) error {
+	if !f.compatible(&other.params) {
+		return ErrIncompatible
+	}
+
+	for i, w := range other.words {
+		f.words[i] |= w
+	}
+	f.n += other.n
+	return nil
+}
+
+// Intersect keeps the positions set in both filters, which must have the same size and number of hashes.
+// The filter then contains values of both filters, with a false positive rate at least as high as if they
+// were added to an empty filter. Len becomes the smaller length, as an upper bound.
+func (f *filter) Intersect(other *filter) error {
+	if !f.compatible(&other.params) {
+		return ErrIncompatible
+	}
+
+	for i, w := range other.words {
+		f.words[i] &= w
+	}
+	if other.n < f.n {
+		f.n = other.n
+	}
+	return nil
+}
+
+// MarshalBinary encodes the filter, without its hash function.
+func (f *filter) MarshalBinary() ([]byte, error) {
+	data := f.marshalHeader(kindStandard, 8*len(f.words))
+	var buf [8]byte
+	for _, w := range f.words {
+		binary.LittleEndian.PutUint64(buf[:], w)
+		data = append(data, buf[:]...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary, keeping its hash function.
+func (f *filter) UnmarshalBinary(data []byte) error {
+	p := f.params
+	rest, err := p.unmarshalHeader(kindStandard, data)
+	if err != nil {
+		return err
+	}
+	// compared in words, since 8 * words overflows for huge m
+	words := p.m / 64
+	if p.m%64 != 0 {
+		words++
+	}
+	if len(rest)%8 != 0 || uint64(len(rest)/8) != words {
+		return errors.Errorf("want %d words of bits, got %d bytes", words, len(rest))
+	}
+
+	f.params = p
+	f.words = make([]uint64, words)
+	for i := range f.words {
+		f.words[i] = binary.LittleEndian.Uint64(rest[8*i:])
+	}
+	return nil
+}
+
+// FromBinary returns a Filter encoded by MarshalBinary, with the hash function it was created with.
+func FromBinary(data []byte, hash hasher.Func) (*filter, error) {
+	f, _ := NewWithSize(1, 1, hash)
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,260 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+var (
+	_ Filter = (*filter)(nil)
+	_ Filter = (*countingFilter)(nil)
+)
+
+var implementations = map[string]func(n int, p float64, hash hasher.Func) (Filter, error){
+	"filter":   func(n int, p float64, hash hasher.Func) (Filter, error) { return New(n, p, hash) },
+	"counting": func(n int, p float64, hash hasher.Func) (Filter, error) { return NewCounting(n, p, hash) },
+}
+
+// intHash hashes int values without going through hasher.Default.
+func intHash(v containers.Value) uint64 {
+	return hasher.Mix(uint64(v.(int)))
+}
+
+func TestOptimal(t *testing.T) {
+	var testCases = map[string]struct {
+		n      int
+		p      float64
+		bits   int
+		hashes int
+		isErr  bool
+	}{
+		"onePercent":    {n: 1000, p: 0.01, bits: 9586, hashes: 7},
+		"tenPercent":    {n: 1000, p: 0.1, bits: 4793, hashes: 3},
+		"oneItem":       {n: 1, p: 0.5, bits: 2, hashes: 1},
+		"zeroItems":     {n: 0, p: 0.01, isErr: true},
+		"zeroRate":      {n: 1000, p: 0, isErr: true},
+		"certainErrors": {n: 1000, p: 1, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bits, hashes, err := Optimal(tc.n, tc.p)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if bits != tc.bits || hashes != tc.hashes {
+				t.Fatalf("want %d bits and %d hashes, got= %d, %d", tc.bits, tc.hashes, bits, hashes)
+			}
+		})
+	}
+}
+
+func TestNewWithSize(t *testing.T) {
+	if _, err := NewWithSize(0, 1, nil); err == nil {
+		t.Fatalf("want error for zero bits, got none")
+	}
+	if _, err := NewWithSize(64, 0, nil); err == nil {
+		t.Fatalf("want error for zero hashes, got none")
+	}
+	if _, err := NewWithSize(64, MaxHashes+1, nil); err == nil {
+		t.Fatalf("want error for more than MaxHashes hashes, got none")
+	}
+
+	f, err := NewWithSize(100, 3, nil)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if f.Bits() != 100 || f.Hashes() != 3 {
+		t.Fatalf("want 100 bits and 3 hashes, got= %d, %d", f.Bits(), f.Hashes())
+	}
+}
+
+// TestFalsePositiveRate checks that filters have no false negatives, and that the measured false positive rate
+// stays near the target when they hold the expected number of values.
+func TestFalsePositiveRate(t *testing.T) {
+	const (
+		n      = 20000
+		probes = 200000
+	)
+
+	for impl, newFilter := range implementations {
+		for _, p := range []float64{0.1, 0.01, 0.001} {
+			for hashName, hash := range map[string]hasher.Func{"default": nil, "custom": intHash} {
+				t.Run(fmt.Sprintf("%s/p=%v/%s", impl, p, hashName), func(t *testing.T) {
+					f, err := newFilter(n, p, hash)
+					if err != nil {
+						t.Fatalf("need a valid filter to test, got %q", err)
+					}
+					for i := 0; i < n; i++ {
+						f.Add(i)
+					}
+
+					for i := 0; i < n; i++ {
+						if !f.Contains(i) {
+							t.Fatalf("want added value %d found", i)
+						}
+					}
+					positives := 0
+					for i := n; i < n+probes; i++ {
+						if f.Contains(i) {
+							positives++
+						}
+					}
+
+					measured := float64(positives) / probes
+					if measured < p/2 || measured > 1.5*p {
+						t.Fatalf("want false positive rate near %v, got= %v", p, measured)
+					}
+					if estimated := f.FalsePositiveRate(); math.Abs(estimated-p) > p/4 {
+						t.Fatalf("want estimated false positive rate near %v, got= %v", p, estimated)
+					}
+					if want, got := n, f.Len(); want != got {
+						t.Fatalf("len: want= %v, got= %v", want, got)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestUnionIntersect(t *testing.T) {
+	a, _ := New(1000, 0.01, nil)
+	b, _ := New(1000, 0.01, nil)
+	for i := 0; i < 600; i++ {
+		a.Add(i)
+	}
+	for i := 400; i < 1000; i++ {
+		b.Add(i)
+	}
+
+	intersection, _ := New(1000, 0.01, nil)
+	intersection.Union(a)
+	if err := intersection.Intersect(b); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	for i := 400; i < 600; i++ {
+		if !intersection.Contains(i) {
+			t.Fatalf("want value %d of both filters in the intersection", i)
+		}
+	}
+	excluded := 0
+	for i := 0; i < 400; i++ {
+		if !intersection.Contains(i) {
+			excluded++
+		}
+	}
+	if excluded < 350 {
+		t.Fatalf("want most values of one filter only excluded from the intersection, got %d of 400", excluded)
+	}
+
+	if err := a.Union(b); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !a.Contains(i) {
+			t.Fatalf("want value %d of either filter in the union", i)
+		}
+	}
+
+	other, _ := New(1000, 0.001, nil)
+	if err := a.Union(other); err != ErrIncompatible {
+		t.Fatalf("union: want= %v, got= %v", ErrIncompatible, err)
+	}
+	if err := a.Intersect(other); err != ErrIncompatible {
+		t.Fatalf("intersect: want= %v, got= %v", ErrIncompatible, err)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	f, _ := New(1000, 0.01, intHash)
+	for i := 0; i < 1000; i++ {
+		f.Add(i)
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	decoded, err := FromBinary(data, intHash)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if decoded.Bits() != f.Bits() || decoded.Hashes() != f.Hashes() || decoded.Len() != f.Len() {
+		t.Fatalf("want same params, got= %d bits, %d hashes, len %d", decoded.Bits(), decoded.Hashes(), decoded.Len())
+	}
+	for i := 0; i < 2000; i++ {
+		if want, got := f.Contains(i), decoded.Contains(i); want != got {
+			t.Fatalf("contains %d: want= %v, got= %v", i, want, got)
+		}
+	}
+
+	counting, _ := NewCounting(1000, 0.01, intHash)
+	countingData, _ := counting.MarshalBinary()
+	small, _ := NewWithSize(100, 3, intHash)
+	smallData, _ := small.MarshalBinary()
+	var testCases = map[string][]byte{
+		"empty":        nil,
+		"truncated":    data[:len(data)-1],
+		"wrongVersion": append([]byte{9}, data[1:]...),
+		"wrongKind":    countingData,
+		"zeroBits":     append(append([]byte{}, data[:2]...), make([]byte, len(data)-2)...),
+		"hugeBits":     hugeBits(data[:headerSize]),
+		"hugeHashes":   withUint32(data, 10, math.MaxUint32),
+		"moreHashes":   withUint32(smallData, 10, 101),
+		"negativeLen":  withUint64(data, 14, math.MaxUint64),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := FromBinary(data, intHash); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+}
+
+// hugeBits returns header with the most bits and 1 hash, and no bits after it.
+func hugeBits(header []byte) []byte {
+	data := append([]byte{}, header...)
+	binary.LittleEndian.PutUint64(data[2:], math.MaxUint64)
+	binary.LittleEndian.PutUint32(data[10:], 1)
+	return data
+}
+
+// withUint64 returns a copy of data with x written at offset.
+func withUint64(data []byte, offset int, x uint64) []byte {
+	data = append([]byte{}, data...)
+	binary.LittleEndian.PutUint64(data[offset:], x)
+	return data
+}
+
+// withUint32 returns a copy of data with x written at offset.
+func withUint32(data []byte, offset int, x uint32) []byte {
+	data = append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(data[offset:], x)
+	return data
+}
+
+func BenchmarkFilter(b *testing.B) {
+	for impl, newFilter := range implementations {
+		f, _ := newFilter(1<<20, 0.01, nil)
+		b.Run(impl+"/add", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				f.Add(i)
+			}
+		})
+		b.Run(impl+"/contains", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				f.Contains(i)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package bloom
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// countingFilter is a concrete implementation of Filter with a counter per position, so values can be removed
+// ("Summary Cache: A Scalable Wide-Area Web Cache Sharing Protocol" - Fan et al. 2000).
+// Counters saturate at 255 and then never decrease, which keeps false negatives impossible.
+type countingFilter struct {
+	params
+	counters []uint8
+}
+
+// NewCounting returns a counting Filter sized to hold expectedItems values with the target false positive rate.
+// It uses 8 times the memory of a Filter. hash can be nil to use hasher.Default.
+func NewCounting(expectedItems int, falsePositiveRate float64, hash hasher.Func) (*countingFilter, error) {
+	bits, hashes, err := Optimal(expectedItems, falsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCountingWithSize(bits, hashes, hash)
+}
+
+// NewCountingWithSize returns a counting Filter with the given number of counters and hashes.
+func NewCountingWithSize(counters, hashes int, hash hasher.Func) (*countingFilter, error) {
+	p, err := newParams(counters, hashes, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingFilter{params: p, counters: make([]uint8, counters)}, nil
+}
+
+// Add increments the counters of v.
+func (f *countingFilter) Add(v containers.Value) {
+	f.positions(v, func(i uint64) bool {
+		if f.counters[i] < math.MaxUint8 {
+			f.counters[i]++
+		}
+		return true
+	})
+	f.n++
+}
+
+// Contains returns whether all counters of v are positive.
+func (f *countingFilter) Contains(v containers.Value) bool {
+	found := true
+	f.positions(v, func(i uint64) bool {
+		found = f.counters[i] > 0
+		return found
+	})
+
+	return found
+}
+
+// Remove decrements the counters of v, and returns whether it was probably there.
+// Removing a value which was never added corrupts the filter if Contains returned a false positive for it.
+func (f *countingFilter) Remove(v containers.Value) bool {
+	if !f.Contains(v) {
+		return false
+	}
+
+	f.positions(v, func(i uint64) bool {
+		if f.counters[i] < math.MaxUint8 {
+			f.counters[i]--
+		}
+		return true
+	})
+	if f.n > 0 {
+		f.n--
+	}
+	return true
+}
+
+// FalsePositiveRate estimates the false positive rate from the fraction of positive counters.
+func (f *countingFilter) FalsePositiveRate() float64 {
+	var set uint64
+	for _, c := range f.counters {
+		if c > 0 {
+			set++
+		}
+	}
+
+	return f.estimateRate(set)
+}
+
+// Union adds the counters of other, which must have the same size and number of hashes.
+func (f *countingFilter) Union(other *countingFilter) error {
+	if !f.compatible(&other.params) {
+		return ErrIncompatible
+	}
+
+	for i, c := range other.counters {
+		if sum := int(f.counters[i]) + int(c); sum < math.MaxUint8 {
+			f.counters[i] = uint8(sum)
+		} else {
+			f.counters[i] = math.MaxUint8
+		}
+	}
+	f.n += other.n
+	return nil
+}
+
+// Intersect keeps the smaller of each pair of counters, which must have the same size and number of hashes.
+func (f *countingFilter) Intersect(other *countingFilter) error {
+	if !f.compatible(&other.params) {
+		return ErrIncompatible
+	}
+
+	for i, c := range other.counters {
+		if c < f.counters[i] {
+			f.counters[i] = c
+		}
+	}
+	if other.n < f.n {
+		f.n = other.n
+	}
+	return nil
+}
+
+// Filter returns a Filter with the positions of positive counters, e.g. to ship a compact copy.
+func (f *countingFilter) Filter() *filter {
+	bf := &filter{params: f.params, words: make([]uint64, (f.m+63)/64)}
+	for i, c := range f.counters {
+		if c > 0 {
+			bf.words[i/64] |= 1 << (uint(i) % 64)
+		}
+	}
+
+	return bf
+}
+
+// MarshalBinary encodes the filter, without its hash function.
+func (f *countingFilter) MarshalBinary() ([]byte, error) {
+	data := f.marshalHeader(kindCounting, len(f.counters))
+	return append(data, f.counters...), nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary, keeping its hash function.
+func (f *countingFilter) UnmarshalBinary(data []byte) error {
+	p := f.params
+	rest, err := p.unmarshalHeader(kindCounting, data)
+	if err != nil {
+		return err
+	}
+	if uint64(len(rest)) != p.m {
+		return errors.Errorf("want %d bytes of counters, got %d", p.m, len(rest))
+	}
+
+	f.params = p
+	f.counters = append([]uint8{}, rest...)
+	return nil
+}
+
+// CountingFromBinary returns a counting Filter encoded by MarshalBinary, with the hash function it was created with.
+func CountingFromBinary(data []byte, hash hasher.Func) (*countingFilter, error) {
+	f, _ := NewCountingWithSize(1, 1, hash)
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
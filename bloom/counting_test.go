@@ -0,0 +1,127 @@
+package bloom
+
+import (
+	"testing"
+)
+
+func TestCountingRemove(t *testing.T) {
+	f, _ := NewCounting(1000, 0.01, intHash)
+	for i := 0; i < 1000; i++ {
+		f.Add(i)
+	}
+	for i := 0; i < 500; i++ {
+		if !f.Remove(i) {
+			t.Fatalf("want added value %d removed", i)
+		}
+	}
+
+	for i := 500; i < 1000; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("want value %d which was not removed found", i)
+		}
+	}
+	stillFound := 0
+	for i := 0; i < 500; i++ {
+		if f.Contains(i) {
+			stillFound++
+		}
+	}
+	if stillFound > 25 {
+		t.Fatalf("want removed values mostly gone, got %d of 500 found", stillFound)
+	}
+	if want, got := 500, f.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+func TestCountingRemoveMissing(t *testing.T) {
+	f, _ := NewCounting(100, 0.01, intHash)
+	f.Add(1)
+	if f.Remove(2) {
+		t.Fatalf("want missing value not removed")
+	}
+	if !f.Contains(1) {
+		t.Fatalf("want other values kept")
+	}
+}
+
+func TestCountingSaturation(t *testing.T) {
+	f, _ := NewCountingWithSize(64, 2, intHash)
+	for i := 0; i < 300; i++ {
+		f.Add(1)
+	}
+	for i := 0; i < 300; i++ {
+		f.Remove(1)
+	}
+
+	if !f.Contains(1) {
+		t.Fatalf("want saturated counters kept, to avoid false negatives")
+	}
+}
+
+func TestCountingUnionIntersect(t *testing.T) {
+	a, _ := NewCounting(1000, 0.01, nil)
+	b, _ := NewCounting(1000, 0.01, nil)
+	a.Add("x")
+	a.Add("shared")
+	b.Add("y")
+	b.Add("shared")
+
+	union, _ := NewCounting(1000, 0.01, nil)
+	union.Union(a)
+	if err := union.Union(b); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	union.Remove("shared") // added by both filters, so it is still counted once
+	for _, v := range []string{"x", "y", "shared"} {
+		if !union.Contains(v) {
+			t.Fatalf("want %v in the union", v)
+		}
+	}
+
+	if err := a.Intersect(b); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if !a.Contains("shared") || a.Contains("x") || a.Contains("y") {
+		t.Fatalf("want only the shared value in the intersection")
+	}
+
+	other, _ := NewCounting(10, 0.01, nil)
+	if err := a.Union(other); err != ErrIncompatible {
+		t.Fatalf("want= %v, got= %v", ErrIncompatible, err)
+	}
+}
+
+func TestCountingFilterAndMarshal(t *testing.T) {
+	f, _ := NewCounting(1000, 0.01, intHash)
+	for i := 0; i < 1000; i++ {
+		f.Add(i)
+	}
+	f.Remove(0)
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	decoded, err := CountingFromBinary(data, intHash)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	compact := f.Filter()
+	for i := 0; i < 2000; i++ {
+		if want, got := f.Contains(i), decoded.Contains(i); want != got {
+			t.Fatalf("decoded contains %d: want= %v, got= %v", i, want, got)
+		}
+		if want, got := f.Contains(i), compact.Contains(i); want != got {
+			t.Fatalf("compact contains %d: want= %v, got= %v", i, want, got)
+		}
+	}
+
+	if _, err := CountingFromBinary(data[:len(data)-1], intHash); err == nil {
+		t.Fatalf("want error for truncated data, got none")
+	}
+	standard, _ := compact.MarshalBinary()
+	if _, err := CountingFromBinary(standard, intHash); err == nil {
+		t.Fatalf("want error for a standard filter, got none")
+	}
+}
@@ -0,0 +1,125 @@
+package btree
+
+// avlState is embedded in the TreeNode.Value of nodes of AVL trees.
+type avlState struct {
+	height int // of the node's subtree
+}
+
+func (s *avlState) avl() *avlState {
+	return s
+}
+
+// avlValue is implemented by TreeNode.Values embedding avlState.
+type avlValue interface {
+	avl() *avlState
+}
+
+func height(node *TreeNode) int {
+	if node == nil {
+		return 0
+	}
+
+	return node.Value.(avlValue).avl().height
+}
+
+// avl balances the trees embedding it, whose nodes' Values embed avlState:
+// the heights of the subtrees of any node differ by at most one.
+type avl struct {
+	// update recomputes the fields a tree derives from the children of node, besides its height. It can be nil.
+	update func(node *TreeNode)
+}
+
+// refresh recomputes the height of node from its children, then calls update.
+func (a avl) refresh(node *TreeNode) {
+	h := height(node.Left)
+	if r := height(node.Right); r > h {
+		h = r
+	}
+	node.Value.(avlValue).avl().height = h + 1
+
+	if a.update != nil {
+		a.update(node)
+	}
+}
+
+// rebalance refreshes node and rotates its subtree if it is not balanced. It returns the subtree's new root.
+func (a avl) rebalance(node *TreeNode) *TreeNode {
+	a.refresh(node)
+
+	switch balance := height(node.Left) - height(node.Right); {
+	case balance > 1:
+		if height(node.Left.Left) < height(node.Left.Right) {
+			node.Left = a.rotateLeft(node.Left)
+		}
+		return a.rotateRight(node)
+	case balance < -1:
+		if height(node.Right.Right) < height(node.Right.Left) {
+			node.Right = a.rotateRight(node.Right)
+		}
+		return a.rotateLeft(node)
+	}
+
+	return node
+}
+
+func (a avl) rotateLeft(node *TreeNode) *TreeNode {
+	right := node.Right
+	node.Right = right.Left
+	if right.Left != nil {
+		right.Left.Parent = node
+	}
+	right.Left = node
+	right.Parent, node.Parent = node.Parent, right
+
+	a.refresh(node)
+	a.refresh(right)
+	return right
+}
+
+func (a avl) rotateRight(node *TreeNode) *TreeNode {
+	left := node.Left
+	node.Left = left.Right
+	if left.Right != nil {
+		left.Right.Parent = node
+	}
+	left.Right = node
+	left.Parent, node.Parent = node.Parent, left
+
+	a.refresh(node)
+	a.refresh(left)
+	return left
+}
+
+// remove unlinks node's Value and returns the root of the balanced subtree replacing node.
+func (a avl) remove(node *TreeNode) *TreeNode {
+	if node.Left == nil || node.Right == nil {
+		child := node.Left
+		if child == nil {
+			child = node.Right
+		}
+		if child != nil {
+			child.Parent = node.Parent
+		}
+		return child
+	}
+
+	// take the Value of the successor, whose node is removed instead
+	var successor *TreeNode
+	node.Right, successor = a.removeMin(node.Right)
+	node.Value = successor.Value
+	return a.rebalance(node)
+}
+
+// removeMin unlinks the least node of the subtree at node, returning the new subtree and the unlinked node.
+func (a avl) removeMin(node *TreeNode) (*TreeNode, *TreeNode) {
+	if node.Left == nil {
+		if node.Right != nil {
+			node.Right.Parent = node.Parent
+		}
+		return node.Right, node
+	}
+
+	var min *TreeNode
+	node.Left, min = a.removeMin(node.Left)
+	return a.rebalance(node), min
+}
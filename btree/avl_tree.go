@@ -0,0 +1,307 @@
+package btree
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// avlEntry is the TreeNode.Value of nodes in an AVL tree.
+type avlEntry struct {
+	avlState
+	key, value containers.Value
+}
+
+func avlEntryOf(node *TreeNode) *avlEntry {
+	return node.Value.(*avlEntry)
+}
+
+// avlTree is an ordered map on an AVL tree: the heights of the subtrees of any node differ by at most one,
+// so operations take O(log n).
+type avlTree struct {
+	avl
+	less func(a, b containers.Value) bool
+	root *TreeNode
+	size int
+}
+
+// NewAVLTree returns an empty AVL tree whose keys are ordered by less.
+func NewAVLTree(less func(a, b containers.Value) bool) *avlTree {
+	return &avlTree{less: less}
+}
+
+// Root returns the root of the tree. Each node's Value is internal to the tree and must not be changed.
+func (t *avlTree) Root() *TreeNode {
+	return t.root
+}
+
+// Len returns the number of keys.
+func (t *avlTree) Len() int {
+	return t.size
+}
+
+// Insert sets the value of key. It returns whether key was already there, in which case its value is replaced.
+func (t *avlTree) Insert(key, value containers.Value) bool {
+	var replaced bool
+	t.root = t.insert(t.root, nil, key, value, &replaced)
+	if !replaced {
+		t.size++
+	}
+
+	return replaced
+}
+
+func (t *avlTree) insert(node, parent *TreeNode, key, value containers.Value, replaced *bool) *TreeNode {
+	if node == nil {
+		return &TreeNode{
+			Value:  &avlEntry{avlState: avlState{height: 1}, key: key, value: value},
+			Parent: parent,
+		}
+	}
+
+	e := avlEntryOf(node)
+	switch {
+	case t.less(key, e.key):
+		node.Left = t.insert(node.Left, node, key, value, replaced)
+	case t.less(e.key, key):
+		node.Right = t.insert(node.Right, node, key, value, replaced)
+	default:
+		e.value = value
+		*replaced = true
+		return node
+	}
+
+	return t.rebalance(node)
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (t *avlTree) Delete(key containers.Value) (containers.Value, bool) {
+	var removed *avlEntry
+	t.root = t.delete(t.root, key, &removed)
+	if removed == nil {
+		return nil, false
+	}
+
+	t.size--
+	return removed.value, true
+}
+
+func (t *avlTree) delete(node *TreeNode, key containers.Value, removed **avlEntry) *TreeNode {
+	if node == nil {
+		return nil
+	}
+
+	e := avlEntryOf(node)
+	switch {
+	case t.less(key, e.key):
+		node.Left = t.delete(node.Left, key, removed)
+	case t.less(e.key, key):
+		node.Right = t.delete(node.Right, key, removed)
+	default:
+		*removed = e
+		return t.remove(node)
+	}
+	if *removed == nil {
+		return node
+	}
+
+	return t.rebalance(node)
+}
+
+// entry returns the key and value of node, unless it is nil.
+func (t *avlTree) entry(node *TreeNode) (containers.Value, containers.Value, bool) {
+	if node == nil {
+		return nil, nil, false
+	}
+
+	e := avlEntryOf(node)
+	return e.key, e.value, true
+}
+
+// Get returns the value of key, and whether key is there.
+func (t *avlTree) Get(key containers.Value) (containers.Value, bool) {
+	node := t.root
+	for node != nil {
+		e := avlEntryOf(node)
+		switch {
+		case t.less(key, e.key):
+			node = node.Left
+		case t.less(e.key, key):
+			node = node.Right
+		default:
+			return e.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// below returns the node with the greatest key less than key, or not greater than key if orEqual.
+func (t *avlTree) below(key containers.Value, orEqual bool) *TreeNode {
+	var found *TreeNode
+	node := t.root
+	for node != nil {
+		k := avlEntryOf(node).key
+		if t.less(k, key) || (orEqual && !t.less(key, k)) {
+			found, node = node, node.Right
+		} else {
+			node = node.Left
+		}
+	}
+
+	return found
+}
+
+// above returns the node with the least key greater than key, or not less than key if orEqual.
+func (t *avlTree) above(key containers.Value, orEqual bool) *TreeNode {
+	var found *TreeNode
+	node := t.root
+	for node != nil {
+		k := avlEntryOf(node).key
+		if t.less(key, k) || (orEqual && !t.less(k, key)) {
+			found, node = node, node.Left
+		} else {
+			node = node.Right
+		}
+	}
+
+	return found
+}
+
+// Floor returns the greatest key not greater than key, and its value.
+func (t *avlTree) Floor(key containers.Value) (containers.Value, containers.Value, bool) {
+	return t.entry(t.below(key, true))
+}
+
+// Ceiling returns the least key not less than key, and its value.
+func (t *avlTree) Ceiling(key containers.Value) (containers.Value, containers.Value, bool) {
+	return t.entry(t.above(key, true))
+}
+
+// Lower returns the greatest key less than key, and its value.
+func (t *avlTree) Lower(key containers.Value) (containers.Value, containers.Value, bool) {
+	return t.entry(t.below(key, false))
+}
+
+// Higher returns the least key greater than key, and its value.
+func (t *avlTree) Higher(key containers.Value) (containers.Value, containers.Value, bool) {
+	return t.entry(t.above(key, false))
+}
+
+// First returns the least key and its value.
+func (t *avlTree) First() (containers.Value, containers.Value, bool) {
+	node := t.root
+	for node != nil && node.Left != nil {
+		node = node.Left
+	}
+
+	return t.entry(node)
+}
+
+// Last returns the greatest key and its value.
+func (t *avlTree) Last() (containers.Value, containers.Value, bool) {
+	node := t.root
+	for node != nil && node.Right != nil {
+		node = node.Right
+	}
+
+	return t.entry(node)
+}
+
+// successor returns the node after node in order, following parent links.
+func successor(node *TreeNode) *TreeNode {
+	if node.Right != nil {
+		node = node.Right
+		for node.Left != nil {
+			node = node.Left
+		}
+		return node
+	}
+
+	for node.Parent != nil && node.Parent.Right == node {
+		node = node.Parent
+	}
+	return node.Parent
+}
+
+// Ascend calls fn on keys in order, until fn returns false.
+func (t *avlTree) Ascend(fn func(key, value containers.Value) bool) {
+	node := t.root
+	for node != nil && node.Left != nil {
+		node = node.Left
+	}
+
+	for ; node != nil; node = successor(node) {
+		if e := avlEntryOf(node); !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Range calls fn on keys in [from, to) in order, until fn returns false.
+func (t *avlTree) Range(from, to containers.Value, fn func(key, value containers.Value) bool) {
+	for node := t.above(from, true); node != nil; node = successor(node) {
+		e := avlEntryOf(node)
+		if !t.less(e.key, to) || !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// validate checks the parent links, heights and balance of every node, and the order of keys.
+func (t *avlTree) validate() error {
+	if t.root != nil && t.root.Parent != nil {
+		return errors.New("root has a parent")
+	}
+
+	size, err := t.validateNode(t.root, nil, nil)
+	if err != nil {
+		return err
+	}
+	if size != t.size {
+		return errors.Errorf("size is %d, but there are %d nodes", t.size, size)
+	}
+
+	return nil
+}
+
+// validateNode checks the subtree at node, whose keys must be in (min, max) unless those are nil.
+func (t *avlTree) validateNode(node *TreeNode, min, max *avlEntry) (int, error) {
+	if node == nil {
+		return 0, nil
+	}
+
+	e := avlEntryOf(node)
+	if (min != nil && !t.less(min.key, e.key)) || (max != nil && !t.less(e.key, max.key)) {
+		return 0, errors.Errorf("%v is out of order", e.key)
+	}
+
+	size := 1
+	for _, child := range []*TreeNode{node.Left, node.Right} {
+		if child != nil && child.Parent != node {
+			return 0, errors.Errorf("child of %v does not link back to it", e.key)
+		}
+	}
+	left, err := t.validateNode(node.Left, min, e)
+	if err != nil {
+		return 0, err
+	}
+	right, err := t.validateNode(node.Right, e, max)
+	if err != nil {
+		return 0, err
+	}
+	size += left + right
+
+	wantHeight := height(node.Left)
+	if h := height(node.Right); h > wantHeight {
+		wantHeight = h
+	}
+	if e.height != wantHeight+1 {
+		return 0, errors.Errorf("height of %v is %d, want %d", e.key, e.height, wantHeight+1)
+	}
+	if balance := height(node.Left) - height(node.Right); balance < -1 || balance > 1 {
+		return 0, errors.Errorf("%v is not balanced: %d", e.key, balance)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,110 @@
+package btree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// TestAVLTreeRandom checks random inserts and deletes against a map, and the tree's invariants after each of them.
+func TestAVLTreeRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewAVLTree(intLess)
+	model := map[int]int{}
+	for op := 0; op < 3000; op++ {
+		key := rng.Intn(300)
+		if rng.Intn(3) == 0 {
+			value, ok := tree.Delete(key)
+			want, wantOK := model[key]
+			if ok != wantOK || (ok && value != want) {
+				t.Fatalf("delete %d: want= %v, %v, got= %v, %v", key, want, wantOK, value, ok)
+			}
+			delete(model, key)
+		} else {
+			_, wantReplaced := model[key]
+			if replaced := tree.Insert(key, op); replaced != wantReplaced {
+				t.Fatalf("insert %d: want replaced= %v, got= %v", key, wantReplaced, replaced)
+			}
+			model[key] = op
+		}
+
+		if err := tree.validate(); err != nil {
+			t.Fatalf("invalid tree after op %d: %v", op, err)
+		}
+	}
+
+	var keys []int
+	for key := range model {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	var got []int
+	tree.Ascend(func(key, value containers.Value) bool {
+		if value != model[key.(int)] {
+			t.Fatalf("value of %v: want= %v, got= %v", key, model[key.(int)], value)
+		}
+		got = append(got, key.(int))
+		return true
+	})
+	if !cmp.Equal(keys, got) {
+		t.Fatalf("want= %v, got= %v", keys, got)
+	}
+}
+
+func TestAVLTreeQueries(t *testing.T) {
+	tree := NewAVLTree(intLess)
+	for _, key := range []int{10, 20, 30, 40} {
+		tree.Insert(key, key*10)
+	}
+
+	var testCases = map[string]struct {
+		query func(key containers.Value) (containers.Value, containers.Value, bool)
+		key   int
+		want  containers.Value
+	}{
+		"floorEqual":      {query: tree.Floor, key: 20, want: 20},
+		"floorBetween":    {query: tree.Floor, key: 25, want: 20},
+		"floorBelowAll":   {query: tree.Floor, key: 5, want: nil},
+		"ceilingEqual":    {query: tree.Ceiling, key: 20, want: 20},
+		"ceilingBetween":  {query: tree.Ceiling, key: 25, want: 30},
+		"ceilingAboveAll": {query: tree.Ceiling, key: 45, want: nil},
+		"lowerEqual":      {query: tree.Lower, key: 20, want: 10},
+		"lowerFirst":      {query: tree.Lower, key: 10, want: nil},
+		"higherEqual":     {query: tree.Higher, key: 20, want: 30},
+		"higherLast":      {query: tree.Higher, key: 40, want: nil},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			key, value, ok := tc.query(tc.key)
+			if tc.want == nil {
+				if ok {
+					t.Fatalf("want none, got= %v", key)
+				}
+				return
+			}
+			if !ok || key != tc.want || value != tc.want.(int)*10 {
+				t.Fatalf("want= %v, got= %v, %v, %v", tc.want, key, value, ok)
+			}
+		})
+	}
+
+	if key, _, _ := tree.First(); key != 10 {
+		t.Fatalf("first: want= 10, got= %v", key)
+	}
+	if key, _, _ := tree.Last(); key != 40 {
+		t.Fatalf("last: want= 40, got= %v", key)
+	}
+	var keys []containers.Value
+	tree.Range(15, 40, func(key, value containers.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if want := []containers.Value{20, 30}; !cmp.Equal(want, keys) {
+		t.Fatalf("range: want= %v, got= %v", want, keys)
+	}
+}
@@ -0,0 +1,286 @@
+package btree
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Interval is a half-open interval [Start, End) carrying a Value.
+type Interval struct {
+	Start containers.Value
+	End   containers.Value
+	Value containers.Value
+}
+
+// intervalEntry is the TreeNode.Value of nodes in an interval tree.
+type intervalEntry struct {
+	avlState
+	interval Interval
+	max      containers.Value // greatest End in the subtree
+}
+
+func entryOf(node *TreeNode) *intervalEntry {
+	return node.Value.(*intervalEntry)
+}
+
+// intervalTree is an AVL tree of intervals ordered by (Start, End),
+// where each node also tracks the greatest End of its subtree to prune overlap queries.
+type intervalTree struct {
+	avl
+	less func(a, b containers.Value) bool
+	root *TreeNode
+	size int
+}
+
+// NewIntervalTree returns an empty interval tree whose endpoints are ordered by less.
+func NewIntervalTree(less func(a, b containers.Value) bool) *intervalTree {
+	t := &intervalTree{less: less}
+	t.avl = avl{update: t.updateMax}
+	return t
+}
+
+// Root returns the root of the tree. Each node's Value is internal to the tree and must not be changed.
+func (t *intervalTree) Root() *TreeNode {
+	return t.root
+}
+
+// Len returns the number of intervals in the tree.
+func (t *intervalTree) Len() int {
+	return t.size
+}
+
+// Intervals returns all intervals ordered by (Start, End).
+func (t *intervalTree) Intervals() []Interval {
+	var intervals []Interval
+	walkInOrder(t.root, func(node *TreeNode) {
+		intervals = append(intervals, entryOf(node).interval)
+	})
+
+	return intervals
+}
+
+// compare orders intervals by Start, then End.
+func (t *intervalTree) compare(a, b Interval) int {
+	switch {
+	case t.less(a.Start, b.Start):
+		return -1
+	case t.less(b.Start, a.Start):
+		return 1
+	case t.less(a.End, b.End):
+		return -1
+	case t.less(b.End, a.End):
+		return 1
+	}
+
+	return 0
+}
+
+// overlaps returns whether iv overlaps [start, end).
+func (t *intervalTree) overlaps(iv Interval, start, end containers.Value) bool {
+	return t.less(iv.Start, end) && t.less(start, iv.End)
+}
+
+// Insert adds an interval. The same interval can be added more than once.
+func (t *intervalTree) Insert(iv Interval) error {
+	if !t.less(iv.Start, iv.End) {
+		return errors.Errorf("interval start must be less than its end, got [%v, %v)", iv.Start, iv.End)
+	}
+
+	t.root = t.insert(t.root, nil, iv)
+	t.size++
+	return nil
+}
+
+func (t *intervalTree) insert(node, parent *TreeNode, iv Interval) *TreeNode {
+	if node == nil {
+		return &TreeNode{
+			Value:  &intervalEntry{avlState: avlState{height: 1}, interval: iv, max: iv.End},
+			Parent: parent,
+		}
+	}
+
+	if t.compare(iv, entryOf(node).interval) < 0 {
+		node.Left = t.insert(node.Left, node, iv)
+	} else {
+		node.Right = t.insert(node.Right, node, iv)
+	}
+
+	return t.rebalance(node)
+}
+
+// Delete removes one interval with the same Start and End as iv, and a Value equal to iv.Value.
+// Values must be comparable. It returns whether an interval was removed.
+func (t *intervalTree) Delete(iv Interval) bool {
+	var deleted bool
+	t.root, deleted = t.delete(t.root, iv)
+	if deleted {
+		t.size--
+	}
+
+	return deleted
+}
+
+func (t *intervalTree) delete(node *TreeNode, iv Interval) (*TreeNode, bool) {
+	if node == nil {
+		return nil, false
+	}
+
+	var deleted bool
+	e := entryOf(node)
+	switch c := t.compare(iv, e.interval); {
+	case c < 0:
+		node.Left, deleted = t.delete(node.Left, iv)
+	case c > 0:
+		node.Right, deleted = t.delete(node.Right, iv)
+	case e.interval.Value == iv.Value:
+		return t.remove(node), true
+	default: // rotations can put equal intervals on both sides
+		if node.Left, deleted = t.delete(node.Left, iv); !deleted {
+			node.Right, deleted = t.delete(node.Right, iv)
+		}
+	}
+	if !deleted {
+		return node, false
+	}
+
+	return t.rebalance(node), true
+}
+
+// updateMax recomputes the max of node from its children.
+func (t *intervalTree) updateMax(node *TreeNode) {
+	e := entryOf(node)
+	e.max = e.interval.End
+	for _, child := range []*TreeNode{node.Left, node.Right} {
+		if child != nil && t.less(e.max, entryOf(child).max) {
+			e.max = entryOf(child).max
+		}
+	}
+}
+
+// Overlaps returns the intervals overlapping [start, end), ordered by (Start, End).
+func (t *intervalTree) Overlaps(start, end containers.Value) []Interval {
+	var intervals []Interval
+	t.collect(t.root, start, func(iv Interval) bool {
+		return !t.less(iv.Start, end)
+	}, &intervals)
+
+	return intervals
+}
+
+// Stab returns the intervals containing point, ordered by (Start, End).
+func (t *intervalTree) Stab(point containers.Value) []Interval {
+	var intervals []Interval
+	t.collect(t.root, point, func(iv Interval) bool {
+		return t.less(point, iv.Start)
+	}, &intervals)
+
+	return intervals
+}
+
+// collect appends intervals of the subtree at node which end after start and are not too late.
+// Subtrees whose max is not after start are skipped, as are intervals after the first one which is too late.
+func (t *intervalTree) collect(node *TreeNode, start containers.Value, tooLate func(Interval) bool, intervals *[]Interval) {
+	if node == nil || !t.less(start, entryOf(node).max) {
+		return
+	}
+
+	t.collect(node.Left, start, tooLate, intervals)
+
+	iv := entryOf(node).interval
+	if tooLate(iv) { // so is the right subtree, which starts even later
+		return
+	}
+	if t.less(start, iv.End) {
+		*intervals = append(*intervals, iv)
+	}
+	t.collect(node.Right, start, tooLate, intervals)
+}
+
+// AnyOverlap returns an interval overlapping [start, end), if there is one.
+func (t *intervalTree) AnyOverlap(start, end containers.Value) (Interval, bool) {
+	node := t.root
+	for node != nil {
+		iv := entryOf(node).interval
+		if t.overlaps(iv, start, end) {
+			return iv, true
+		}
+
+		// if the left subtree may overlap but does not, no interval to the right does either
+		if node.Left != nil && t.less(start, entryOf(node.Left).max) {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+
+	return Interval{}, false
+}
+
+// validate checks the parent links, heights, balance and max of every node, and the order of intervals.
+func (t *intervalTree) validate() error {
+	if t.root != nil && t.root.Parent != nil {
+		return errors.New("root has a parent")
+	}
+
+	size, err := t.validateNode(t.root)
+	if err != nil {
+		return err
+	}
+	if size != t.size {
+		return errors.Errorf("size is %d, but there are %d nodes", t.size, size)
+	}
+
+	intervals := t.Intervals()
+	for i := 1; i < len(intervals); i++ {
+		if t.compare(intervals[i-1], intervals[i]) > 0 {
+			return errors.Errorf("%v is before %v in order", intervals[i-1], intervals[i])
+		}
+	}
+
+	return nil
+}
+
+func (t *intervalTree) validateNode(node *TreeNode) (int, error) {
+	if node == nil {
+		return 0, nil
+	}
+
+	e := entryOf(node)
+	wantHeight, wantMax := 1, e.interval.End
+	size := 1
+	for _, child := range []*TreeNode{node.Left, node.Right} {
+		if child == nil {
+			continue
+		}
+		if child.Parent != node {
+			return 0, errors.Errorf("child of %v does not link back to it", e.interval)
+		}
+
+		n, err := t.validateNode(child)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+
+		c := entryOf(child)
+		if c.height+1 > wantHeight {
+			wantHeight = c.height + 1
+		}
+		if t.less(wantMax, c.max) {
+			wantMax = c.max
+		}
+	}
+
+	if e.height != wantHeight {
+		return 0, errors.Errorf("height of %v is %d, want %d", e.interval, e.height, wantHeight)
+	}
+	if balance := height(node.Left) - height(node.Right); balance < -1 || balance > 1 {
+		return 0, errors.Errorf("%v is not balanced: %d", e.interval, balance)
+	}
+	if t.less(e.max, wantMax) || t.less(wantMax, e.max) {
+		return 0, errors.Errorf("max of %v is %v, want %v", e.interval, e.max, wantMax)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,250 @@
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func intLess(a, b containers.Value) bool {
+	return a.(int) < b.(int)
+}
+
+func TestIntervalTreeInsert(t *testing.T) {
+	var testCases = map[string]struct {
+		interval Interval
+		isErr    bool
+	}{
+		"valid": {
+			interval: Interval{Start: 1, End: 2},
+		},
+		"empty": {
+			interval: Interval{Start: 2, End: 2},
+			isErr:    true,
+		},
+		"reversed": {
+			interval: Interval{Start: 3, End: 2},
+			isErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := NewIntervalTree(intLess).Insert(tc.interval)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestIntervalTreeQueries(t *testing.T) {
+	// bookings of a room, in hours
+	tree := NewIntervalTree(intLess)
+	for _, iv := range []Interval{
+		{Start: 9, End: 10, Value: "standup"},
+		{Start: 10, End: 12, Value: "design review"},
+		{Start: 11, End: 13, Value: "lunch"},
+		{Start: 14, End: 15, Value: "1:1"},
+		{Start: 8, End: 18, Value: "on call"},
+	} {
+		if err := tree.Insert(iv); err != nil {
+			t.Fatalf("cannot insert %v: %v", iv, err)
+		}
+	}
+	if err := tree.validate(); err != nil {
+		t.Fatalf("invalid tree: %v", err)
+	}
+
+	values := func(intervals []Interval) []containers.Value {
+		var vals []containers.Value
+		for _, iv := range intervals {
+			vals = append(vals, iv.Value)
+		}
+		return vals
+	}
+
+	var testCases = map[string]struct {
+		start, end int
+		overlaps   []containers.Value
+		stab       []containers.Value // at start
+	}{
+		"beforeAll": {
+			start: 6, end: 8,
+		},
+		"touchingIsNotOverlapping": {
+			start: 13, end: 14,
+			overlaps: []containers.Value{"on call"},
+			stab:     []containers.Value{"on call"},
+		},
+		"several": {
+			start: 10, end: 12,
+			overlaps: []containers.Value{"on call", "design review", "lunch"},
+			stab:     []containers.Value{"on call", "design review"},
+		},
+		"all": {
+			start: 0, end: 24,
+			overlaps: []containers.Value{"on call", "standup", "design review", "lunch", "1:1"},
+		},
+		"afterAll": {
+			start: 18, end: 20,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.overlaps, values(tree.Overlaps(tc.start, tc.end)); !cmp.Equal(want, got) {
+				t.Fatalf("overlaps: want= %v, got= %v", want, got)
+			}
+			if want, got := tc.stab, values(tree.Stab(tc.start)); !cmp.Equal(want, got) {
+				t.Fatalf("stab: want= %v, got= %v", want, got)
+			}
+			if _, got := tree.AnyOverlap(tc.start, tc.end); len(tc.overlaps) > 0 != got {
+				t.Fatalf("any overlap: want= %v, got= %v", len(tc.overlaps) > 0, got)
+			}
+		})
+	}
+}
+
+func TestIntervalTreeDelete(t *testing.T) {
+	tree := NewIntervalTree(intLess)
+	tree.Insert(Interval{Start: 1, End: 5, Value: "a"})
+	tree.Insert(Interval{Start: 1, End: 5, Value: "b"})
+	tree.Insert(Interval{Start: 2, End: 3, Value: "c"})
+
+	if tree.Delete(Interval{Start: 1, End: 5, Value: "x"}) {
+		t.Fatalf("want no interval with another value deleted")
+	}
+	if !tree.Delete(Interval{Start: 1, End: 5, Value: "b"}) {
+		t.Fatalf("want interval deleted")
+	}
+	if err := tree.validate(); err != nil {
+		t.Fatalf("invalid tree: %v", err)
+	}
+
+	want := []Interval{{Start: 1, End: 5, Value: "a"}, {Start: 2, End: 3, Value: "c"}}
+	if got := tree.Intervals(); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if want, got := 2, tree.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+// TestIntervalTreeAgainstBruteForce inserts and deletes random intervals,
+// validating the tree and comparing queries with a scan of all intervals.
+func TestIntervalTreeAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	tree := NewIntervalTree(intLess)
+	var all []Interval
+
+	randomInterval := func() Interval {
+		start := rng.Intn(1000)
+		return Interval{Start: start, End: start + 1 + rng.Intn(50), Value: rng.Intn(3)}
+	}
+	sortIntervals := func(intervals []Interval) {
+		sort.SliceStable(intervals, func(i, j int) bool {
+			a, b := intervals[i], intervals[j]
+			if a.Start != b.Start {
+				return a.Start.(int) < b.Start.(int)
+			}
+			if a.End != b.End {
+				return a.End.(int) < b.End.(int)
+			}
+			return a.Value.(int) < b.Value.(int)
+		})
+	}
+
+	for step := 0; step < 3000; step++ {
+		if len(all) > 0 && rng.Intn(3) == 0 {
+			i := rng.Intn(len(all))
+			if !tree.Delete(all[i]) {
+				t.Fatalf("step %d: cannot delete %v", step, all[i])
+			}
+			all = append(all[:i], all[i+1:]...)
+		} else {
+			iv := randomInterval()
+			if err := tree.Insert(iv); err != nil {
+				t.Fatalf("step %d: cannot insert %v: %v", step, iv, err)
+			}
+			all = append(all, iv)
+		}
+		if err := tree.validate(); err != nil {
+			t.Fatalf("step %d: invalid tree: %v", step, err)
+		}
+
+		q := randomInterval()
+		var overlaps, stab []Interval
+		for _, iv := range all {
+			if iv.Start.(int) < q.End.(int) && q.Start.(int) < iv.End.(int) {
+				overlaps = append(overlaps, iv)
+			}
+			if iv.Start.(int) <= q.Start.(int) && q.Start.(int) < iv.End.(int) {
+				stab = append(stab, iv)
+			}
+		}
+
+		got := tree.Overlaps(q.Start, q.End)
+		sortIntervals(overlaps)
+		sortIntervals(got) // the tree does not order equal intervals by value
+		if !cmp.Equal(overlaps, got) {
+			t.Fatalf("step %d: overlaps of %v: want= %v, got= %v", step, q, overlaps, got)
+		}
+
+		got = tree.Stab(q.Start)
+		sortIntervals(stab)
+		sortIntervals(got)
+		if !cmp.Equal(stab, got) {
+			t.Fatalf("step %d: stab %v: want= %v, got= %v", step, q.Start, stab, got)
+		}
+
+		iv, ok := tree.AnyOverlap(q.Start, q.End)
+		if want := len(overlaps) > 0; want != ok {
+			t.Fatalf("step %d: any overlap of %v: want= %v, got= %v", step, q, want, ok)
+		}
+		if ok && !(iv.Start.(int) < q.End.(int) && q.Start.(int) < iv.End.(int)) {
+			t.Fatalf("step %d: %v does not overlap %v", step, iv, q)
+		}
+	}
+}
+
+func BenchmarkIntervalTreeOverlaps(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		rng := rand.New(rand.NewSource(1))
+		tree := NewIntervalTree(intLess)
+		var all []Interval
+		for i := 0; i < n; i++ {
+			start := rng.Intn(n * 10)
+			iv := Interval{Start: start, End: start + 1 + rng.Intn(20)}
+			tree.Insert(iv)
+			all = append(all, iv)
+		}
+
+		b.Run(fmt.Sprintf("tree/n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				start := i % (n * 10)
+				tree.Overlaps(start, start+10)
+			}
+		})
+		b.Run(fmt.Sprintf("bruteForce/n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				start := i % (n * 10)
+				var overlaps []Interval
+				for _, iv := range all {
+					if iv.Start.(int) < start+10 && start < iv.End.(int) {
+						overlaps = append(overlaps, iv)
+					}
+				}
+			}
+		})
+	}
+}
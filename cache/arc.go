@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// arc is a concrete implementation of Cache with the Adaptive Replacement Cache policy
+// ("ARC: A Self-Tuning, Low Overhead Replacement Cache" - Megiddo & Modha 2003), extended to weighted entries.
+// Entries used once are in t1, entries used more than once are in t2. b1 and b2 are ghosts of entries evicted
+// from t1 and t2: hitting them moves the target weight p of t1 towards recency or frequency.
+type arc struct {
+	core
+	t1, t2 segment
+	b1, b2 segment
+	p      int64 // target weight of t1
+}
+
+// NewARC returns a Cache with the Adaptive Replacement Cache policy, which balances recency and frequency.
+func NewARC(opts Options) (*arc, error) {
+	c, err := newCore(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &arc{core: c}, nil
+}
+
+func (c *arc) resident(e *entry) bool {
+	return e.seg == &c.t1 || e.seg == &c.t2
+}
+
+// Get returns the value of key, and whether it was found and not expired.
+func (c *arc) Get(key containers.Value) (containers.Value, bool) {
+	e, ok := c.entries[key]
+	if !ok || !c.resident(e) {
+		c.stats.Misses++
+		return nil, false
+	}
+	if c.expired(e) {
+		c.remove(e)
+		c.evicted(e, Expired)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	e.seg.remove(e)
+	c.t2.pushFront(e)
+	return e.value, true
+}
+
+// Set adds or replaces the value of key, evicting other entries if needed.
+// Replacing a value counts as a use.
+func (c *arc) Set(key, value containers.Value) bool {
+	weight, ok := c.weigh(key, value)
+	if !ok {
+		c.Delete(key)
+		return false
+	}
+
+	e, ok := c.entries[key]
+	switch {
+	case !ok:
+		c.makeRoom(weight, false)
+		e = c.newEntry(key, value, weight)
+		c.entries[key] = e
+		c.t1.pushFront(e)
+		c.trimGhosts()
+		return true
+	case e.seg == &c.b1:
+		c.p = min64(c.opts.Capacity, c.p+max64(1, c.b2.weight/c.b1.weight)*weight)
+	case e.seg == &c.b2:
+		c.p = max64(0, c.p-max64(1, c.b1.weight/c.b2.weight)*weight)
+	}
+
+	inB2 := e.seg == &c.b2
+	e.seg.remove(e)
+	c.makeRoom(weight, inB2)
+	e.value, e.weight = value, weight
+	c.refresh(e)
+	c.t2.pushFront(e)
+	c.trimGhosts()
+	return true
+}
+
+// makeRoom evicts entries until an entry of weight fits.
+func (c *arc) makeRoom(weight int64, inB2 bool) {
+	for c.t1.len()+c.t2.len() > 0 && c.t1.weight+c.t2.weight+weight > c.opts.Capacity {
+		c.replace(inB2)
+	}
+}
+
+// replace moves the least recently used entry of t1 or t2 to its ghost list, depending on the target p.
+func (c *arc) replace(inB2 bool) {
+	from, to := &c.t2, &c.b2
+	if c.t1.len() > 0 && (c.t1.weight > c.p || (inB2 && c.t1.weight == c.p) || c.t2.len() == 0) {
+		from, to = &c.t1, &c.b1
+	}
+
+	victim := from.back()
+	from.remove(victim)
+	c.evicted(victim, Evicted)
+	victim.value = nil
+	to.pushFront(victim)
+}
+
+// trimGhosts forgets the oldest ghosts, so that t1 and b1 weigh at most the capacity,
+// and all lists at most twice the capacity.
+func (c *arc) trimGhosts() {
+	for c.b1.len() > 0 && c.t1.weight+c.b1.weight > c.opts.Capacity {
+		c.remove(c.b1.back())
+	}
+	for c.t1.weight+c.t2.weight+c.b1.weight+c.b2.weight > 2*c.opts.Capacity {
+		ghosts := &c.b2
+		if ghosts.len() == 0 {
+			ghosts = &c.b1
+		}
+		c.remove(ghosts.back())
+	}
+}
+
+// Delete removes key, and returns whether it was there. The key's ghost is forgotten as well.
+func (c *arc) Delete(key containers.Value) bool {
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	wasResident := c.resident(e)
+	c.remove(e)
+	return wasResident
+}
+
+func (c *arc) remove(e *entry) {
+	e.seg.remove(e)
+	delete(c.entries, e.key)
+}
+
+// Len returns the number of entries.
+func (c *arc) Len() int {
+	return c.t1.len() + c.t2.len()
+}
+
+// Weight returns the total weight of entries.
+func (c *arc) Weight() int64 {
+	return c.t1.weight + c.t2.weight
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,228 @@
+// Package cache provides in-memory caches with LRU, LFU, ARC and 2Q eviction policies.
+package cache
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+	"github.com/bitsgofer/containers/list"
+)
+
+// Cache provides cache APIs. Implementations are not safe for concurrent use, unless wrapped by NewSharded().
+type Cache interface {
+	// Get returns the value of key, and whether it was found and not expired.
+	Get(key containers.Value) (containers.Value, bool)
+	// Set adds or replaces the value of key, evicting other entries if needed.
+	// It returns false if the entry cannot be stored because of its weight.
+	Set(key, value containers.Value) bool
+	// Delete removes key, and returns whether it was there.
+	Delete(key containers.Value) bool
+	// Len returns the number of entries, including expired entries which were not removed yet.
+	Len() int
+	// Weight returns the total weight of entries.
+	Weight() int64
+	Stats() Stats
+}
+
+// Reason tells why an entry left the cache.
+type Reason int
+
+const (
+	// Evicted entries were removed to make room for others.
+	Evicted Reason = iota
+	// Expired entries were removed because their TTL passed.
+	Expired
+)
+
+// Options configures a cache.
+type Options struct {
+	// Capacity is the maximum number of entries, or the maximum total weight if Weigher is set. It must be positive.
+	Capacity int64
+	// Weigher returns the weight of an entry, which must be positive. If nil, every entry weighs 1.
+	Weigher func(key, value containers.Value) int64
+	// OnEvict is called when an entry is evicted or expires, but not when it is deleted or replaced.
+	OnEvict func(key, value containers.Value, reason Reason)
+	// TTL is how long entries are kept after they are set. 0 keeps them until they are evicted.
+	TTL time.Duration
+	// Clock tells the time for TTL, clock.Real if nil.
+	Clock clock.Clock
+}
+
+// Stats counts cache events.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+}
+
+// HitRatio returns the ratio of hits among lookups, or 0 if there was none.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// add returns the sum of s and other.
+func (s Stats) add(other Stats) Stats {
+	return Stats{
+		Hits:        s.Hits + other.Hits,
+		Misses:      s.Misses + other.Misses,
+		Evictions:   s.Evictions + other.Evictions,
+		Expirations: s.Expirations + other.Expirations,
+	}
+}
+
+// entry is a key in a cache. Ghost entries of ARC and 2Q only remember the key and its weight.
+type entry struct {
+	list.Link
+	key       containers.Value
+	value     containers.Value
+	weight    int64
+	expiresAt time.Time
+
+	seg  *segment      // the list holding the entry
+	freq *list.Element // the frequency bucket of the entry, only used by LFU
+}
+
+// links is the intrusive list of entries in a segment, created by list.NewIntrusive().
+type links interface {
+	Len() int
+	Front() list.Node
+	Back() list.Node
+	Next(n list.Node) list.Node
+	Prev(n list.Node) list.Node
+	PushFront(n list.Node)
+	Remove(n list.Node) bool
+	MoveToFront(n list.Node)
+}
+
+// segment is a list of entries from the most (front) to the least (back) recently used, with their total weight.
+// Its zero value is an empty segment.
+type segment struct {
+	entries links
+	weight  int64
+}
+
+func (s *segment) len() int {
+	if s.entries == nil {
+		return 0
+	}
+
+	return s.entries.Len()
+}
+
+func (s *segment) pushFront(e *entry) {
+	if s.entries == nil {
+		s.entries = list.NewIntrusive()
+	}
+	s.entries.PushFront(e)
+	s.weight += e.weight
+	e.seg = s
+}
+
+func (s *segment) remove(e *entry) {
+	s.entries.Remove(e)
+	s.weight -= e.weight
+	e.seg = nil
+}
+
+func (s *segment) moveToFront(e *entry) {
+	s.entries.MoveToFront(e)
+}
+
+// back returns the least recently used entry, or nil if the segment is empty.
+func (s *segment) back() *entry {
+	if s.entries == nil {
+		return nil
+	}
+
+	return entryOf(s.entries.Back())
+}
+
+// before returns the entry used right after e, or nil if e is the most recently used one.
+func (s *segment) before(e *entry) *entry {
+	return entryOf(s.entries.Prev(e))
+}
+
+func entryOf(n list.Node) *entry {
+	if n == nil {
+		return nil
+	}
+
+	return n.(*entry)
+}
+
+// core is shared by all policies: it validates options, weighs entries, handles TTL and counts events.
+type core struct {
+	opts    Options
+	entries map[containers.Value]*entry // including ghost entries
+	stats   Stats
+}
+
+func newCore(opts Options) (core, error) {
+	if opts.Capacity <= 0 {
+		return core{}, errors.Errorf("capacity must be positive, got %d", opts.Capacity)
+	}
+	if opts.TTL < 0 {
+		return core{}, errors.Errorf("TTL must not be negative, got %v", opts.TTL)
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+
+	return core{
+		opts:    opts,
+		entries: map[containers.Value]*entry{},
+	}, nil
+}
+
+// weigh returns the weight of an entry, and whether it can be stored at all.
+func (c *core) weigh(key, value containers.Value) (int64, bool) {
+	if c.opts.Weigher == nil {
+		return 1, true
+	}
+
+	w := c.opts.Weigher(key, value)
+	return w, w > 0 && w <= c.opts.Capacity
+}
+
+// newEntry returns an entry for key, expiring after the TTL.
+func (c *core) newEntry(key, value containers.Value, weight int64) *entry {
+	e := &entry{key: key, value: value, weight: weight}
+	c.refresh(e)
+	return e
+}
+
+// refresh restarts the TTL of e.
+func (c *core) refresh(e *entry) {
+	if c.opts.TTL > 0 {
+		e.expiresAt = c.opts.Clock.Now().Add(c.opts.TTL)
+	}
+}
+
+func (c *core) expired(e *entry) bool {
+	return c.opts.TTL > 0 && !c.opts.Clock.Now().Before(e.expiresAt)
+}
+
+// evicted counts an entry leaving the cache, and calls OnEvict.
+func (c *core) evicted(e *entry, reason Reason) {
+	if reason == Expired {
+		c.stats.Expirations++
+	} else {
+		c.stats.Evictions++
+	}
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(e.key, e.value, reason)
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *core) Stats() Stats {
+	return c.stats
+}
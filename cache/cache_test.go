@@ -0,0 +1,490 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+var (
+	_ Cache = (*lru)(nil)
+	_ Cache = (*lfu)(nil)
+	_ Cache = (*arc)(nil)
+	_ Cache = (*twoQ)(nil)
+	_ Cache = (*sharded)(nil)
+)
+
+var implementations = map[string]func(opts Options) (Cache, error){
+	"lru": func(opts Options) (Cache, error) { return NewLRU(opts) },
+	"lfu": func(opts Options) (Cache, error) { return NewLFU(opts) },
+	"arc": func(opts Options) (Cache, error) { return NewARC(opts) },
+	"2q":  func(opts Options) (Cache, error) { return New2Q(opts) },
+	"sharded": func(opts Options) (Cache, error) {
+		return NewSharded(1, nil, func() (Cache, error) { return NewLRU(opts) })
+	},
+}
+
+// keys returns the sorted keys of entries in c, without touching them.
+func keys(c Cache) []containers.Value {
+	var segments []*segment
+	switch c := c.(type) {
+	case *lru:
+		segments = []*segment{&c.items}
+	case *lfu:
+		for b := c.buckets.Front(); b != nil; b = b.Next() {
+			segments = append(segments, &b.Value.(*bucket).items)
+		}
+	case *arc:
+		segments = []*segment{&c.t1, &c.t2}
+	case *twoQ:
+		segments = []*segment{&c.a1in, &c.am}
+	case *sharded:
+		var all []containers.Value
+		for i := range c.shards {
+			all = append(all, keys(c.shards[i].cache)...)
+		}
+		return all
+	}
+
+	var all []containers.Value
+	for _, s := range segments {
+		if s.entries == nil {
+			continue
+		}
+		for n := s.entries.Front(); n != nil; n = s.entries.Next(n) {
+			all = append(all, n.(*entry).key)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return fmt.Sprint(all[i]) < fmt.Sprint(all[j]) })
+
+	return all
+}
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		opts  Options
+		isErr bool
+	}{
+		"valid": {
+			opts: Options{Capacity: 10},
+		},
+		"withTTL": {
+			opts: Options{Capacity: 10, TTL: time.Minute},
+		},
+		"zeroCapacity": {
+			opts:  Options{},
+			isErr: true,
+		},
+		"negativeTTL": {
+			opts:  Options{Capacity: 10, TTL: -time.Minute},
+			isErr: true,
+		},
+	}
+
+	for impl, newCache := range implementations {
+		for name, tc := range testCases {
+			t.Run(impl+"/"+name, func(t *testing.T) {
+				_, err := newCache(tc.opts)
+
+				if tc.isErr && err == nil {
+					t.Fatalf("want error, got none")
+				}
+				if !tc.isErr && err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+			})
+		}
+	}
+}
+
+func TestGetSetDelete(t *testing.T) {
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c, err := newCache(Options{Capacity: 10})
+			if err != nil {
+				t.Fatalf("need a valid cache to test, got %q", err)
+			}
+
+			if _, ok := c.Get("a"); ok {
+				t.Fatalf("want missing key not found")
+			}
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("a", 3)
+			if val, ok := c.Get("a"); !ok || val != 3 {
+				t.Fatalf("want replaced value 3, got= %v, %v", val, ok)
+			}
+			if want, got := 2, c.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+
+			if !c.Delete("a") {
+				t.Fatalf("want existing key deleted")
+			}
+			if c.Delete("a") {
+				t.Fatalf("want missing key not deleted")
+			}
+			if _, ok := c.Get("a"); ok {
+				t.Fatalf("want deleted key not found")
+			}
+			if val, ok := c.Get("b"); !ok || val != 2 {
+				t.Fatalf("want value 2, got= %v, %v", val, ok)
+			}
+
+			if want, got := (Stats{Hits: 2, Misses: 2}), c.Stats(); want != got {
+				t.Fatalf("stats: want= %+v, got= %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			var evicted []containers.Value
+			c, _ := newCache(Options{
+				Capacity: 10,
+				OnEvict: func(key, value containers.Value, reason Reason) {
+					if reason != Evicted {
+						t.Fatalf("want reason Evicted, got= %v", reason)
+					}
+					if value != key.(int)*2 {
+						t.Fatalf("want value of %v, got= %v", key, value)
+					}
+					evicted = append(evicted, key)
+				},
+			})
+
+			for i := 0; i < 100; i++ {
+				c.Set(i, i*2)
+				if c.Len() > 10 {
+					t.Fatalf("want at most 10 entries, got= %v", c.Len())
+				}
+			}
+
+			if want, got := 10, c.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+			if want, got := int64(10), c.Weight(); want != got {
+				t.Fatalf("weight: want= %v, got= %v", want, got)
+			}
+			if want, got := 90, len(evicted); want != got {
+				t.Fatalf("evicted: want= %v, got= %v", want, got)
+			}
+			if want, got := uint64(90), c.Stats().Evictions; want != got {
+				t.Fatalf("evictions: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestWeigher(t *testing.T) {
+	byLength := func(key, value containers.Value) int64 { return int64(len(value.(string))) }
+
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c, _ := newCache(Options{Capacity: 10, Weigher: byLength})
+
+			if !c.Set("a", "aaaa") || !c.Set("b", "bbbb") {
+				t.Fatalf("want entries which fit set")
+			}
+			if want, got := int64(8), c.Weight(); want != got {
+				t.Fatalf("weight: want= %v, got= %v", want, got)
+			}
+
+			if c.Set("c", "ccccccccccc") {
+				t.Fatalf("want entry heavier than the capacity rejected")
+			}
+			if c.Set("d", "") {
+				t.Fatalf("want entry without weight rejected")
+			}
+			if want, got := 2, c.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+
+			c.Set("c", "cccccc")
+			if c.Weight() > 10 {
+				t.Fatalf("want weight at most 10, got= %v", c.Weight())
+			}
+			if _, ok := c.Get("c"); !ok {
+				t.Fatalf("want new entry kept")
+			}
+
+			c.Set("c", "cccccccccc")
+			if want, got := []containers.Value{"c"}, keys(c); !cmp.Equal(want, got) {
+				t.Fatalf("keys: want= %v, got= %v", want, got)
+			}
+			if want, got := int64(10), c.Weight(); want != got {
+				t.Fatalf("weight: want= %v, got= %v", want, got)
+			}
+
+			if c.Set("c", "ccccccccccccc") {
+				t.Fatalf("want entry heavier than the capacity rejected")
+			}
+			if _, ok := c.Get("c"); ok {
+				t.Fatalf("want rejected replacement to remove the stale entry")
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			var expired []containers.Value
+			fake := clock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+			c, _ := newCache(Options{
+				Capacity: 10,
+				TTL:      time.Minute,
+				Clock:    fake,
+				OnEvict: func(key, value containers.Value, reason Reason) {
+					if reason != Expired {
+						t.Fatalf("want reason Expired, got= %v", reason)
+					}
+					expired = append(expired, key)
+				},
+			})
+
+			c.Set("a", 1)
+			fake.Advance(30 * time.Second)
+			c.Set("b", 2)
+			fake.Advance(29 * time.Second)
+			if _, ok := c.Get("a"); !ok {
+				t.Fatalf("want key found before it expires")
+			}
+
+			fake.Advance(time.Second)
+			if _, ok := c.Get("a"); ok {
+				t.Fatalf("want expired key not found")
+			}
+			if _, ok := c.Get("b"); !ok {
+				t.Fatalf("want other key found before it expires")
+			}
+
+			c.Set("b", 3) // restarts its TTL
+			fake.Advance(59 * time.Second)
+			if val, ok := c.Get("b"); !ok || val != 3 {
+				t.Fatalf("want replaced key found, got= %v, %v", val, ok)
+			}
+
+			if want, got := []containers.Value{"a"}, expired; !cmp.Equal(want, got) {
+				t.Fatalf("expired: want= %v, got= %v", want, got)
+			}
+			if want, got := (Stats{Hits: 3, Misses: 1, Expirations: 1}), c.Stats(); want != got {
+				t.Fatalf("stats: want= %+v, got= %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestEvictionOrder(t *testing.T) {
+	var testCases = map[string]struct {
+		newCache func(opts Options) (Cache, error)
+		ops      []string // "+k" sets k, "k" gets k
+		want     []containers.Value
+	}{
+		"lruEvictsLeastRecentlyUsed": {
+			newCache: implementations["lru"],
+			ops:      []string{"+a", "+b", "+c", "a", "+d", "+e"},
+			want:     []containers.Value{"a", "d", "e"},
+		},
+		"lfuEvictsLeastFrequentlyUsed": {
+			newCache: implementations["lfu"],
+			ops:      []string{"+a", "+b", "+c", "a", "a", "b", "+d", "+e"},
+			want:     []containers.Value{"a", "b", "e"},
+		},
+		"lfuBreaksTiesByRecency": {
+			newCache: implementations["lfu"],
+			ops:      []string{"+a", "+b", "+c", "c", "b", "a", "+d"},
+			want:     []containers.Value{"a", "b", "d"},
+		},
+		"arcKeepsFrequentlyUsed": {
+			newCache: implementations["arc"],
+			ops:      []string{"+a", "+b", "a", "b", "+c", "+d", "+e"},
+			want:     []containers.Value{"a", "b", "e"},
+		},
+		"2qPromotesRememberedKeys": {
+			newCache: implementations["2q"],
+			ops:      []string{"+a", "+b", "+c", "+d", "+a", "+e", "+f"},
+			want:     []containers.Value{"a", "e", "f"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c, _ := tc.newCache(Options{Capacity: 3})
+			for _, op := range tc.ops {
+				if op[0] == '+' {
+					c.Set(op[1:], op)
+				} else {
+					c.Get(op)
+				}
+			}
+
+			if want, got := tc.want, keys(c); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestScanResistance checks which policies keep a hot working set while a scan reads many keys once.
+func TestScanResistance(t *testing.T) {
+	var testCases = map[string]struct {
+		keepsHotKeys bool
+	}{
+		"lru": {},
+		"lfu": {keepsHotKeys: true},
+		"arc": {keepsHotKeys: true},
+		"2q":  {keepsHotKeys: true},
+	}
+
+	for impl, tc := range testCases {
+		t.Run(impl, func(t *testing.T) {
+			c, _ := implementations[impl](Options{Capacity: 8})
+			access := func(key containers.Value) {
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, key)
+				}
+			}
+
+			hot := []containers.Value{"h0", "h1", "h2", "h3"}
+			cold := 0
+			for round := 0; round < 10; round++ {
+				for _, key := range hot {
+					access(key)
+				}
+				for i := 0; i < 2; i++ {
+					access(cold)
+					cold++
+				}
+			}
+			for i := 0; i < 100; i++ {
+				access(cold)
+				cold++
+			}
+
+			kept := 0
+			for _, key := range keys(c) {
+				if _, isHot := key.(string); isHot {
+					kept++
+				}
+			}
+			if want, got := tc.keepsHotKeys, kept == len(hot); want != got {
+				t.Fatalf("want hot keys kept= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestARCAdapts(t *testing.T) {
+	c, _ := NewARC(Options{Capacity: 4})
+	for _, key := range []string{"a", "b", "c", "d"} {
+		c.Set(key, key)
+	}
+	c.Get("c")
+	c.Get("d")
+	c.Set("e", "e") // evicts a from t1
+	if e, ok := c.entries["a"]; !ok || e.seg != &c.b1 {
+		t.Fatalf("want a in b1")
+	}
+
+	c.Set("a", "a") // recency would have kept a: grow t1
+	if want, got := int64(1), c.p; want != got {
+		t.Fatalf("p: want= %v, got= %v", want, got)
+	}
+	if e := c.entries["a"]; e.seg != &c.t2 {
+		t.Fatalf("want key set again moved to t2")
+	}
+
+	c.Set("f", "f") // t1 is at its target, so c is evicted from t2
+	if e, ok := c.entries["c"]; !ok || e.seg != &c.b2 {
+		t.Fatalf("want c in b2")
+	}
+	c.Set("c", "c") // frequency would have kept c: shrink t1
+	if want, got := int64(0), c.p; want != got {
+		t.Fatalf("p: want= %v, got= %v", want, got)
+	}
+}
+
+func TestGhostsAreBounded(t *testing.T) {
+	for _, impl := range []string{"arc", "2q"} {
+		t.Run(impl, func(t *testing.T) {
+			c, _ := implementations[impl](Options{Capacity: 16})
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 10000; i++ {
+				key := rng.Intn(100)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, key)
+				}
+			}
+
+			var all int
+			switch c := c.(type) {
+			case *arc:
+				all = len(c.entries)
+			case *twoQ:
+				all = len(c.entries)
+			}
+			if all > 32 {
+				t.Fatalf("want at most 32 entries and ghosts, got= %v", all)
+			}
+		})
+	}
+}
+
+// zipfKeys returns n keys following a Zipf distribution over keySpace keys, like typical cache lookups.
+func zipfKeys(n int, keySpace uint64) []containers.Value {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, keySpace-1)
+	keys := make([]containers.Value, n)
+	for i := range keys {
+		keys[i] = zipf.Uint64()
+	}
+
+	return keys
+}
+
+// TestZipfHitRatio checks that all policies get a reasonable hit ratio on a skewed workload.
+func TestZipfHitRatio(t *testing.T) {
+	lookups := zipfKeys(200000, 100000)
+
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c, _ := newCache(Options{Capacity: 1000})
+			for _, key := range lookups {
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, key)
+				}
+			}
+
+			ratio := c.Stats().HitRatio()
+			t.Logf("hit ratio= %.3f", ratio)
+			if ratio < 0.4 {
+				t.Fatalf("want hit ratio at least 0.4, got= %.3f", ratio)
+			}
+		})
+	}
+}
+
+func BenchmarkZipf(b *testing.B) {
+	lookups := zipfKeys(1<<16, 100000)
+
+	for impl, newCache := range implementations {
+		b.Run(impl, func(b *testing.B) {
+			c, _ := newCache(Options{Capacity: 1000})
+			for i := 0; i < b.N; i++ {
+				key := lookups[i&(len(lookups)-1)]
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/list"
+)
+
+// bucket holds entries used freq times, from the most to the least recently used.
+type bucket struct {
+	freq  uint64
+	items segment
+}
+
+// lfu is a concrete implementation of Cache, which evicts the least frequently used entries,
+// and the least recently used among them. Entries are kept in buckets of equal frequency,
+// so all operations are O(1) ("An O(1) algorithm for implementing the LFU cache eviction scheme" - Shah et al. 2010).
+type lfu struct {
+	core
+	buckets bucketList // *bucket by increasing freq, without empty buckets
+	weight  int64
+}
+
+// bucketList is the list of buckets, created by list.New().
+type bucketList interface {
+	Front() *list.Element
+	PushFront(v containers.Value) *list.Element
+	InsertAfter(v containers.Value, mark *list.Element) *list.Element
+	Remove(e *list.Element) containers.Value
+}
+
+// NewLFU returns a Cache evicting the least frequently used entries.
+func NewLFU(opts Options) (*lfu, error) {
+	c, err := newCore(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lfu{core: c, buckets: list.New()}, nil
+}
+
+// Get returns the value of key, and whether it was found and not expired.
+func (c *lfu) Get(key containers.Value) (containers.Value, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	if c.expired(e) {
+		c.remove(e)
+		c.evicted(e, Expired)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.touch(e)
+	return e.value, true
+}
+
+// touch moves e to the bucket of the next frequency.
+func (c *lfu) touch(e *entry) {
+	current := e.freq
+	b := current.Value.(*bucket)
+
+	next := current.Next()
+	if next == nil || next.Value.(*bucket).freq != b.freq+1 {
+		next = c.buckets.InsertAfter(&bucket{freq: b.freq + 1}, current)
+	}
+
+	b.items.remove(e)
+	next.Value.(*bucket).items.pushFront(e)
+	e.freq = next
+	if b.items.len() == 0 {
+		c.buckets.Remove(current)
+	}
+}
+
+// Set adds or replaces the value of key, evicting the least frequently used entries if needed.
+// Replacing a value counts as a use.
+func (c *lfu) Set(key, value containers.Value) bool {
+	weight, ok := c.weigh(key, value)
+	if !ok {
+		c.Delete(key)
+		return false
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.weight += weight - e.weight
+		e.seg.weight += weight - e.weight
+		e.value, e.weight = value, weight
+		c.refresh(e)
+		c.touch(e)
+		c.evict(e)
+		return true
+	}
+
+	// make room first, so the new entry is not the least frequently used one
+	c.weight += weight
+	c.evict(nil)
+
+	e := c.newEntry(key, value, weight)
+	c.entries[key] = e
+	first := c.buckets.Front()
+	if first == nil || first.Value.(*bucket).freq != 1 {
+		first = c.buckets.PushFront(&bucket{freq: 1})
+	}
+	first.Value.(*bucket).items.pushFront(e)
+	e.freq = first
+	return true
+}
+
+// evict removes the least frequently used entries other than keep, until the weight is within capacity.
+func (c *lfu) evict(keep *entry) {
+	for c.weight > c.opts.Capacity {
+		var victim *entry
+		for b := c.buckets.Front(); victim == nil; b = b.Next() {
+			items := &b.Value.(*bucket).items
+			if victim = items.back(); victim == keep {
+				victim = items.before(keep)
+			}
+		}
+
+		c.remove(victim)
+		c.evicted(victim, Evicted)
+	}
+}
+
+// Delete removes key, and returns whether it was there.
+func (c *lfu) Delete(key containers.Value) bool {
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	c.remove(e)
+	return true
+}
+
+func (c *lfu) remove(e *entry) {
+	b := e.freq.Value.(*bucket)
+	b.items.remove(e)
+	if b.items.len() == 0 {
+		c.buckets.Remove(e.freq)
+	}
+	e.freq = nil
+	c.weight -= e.weight
+	delete(c.entries, e.key)
+}
+
+// Len returns the number of entries.
+func (c *lfu) Len() int {
+	return len(c.entries)
+}
+
+// Weight returns the total weight of entries.
+func (c *lfu) Weight() int64 {
+	return c.weight
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// lru is a concrete implementation of Cache, which evicts the least recently used entries.
+type lru struct {
+	core
+	items segment
+}
+
+// NewLRU returns a Cache evicting the least recently used entries.
+func NewLRU(opts Options) (*lru, error) {
+	c, err := newCore(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lru{core: c}, nil
+}
+
+// Get returns the value of key, and whether it was found and not expired.
+func (c *lru) Get(key containers.Value) (containers.Value, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	if c.expired(e) {
+		c.remove(e)
+		c.evicted(e, Expired)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.items.moveToFront(e)
+	return e.value, true
+}
+
+// Set adds or replaces the value of key, evicting the least recently used entries if needed.
+func (c *lru) Set(key, value containers.Value) bool {
+	weight, ok := c.weigh(key, value)
+	if !ok {
+		c.Delete(key)
+		return false
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.items.weight += weight - e.weight
+		e.value, e.weight = value, weight
+		c.refresh(e)
+		c.items.moveToFront(e)
+	} else {
+		e = c.newEntry(key, value, weight)
+		c.entries[key] = e
+		c.items.pushFront(e)
+	}
+
+	for c.items.weight > c.opts.Capacity {
+		victim := c.items.back()
+		c.remove(victim)
+		c.evicted(victim, Evicted)
+	}
+	return true
+}
+
+// Delete removes key, and returns whether it was there.
+func (c *lru) Delete(key containers.Value) bool {
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	c.remove(e)
+	return true
+}
+
+func (c *lru) remove(e *entry) {
+	c.items.remove(e)
+	delete(c.entries, e.key)
+}
+
+// Len returns the number of entries.
+func (c *lru) Len() int {
+	return c.items.len()
+}
+
+// Weight returns the total weight of entries.
+func (c *lru) Weight() int64 {
+	return c.items.weight
+}
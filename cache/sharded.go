@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// shard is a cache with its lock.
+type shard struct {
+	mu    sync.Mutex
+	cache Cache
+}
+
+// sharded is a concrete implementation of Cache which is safe for concurrent use.
+// Keys are spread over shards by hash, each guarded by its own mutex.
+type sharded struct {
+	hash   hasher.Func
+	shards []shard
+}
+
+// NewSharded returns a Cache safe for concurrent use, which spreads keys over shards created by newShard.
+// Each shard has its own capacity, so the total capacity is shards times the capacity of a shard.
+// hash can be nil to use hasher.Default.
+func NewSharded(shards int, hash hasher.Func, newShard func() (Cache, error)) (*sharded, error) {
+	if shards <= 0 {
+		return nil, errors.Errorf("number of shards must be positive, got %d", shards)
+	}
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	c := &sharded{
+		hash:   hash,
+		shards: make([]shard, shards),
+	}
+	for i := range c.shards {
+		cache, err := newShard()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create shard %d", i)
+		}
+		c.shards[i].cache = cache
+	}
+
+	return c, nil
+}
+
+func (c *sharded) shard(key containers.Value) *shard {
+	return &c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+// Get returns the value of key, and whether it was found and not expired.
+func (c *sharded) Get(key containers.Value) (containers.Value, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cache.Get(key)
+}
+
+// Set adds or replaces the value of key, evicting other entries of its shard if needed.
+func (c *sharded) Set(key, value containers.Value) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cache.Set(key, value)
+}
+
+// Delete removes key, and returns whether it was there.
+func (c *sharded) Delete(key containers.Value) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cache.Delete(key)
+}
+
+// Len returns the number of entries in all shards.
+func (c *sharded) Len() int {
+	var n int
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		n += s.cache.Len()
+		s.mu.Unlock()
+	}
+
+	return n
+}
+
+// Weight returns the total weight of entries in all shards.
+func (c *sharded) Weight() int64 {
+	var w int64
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		w += s.cache.Weight()
+		s.mu.Unlock()
+	}
+
+	return w
+}
+
+// Stats returns the sum of the counters of all shards.
+func (c *sharded) Stats() Stats {
+	var stats Stats
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		stats = stats.add(s.cache.Stats())
+		s.mu.Unlock()
+	}
+
+	return stats
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+func TestNewSharded(t *testing.T) {
+	var testCases = map[string]struct {
+		shards   int
+		newShard func() (Cache, error)
+		isErr    bool
+	}{
+		"valid": {
+			shards:   4,
+			newShard: func() (Cache, error) { return NewLRU(Options{Capacity: 10}) },
+		},
+		"zeroShards": {
+			shards:   0,
+			newShard: func() (Cache, error) { return NewLRU(Options{Capacity: 10}) },
+			isErr:    true,
+		},
+		"invalidShard": {
+			shards:   4,
+			newShard: func() (Cache, error) { return NewLRU(Options{}) },
+			isErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSharded(tc.shards, nil, tc.newShard)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestShardedSpreadsKeys(t *testing.T) {
+	c, _ := NewSharded(4, nil, func() (Cache, error) { return NewLFU(Options{Capacity: 100}) })
+	for i := 0; i < 200; i++ {
+		c.Set(i, i)
+	}
+
+	for i := range c.shards {
+		if n := c.shards[i].cache.Len(); n < 20 {
+			t.Fatalf("want keys spread over shards, got %d keys in shard %d", n, i)
+		}
+	}
+	if want, got := 200, c.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+	if want, got := int64(200), c.Weight(); want != got {
+		t.Fatalf("weight: want= %v, got= %v", want, got)
+	}
+}
+
+// TestShardedConcurrent should be run with -race.
+// TestShardedPointerKeys checks that pointer keys stay in their shard when their target changes.
+func TestShardedPointerKeys(t *testing.T) {
+	type session struct{ user string }
+
+	c, _ := NewSharded(16, nil, func() (Cache, error) { return NewLRU(Options{Capacity: 100}) })
+	keys := make([]*session, 50)
+	for i := range keys {
+		keys[i] = &session{user: fmt.Sprint(i)}
+		c.Set(keys[i], i)
+	}
+
+	for i, key := range keys {
+		key.user += "-renamed"
+		if value, ok := c.Get(key); !ok || value != i {
+			t.Fatalf("get %d: want= %v, got= %v, %v", i, i, value, ok)
+		}
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	for impl, newCache := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c, _ := NewSharded(8, nil, func() (Cache, error) { return newCache(Options{Capacity: 64}) })
+
+			var wg sync.WaitGroup
+			failures := make(chan error, 8)
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 2000; i++ {
+						key := fmt.Sprintf("%d", (g*31+i)%300)
+						if val, ok := c.Get(key); ok && val != key {
+							failures <- errors.Errorf("want value %v, got= %v", key, val)
+							return
+						}
+						c.Set(key, key)
+						if i%10 == 0 {
+							c.Delete(key)
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+			close(failures)
+
+			for err := range failures {
+				t.Fatal(err)
+			}
+			stats := c.Stats()
+			if want, got := uint64(8*2000), stats.Hits+stats.Misses; want != got {
+				t.Fatalf("lookups: want= %v, got= %v", want, got)
+			}
+			if c.Len() > 8*64 {
+				t.Fatalf("want at most %d entries, got= %v", 8*64, c.Len())
+			}
+		})
+	}
+}
+
+func BenchmarkShardedParallel(b *testing.B) {
+	lookups := zipfKeys(1<<16, 100000)
+
+	for _, shards := range []int{1, 16} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c, _ := NewSharded(shards, nil, func() (Cache, error) {
+				return NewLRU(Options{Capacity: int64(16000 / shards)})
+			})
+
+			b.RunParallel(func(pb *testing.PB) {
+				var key containers.Value
+				for i := 0; pb.Next(); i++ {
+					key = lookups[i&(len(lookups)-1)]
+					if _, ok := c.Get(key); !ok {
+						c.Set(key, key)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// twoQ is a concrete implementation of Cache with the full 2Q policy
+// ("2Q: A Low Overhead High Performance Buffer Management Replacement Algorithm" - Johnson & Shasha 1994).
+// New entries go to the FIFO a1in. When evicted from it, their key is remembered in the FIFO a1out:
+// entries set again while remembered are hot and go to the LRU am. A scan only flushes a1in.
+type twoQ struct {
+	core
+	a1in  segment
+	a1out segment // ghosts
+	am    segment
+
+	kin  int64 // target weight of a1in
+	kout int64 // maximum weight of a1out
+}
+
+// New2Q returns a Cache with the 2Q policy, which resists scans.
+// a1in holds 25% of the capacity, and a1out remembers keys weighing up to 50% of it.
+func New2Q(opts Options) (*twoQ, error) {
+	c, err := newCore(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoQ{
+		core: c,
+		kin:  max64(1, opts.Capacity/4),
+		kout: max64(1, opts.Capacity/2),
+	}, nil
+}
+
+func (c *twoQ) resident(e *entry) bool {
+	return e.seg == &c.a1in || e.seg == &c.am
+}
+
+// Get returns the value of key, and whether it was found and not expired.
+func (c *twoQ) Get(key containers.Value) (containers.Value, bool) {
+	e, ok := c.entries[key]
+	if !ok || !c.resident(e) {
+		c.stats.Misses++
+		return nil, false
+	}
+	if c.expired(e) {
+		c.remove(e)
+		c.evicted(e, Expired)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	if e.seg == &c.am { // hits in a1in are likely correlated, so they do not promote
+		c.am.moveToFront(e)
+	}
+	return e.value, true
+}
+
+// Set adds or replaces the value of key, evicting other entries if needed.
+func (c *twoQ) Set(key, value containers.Value) bool {
+	weight, ok := c.weigh(key, value)
+	if !ok {
+		c.Delete(key)
+		return false
+	}
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.makeRoom(weight)
+		e = c.newEntry(key, value, weight)
+		c.entries[key] = e
+		c.a1in.pushFront(e)
+		return true
+	}
+
+	to := &c.am
+	if e.seg == &c.a1in {
+		to = &c.a1in
+	}
+	e.seg.remove(e)
+	c.makeRoom(weight)
+	e.value, e.weight = value, weight
+	c.refresh(e)
+	to.pushFront(e)
+	return true
+}
+
+// makeRoom evicts entries until an entry of weight fits.
+// Entries leave a1in when it is over its target weight, and am otherwise.
+func (c *twoQ) makeRoom(weight int64) {
+	for c.a1in.weight+c.am.weight+weight > c.opts.Capacity {
+		if c.a1in.len() == 0 || (c.a1in.weight <= c.kin && c.am.len() > 0) {
+			victim := c.am.back()
+			c.remove(victim)
+			c.evicted(victim, Evicted)
+			continue
+		}
+
+		victim := c.a1in.back()
+		c.a1in.remove(victim)
+		c.evicted(victim, Evicted)
+		victim.value = nil
+		c.a1out.pushFront(victim)
+		for c.a1out.weight > c.kout {
+			c.remove(c.a1out.back())
+		}
+	}
+}
+
+// Delete removes key, and returns whether it was there. The key's ghost is forgotten as well.
+func (c *twoQ) Delete(key containers.Value) bool {
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	wasResident := c.resident(e)
+	c.remove(e)
+	return wasResident
+}
+
+func (c *twoQ) remove(e *entry) {
+	e.seg.remove(e)
+	delete(c.entries, e.key)
+}
+
+// Len returns the number of entries.
+func (c *twoQ) Len() int {
+	return c.a1in.len() + c.am.len()
+}
+
+// Weight returns the total weight of entries.
+func (c *twoQ) Weight() int64 {
+	return c.a1in.weight + c.am.weight
+}
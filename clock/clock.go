@@ -0,0 +1,39 @@
+// Package clock abstracts time, so code waiting on deadlines can be tested without sleeping.
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the current time and creates timers.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer sends the current time on C() once, after its duration has passed.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Real is a Clock using the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,110 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeTimer(t *testing.T) {
+	var testCases = map[string]struct {
+		d       time.Duration
+		advance []time.Duration
+		fired   bool
+	}{
+		"notAdvanced": {
+			d: time.Second,
+		},
+		"advancedTooLittle": {
+			d:       time.Second,
+			advance: []time.Duration{500 * time.Millisecond, 499 * time.Millisecond},
+		},
+		"advancedExactly": {
+			d:       time.Second,
+			advance: []time.Duration{500 * time.Millisecond, 500 * time.Millisecond},
+			fired:   true,
+		},
+		"advancedPast": {
+			d:       time.Second,
+			advance: []time.Duration{time.Hour},
+			fired:   true,
+		},
+		"zeroDuration": {
+			d:     0,
+			fired: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f := NewFake(epoch)
+			timer := f.NewTimer(tc.d)
+			for _, d := range tc.advance {
+				f.Advance(d)
+			}
+
+			select {
+			case now := <-timer.C():
+				if !tc.fired {
+					t.Fatalf("want timer not fired, fired at %v", now)
+				}
+				if want, got := f.Now(), now; !want.Equal(got) && tc.d > 0 {
+					t.Fatalf("want fired at %v, got %v", want, got)
+				}
+			default:
+				if tc.fired {
+					t.Fatalf("want timer fired, got none")
+				}
+			}
+		})
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+	if want, got := 1, f.Timers(); want != got {
+		t.Fatalf("want timers= %v, got= %v", want, got)
+	}
+
+	if want, got := time.Second, f.NextTimer(); want != got {
+		t.Fatalf("want next timer in %v, got %v", want, got)
+	}
+
+	if !timer.Stop() {
+		t.Fatalf("want Stop() to stop a pending timer")
+	}
+	if timer.Stop() {
+		t.Fatalf("want Stop() to return false for a stopped timer")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatalf("want stopped timer not to fire")
+	default:
+	}
+}
+
+func TestFakeWaitForTimers(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan struct{})
+	go func() {
+		<-f.NewTimer(time.Minute).C()
+		close(done)
+	}()
+
+	f.WaitForTimers(1)
+	f.Advance(time.Minute)
+	<-done
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	timer := Real.NewTimer(time.Millisecond)
+	now := <-timer.C()
+
+	if now.Before(before) || Real.Now().Before(now) {
+		t.Fatalf("want real time to move forward, got %v, %v", before, now)
+	}
+}
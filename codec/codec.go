@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Codec converts a containers.Value to bytes and back,
+// so it can be stored outside of memory.
+type Codec interface {
+	Encode(v containers.Value) ([]byte, error)
+	Decode(data []byte) (containers.Value, error)
+}
+
+// Gob is a Codec using encoding/gob.
+// Concrete types other than Go's builtin ones must be registered with gob.Register() first.
+type Gob struct{}
+
+// Encode returns the gob encoding of v.
+func (Gob) Encode(v containers.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, errors.Wrapf(err, "cannot gob encode %T", v)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode returns the value from its gob encoding.
+func (Gob) Decode(data []byte) (containers.Value, error) {
+	var v containers.Value
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "cannot gob decode")
+	}
+
+	return v, nil
+}
+
+// String is a Codec for string values, stored as their bytes.
+type String struct{}
+
+// Encode returns the bytes of the string v.
+func (String) Encode(v containers.Value) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("want string, got %T", v)
+	}
+
+	return []byte(s), nil
+}
+
+// Decode returns data as a string.
+func (String) Decode(data []byte) (containers.Value, error) {
+	return string(data), nil
+}
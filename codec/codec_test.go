@@ -0,0 +1,84 @@
+package codec
+
+import (
+	"encoding/gob"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+type point struct {
+	X, Y int
+}
+
+func init() {
+	gob.Register(point{})
+}
+
+func TestRoundTrip(t *testing.T) {
+	var testCases = map[string]struct {
+		codec Codec
+		val   containers.Value
+		isErr bool
+	}{
+		"gobInt": {
+			codec: Gob{},
+			val:   42,
+		},
+		"gobString": {
+			codec: Gob{},
+			val:   "this is a string",
+		},
+		"gobFloats": {
+			codec: Gob{},
+			val:   []float64{1.5, 2.5},
+		},
+		"gobRegisteredStruct": {
+			codec: Gob{},
+			val:   point{X: 1, Y: 2},
+		},
+		"gobNotRegisteredStruct": {
+			codec: Gob{},
+			val:   struct{ A int }{A: 1},
+			isErr: true,
+		},
+		"string": {
+			codec: String{},
+			val:   "this is a string",
+		},
+		"stringEmpty": {
+			codec: String{},
+			val:   "",
+		},
+		"stringNotAString": {
+			codec: String{},
+			val:   42,
+			isErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			data, err := tc.codec.Encode(tc.val)
+
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			case tc.isErr && err != nil:
+				return
+			}
+
+			val, err := tc.codec.Decode(data)
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if want, got := tc.val, val; !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+// Package cuckoo provides a cuckoo filter, which tells whether a containers.Value may have been inserted,
+// and supports deletion ("Cuckoo Filter: Practically Better Than Bloom" - Fan et al. 2014).
+package cuckoo
+
+import (
+	"encoding/binary"
+	"math/rand"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// Options configures a cuckoo filter. Zero values use the defaults.
+type Options struct {
+	// Capacity is the number of values the filter should hold. It must be positive.
+	Capacity int
+	// FingerprintBits is the size of fingerprints in [2, 32], 16 by default.
+	// The false positive rate is about 2 * BucketSize / 2^FingerprintBits.
+	FingerprintBits int
+	// BucketSize is the number of fingerprints per bucket in [1, 8], 4 by default.
+	// Bigger buckets reach higher load factors, but have more false positives.
+	BucketSize int
+	// MaxKicks is the number of fingerprints moved to make room for a new one before Insert fails, 500 by default.
+	MaxKicks int
+	// Hash hashes values, hasher.Default if nil. Filters unmarshaled together must use the same hash.
+	Hash hasher.Func
+}
+
+const (
+	defaultFingerprintBits = 16
+	defaultBucketSize      = 4
+	defaultMaxKicks        = 500
+	targetLoadFactor       = 0.95 // for sizing buckets from the capacity
+)
+
+// filter is a cuckoo filter. Each value has a fingerprint, stored in one of two buckets:
+// the second one is derived from the first one and the fingerprint, so fingerprints can be moved
+// between their buckets without knowing their value. It is not safe for concurrent use.
+type filter struct {
+	fpBits     uint
+	bucketSize int
+	maxKicks   int
+	hash       hasher.Func
+
+	buckets uint64   // number of buckets, a power of 2
+	slots   []uint64 // fingerprints of buckets * bucketSize slots, packed in fpBits each. 0 is an empty slot.
+	n       int
+	rng     *rand.Rand
+
+	// victim is a fingerprint which could not be placed when the filter filled up.
+	// It is kept so that it is still found, and makes the filter full until a deletion makes room for it.
+	hasVictim   bool
+	victimIndex uint64
+	victimFp    uint32
+}
+
+// New returns an empty cuckoo filter.
+func New(opts Options) (*filter, error) {
+	if opts.Capacity <= 0 {
+		return nil, errors.Errorf("capacity must be positive, got %d", opts.Capacity)
+	}
+	if opts.FingerprintBits == 0 {
+		opts.FingerprintBits = defaultFingerprintBits
+	}
+	if opts.FingerprintBits < 2 || opts.FingerprintBits > 32 {
+		return nil, errors.Errorf("fingerprint bits must be in [2, 32], got %d", opts.FingerprintBits)
+	}
+	if opts.BucketSize == 0 {
+		opts.BucketSize = defaultBucketSize
+	}
+	if opts.BucketSize < 1 || opts.BucketSize > 8 {
+		return nil, errors.Errorf("bucket size must be in [1, 8], got %d", opts.BucketSize)
+	}
+	if opts.MaxKicks == 0 {
+		opts.MaxKicks = defaultMaxKicks
+	}
+	if opts.MaxKicks < 0 {
+		return nil, errors.Errorf("max kicks must not be negative, got %d", opts.MaxKicks)
+	}
+	if opts.Hash == nil {
+		opts.Hash = hasher.Default
+	}
+
+	buckets := uint64(1)
+	for float64(buckets)*float64(opts.BucketSize)*targetLoadFactor < float64(opts.Capacity) {
+		buckets *= 2
+	}
+	f := &filter{
+		fpBits:     uint(opts.FingerprintBits),
+		bucketSize: opts.BucketSize,
+		maxKicks:   opts.MaxKicks,
+		hash:       opts.Hash,
+	}
+	f.reset(buckets)
+	return f, nil
+}
+
+func (f *filter) reset(buckets uint64) {
+	f.buckets = buckets
+	f.slots = make([]uint64, (buckets*uint64(f.bucketSize)*uint64(f.fpBits)+63)/64)
+	f.n = 0
+	f.rng = rand.New(rand.NewSource(1))
+	f.hasVictim = false
+}
+
+// Len returns the number of fingerprints stored.
+func (f *filter) Len() int {
+	return f.n
+}
+
+// Capacity returns the number of slots for fingerprints.
+func (f *filter) Capacity() int {
+	return int(f.buckets) * f.bucketSize
+}
+
+// LoadFactor returns the fraction of slots used.
+func (f *filter) LoadFactor() float64 {
+	return float64(f.n) / float64(f.Capacity())
+}
+
+// locate returns the first bucket and the fingerprint of v.
+func (f *filter) locate(v containers.Value) (uint64, uint32) {
+	h := f.hash(v)
+	fp := uint32(h>>32) & (1<<f.fpBits - 1)
+	if fp == 0 {
+		fp = 1
+	}
+
+	return h & (f.buckets - 1), fp
+}
+
+// alternate returns the other bucket of a fingerprint in bucket i. alternate(alternate(i, fp), fp) == i.
+func (f *filter) alternate(i uint64, fp uint32) uint64 {
+	return (i ^ hasher.Mix(uint64(fp))) & (f.buckets - 1)
+}
+
+// get returns the fingerprint in slot s.
+func (f *filter) get(s uint64) uint32 {
+	bit := s * uint64(f.fpBits)
+	word, offset := bit/64, uint(bit%64)
+	v := f.slots[word] >> offset
+	if offset+f.fpBits > 64 {
+		v |= f.slots[word+1] << (64 - offset)
+	}
+
+	return uint32(v) & (1<<f.fpBits - 1)
+}
+
+// set writes fp into slot s.
+func (f *filter) set(s uint64, fp uint32) {
+	bit := s * uint64(f.fpBits)
+	word, offset := bit/64, uint(bit%64)
+	mask := uint64(1)<<f.fpBits - 1
+	f.slots[word] = f.slots[word]&^(mask<<offset) | uint64(fp)<<offset
+	if offset+f.fpBits > 64 {
+		spill := 64 - offset
+		f.slots[word+1] = f.slots[word+1]&^(mask>>spill) | uint64(fp)>>spill
+	}
+}
+
+// slot returns the index of slot j of bucket i.
+func (f *filter) slot(i uint64, j int) uint64 {
+	return i*uint64(f.bucketSize) + uint64(j)
+}
+
+// place puts fp in an empty slot of bucket i, and returns whether there was one.
+func (f *filter) place(i uint64, fp uint32) bool {
+	for j := 0; j < f.bucketSize; j++ {
+		if s := f.slot(i, j); f.get(s) == 0 {
+			f.set(s, fp)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Insert adds v, and returns false if the filter is full. Values can be inserted more than once,
+// but at most 2 * BucketSize times.
+func (f *filter) Insert(v containers.Value) bool {
+	if f.hasVictim {
+		return false
+	}
+
+	i, fp := f.locate(v)
+	f.add(i, fp)
+	f.n++
+	return true
+}
+
+// add puts fp into bucket i or its alternate, moving other fingerprints to their alternate buckets to make room.
+// If there is still none after maxKicks, the last moved fingerprint becomes the victim.
+func (f *filter) add(i uint64, fp uint32) {
+	if f.place(i, fp) || f.place(f.alternate(i, fp), fp) {
+		return
+	}
+
+	if f.rng.Intn(2) == 1 {
+		i = f.alternate(i, fp)
+	}
+	for kick := 0; kick < f.maxKicks; kick++ {
+		s := f.slot(i, f.rng.Intn(f.bucketSize))
+		kicked := f.get(s)
+		f.set(s, fp)
+		fp = kicked
+		i = f.alternate(i, fp)
+		if f.place(i, fp) {
+			return
+		}
+	}
+
+	f.hasVictim, f.victimIndex, f.victimFp = true, i, fp
+}
+
+// Lookup returns false if v was never inserted (or was deleted), and true if it probably was.
+func (f *filter) Lookup(v containers.Value) bool {
+	return f.Count(v) > 0
+}
+
+// Count returns how many times the fingerprint of v is stored, which is at least the number of times v was inserted
+// and not deleted.
+func (f *filter) Count(v containers.Value) int {
+	i1, fp := f.locate(v)
+	i2 := f.alternate(i1, fp)
+
+	count := 0
+	for j := 0; j < f.bucketSize; j++ {
+		if f.get(f.slot(i1, j)) == fp {
+			count++
+		}
+		if i2 != i1 && f.get(f.slot(i2, j)) == fp {
+			count++
+		}
+	}
+	if f.hasVictim && f.victimFp == fp && (f.victimIndex == i1 || f.victimIndex == i2) {
+		count++
+	}
+	return count
+}
+
+// Delete removes one occurrence of v, and returns whether its fingerprint was found.
+// Deleting a value which was never inserted can delete another value with the same fingerprint.
+func (f *filter) Delete(v containers.Value) bool {
+	i1, fp := f.locate(v)
+	i2 := f.alternate(i1, fp)
+
+	if f.hasVictim && f.victimFp == fp && (f.victimIndex == i1 || f.victimIndex == i2) {
+		f.hasVictim = false
+		f.n--
+		return true
+	}
+	for _, i := range []uint64{i1, i2} {
+		for j := 0; j < f.bucketSize; j++ {
+			if s := f.slot(i, j); f.get(s) == fp {
+				f.set(s, 0)
+				f.n--
+				f.placeVictim()
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// placeVictim tries to move the victim back into the table, after a deletion made room.
+func (f *filter) placeVictim() {
+	if !f.hasVictim {
+		return
+	}
+
+	f.hasVictim = false
+	f.add(f.victimIndex, f.victimFp)
+}
+
+// Reset empties the filter.
+func (f *filter) Reset() {
+	f.reset(f.buckets)
+}
+
+const (
+	formatVersion = 1
+	headerSize    = 1 + 1 + 1 + 4 + 8 + 8 + 1 + 8 + 4 // version, fpBits, bucketSize, maxKicks, buckets, n, victim
+)
+
+// MarshalBinary encodes the filter, without its hash function.
+func (f *filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, headerSize, headerSize+8*len(f.slots))
+	data[0], data[1], data[2] = formatVersion, byte(f.fpBits), byte(f.bucketSize)
+	binary.LittleEndian.PutUint32(data[3:], uint32(f.maxKicks))
+	binary.LittleEndian.PutUint64(data[7:], f.buckets)
+	binary.LittleEndian.PutUint64(data[15:], uint64(f.n))
+	if f.hasVictim {
+		data[23] = 1
+	}
+	binary.LittleEndian.PutUint64(data[24:], f.victimIndex)
+	binary.LittleEndian.PutUint32(data[32:], f.victimFp)
+
+	var buf [8]byte
+	for _, w := range f.slots {
+		binary.LittleEndian.PutUint64(buf[:], w)
+		data = append(data, buf[:]...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary, keeping its hash function.
+func (f *filter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return errors.Errorf("data is too short: %d bytes", len(data))
+	}
+	if data[0] != formatVersion {
+		return errors.Errorf("unknown format version %d", data[0])
+	}
+
+	decoded := filter{
+		fpBits:     uint(data[1]),
+		bucketSize: int(data[2]),
+		maxKicks:   int(binary.LittleEndian.Uint32(data[3:])),
+		hash:       f.hash,
+	}
+	buckets := binary.LittleEndian.Uint64(data[7:])
+	if decoded.fpBits < 2 || decoded.fpBits > 32 || decoded.bucketSize < 1 || decoded.bucketSize > 8 ||
+		buckets == 0 || buckets&(buckets-1) != 0 {
+		return errors.Errorf("invalid filter with %d bit fingerprints, %d per bucket and %d buckets",
+			decoded.fpBits, decoded.bucketSize, buckets)
+	}
+	// bound buckets by the data first, so that the number of bits cannot overflow
+	bucketBits := uint64(decoded.bucketSize) * uint64(decoded.fpBits)
+	if buckets > uint64(len(data)-headerSize)*8/bucketBits {
+		return errors.Errorf("want %d buckets of %d bits, got %d bytes", buckets, bucketBits, len(data)-headerSize)
+	}
+	words := (buckets*bucketBits + 63) / 64
+	if uint64(len(data)-headerSize) != 8*words {
+		return errors.Errorf("want %d bytes of fingerprints, got %d", 8*words, len(data)-headerSize)
+	}
+
+	n := binary.LittleEndian.Uint64(data[15:])
+	hasVictim := data[23] == 1
+	victimIndex := binary.LittleEndian.Uint64(data[24:])
+	victimFp := binary.LittleEndian.Uint32(data[32:])
+	if hasVictim && (victimIndex >= buckets || victimFp == 0 || uint64(victimFp)>>decoded.fpBits != 0) {
+		return errors.Errorf("invalid victim %d in bucket %d", victimFp, victimIndex)
+	}
+	maxN := buckets * uint64(decoded.bucketSize)
+	if hasVictim {
+		maxN++
+	}
+	if n > maxN {
+		return errors.Errorf("invalid filter with %d values, at most %d fit", n, maxN)
+	}
+
+	decoded.reset(buckets)
+	for i := range decoded.slots {
+		decoded.slots[i] = binary.LittleEndian.Uint64(data[headerSize+8*i:])
+	}
+	decoded.n = int(n)
+	decoded.hasVictim, decoded.victimIndex, decoded.victimFp = hasVictim, victimIndex, victimFp
+	*f = decoded
+	return nil
+}
+
+// FromBinary returns a filter encoded by MarshalBinary, with the hash function it was created with.
+func FromBinary(data []byte, hash hasher.Func) (*filter, error) {
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	f := &filter{hash: hash}
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
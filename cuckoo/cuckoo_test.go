@@ -0,0 +1,287 @@
+package cuckoo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// intHash hashes int values without going through hasher.Default.
+func intHash(v containers.Value) uint64 {
+	return hasher.Mix(uint64(v.(int)))
+}
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		opts     Options
+		capacity int
+		isErr    bool
+	}{
+		"defaults":            {opts: Options{Capacity: 972}, capacity: 1024},
+		"roundsUpBuckets":     {opts: Options{Capacity: 973}, capacity: 2048},
+		"smallBuckets":        {opts: Options{Capacity: 1000, BucketSize: 2}, capacity: 2048},
+		"oneValue":            {opts: Options{Capacity: 1, BucketSize: 1}, capacity: 2},
+		"zeroCapacity":        {opts: Options{}, isErr: true},
+		"fingerprintTooSmall": {opts: Options{Capacity: 1000, FingerprintBits: 1}, isErr: true},
+		"fingerprintTooBig":   {opts: Options{Capacity: 1000, FingerprintBits: 33}, isErr: true},
+		"bucketTooBig":        {opts: Options{Capacity: 1000, BucketSize: 9}, isErr: true},
+		"negativeBucket":      {opts: Options{Capacity: 1000, BucketSize: -1}, isErr: true},
+		"negativeKicks":       {opts: Options{Capacity: 1000, MaxKicks: -1}, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f, err := New(tc.opts)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if err == nil && f.Capacity() != tc.capacity {
+				t.Fatalf("capacity: want= %v, got= %v", tc.capacity, f.Capacity())
+			}
+		})
+	}
+}
+
+// TestInsertDelete checks against a multiset that values are never lost, for fingerprints not aligned to words.
+func TestInsertDelete(t *testing.T) {
+	for _, bits := range []int{7, 13, 16, 32} {
+		t.Run(fmt.Sprintf("bits=%d", bits), func(t *testing.T) {
+			f, err := New(Options{Capacity: 3000, FingerprintBits: bits, Hash: intHash})
+			if err != nil {
+				t.Fatalf("need a valid filter to test, got %q", err)
+			}
+
+			rng := rand.New(rand.NewSource(int64(bits)))
+			inserted := map[int]int{}
+			n := 0
+			for op := 0; op < 20000; op++ {
+				v := rng.Intn(1500)
+				if rng.Intn(2) == 0 && inserted[v] < 2 {
+					if !f.Insert(v) {
+						t.Fatalf("want value %d inserted with %d values", v, n)
+					}
+					inserted[v]++
+					n++
+					continue
+				}
+				if inserted[v] > 0 {
+					if !f.Delete(v) {
+						t.Fatalf("want inserted value %d deleted", v)
+					}
+					inserted[v]--
+					n--
+				}
+			}
+
+			for v, count := range inserted {
+				if got := f.Count(v); got < count {
+					t.Fatalf("count %d: want at least %d, got= %d", v, count, got)
+				}
+			}
+			if want, got := n, f.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	f, _ := New(Options{Capacity: 100, Hash: intHash})
+	for i := 0; i < 3; i++ {
+		f.Insert(42)
+	}
+	if want, got := 3, f.Count(42); want != got {
+		t.Fatalf("count: want= %v, got= %v", want, got)
+	}
+
+	f.Delete(42)
+	f.Delete(42)
+	if !f.Lookup(42) {
+		t.Fatalf("want value inserted three times and deleted twice found")
+	}
+	f.Delete(42)
+	if f.Lookup(42) {
+		t.Fatalf("want deleted value not found")
+	}
+	if f.Delete(42) {
+		t.Fatalf("want deleting a missing value to fail")
+	}
+}
+
+// TestLoadFactor fills filters until Insert fails, and checks how full they got.
+func TestLoadFactor(t *testing.T) {
+	var testCases = map[string]struct {
+		bucketSize int
+		load       float64
+	}{
+		"bucketSize=1": {bucketSize: 1, load: 0.45},
+		"bucketSize=2": {bucketSize: 2, load: 0.8},
+		"bucketSize=4": {bucketSize: 4, load: 0.93},
+		"bucketSize=8": {bucketSize: 8, load: 0.97},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f, _ := New(Options{Capacity: 1 << 16, BucketSize: tc.bucketSize, Hash: intHash})
+
+			i := 0
+			for f.Insert(i) {
+				i++
+			}
+			if load := f.LoadFactor(); load < tc.load {
+				t.Fatalf("want load factor at least %v, got= %v", tc.load, load)
+			}
+
+			// the full filter still finds everything, including the value which could not be placed
+			for v := 0; v < i; v++ {
+				if !f.Lookup(v) {
+					t.Fatalf("want inserted value %d found", v)
+				}
+			}
+
+			// deleting makes room again
+			for v := 0; v < i/10; v++ {
+				f.Delete(v)
+			}
+			if !f.Insert(-1) {
+				t.Fatalf("want insert after deletes to succeed")
+			}
+		})
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const probes = 200000
+
+	for _, bits := range []int{8, 12, 16} {
+		for _, bucketSize := range []int{2, 4} {
+			for hashName, hash := range map[string]hasher.Func{"default": nil, "custom": intHash} {
+				t.Run(fmt.Sprintf("bits=%d/bucketSize=%d/%s", bits, bucketSize, hashName), func(t *testing.T) {
+					f, _ := New(Options{Capacity: 20000, FingerprintBits: bits, BucketSize: bucketSize, Hash: hash})
+					n := int(0.75 * float64(f.Capacity()))
+					for i := 0; i < n; i++ {
+						if !f.Insert(i) {
+							t.Fatalf("want value %d inserted", i)
+						}
+					}
+
+					positives := 0
+					for i := n; i < n+probes; i++ {
+						if f.Lookup(i) {
+							positives++
+						}
+					}
+
+					// each lookup compares 2 * bucketSize slots with a 1 / (2^bits - 1) chance to match
+					expected := 2 * float64(bucketSize) * f.LoadFactor() / float64(int(1)<<uint(bits)-1)
+					measured := float64(positives) / probes
+					if measured > 1.5*expected || (expected > 10.0/probes && measured < expected/2) {
+						t.Fatalf("want false positive rate near %v, got= %v", expected, measured)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	f, _ := New(Options{Capacity: 4, BucketSize: 1, Hash: intHash})
+	for i := 0; f.Insert(i); i++ {
+	}
+	f.Reset()
+
+	if want, got := 0, f.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+	if !f.Insert(0) {
+		t.Fatalf("want insert after reset to succeed")
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	f, _ := New(Options{Capacity: 1000, FingerprintBits: 12, Hash: intHash})
+	i := 0
+	for f.Insert(i) { // fill it up, so there is a victim to encode
+		i++
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	decoded, err := FromBinary(data, intHash)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if decoded.Capacity() != f.Capacity() || decoded.Len() != f.Len() {
+		t.Fatalf("want same params, got= capacity %d, len %d", decoded.Capacity(), decoded.Len())
+	}
+	for v := 0; v < 2*i; v++ {
+		if want, got := f.Count(v), decoded.Count(v); want != got {
+			t.Fatalf("count %d: want= %v, got= %v", v, want, got)
+		}
+	}
+	if decoded.Insert(-1) {
+		t.Fatalf("want decoded full filter to stay full")
+	}
+
+	var testCases = map[string][]byte{
+		"empty":           nil,
+		"truncated":       data[:len(data)-1],
+		"wrongVersion":    append([]byte{9}, data[1:]...),
+		"zeroFingerprint": append([]byte{data[0], 0}, data[2:]...),
+		"oddBuckets":      append(append(append([]byte{}, data[:7]...), 3, 0, 0, 0, 0, 0, 0, 0), data[15:]...),
+		"hugeBuckets":     withUint64(data[:headerSize], 7, 1<<62),
+		"tooManyValues":   withUint64(data, 15, uint64(f.Capacity()+2)),
+		"victimOutside":   withUint64(data, 24, f.buckets),
+		"zeroVictim":      withUint32(data, 32, 0),
+		"wideVictim":      withUint32(data, 32, 1<<12),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := FromBinary(data, intHash); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+}
+
+// withUint64 returns a copy of data with x written at offset.
+func withUint64(data []byte, offset int, x uint64) []byte {
+	data = append([]byte{}, data...)
+	binary.LittleEndian.PutUint64(data[offset:], x)
+	return data
+}
+
+// withUint32 returns a copy of data with x written at offset.
+func withUint32(data []byte, offset int, x uint32) []byte {
+	data = append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(data[offset:], x)
+	return data
+}
+
+func BenchmarkFilter(b *testing.B) {
+	for _, bits := range []int{8, 16} {
+		f, _ := New(Options{Capacity: 1 << 20, FingerprintBits: bits})
+		b.Run(fmt.Sprintf("bits=%d/insert", bits), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !f.Insert(i) {
+					f.Reset()
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("bits=%d/lookup", bits), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				f.Lookup(i)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package fenwick provides Fenwick (binary indexed) trees for prefix sums of int64 values
+// ("A New Data Structure for Cumulative Frequency Tables" - Fenwick 1994).
+package fenwick
+
+import (
+	"github.com/pkg/errors"
+)
+
+// tree keeps sums[i] = sum of values in (i - lowbit(i), i], with 1-based i.
+type tree struct {
+	sums []int64
+}
+
+// New returns a tree of n zeros.
+func New(n int) (*tree, error) {
+	if n < 0 {
+		return nil, errors.Errorf("size must not be negative, got %d", n)
+	}
+
+	return &tree{sums: make([]int64, n+1)}, nil
+}
+
+// FromValues returns a tree of values, built in O(n).
+func FromValues(values []int64) *tree {
+	t := &tree{sums: make([]int64, len(values)+1)}
+	copy(t.sums[1:], values)
+	for i := 1; i < len(t.sums); i++ {
+		if parent := i + lowbit(i); parent < len(t.sums) {
+			t.sums[parent] += t.sums[i]
+		}
+	}
+
+	return t
+}
+
+// lowbit returns the lowest set bit of i.
+func lowbit(i int) int {
+	return i & -i
+}
+
+// Len returns the number of values.
+func (t *tree) Len() int {
+	return len(t.sums) - 1
+}
+
+// Add adds delta to the value at i.
+func (t *tree) Add(i int, delta int64) error {
+	if i < 0 || i >= t.Len() {
+		return errors.Errorf("index %d is out of [0, %d)", i, t.Len())
+	}
+
+	for i++; i < len(t.sums); i += lowbit(i) {
+		t.sums[i] += delta
+	}
+	return nil
+}
+
+// Set replaces the value at i.
+func (t *tree) Set(i int, value int64) error {
+	old, err := t.Get(i)
+	if err != nil {
+		return err
+	}
+
+	return t.Add(i, value-old)
+}
+
+// Get returns the value at i.
+func (t *tree) Get(i int) (int64, error) {
+	return t.RangeSum(i, i+1)
+}
+
+// PrefixSum returns the sum of values in [0, i).
+func (t *tree) PrefixSum(i int) (int64, error) {
+	if i < 0 || i > t.Len() {
+		return 0, errors.Errorf("prefix length %d is out of [0, %d]", i, t.Len())
+	}
+
+	return t.prefixSum(i), nil
+}
+
+func (t *tree) prefixSum(i int) int64 {
+	var sum int64
+	for ; i > 0; i -= lowbit(i) {
+		sum += t.sums[i]
+	}
+
+	return sum
+}
+
+// RangeSum returns the sum of values in [l, r).
+func (t *tree) RangeSum(l, r int) (int64, error) {
+	if l < 0 || r > t.Len() || l > r {
+		return 0, errors.Errorf("range [%d, %d) is out of [0, %d)", l, r, t.Len())
+	}
+
+	return t.prefixSum(r) - t.prefixSum(l), nil
+}
+
+// LowerBound returns the least i such that the sum of [0, i] is at least sum, or Len() if there is none.
+// Values must not be negative.
+func (t *tree) LowerBound(sum int64) int {
+	if sum <= 0 {
+		return 0
+	}
+
+	pos := 0
+	step := 1
+	for step*2 < len(t.sums) {
+		step *= 2
+	}
+	for ; step > 0; step /= 2 {
+		if next := pos + step; next < len(t.sums) && t.sums[next] < sum {
+			pos = next
+			sum -= t.sums[next]
+		}
+	}
+
+	return pos // the sum of [0, pos) is less than sum, so the answer is index pos
+}
@@ -0,0 +1,73 @@
+package fenwick
+
+import (
+	"github.com/pkg/errors"
+)
+
+// tree2D is a Fenwick tree over a matrix, for sums of sub-matrices.
+type tree2D struct {
+	rows, cols int
+	sums       [][]int64 // 1-based in both dimensions
+}
+
+// New2D returns a tree of rows x cols zeros.
+func New2D(rows, cols int) (*tree2D, error) {
+	if rows < 0 || cols < 0 {
+		return nil, errors.Errorf("size must not be negative, got %dx%d", rows, cols)
+	}
+
+	sums := make([][]int64, rows+1)
+	for r := range sums {
+		sums[r] = make([]int64, cols+1)
+	}
+
+	return &tree2D{rows: rows, cols: cols, sums: sums}, nil
+}
+
+// Size returns the number of rows and columns.
+func (t *tree2D) Size() (int, int) {
+	return t.rows, t.cols
+}
+
+// Add adds delta to the value at (r, c).
+func (t *tree2D) Add(r, c int, delta int64) error {
+	if r < 0 || r >= t.rows || c < 0 || c >= t.cols {
+		return errors.Errorf("cell (%d, %d) is out of %dx%d", r, c, t.rows, t.cols)
+	}
+
+	for i := r + 1; i <= t.rows; i += lowbit(i) {
+		for j := c + 1; j <= t.cols; j += lowbit(j) {
+			t.sums[i][j] += delta
+		}
+	}
+	return nil
+}
+
+// PrefixSum returns the sum of values in [0, r) x [0, c).
+func (t *tree2D) PrefixSum(r, c int) (int64, error) {
+	if r < 0 || r > t.rows || c < 0 || c > t.cols {
+		return 0, errors.Errorf("prefix %dx%d is out of %dx%d", r, c, t.rows, t.cols)
+	}
+
+	return t.prefixSum(r, c), nil
+}
+
+func (t *tree2D) prefixSum(r, c int) int64 {
+	var sum int64
+	for i := r; i > 0; i -= lowbit(i) {
+		for j := c; j > 0; j -= lowbit(j) {
+			sum += t.sums[i][j]
+		}
+	}
+
+	return sum
+}
+
+// RangeSum returns the sum of values in [r1, r2) x [c1, c2).
+func (t *tree2D) RangeSum(r1, c1, r2, c2 int) (int64, error) {
+	if r1 < 0 || r2 > t.rows || r1 > r2 || c1 < 0 || c2 > t.cols || c1 > c2 {
+		return 0, errors.Errorf("range [%d, %d) x [%d, %d) is out of %dx%d", r1, r2, c1, c2, t.rows, t.cols)
+	}
+
+	return t.prefixSum(r2, c2) - t.prefixSum(r1, c2) - t.prefixSum(r2, c1) + t.prefixSum(r1, c1), nil
+}
@@ -0,0 +1,101 @@
+// +build fuzz
+
+package fenwick
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestFuzzAgainstNaive performs N random updates and queries, and compares the tree with loops over a slice.
+func TestFuzzAgainstNaive(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	n := rng.Intn(200) + 1
+	naive := make([]int64, n)
+	tree, err := New(n)
+	if err != nil {
+		t.Fatalf("need a valid tree to test, got %q", err)
+	}
+
+	steps := rng.Intn(10000) + 2000
+	for i := 0; i < steps; i++ {
+		j := rng.Intn(n)
+		switch rng.Intn(4) {
+		case 0:
+			delta := rng.Int63n(200) // not negative, for LowerBound
+			tree.Add(j, delta)
+			naive[j] += delta
+		case 1:
+			v := rng.Int63n(200)
+			tree.Set(j, v)
+			naive[j] = v
+		case 2:
+			l, r := j, j+rng.Intn(n-j+1)
+			var want int64
+			for _, v := range naive[l:r] {
+				want += v
+			}
+			if got, _ := tree.RangeSum(l, r); want != got {
+				t.Fatalf("step %d: range [%d, %d): want= %v, got= %v", i, l, r, want, got)
+			}
+		default:
+			sum := rng.Int63n(200 * int64(n))
+			want, prefix := n, int64(0)
+			for k, v := range naive {
+				if prefix += v; prefix >= sum {
+					want = k
+					break
+				}
+			}
+			if sum <= 0 {
+				want = 0
+			}
+			if got := tree.LowerBound(sum); want != got {
+				t.Fatalf("step %d: lower bound of %d: want= %v, got= %v", i, sum, want, got)
+			}
+		}
+	}
+}
+
+// TestFuzz2DAgainstNaive performs N random updates and queries, and compares the tree with loops over a matrix.
+func TestFuzz2DAgainstNaive(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	rows, cols := rng.Intn(30)+1, rng.Intn(30)+1
+	naive := make([][]int64, rows)
+	for r := range naive {
+		naive[r] = make([]int64, cols)
+	}
+	tree, err := New2D(rows, cols)
+	if err != nil {
+		t.Fatalf("need a valid tree to test, got %q", err)
+	}
+
+	steps := rng.Intn(10000) + 2000
+	for i := 0; i < steps; i++ {
+		r1, c1 := rng.Intn(rows), rng.Intn(cols)
+		if rng.Intn(2) == 0 {
+			delta := rng.Int63n(2000) - 1000
+			tree.Add(r1, c1, delta)
+			naive[r1][c1] += delta
+			continue
+		}
+
+		r2, c2 := r1+rng.Intn(rows-r1+1), c1+rng.Intn(cols-c1+1)
+		var want int64
+		for r := r1; r < r2; r++ {
+			for c := c1; c < c2; c++ {
+				want += naive[r][c]
+			}
+		}
+		if got, _ := tree.RangeSum(r1, c1, r2, c2); want != got {
+			t.Fatalf("step %d: range [%d, %d) x [%d, %d): want= %v, got= %v", i, r1, r2, c1, c2, want, got)
+		}
+	}
+}
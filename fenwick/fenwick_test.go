@@ -0,0 +1,163 @@
+package fenwick
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		rows, cols int
+		isErr      bool
+	}{
+		"empty":    {rows: 0, cols: 0},
+		"valid":    {rows: 3, cols: 4},
+		"negative": {rows: -1, cols: 4, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(tc.rows)
+			_, err2D := New2D(tc.rows, tc.cols)
+
+			if tc.isErr && (err == nil || err2D == nil) {
+				t.Fatalf("want errors, got= %v, %v", err, err2D)
+			}
+			if !tc.isErr && (err != nil || err2D != nil) {
+				t.Fatalf("want no error, got= %v, %v", err, err2D)
+			}
+		})
+	}
+}
+
+func TestSums(t *testing.T) {
+	tree := FromValues([]int64{3, 1, 4, 1, 5, 9, 2, 6})
+	tree.Add(2, 10) // 3 1 14 1 5 9 2 6
+	tree.Set(7, 0)  // 3 1 14 1 5 9 2 0
+
+	var testCases = map[string]struct {
+		l, r  int
+		want  int64
+		isErr bool
+	}{
+		"all":        {l: 0, r: 8, want: 35},
+		"prefix":     {l: 0, r: 3, want: 18},
+		"middle":     {l: 2, r: 6, want: 29},
+		"one":        {l: 7, r: 8, want: 0},
+		"empty":      {l: 4, r: 4, want: 0},
+		"outOfRange": {l: 0, r: 9, isErr: true},
+		"reversed":   {l: 3, r: 1, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tree.RangeSum(tc.l, tc.r)
+
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if want := tc.want; want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if tc.l == 0 {
+				if prefix, _ := tree.PrefixSum(tc.r); prefix != got {
+					t.Fatalf("prefix: want= %v, got= %v", got, prefix)
+				}
+			}
+		})
+	}
+
+	if err := tree.Add(8, 1); err == nil {
+		t.Fatalf("add out of range: want error, got none")
+	}
+	if v, _ := tree.Get(2); v != 14 {
+		t.Fatalf("get: want= 14, got= %v", v)
+	}
+}
+
+func TestLowerBound(t *testing.T) {
+	tree := FromValues([]int64{2, 0, 3, 1, 4})
+
+	var testCases = map[string]struct {
+		sum  int64
+		want int
+	}{
+		"zero":        {sum: 0, want: 0},
+		"first":       {sum: 2, want: 0},
+		"skipsZero":   {sum: 3, want: 2},
+		"exact":       {sum: 5, want: 2},
+		"last":        {sum: 10, want: 4},
+		"beyondTotal": {sum: 11, want: 5},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.want, tree.LowerBound(tc.sum); want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestTree2D(t *testing.T) {
+	tree, _ := New2D(3, 4)
+	// 1 0 0 2
+	// 0 3 0 0
+	// 4 0 5 0
+	for _, cell := range []struct {
+		r, c int
+		v    int64
+	}{{0, 0, 1}, {0, 3, 2}, {1, 1, 3}, {2, 0, 4}, {2, 2, 5}} {
+		if err := tree.Add(cell.r, cell.c, cell.v); err != nil {
+			t.Fatalf("want no error, got %q", err)
+		}
+	}
+
+	if got, _ := tree.RangeSum(0, 0, 3, 4); got != 15 {
+		t.Fatalf("all: want= 15, got= %v", got)
+	}
+	if got, _ := tree.RangeSum(1, 1, 3, 3); got != 8 {
+		t.Fatalf("bottom middle: want= 8, got= %v", got)
+	}
+	if got, _ := tree.PrefixSum(2, 2); got != 4 {
+		t.Fatalf("prefix: want= 4, got= %v", got)
+	}
+	if _, err := tree.RangeSum(0, 0, 4, 4); err == nil {
+		t.Fatalf("out of range: want error, got none")
+	}
+	if err := tree.Add(3, 0, 1); err == nil {
+		t.Fatalf("add out of range: want error, got none")
+	}
+}
+
+func BenchmarkPrefixSum(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		values := make([]int64, n)
+		for i := range values {
+			values[i] = int64(i)
+		}
+
+		b.Run(fmt.Sprintf("fenwick/n=%d", n), func(b *testing.B) {
+			tree := FromValues(values)
+			for i := 0; i < b.N; i++ {
+				tree.Add(i%n, 1)
+				tree.PrefixSum(n - i%n)
+			}
+		})
+		b.Run(fmt.Sprintf("naive/n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				values[i%n]++
+				var sum int64
+				for _, v := range values[:n-i%n] {
+					sum += v
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/stack"
+	"github.com/bitsgofer/containers/unionfind"
+)
+
+// CycleError is returned by TopologicalSort when the graph has a cycle.
+type CycleError struct {
+	// Cycle lists the vertices of a cycle, each having an edge to the next one, and the last one to the first.
+	Cycle []containers.Value
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("graph has a cycle %v", e.Cycle)
+}
+
+// dfsFrame is a vertex on the stack of a depth-first search, with the index of its next edge to explore.
+type dfsFrame struct {
+	id   int
+	next int
+}
+
+const (
+	white = iota // not visited
+	gray         // on the stack
+	black        // done
+)
+
+// TopologicalSort returns the vertices ordered so that every edge goes from a vertex to a later one.
+// It returns ErrUndirected for undirected graphs, and a *CycleError if there is a cycle.
+func (g *graph) TopologicalSort() ([]containers.Value, error) {
+	if !g.directed {
+		return nil, ErrUndirected
+	}
+
+	color := make([]int, len(g.vertices))
+	parent := make([]int, len(g.vertices))
+	postorder := make([]int, 0, len(g.vertices))
+	frames := stack.New() // items are *dfsFrame
+	for root := range g.vertices {
+		if color[root] != white {
+			continue
+		}
+
+		color[root] = gray
+		frames.Push(&dfsFrame{id: root})
+		for frames.Size() > 0 {
+			top, _ := frames.Top()
+			f := top.(*dfsFrame)
+			if f.next == len(g.out[f.id]) {
+				frames.Pop()
+				color[f.id] = black
+				postorder = append(postorder, f.id)
+				continue
+			}
+
+			to := g.out[f.id][f.next].to
+			f.next++
+			switch color[to] {
+			case white:
+				color[to], parent[to] = gray, f.id
+				frames.Push(&dfsFrame{id: to})
+			case gray: // back edge: the cycle is on the stack, from to to f.id
+				cycle := []containers.Value{g.vertices[f.id]}
+				for id := f.id; id != to; {
+					id = parent[id]
+					cycle = append(cycle, g.vertices[id])
+				}
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return nil, &CycleError{Cycle: cycle}
+			}
+		}
+	}
+
+	order := make([]containers.Value, len(postorder))
+	for i, id := range postorder {
+		order[len(order)-1-i] = g.vertices[id]
+	}
+	return order, nil
+}
+
+// Components returns the connected components, ignoring the direction of edges (i.e. weakly connected components).
+// Components are ordered by their first vertex, and vertices in the order they were added.
+func (g *graph) Components() [][]containers.Value {
+	sets := unionfind.New()
+	for id := range g.vertices {
+		sets.Add(id)
+		for _, a := range g.out[id] {
+			sets.Union(id, a.to)
+		}
+	}
+
+	index := map[containers.Value]int{} // representative => index of its component
+	var components [][]containers.Value
+	for id, v := range g.vertices {
+		root, _ := sets.Find(id)
+		i, ok := index[root]
+		if !ok {
+			i = len(components)
+			index[root] = i
+			components = append(components, nil)
+		}
+		components[i] = append(components[i], v)
+	}
+
+	return components
+}
+
+// StronglyConnectedComponents returns the strongly connected components: vertices are in the same component
+// if there are paths between them both ways. Components are in reverse topological order of the condensed graph:
+// no edge goes from a component to a later one ("Depth-First Search and Linear Graph Algorithms" - Tarjan 1972).
+func (g *graph) StronglyConnectedComponents() [][]containers.Value {
+	const unvisited = -1
+	index := make([]int, len(g.vertices))
+	low := make([]int, len(g.vertices))
+	onStack := make([]bool, len(g.vertices))
+	for i := range index {
+		index[i] = unvisited
+	}
+
+	var components [][]containers.Value
+	visited := 0
+	members := stack.New() // items are vertex ids of components being built
+	frames := stack.New()  // items are *dfsFrame
+	visit := func(id int) {
+		index[id], low[id] = visited, visited
+		visited++
+		members.Push(id)
+		onStack[id] = true
+		frames.Push(&dfsFrame{id: id})
+	}
+
+	for root := range g.vertices {
+		if index[root] != unvisited {
+			continue
+		}
+
+		visit(root)
+		for frames.Size() > 0 {
+			top, _ := frames.Top()
+			f := top.(*dfsFrame)
+			if f.next < len(g.out[f.id]) {
+				to := g.out[f.id][f.next].to
+				f.next++
+				if index[to] == unvisited {
+					visit(to)
+				} else if onStack[to] && index[to] < low[f.id] {
+					low[f.id] = index[to]
+				}
+				continue
+			}
+
+			frames.Pop()
+			if parent, err := frames.Top(); err == nil {
+				if p := parent.(*dfsFrame); low[f.id] < low[p.id] {
+					low[p.id] = low[f.id]
+				}
+			}
+			if low[f.id] != index[f.id] {
+				continue
+			}
+
+			var component []containers.Value
+			for {
+				top, _ := members.Pop()
+				id := top.(int)
+				onStack[id] = false
+				component = append(component, g.vertices[id])
+				if id == f.id {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	return components
+}
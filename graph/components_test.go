@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func TestTopologicalSort(t *testing.T) {
+	var testCases = map[string]struct {
+		edges []string
+		order []containers.Value
+		cycle []containers.Value
+	}{
+		"chain": {
+			edges: []string{"ab", "bc", "cd"},
+			order: []containers.Value{"a", "b", "c", "d"},
+		},
+		"diamond": {
+			edges: []string{"ab", "ac", "bd", "cd"},
+			order: []containers.Value{"a", "c", "b", "d"},
+		},
+		"cycle": {
+			edges: []string{"ab", "bc", "cd", "db", "ce"},
+			cycle: []containers.Value{"b", "c", "d"},
+		},
+		"selfLoop": {
+			edges: []string{"ab", "bb"},
+			cycle: []containers.Value{"b"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			g := newGraph(true, tc.edges...)
+			order, err := g.TopologicalSort()
+
+			if tc.cycle != nil {
+				cerr, ok := err.(*CycleError)
+				if !ok {
+					t.Fatalf("want *CycleError, got= %v", err)
+				}
+				if want, got := tc.cycle, cerr.Cycle; !cmp.Equal(want, got) {
+					t.Fatalf("cycle: want= %v, got= %v", want, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if want, got := tc.order, order; !cmp.Equal(want, got) {
+				t.Fatalf("order: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestTopologicalSortUndirected(t *testing.T) {
+	if _, err := newGraph(false, "ab").TopologicalSort(); err != ErrUndirected {
+		t.Fatalf("want= %v, got= %v", ErrUndirected, err)
+	}
+}
+
+// TestTopologicalSortRandom checks orders of random DAGs, and cycles of random graphs.
+func TestTopologicalSortRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		g := NewDirected()
+		for i := 0; i < 50; i++ {
+			g.AddVertex(i)
+		}
+		acyclic := round%2 == 0
+		for i := 0; i < 100; i++ {
+			a, b := rng.Intn(50), rng.Intn(50)
+			if acyclic && a >= b {
+				continue
+			}
+			g.AddEdge(a, b, 1)
+		}
+
+		order, err := g.TopologicalSort()
+		if cerr, ok := err.(*CycleError); ok {
+			if acyclic {
+				t.Fatalf("want no cycle in a DAG, got= %v", cerr.Cycle)
+			}
+			for i, v := range cerr.Cycle {
+				if next := cerr.Cycle[(i+1)%len(cerr.Cycle)]; !g.HasEdge(v, next) {
+					t.Fatalf("cycle %v: want edge from %v to %v", cerr.Cycle, v, next)
+				}
+			}
+			continue
+		}
+
+		position := map[containers.Value]int{}
+		for i, v := range order {
+			position[v] = i
+		}
+		for _, v := range g.Vertices() {
+			for _, to := range g.Neighbors(v) {
+				if position[v] >= position[to] {
+					t.Fatalf("want %v before %v in %v", v, to, order)
+				}
+			}
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	g := newGraph(true, "ab", "cb", "de", "fe")
+	g.AddVertex("g")
+
+	want := [][]containers.Value{{"a", "b", "c"}, {"d", "e", "f"}, {"g"}}
+	if got := g.Components(); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func sortedComponents(components [][]containers.Value) [][]containers.Value {
+	var sorted [][]containers.Value
+	for _, c := range components {
+		c = append([]containers.Value{}, c...)
+		sort.Slice(c, func(i, j int) bool { return fmt.Sprint(c[i]) < fmt.Sprint(c[j]) })
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return fmt.Sprint(sorted[i][0]) < fmt.Sprint(sorted[j][0]) })
+
+	return sorted
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	// a ⇄ b → c → d → e → c, f alone, g → f
+	g := newGraph(true, "ab", "ba", "bc", "cd", "de", "ec", "gf")
+
+	components := g.StronglyConnectedComponents()
+	want := [][]containers.Value{{"a", "b"}, {"c", "d", "e"}, {"f"}, {"g"}}
+	if got := sortedComponents(components); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+
+	// reverse topological order: no edge from a component to a later one
+	position := map[containers.Value]int{}
+	for i, c := range components {
+		for _, v := range c {
+			position[v] = i
+		}
+	}
+	for _, v := range g.Vertices() {
+		for _, to := range g.Neighbors(v) {
+			if position[v] < position[to] {
+				t.Fatalf("want component of %v after the one of %v in %v", v, to, components)
+			}
+		}
+	}
+}
+
+// TestStronglyConnectedComponentsRandom compares components with mutual reachability.
+func TestStronglyConnectedComponentsRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewDirected()
+	for i := 0; i < 60; i++ {
+		g.AddVertex(i)
+	}
+	for i := 0; i < 80; i++ {
+		g.AddEdge(rng.Intn(60), rng.Intn(60), 1)
+	}
+
+	reachable := func(from containers.Value) map[containers.Value]bool {
+		seen := map[containers.Value]bool{}
+		bfs, _ := g.BFS(from)
+		for v, ok := bfs.Next(); ok; v, ok = bfs.Next() {
+			seen[v] = true
+		}
+		return seen
+	}
+	reach := map[containers.Value]map[containers.Value]bool{}
+	for _, v := range g.Vertices() {
+		reach[v] = reachable(v)
+	}
+
+	component := map[containers.Value]int{}
+	for i, c := range g.StronglyConnectedComponents() {
+		for _, v := range c {
+			component[v] = i
+		}
+	}
+	for _, a := range g.Vertices() {
+		for _, b := range g.Vertices() {
+			if want, got := reach[a][b] && reach[b][a], component[a] == component[b]; want != got {
+				t.Fatalf("%v and %v: want same component= %v, got= %v", a, b, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/queue"
+)
+
+var (
+	// ErrNegativeCapacity is returned by MaxFlow when an edge has a negative weight.
+	ErrNegativeCapacity = errors.New("negative edge capacity")
+	// ErrSameSourceAndSink is returned by MaxFlow when the source is the sink.
+	ErrSameSourceAndSink = errors.New("source and sink are the same vertex")
+)
+
+// flowEpsilon is the residual capacity under which an arc is saturated, to absorb rounding errors.
+const flowEpsilon = 1e-9
+
+// FlowEdge is an edge of a flow network with the flow going through it.
+// The flow of an undirected edge is negative when it goes from To to From.
+type FlowEdge struct {
+	From, To containers.Value
+	Capacity float64
+	Flow     float64
+}
+
+// residualArc is an arc of the residual network. Its reverse arc is at index rev,
+// and carries the opposite flow: flow on the reverse arc cancels flow on this one.
+type residualArc struct {
+	to       int
+	rev      int
+	capacity float64
+	flow     float64
+}
+
+func (a *residualArc) residual() float64 {
+	return a.capacity - a.flow
+}
+
+// flow is a maximum flow from a source to a sink, with a minimum cut.
+type flow struct {
+	g          *graph
+	value      float64
+	edges      []FlowEdge
+	sourceSide []bool // vertices reachable from the source in the residual network
+}
+
+// MaxFlow returns a maximum flow from source to sink, using edge weights as capacities.
+// An undirected edge can carry flow either way, up to its capacity.
+// It uses Dinic's algorithm in O(V^2 * E) ("Algorithm for solution of a problem of maximum flow
+// in networks with power estimation" - Dinic 1970).
+func (g *graph) MaxFlow(source, sink containers.Value) (*flow, error) {
+	s, oks := g.ids[source]
+	t, okt := g.ids[sink]
+	if !oks || !okt {
+		return nil, ErrUnknownVertex
+	}
+	if s == t {
+		return nil, ErrSameSourceAndSink
+	}
+
+	refs := g.edgeRefs()
+	arcs := make([]residualArc, 0, 2*len(refs))
+	adj := make([][]int, len(g.vertices)) // vertex => indices in arcs
+	for _, ref := range refs {
+		if ref.weight < 0 {
+			return nil, ErrNegativeCapacity
+		}
+
+		reverseCapacity := 0.0
+		if !g.directed {
+			reverseCapacity = ref.weight
+		}
+		i := len(arcs)
+		arcs = append(arcs,
+			residualArc{to: ref.to, rev: i + 1, capacity: ref.weight},
+			residualArc{to: ref.from, rev: i, capacity: reverseCapacity},
+		)
+		adj[ref.from] = append(adj[ref.from], i)
+		adj[ref.to] = append(adj[ref.to], i+1)
+	}
+
+	d := dinic{arcs: arcs, adj: adj, source: s, sink: t}
+	f := &flow{g: g}
+	for d.buildLevels() {
+		f.value += d.blockingFlow()
+	}
+
+	f.edges = make([]FlowEdge, len(refs))
+	for i, ref := range refs {
+		f.edges[i] = FlowEdge{
+			From:     g.vertices[ref.from],
+			To:       g.vertices[ref.to],
+			Capacity: ref.weight,
+			Flow:     arcs[2*i].flow,
+		}
+	}
+	f.sourceSide = make([]bool, len(g.vertices))
+	for id, level := range d.level {
+		f.sourceSide[id] = level >= 0
+	}
+	return f, nil
+}
+
+// dinic holds the state of Dinic's algorithm on a residual network.
+type dinic struct {
+	arcs         []residualArc
+	adj          [][]int
+	source, sink int
+
+	level []int // distance from the source in the residual network, -1 if unreachable or a dead end
+	next  []int // index in adj of the next arc to try from each vertex
+}
+
+// buildLevels computes levels by BFS from the source, and returns whether the sink is reachable.
+func (d *dinic) buildLevels() bool {
+	if d.level == nil {
+		d.level = make([]int, len(d.adj))
+		d.next = make([]int, len(d.adj))
+	}
+	for i := range d.level {
+		d.level[i], d.next[i] = -1, 0
+	}
+
+	d.level[d.source] = 0
+	pending := queue.New() // items are vertex ids
+	pending.Enqueue(d.source)
+	for pending.Size() > 0 {
+		front, _ := pending.Dequeue()
+		id := front.(int)
+		for _, i := range d.adj[id] {
+			a := &d.arcs[i]
+			if d.level[a.to] < 0 && a.residual() > flowEpsilon {
+				d.level[a.to] = d.level[id] + 1
+				pending.Enqueue(a.to)
+			}
+		}
+	}
+
+	return d.level[d.sink] >= 0
+}
+
+// blockingFlow pushes flow along shortest paths of the level graph until none is left, and returns how much.
+// Paths are found iteratively, skipping arcs which lead to dead ends.
+func (d *dinic) blockingFlow() float64 {
+	var total float64
+	var path []int // indices in arcs, from the source
+	for {
+		id := d.source
+		for id != d.sink {
+			advanced := false
+			for ; d.next[id] < len(d.adj[id]); d.next[id]++ {
+				i := d.adj[id][d.next[id]]
+				a := &d.arcs[i]
+				if d.level[a.to] == d.level[id]+1 && a.residual() > flowEpsilon {
+					path = append(path, i)
+					id = a.to
+					advanced = true
+					break
+				}
+			}
+			if advanced {
+				continue
+			}
+
+			if id == d.source {
+				return total
+			}
+			d.level[id] = -1 // dead end: retreat and skip the arc to it
+			last := path[len(path)-1]
+			path = path[:len(path)-1]
+			id = d.arcs[d.arcs[last].rev].to
+			d.next[id]++
+		}
+
+		bottleneck := math.Inf(1)
+		for _, i := range path {
+			bottleneck = math.Min(bottleneck, d.arcs[i].residual())
+		}
+		for _, i := range path {
+			d.arcs[i].flow += bottleneck
+			d.arcs[d.arcs[i].rev].flow -= bottleneck
+		}
+		total += bottleneck
+		path = path[:0]
+	}
+}
+
+// Value returns the amount of flow from the source to the sink.
+func (f *flow) Value() float64 {
+	return f.value
+}
+
+// Edges returns all edges with their flow, grouped by their first vertex.
+func (f *flow) Edges() []FlowEdge {
+	return append([]FlowEdge{}, f.edges...)
+}
+
+// MinCut returns the source side of a minimum cut, i.e. the vertices which can still be reached from the source,
+// and the edges crossing the cut. Their capacities sum to the value of the flow.
+func (f *flow) MinCut() ([]containers.Value, []FlowEdge) {
+	var side []containers.Value
+	for id, in := range f.sourceSide {
+		if in {
+			side = append(side, f.g.vertices[id])
+		}
+	}
+
+	var cut []FlowEdge
+	for _, e := range f.edges {
+		from, to := f.sourceSide[f.g.ids[e.From]], f.sourceSide[f.g.ids[e.To]]
+		if from && !to || !f.g.directed && to && !from {
+			cut = append(cut, e)
+		}
+	}
+	return side, cut
+}
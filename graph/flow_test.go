@@ -0,0 +1,215 @@
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// clrsNetwork returns the example of "Introduction to Algorithms" (26.6), whose maximum flow is 23.
+func clrsNetwork() *graph {
+	return weightedGraph(
+		Edge{"s", "v1", 16}, Edge{"s", "v2", 13}, Edge{"v1", "v3", 12}, Edge{"v2", "v1", 4}, Edge{"v2", "v4", 14},
+		Edge{"v3", "v2", 9}, Edge{"v3", "t", 20}, Edge{"v4", "v3", 7}, Edge{"v4", "t", 4},
+	)
+}
+
+// checkFlow checks capacities, conservation and the value of f, and that its minimum cut matches the value.
+func checkFlow(t *testing.T, g *graph, f *flow, source, sink containers.Value) {
+	net := map[containers.Value]float64{} // vertex => outgoing minus incoming flow
+	for _, e := range f.Edges() {
+		low := 0.0
+		if !g.Directed() {
+			low = -e.Capacity
+		}
+		if e.Flow < low-flowEpsilon || e.Flow > e.Capacity+flowEpsilon {
+			t.Fatalf("edge %v: flow out of capacity", e)
+		}
+		net[e.From] += e.Flow
+		net[e.To] -= e.Flow
+	}
+	for _, v := range g.Vertices() {
+		want := 0.0
+		switch v {
+		case source:
+			want = f.Value()
+		case sink:
+			want = -f.Value()
+		}
+		if math.Abs(net[v]-want) > 1e-6 {
+			t.Fatalf("vertex %v: want net flow= %v, got= %v", v, want, net[v])
+		}
+	}
+
+	side, cut := f.MinCut()
+	inSide := map[containers.Value]bool{}
+	for _, v := range side {
+		inSide[v] = true
+	}
+	if !inSide[source] || inSide[sink] {
+		t.Fatalf("want source and not sink in the source side, got= %v", side)
+	}
+	var capacity float64
+	for _, e := range cut {
+		capacity += e.Capacity
+	}
+	if math.Abs(capacity-f.Value()) > 1e-6 {
+		t.Fatalf("want cut capacity= %v, got= %v", f.Value(), capacity)
+	}
+}
+
+func TestMaxFlow(t *testing.T) {
+	g := clrsNetwork()
+	f, err := g.MaxFlow("s", "t")
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	if want, got := 23.0, f.Value(); want != got {
+		t.Fatalf("value: want= %v, got= %v", want, got)
+	}
+	checkFlow(t, g, f, "s", "t")
+
+	side, cut := f.MinCut()
+	if want, got := []containers.Value{"s", "v1", "v2", "v4"}, side; !cmp.Equal(want, got) {
+		t.Fatalf("source side: want= %v, got= %v", want, got)
+	}
+	wantCut := []FlowEdge{{"v1", "v3", 12, 12}, {"v4", "v3", 7, 7}, {"v4", "t", 4, 4}}
+	if got := cut; !cmp.Equal(wantCut, got) {
+		t.Fatalf("cut: want= %v, got= %v", wantCut, got)
+	}
+}
+
+func TestMaxFlowUndirected(t *testing.T) {
+	// s - a - t with a bypass s - b - a: the edge a-b carries flow from b to a
+	g := NewUndirected()
+	g.AddEdge("s", "a", 1)
+	g.AddEdge("a", "b", 5)
+	g.AddEdge("s", "b", 4)
+	g.AddEdge("a", "t", 10)
+
+	f, err := g.MaxFlow("s", "t")
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := 5.0, f.Value(); want != got {
+		t.Fatalf("value: want= %v, got= %v", want, got)
+	}
+	checkFlow(t, g, f, "s", "t")
+	for _, e := range f.Edges() {
+		if e.From == "a" && e.To == "b" && e.Flow != -4 {
+			t.Fatalf("want flow -4 from a to b, got= %v", e.Flow)
+		}
+	}
+}
+
+func TestMaxFlowErrors(t *testing.T) {
+	var testCases = map[string]struct {
+		g            *graph
+		source, sink containers.Value
+		err          error
+	}{
+		"unknownVertex": {
+			g:      clrsNetwork(),
+			source: "s",
+			sink:   "x",
+			err:    ErrUnknownVertex,
+		},
+		"sameVertex": {
+			g:      clrsNetwork(),
+			source: "s",
+			sink:   "s",
+			err:    ErrSameSourceAndSink,
+		},
+		"negativeCapacity": {
+			g:      weightedGraph(Edge{"s", "t", -1}),
+			source: "s",
+			sink:   "t",
+			err:    ErrNegativeCapacity,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tc.g.MaxFlow(tc.source, tc.sink); err != tc.err {
+				t.Fatalf("want= %v, got= %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestMaxFlowUnreachableSink(t *testing.T) {
+	g := weightedGraph(Edge{"s", "a", 3}, Edge{"t", "a", 3})
+	f, _ := g.MaxFlow("s", "t")
+
+	if want, got := 0.0, f.Value(); want != got {
+		t.Fatalf("value: want= %v, got= %v", want, got)
+	}
+	if _, cut := f.MinCut(); len(cut) != 0 {
+		t.Fatalf("want empty cut, got= %v", cut)
+	}
+}
+
+// bruteForceMinCut returns the minimum capacity of the edges leaving a set holding the source and not the sink.
+func bruteForceMinCut(g *graph, source, sink int) float64 {
+	best := math.Inf(1)
+	for mask := 0; mask < 1<<uint(g.Order()); mask++ {
+		in := func(id int) bool { return mask&(1<<uint(id)) != 0 }
+		if !in(source) || in(sink) {
+			continue
+		}
+
+		var capacity float64
+		for _, ref := range g.edgeRefs() {
+			if in(ref.from) && !in(ref.to) || !g.directed && in(ref.to) && !in(ref.from) {
+				capacity += ref.weight
+			}
+		}
+		best = math.Min(best, capacity)
+	}
+
+	return best
+}
+
+// TestMaxFlowRandom cross-checks the maximum flow with the minimum cut found by brute force.
+func TestMaxFlowRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		g := NewDirected()
+		if round%2 == 1 {
+			g = NewUndirected()
+		}
+		n := rng.Intn(9) + 2
+		for i := 0; i < n; i++ {
+			g.AddVertex(i)
+		}
+		for i := rng.Intn(4 * n); i > 0; i-- {
+			g.AddEdge(rng.Intn(n), rng.Intn(n), float64(rng.Intn(20)))
+		}
+
+		f, err := g.MaxFlow(0, n-1)
+		if err != nil {
+			t.Fatalf("want no error, got %q", err)
+		}
+		if want, got := bruteForceMinCut(g, 0, n-1), f.Value(); want != got {
+			t.Fatalf("round %d: want= %v, got= %v", round, want, got)
+		}
+		checkFlow(t, g, f, 0, n-1)
+	}
+}
+
+func BenchmarkMaxFlow(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewDirected()
+	for i := 0; i < 20000; i++ {
+		g.AddEdge(rng.Intn(2000), rng.Intn(2000), float64(rng.Intn(100)))
+	}
+
+	for i := 0; i < b.N; i++ {
+		g.MaxFlow(0, 1)
+	}
+}
@@ -0,0 +1,151 @@
+// Package graph provides directed and undirected graphs over containers.Value vertices,
+// with traversals, topological sort, components, shortest paths, spanning trees and maximum flows.
+package graph
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+var (
+	// ErrUnknownVertex is returned when a vertex is not in the graph.
+	ErrUnknownVertex = errors.New("unknown vertex")
+	// ErrUndirected is returned by algorithms which need a directed graph.
+	ErrUndirected = errors.New("graph is undirected")
+)
+
+// Edge is an edge from From to To. Edges of undirected graphs are given from each of their ends.
+type Edge struct {
+	From, To containers.Value
+	Weight   float64
+}
+
+// arc is an edge in an adjacency list.
+type arc struct {
+	to     int
+	weight float64
+}
+
+// graph is an adjacency-list graph. Vertices are numbered in the order they were added,
+// and algorithms visit them, and their edges, in that order. It is not safe for concurrent use.
+type graph struct {
+	directed bool
+	ids      map[containers.Value]int
+	vertices []containers.Value
+	out      [][]arc
+	edges    int
+}
+
+// NewDirected returns an empty directed graph. Vertices must be comparable.
+func NewDirected() *graph {
+	return &graph{directed: true, ids: map[containers.Value]int{}}
+}
+
+// NewUndirected returns an empty undirected graph. Vertices must be comparable.
+func NewUndirected() *graph {
+	return &graph{ids: map[containers.Value]int{}}
+}
+
+// Directed returns whether the graph is directed.
+func (g *graph) Directed() bool {
+	return g.directed
+}
+
+// Order returns the number of vertices.
+func (g *graph) Order() int {
+	return len(g.vertices)
+}
+
+// Size returns the number of edges.
+func (g *graph) Size() int {
+	return g.edges
+}
+
+// AddVertex adds v, and returns whether it was new.
+func (g *graph) AddVertex(v containers.Value) bool {
+	if _, ok := g.ids[v]; ok {
+		return false
+	}
+
+	g.add(v)
+	return true
+}
+
+func (g *graph) add(v containers.Value) int {
+	id, ok := g.ids[v]
+	if !ok {
+		id = len(g.vertices)
+		g.ids[v] = id
+		g.vertices = append(g.vertices, v)
+		g.out = append(g.out, nil)
+	}
+
+	return id
+}
+
+// AddEdge adds an edge from one vertex to another, adding them if needed.
+// Parallel edges and self-loops are allowed.
+func (g *graph) AddEdge(from, to containers.Value, weight float64) {
+	f, t := g.add(from), g.add(to)
+	g.out[f] = append(g.out[f], arc{to: t, weight: weight})
+	if !g.directed && f != t {
+		g.out[t] = append(g.out[t], arc{to: f, weight: weight})
+	}
+	g.edges++
+}
+
+// HasVertex returns whether v is in the graph.
+func (g *graph) HasVertex(v containers.Value) bool {
+	_, ok := g.ids[v]
+	return ok
+}
+
+// HasEdge returns whether there is an edge from one vertex to another.
+func (g *graph) HasEdge(from, to containers.Value) bool {
+	f, okf := g.ids[from]
+	t, okt := g.ids[to]
+	if !okf || !okt {
+		return false
+	}
+
+	for _, a := range g.out[f] {
+		if a.to == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Vertices returns all vertices, in the order they were added.
+func (g *graph) Vertices() []containers.Value {
+	return append([]containers.Value{}, g.vertices...)
+}
+
+// Neighbors returns the vertices which v has an edge to, or nil if v is not in the graph.
+func (g *graph) Neighbors(v containers.Value) []containers.Value {
+	id, ok := g.ids[v]
+	if !ok {
+		return nil
+	}
+
+	neighbors := make([]containers.Value, 0, len(g.out[id]))
+	for _, a := range g.out[id] {
+		neighbors = append(neighbors, g.vertices[a.to])
+	}
+	return neighbors
+}
+
+// Edges returns the edges from v, or nil if v is not in the graph.
+func (g *graph) Edges(v containers.Value) []Edge {
+	id, ok := g.ids[v]
+	if !ok {
+		return nil
+	}
+
+	edges := make([]Edge, 0, len(g.out[id]))
+	for _, a := range g.out[id] {
+		edges = append(edges, Edge{From: v, To: g.vertices[a.to], Weight: a.weight})
+	}
+	return edges
+}
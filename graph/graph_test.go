@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// newGraph returns a graph with unit weight edges like "ab" from a to b.
+func newGraph(directed bool, edges ...string) *graph {
+	g := NewUndirected()
+	if directed {
+		g = NewDirected()
+	}
+	for _, e := range edges {
+		g.AddEdge(string(e[0]), string(e[1]), 1)
+	}
+
+	return g
+}
+
+func TestGraph(t *testing.T) {
+	var testCases = map[string]struct {
+		directed  bool
+		edges     []string
+		size      int
+		neighbors map[string][]containers.Value
+	}{
+		"directed": {
+			directed: true,
+			edges:    []string{"ab", "ac", "cb", "ba"},
+			size:     4,
+			neighbors: map[string][]containers.Value{
+				"a": {"b", "c"},
+				"b": {"a"},
+				"c": {"b"},
+			},
+		},
+		"undirected": {
+			edges: []string{"ab", "ac", "cb"},
+			size:  3,
+			neighbors: map[string][]containers.Value{
+				"a": {"b", "c"},
+				"b": {"a", "c"},
+				"c": {"a", "b"},
+			},
+		},
+		"selfLoop": {
+			edges: []string{"aa", "ab"},
+			size:  2,
+			neighbors: map[string][]containers.Value{
+				"a": {"a", "b"},
+				"b": {"a"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			g := newGraph(tc.directed, tc.edges...)
+
+			if want, got := tc.directed, g.Directed(); want != got {
+				t.Fatalf("directed: want= %v, got= %v", want, got)
+			}
+			if want, got := len(tc.neighbors), g.Order(); want != got {
+				t.Fatalf("order: want= %v, got= %v", want, got)
+			}
+			if want, got := tc.size, g.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			for v, want := range tc.neighbors {
+				if got := g.Neighbors(v); !cmp.Equal(want, got) {
+					t.Fatalf("neighbors of %v: want= %v, got= %v", v, want, got)
+				}
+				for _, to := range want {
+					if !g.HasEdge(v, to) {
+						t.Fatalf("want edge from %v to %v", v, to)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVerticesAndEdges(t *testing.T) {
+	g := NewDirected()
+	if !g.AddVertex("x") || g.AddVertex("x") {
+		t.Fatalf("want vertex added once")
+	}
+	g.AddEdge("a", "b", 2.5)
+	g.AddEdge("a", "x", -1)
+
+	if want, got := []containers.Value{"x", "a", "b"}, g.Vertices(); !cmp.Equal(want, got) {
+		t.Fatalf("vertices: want= %v, got= %v", want, got)
+	}
+	want := []Edge{{From: "a", To: "b", Weight: 2.5}, {From: "a", To: "x", Weight: -1}}
+	if got := g.Edges("a"); !cmp.Equal(want, got) {
+		t.Fatalf("edges: want= %v, got= %v", want, got)
+	}
+	if g.HasEdge("b", "a") || g.HasEdge("a", "y") {
+		t.Fatalf("want no edge")
+	}
+	if g.Edges("y") != nil || g.Neighbors("y") != nil || g.HasVertex("y") {
+		t.Fatalf("want nothing for a missing vertex")
+	}
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers/unionfind"
+)
+
+// ErrDirected is returned by algorithms which need an undirected graph.
+var ErrDirected = errors.New("graph is directed")
+
+// edgeRef is an edge between vertex ids. Edges of undirected graphs are only given once, from their lower id.
+type edgeRef struct {
+	from, to int
+	weight   float64
+}
+
+// edgeRefs returns all edges, grouped by their first vertex.
+func (g *graph) edgeRefs() []edgeRef {
+	refs := make([]edgeRef, 0, g.edges)
+	for id, arcs := range g.out {
+		for _, a := range arcs {
+			if g.directed || id <= a.to {
+				refs = append(refs, edgeRef{from: id, to: a.to, weight: a.weight})
+			}
+		}
+	}
+
+	return refs
+}
+
+func (g *graph) edge(ref edgeRef) Edge {
+	return Edge{From: g.vertices[ref.from], To: g.vertices[ref.to], Weight: ref.weight}
+}
+
+// MinimumSpanningTreeKruskal returns the edges of a minimum spanning forest, which is a minimum spanning tree
+// of each component, by increasing weight. It runs in O(E log E) and returns ErrDirected for directed graphs.
+func (g *graph) MinimumSpanningTreeKruskal() ([]Edge, error) {
+	if g.directed {
+		return nil, ErrDirected
+	}
+
+	refs := g.edgeRefs()
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].weight < refs[j].weight })
+
+	sets := unionfind.New()
+	for id := range g.vertices {
+		sets.Add(id)
+	}
+	var tree []Edge
+	for _, ref := range refs {
+		if sets.Union(ref.from, ref.to) {
+			tree = append(tree, g.edge(ref))
+		}
+	}
+
+	return tree, nil
+}
+
+// edgeHeap is a min-heap of edges by weight, implementing heap.Interface.
+type edgeHeap []edgeRef
+
+func (h edgeHeap) Len() int { return len(h) }
+
+func (h edgeHeap) Less(i, j int) bool { return h[i].weight < h[j].weight }
+
+func (h edgeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *edgeHeap) Push(x interface{}) { *h = append(*h, x.(edgeRef)) }
+
+func (h *edgeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ref := old[n-1]
+	*h = old[:n-1]
+	return ref
+}
+
+// MinimumSpanningTreePrim returns the edges of a minimum spanning forest, growing a tree from the first vertex
+// of each component, in the order they join it. It runs in O(E log E) and returns ErrDirected for directed graphs.
+func (g *graph) MinimumSpanningTreePrim() ([]Edge, error) {
+	if g.directed {
+		return nil, ErrDirected
+	}
+
+	inTree := make([]bool, len(g.vertices))
+	candidates := &edgeHeap{} // edges leaving the tree, with lazy deletion of the ones which don't anymore
+	addVertex := func(id int) {
+		inTree[id] = true
+		for _, a := range g.out[id] {
+			if !inTree[a.to] {
+				heap.Push(candidates, edgeRef{from: id, to: a.to, weight: a.weight})
+			}
+		}
+	}
+
+	var tree []Edge
+	for root := range g.vertices {
+		if inTree[root] {
+			continue
+		}
+
+		addVertex(root)
+		for candidates.Len() > 0 {
+			ref := heap.Pop(candidates).(edgeRef)
+			if inTree[ref.to] {
+				continue
+			}
+
+			tree = append(tree, g.edge(ref))
+			addVertex(ref.to)
+		}
+	}
+
+	return tree, nil
+}
+
+// TotalWeight returns the sum of the weights of edges.
+func TotalWeight(edges []Edge) float64 {
+	var total float64
+	for _, e := range edges {
+		total += e.Weight
+	}
+
+	return total
+}
@@ -0,0 +1,163 @@
+package graph
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers/unionfind"
+)
+
+var spanningTrees = map[string]func(g *graph) ([]Edge, error){
+	"kruskal": func(g *graph) ([]Edge, error) { return g.MinimumSpanningTreeKruskal() },
+	"prim":    func(g *graph) ([]Edge, error) { return g.MinimumSpanningTreePrim() },
+}
+
+// clrsGraph returns the example of "Introduction to Algorithms" (23.1), whose minimum spanning trees weigh 37.
+func clrsGraph() *graph {
+	g := NewUndirected()
+	for _, e := range []Edge{
+		{"a", "b", 4}, {"a", "h", 8}, {"b", "c", 8}, {"b", "h", 11}, {"c", "d", 7}, {"c", "f", 4}, {"c", "i", 2},
+		{"d", "e", 9}, {"d", "f", 14}, {"e", "f", 10}, {"f", "g", 2}, {"g", "h", 1}, {"g", "i", 6}, {"h", "i", 7},
+	} {
+		g.AddEdge(e.From, e.To, e.Weight)
+	}
+
+	return g
+}
+
+func TestMinimumSpanningTree(t *testing.T) {
+	var testCases = map[string]struct {
+		g      *graph
+		edges  int
+		weight float64
+	}{
+		"clrs": {
+			g:      clrsGraph(),
+			edges:  8,
+			weight: 37,
+		},
+		"forest": {
+			g: func() *graph {
+				g := NewUndirected()
+				g.AddEdge("a", "b", 3)
+				g.AddEdge("b", "c", 1)
+				g.AddEdge("a", "c", 2)
+				g.AddEdge("x", "y", 5)
+				g.AddVertex("z")
+				return g
+			}(),
+			edges:  3,
+			weight: 8,
+		},
+		"negativeWeightsAndParallelEdges": {
+			g: func() *graph {
+				g := NewUndirected()
+				g.AddEdge("a", "b", 1)
+				g.AddEdge("a", "b", -4)
+				g.AddEdge("b", "c", -1)
+				g.AddEdge("c", "c", -10)
+				return g
+			}(),
+			edges:  2,
+			weight: -5,
+		},
+	}
+
+	for name, tc := range testCases {
+		for algo, spanningTree := range spanningTrees {
+			t.Run(name+"/"+algo, func(t *testing.T) {
+				tree, err := spanningTree(tc.g)
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+
+				if want, got := tc.edges, len(tree); want != got {
+					t.Fatalf("edges: want= %v, got= %v", want, got)
+				}
+				if want, got := tc.weight, TotalWeight(tree); want != got {
+					t.Fatalf("weight: want= %v, got= %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestMinimumSpanningTreeKruskalOrder(t *testing.T) {
+	tree, _ := clrsGraph().MinimumSpanningTreeKruskal()
+	want := []Edge{{"h", "g", 1}, {"c", "i", 2}, {"f", "g", 2}, {"a", "b", 4}, {"c", "f", 4}, {"c", "d", 7}, {"a", "h", 8}, {"d", "e", 9}}
+	if got := tree; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func TestMinimumSpanningTreeDirected(t *testing.T) {
+	for algo, spanningTree := range spanningTrees {
+		if _, err := spanningTree(NewDirected()); err != ErrDirected {
+			t.Fatalf("%s: want= %v, got= %v", algo, ErrDirected, err)
+		}
+	}
+}
+
+// TestMinimumSpanningTreeRandom cross-checks both algorithms on random graphs: forests must weigh the same,
+// and connect the same vertices as the graph.
+func TestMinimumSpanningTreeRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		g := NewUndirected()
+		n := rng.Intn(60) + 1
+		for i := 0; i < n; i++ {
+			g.AddVertex(i)
+		}
+		for i := rng.Intn(3 * n); i > 0; i-- {
+			g.AddEdge(rng.Intn(n), rng.Intn(n), float64(rng.Intn(50)-10))
+		}
+		components := len(g.Components())
+
+		var weights []float64
+		for algo, spanningTree := range spanningTrees {
+			tree, _ := spanningTree(g)
+			if want, got := n-components, len(tree); want != got {
+				t.Fatalf("%s: want %d edges, got= %v", algo, want, got)
+			}
+
+			sets := unionfind.New()
+			for i := 0; i < n; i++ {
+				sets.Add(i)
+			}
+			for _, e := range tree {
+				if !g.HasEdge(e.From, e.To) {
+					t.Fatalf("%s: edge %v is not in the graph", algo, e)
+				}
+				if !sets.Union(e.From, e.To) {
+					t.Fatalf("%s: edge %v closes a cycle", algo, e)
+				}
+			}
+			if want, got := components, sets.Count(); want != got {
+				t.Fatalf("%s: want forest with %d trees, got= %v", algo, want, got)
+			}
+			weights = append(weights, TotalWeight(tree))
+		}
+		if weights[0] != weights[1] {
+			t.Fatalf("want same weights, got= %v", weights)
+		}
+	}
+}
+
+func BenchmarkMinimumSpanningTree(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewUndirected()
+	for i := 0; i < 10000; i++ {
+		g.AddEdge(rng.Intn(10000), rng.Intn(10000), float64(rng.Intn(1000)))
+		g.AddEdge(rng.Intn(10000), rng.Intn(10000), float64(rng.Intn(1000)))
+	}
+
+	for algo, spanningTree := range spanningTrees {
+		b.Run(algo, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				spanningTree(g)
+			}
+		})
+	}
+}
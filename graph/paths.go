@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/queue"
+)
+
+var (
+	// ErrNegativeWeight is returned by Dijkstra when an edge has a negative weight.
+	ErrNegativeWeight = errors.New("negative edge weight")
+	// ErrNegativeCycle is returned by BellmanFord when a cycle of negative weight is reachable from the source.
+	ErrNegativeCycle = errors.New("negative cycle")
+)
+
+const noParent = -1
+
+// paths holds the shortest paths from a source to all vertices.
+type paths struct {
+	g      *graph
+	source int
+	dist   []float64 // +Inf for unreachable vertices
+	parent []int     // previous vertex on the shortest path, noParent for the source and unreachable vertices
+}
+
+func newPaths(g *graph, source int) *paths {
+	p := &paths{
+		g:      g,
+		source: source,
+		dist:   make([]float64, len(g.vertices)),
+		parent: make([]int, len(g.vertices)),
+	}
+	for i := range p.dist {
+		p.dist[i], p.parent[i] = math.Inf(1), noParent
+	}
+	p.dist[source] = 0
+
+	return p
+}
+
+// DistanceTo returns the length of the shortest path to v, and whether v is reachable.
+func (p *paths) DistanceTo(v containers.Value) (float64, bool) {
+	id, ok := p.g.ids[v]
+	if !ok || id >= len(p.dist) || math.IsInf(p.dist[id], 1) {
+		return math.Inf(1), false
+	}
+
+	return p.dist[id], true
+}
+
+// PathTo returns the vertices of the shortest path to v, from the source to v, and whether v is reachable.
+func (p *paths) PathTo(v containers.Value) ([]containers.Value, bool) {
+	if _, ok := p.DistanceTo(v); !ok {
+		return nil, false
+	}
+
+	var path []containers.Value
+	for id := p.g.ids[v]; id != noParent; id = p.parent[id] {
+		path = append(path, p.g.vertices[id])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, true
+}
+
+// ShortestPathsBFS returns the paths from source with the fewest edges, ignoring weights, in O(V + E).
+func (g *graph) ShortestPathsBFS(source containers.Value) (*paths, error) {
+	s, ok := g.ids[source]
+	if !ok {
+		return nil, ErrUnknownVertex
+	}
+
+	p := newPaths(g, s)
+	pending := queue.New() // items are vertex ids
+	pending.Enqueue(s)
+	for pending.Size() > 0 {
+		front, _ := pending.Dequeue()
+		id := front.(int)
+		for _, a := range g.out[id] {
+			if math.IsInf(p.dist[a.to], 1) {
+				p.dist[a.to], p.parent[a.to] = p.dist[id]+1, id
+				pending.Enqueue(a.to)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// distItem is a vertex in the heap of Dijkstra's algorithm, with its tentative distance.
+type distItem struct {
+	id   int
+	dist float64
+}
+
+// distHeap is a min-heap of vertices by distance, implementing heap.Interface.
+type distHeap []distItem
+
+func (h distHeap) Len() int { return len(h) }
+
+func (h distHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+
+func (h distHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *distHeap) Push(x interface{}) { *h = append(*h, x.(distItem)) }
+
+func (h *distHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// ShortestPathsDijkstra returns the shortest paths from source in O((V + E) log V).
+// It returns ErrNegativeWeight if any edge has a negative weight.
+func (g *graph) ShortestPathsDijkstra(source containers.Value) (*paths, error) {
+	s, ok := g.ids[source]
+	if !ok {
+		return nil, ErrUnknownVertex
+	}
+	for _, arcs := range g.out {
+		for _, a := range arcs {
+			if a.weight < 0 {
+				return nil, ErrNegativeWeight
+			}
+		}
+	}
+
+	p := newPaths(g, s)
+	done := make([]bool, len(g.vertices))
+	pending := &distHeap{{id: s}} // with lazy deletion: a vertex can be pushed again with a shorter distance
+	for pending.Len() > 0 {
+		item := heap.Pop(pending).(distItem)
+		if done[item.id] {
+			continue
+		}
+
+		done[item.id] = true
+		for _, a := range g.out[item.id] {
+			if d := item.dist + a.weight; d < p.dist[a.to] {
+				p.dist[a.to], p.parent[a.to] = d, item.id
+				heap.Push(pending, distItem{id: a.to, dist: d})
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// ShortestPathsBellmanFord returns the shortest paths from source in O(V * E), allowing negative weights.
+// It returns ErrNegativeCycle if a cycle of negative weight is reachable from source, since paths through it
+// have no minimum. An undirected edge of negative weight is such a cycle.
+func (g *graph) ShortestPathsBellmanFord(source containers.Value) (*paths, error) {
+	s, ok := g.ids[source]
+	if !ok {
+		return nil, ErrUnknownVertex
+	}
+
+	p := newPaths(g, s)
+	relax := func() bool {
+		relaxed := false
+		for id, arcs := range g.out {
+			if math.IsInf(p.dist[id], 1) {
+				continue
+			}
+			for _, a := range arcs {
+				if d := p.dist[id] + a.weight; d < p.dist[a.to] {
+					p.dist[a.to], p.parent[a.to] = d, id
+					relaxed = true
+				}
+			}
+		}
+
+		return relaxed
+	}
+
+	for i := 1; i < len(g.vertices); i++ {
+		if !relax() {
+			return p, nil
+		}
+	}
+	if relax() {
+		return nil, ErrNegativeCycle
+	}
+
+	return p, nil
+}
@@ -0,0 +1,215 @@
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// weightedGraph returns a directed graph of Edges.
+func weightedGraph(edges ...Edge) *graph {
+	g := NewDirected()
+	for _, e := range edges {
+		g.AddEdge(e.From, e.To, e.Weight)
+	}
+
+	return g
+}
+
+func TestShortestPathsBFS(t *testing.T) {
+	g := newGraph(false, "ab", "bc", "cd", "ae", "ed")
+	g.AddVertex("x")
+	p, err := g.ShortestPathsBFS("a")
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	if d, ok := p.DistanceTo("d"); !ok || d != 2 {
+		t.Fatalf("distance: want 2, got= %v, %v", d, ok)
+	}
+	if want, got := []containers.Value{"a", "e", "d"}, mustPath(t, p, "d"); !cmp.Equal(want, got) {
+		t.Fatalf("path: want= %v, got= %v", want, got)
+	}
+	if want, got := []containers.Value{"a"}, mustPath(t, p, "a"); !cmp.Equal(want, got) {
+		t.Fatalf("path: want= %v, got= %v", want, got)
+	}
+	if _, ok := p.PathTo("x"); ok {
+		t.Fatalf("want no path to an unreachable vertex")
+	}
+	if _, err := g.ShortestPathsBFS("y"); err != ErrUnknownVertex {
+		t.Fatalf("want= %v, got= %v", ErrUnknownVertex, err)
+	}
+}
+
+func mustPath(t *testing.T, p *paths, to containers.Value) []containers.Value {
+	path, ok := p.PathTo(to)
+	if !ok {
+		t.Fatalf("want path to %v, got none", to)
+	}
+
+	return path
+}
+
+func TestShortestPathsWeighted(t *testing.T) {
+	var testCases = map[string]struct {
+		edges    []Edge
+		path     []containers.Value
+		distance float64
+		dijkstra error
+		bellman  error
+	}{
+		"shortcutWithMoreEdges": {
+			edges:    []Edge{{"s", "t", 10}, {"s", "a", 1}, {"a", "b", 2}, {"b", "t", 3}},
+			path:     []containers.Value{"s", "a", "b", "t"},
+			distance: 6,
+		},
+		"negativeWeight": {
+			edges:    []Edge{{"s", "a", 4}, {"s", "b", 5}, {"b", "a", -3}, {"a", "t", 1}},
+			path:     []containers.Value{"s", "b", "a", "t"},
+			distance: 3,
+			dijkstra: ErrNegativeWeight,
+		},
+		"negativeCycle": {
+			edges:    []Edge{{"s", "a", 1}, {"a", "b", -2}, {"b", "a", 1}, {"b", "t", 1}},
+			dijkstra: ErrNegativeWeight,
+			bellman:  ErrNegativeCycle,
+		},
+	}
+
+	for name, tc := range testCases {
+		for algo, shortestPaths := range map[string]func(g *graph) (*paths, error){
+			"dijkstra":    func(g *graph) (*paths, error) { return g.ShortestPathsDijkstra("s") },
+			"bellmanFord": func(g *graph) (*paths, error) { return g.ShortestPathsBellmanFord("s") },
+		} {
+			t.Run(name+"/"+algo, func(t *testing.T) {
+				wantErr := tc.bellman
+				if algo == "dijkstra" {
+					wantErr = tc.dijkstra
+				}
+
+				p, err := shortestPaths(weightedGraph(tc.edges...))
+				if want, got := wantErr, err; want != got {
+					t.Fatalf("error: want= %v, got= %v", want, got)
+				}
+				if err != nil {
+					return
+				}
+				if d, ok := p.DistanceTo("t"); !ok || d != tc.distance {
+					t.Fatalf("distance: want= %v, got= %v, %v", tc.distance, d, ok)
+				}
+				if want, got := tc.path, mustPath(t, p, "t"); !cmp.Equal(want, got) {
+					t.Fatalf("path: want= %v, got= %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestNegativeUndirectedEdge(t *testing.T) {
+	g := NewUndirected()
+	g.AddEdge("s", "a", -1)
+	if _, err := g.ShortestPathsBellmanFord("s"); err != ErrNegativeCycle {
+		t.Fatalf("want= %v, got= %v", ErrNegativeCycle, err)
+	}
+}
+
+// floydWarshall returns the distances between all vertices of g.
+func floydWarshall(g *graph) [][]float64 {
+	n := g.Order()
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+		for j := range dist[i] {
+			if i != j {
+				dist[i][j] = math.Inf(1)
+			}
+		}
+		for _, a := range g.out[i] {
+			dist[i][a.to] = math.Min(dist[i][a.to], a.weight)
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				dist[i][j] = math.Min(dist[i][j], dist[i][k]+dist[k][j])
+			}
+		}
+	}
+
+	return dist
+}
+
+func TestShortestPathsAgainstFloydWarshall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewDirected()
+	for i := 0; i < 40; i++ {
+		g.AddVertex(i)
+	}
+	for i := 0; i < 200; i++ {
+		g.AddEdge(rng.Intn(40), rng.Intn(40), float64(rng.Intn(20)))
+	}
+	want := floydWarshall(g)
+
+	for s := 0; s < 40; s++ {
+		dijkstra, _ := g.ShortestPathsDijkstra(s)
+		bellmanFord, _ := g.ShortestPathsBellmanFord(s)
+		for to := 0; to < 40; to++ {
+			for algo, p := range map[string]*paths{"dijkstra": dijkstra, "bellmanFord": bellmanFord} {
+				d, ok := p.DistanceTo(to)
+				if want := want[s][to]; d != want || ok == math.IsInf(want, 1) {
+					t.Fatalf("%s from %d to %d: want= %v, got= %v", algo, s, to, want, d)
+				}
+				if !ok {
+					continue
+				}
+
+				path := mustPath(t, p, to)
+				var length float64
+				for i := 1; i < len(path); i++ {
+					length += edgeWeight(g, path[i-1], path[i])
+				}
+				if length != d {
+					t.Fatalf("%s path %v: want length= %v, got= %v", algo, path, d, length)
+				}
+			}
+		}
+	}
+}
+
+// edgeWeight returns the lightest weight of the edges from one vertex to another.
+func edgeWeight(g *graph, from, to containers.Value) float64 {
+	w := math.Inf(1)
+	for _, e := range g.Edges(from) {
+		if e.To == to {
+			w = math.Min(w, e.Weight)
+		}
+	}
+
+	return w
+}
+
+func BenchmarkShortestPaths(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewDirected()
+	for i := 0; i < 10000; i++ {
+		g.AddVertex(i)
+	}
+	for i := 0; i < 50000; i++ {
+		g.AddEdge(rng.Intn(10000), rng.Intn(10000), float64(rng.Intn(100)))
+	}
+
+	b.Run("bfs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.ShortestPathsBFS(i % 10000)
+		}
+	})
+	b.Run("dijkstra", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.ShortestPathsDijkstra(i % 10000)
+		}
+	})
+}
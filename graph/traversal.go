@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/queue"
+	"github.com/bitsgofer/containers/stack"
+)
+
+// Iterator returns vertices one by one. The graph must not change while it is used.
+type Iterator interface {
+	// Next returns the next vertex, or false when there is none left.
+	Next() (containers.Value, bool)
+}
+
+// bfsIterator is a concrete implementation of Iterator, returning vertices in breadth-first order.
+type bfsIterator struct {
+	g       *graph
+	pending queue.Queueable // items are vertex ids, marked as seen when enqueued
+	seen    []bool
+	depth   []int
+	last    int
+}
+
+// BFS returns an Iterator over the vertices reachable from start, in breadth-first order.
+func (g *graph) BFS(start containers.Value) (*bfsIterator, error) {
+	id, ok := g.ids[start]
+	if !ok {
+		return nil, ErrUnknownVertex
+	}
+
+	it := &bfsIterator{
+		g:       g,
+		pending: queue.New(),
+		seen:    make([]bool, len(g.vertices)),
+		depth:   make([]int, len(g.vertices)),
+		last:    -1,
+	}
+	it.seen[id] = true
+	it.pending.Enqueue(id)
+	return it, nil
+}
+
+// Next returns the next vertex in breadth-first order.
+func (it *bfsIterator) Next() (containers.Value, bool) {
+	front, err := it.pending.Dequeue()
+	if err != nil {
+		return nil, false
+	}
+
+	id := front.(int)
+	for _, a := range it.g.out[id] {
+		if !it.seen[a.to] {
+			it.seen[a.to] = true
+			it.depth[a.to] = it.depth[id] + 1
+			it.pending.Enqueue(a.to)
+		}
+	}
+	it.last = id
+	return it.g.vertices[id], true
+}
+
+// Depth returns the number of edges between the start and the vertex last returned by Next.
+func (it *bfsIterator) Depth() int {
+	if it.last < 0 {
+		return 0
+	}
+
+	return it.depth[it.last]
+}
+
+// dfsIterator is a concrete implementation of Iterator, returning vertices in depth-first preorder.
+type dfsIterator struct {
+	g       *graph
+	pending stack.Stackable // items are vertex ids, marked as seen when popped
+	seen    []bool
+}
+
+// DFS returns an Iterator over the vertices reachable from start, in depth-first preorder.
+// Neighbors are explored in the order their edges were added.
+func (g *graph) DFS(start containers.Value) (*dfsIterator, error) {
+	id, ok := g.ids[start]
+	if !ok {
+		return nil, ErrUnknownVertex
+	}
+
+	it := &dfsIterator{
+		g:       g,
+		pending: stack.New(),
+		seen:    make([]bool, len(g.vertices)),
+	}
+	it.pending.Push(id)
+	return it, nil
+}
+
+// Next returns the next vertex in depth-first preorder.
+func (it *dfsIterator) Next() (containers.Value, bool) {
+	for it.pending.Size() > 0 {
+		top, _ := it.pending.Pop()
+		id := top.(int)
+		if it.seen[id] {
+			continue
+		}
+
+		it.seen[id] = true
+		arcs := it.g.out[id]
+		for i := len(arcs) - 1; i >= 0; i-- { // the first neighbor is popped first
+			if !it.seen[arcs[i].to] {
+				it.pending.Push(arcs[i].to)
+			}
+		}
+		return it.g.vertices[id], true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+var _ Iterator = (*bfsIterator)(nil)
+var _ Iterator = (*dfsIterator)(nil)
+
+func walk(it Iterator) []containers.Value {
+	var visited []containers.Value
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		visited = append(visited, v)
+	}
+
+	return visited
+}
+
+func TestTraversals(t *testing.T) {
+	//   a → b → d
+	//   ↓   ↓
+	//   c → e → f    g (unreachable)
+	g := newGraph(true, "ab", "ac", "bd", "be", "ce", "ef")
+	g.AddVertex("g")
+
+	bfs, err := g.BFS("a")
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := []containers.Value{"a", "b", "c", "d", "e", "f"}, walk(bfs); !cmp.Equal(want, got) {
+		t.Fatalf("bfs: want= %v, got= %v", want, got)
+	}
+
+	dfs, err := g.DFS("a")
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := []containers.Value{"a", "b", "d", "e", "f", "c"}, walk(dfs); !cmp.Equal(want, got) {
+		t.Fatalf("dfs: want= %v, got= %v", want, got)
+	}
+
+	if _, err := g.BFS("x"); err != ErrUnknownVertex {
+		t.Fatalf("bfs: want= %v, got= %v", ErrUnknownVertex, err)
+	}
+	if _, err := g.DFS("x"); err != ErrUnknownVertex {
+		t.Fatalf("dfs: want= %v, got= %v", ErrUnknownVertex, err)
+	}
+}
+
+func TestBFSDepth(t *testing.T) {
+	g := newGraph(false, "ab", "bc", "cd", "ad", "de")
+	bfs, _ := g.BFS("a")
+
+	depths := map[containers.Value]int{}
+	for v, ok := bfs.Next(); ok; v, ok = bfs.Next() {
+		depths[v] = bfs.Depth()
+	}
+	want := map[containers.Value]int{"a": 0, "b": 1, "d": 1, "c": 2, "e": 2}
+	if got := depths; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+// TestDFSDeepGraph checks that the traversal does not recurse, so long paths do not grow the goroutine stack.
+func TestDFSDeepGraph(t *testing.T) {
+	const n = 100000
+	g := NewDirected()
+	for i := 0; i < n; i++ {
+		g.AddEdge(i, i+1, 1)
+	}
+
+	dfs, _ := g.DFS(0)
+	if want, got := n+1, len(walk(dfs)); want != got {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if _, err := g.TopologicalSort(); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := n+1, len(g.StronglyConnectedComponents()); want != got {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
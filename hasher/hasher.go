@@ -0,0 +1,112 @@
+// Package hasher provides hash functions for containers.Value.
+package hasher
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Func returns a 64-bit hash of v. Values which are equal must have equal hashes.
+type Func func(v containers.Value) uint64
+
+// Default hashes strings, byte slices, bools, integers and floats by their content with FNV-1a,
+// pointers, channels and funcs by their address, as == compares them,
+// and other values by their Go-syntax representation, which is slower.
+// It is deterministic except for addresses, so hashes of values without them can be persisted.
+func Default(v containers.Value) uint64 {
+	var buf [9]byte // a type tag, then 8 bytes of content
+	var b []byte
+	switch v := v.(type) {
+	case string:
+		return Mix(fnvString(v))
+	case []byte:
+		return Mix(fnvBytes(v))
+	case bool:
+		buf[0] = 1
+		if v {
+			buf[1] = 1
+		}
+		b = buf[:2]
+	case int:
+		b = tagged(&buf, 2, uint64(v))
+	case int8:
+		b = tagged(&buf, 2, uint64(v))
+	case int16:
+		b = tagged(&buf, 2, uint64(v))
+	case int32:
+		b = tagged(&buf, 2, uint64(v))
+	case int64:
+		b = tagged(&buf, 2, uint64(v))
+	case uint:
+		b = tagged(&buf, 3, uint64(v))
+	case uint8:
+		b = tagged(&buf, 3, uint64(v))
+	case uint16:
+		b = tagged(&buf, 3, uint64(v))
+	case uint32:
+		b = tagged(&buf, 3, uint64(v))
+	case uint64:
+		b = tagged(&buf, 3, v)
+	case uintptr:
+		b = tagged(&buf, 3, uint64(v))
+	case float32:
+		b = tagged(&buf, 4, floatBits(float64(v)))
+	case float64:
+		b = tagged(&buf, 4, floatBits(v))
+	default:
+		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Ptr, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+			// compared by identity, so their target must not change the hash
+			b = tagged(&buf, 5, uint64(rv.Pointer()))
+		default:
+			return Mix(fnvString(fmt.Sprintf("%T%#v", v, v)))
+		}
+	}
+
+	return Mix(fnvBytes(b))
+}
+
+// tagged writes tag and x into buf, and returns the written part.
+// Integers of different sizes share a tag, so int8(1) and int64(1) hash the same.
+func tagged(buf *[9]byte, tag byte, x uint64) []byte {
+	buf[0] = tag
+	binary.LittleEndian.PutUint64(buf[1:], x)
+	return buf[:]
+}
+
+// floatBits returns the bits of f, with -0 as +0 since they are ==.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+
+	return math.Float64bits(f)
+}
+
+func fnvString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func fnvBytes(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// Mix scrambles the bits of h (the finalizer of SplitMix64), so that all bits of the result depend on all bits of h.
+// It also derives more hashes from one, e.g. Mix(h + i).
+func Mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
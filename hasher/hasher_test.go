@@ -0,0 +1,86 @@
+package hasher
+
+import (
+	"math"
+	"math/bits"
+	"testing"
+
+	"github.com/bitsgofer/containers"
+)
+
+type point struct {
+	x, y int
+}
+
+func TestDefault(t *testing.T) {
+	shared, ch := &point{1, 2}, make(chan int)
+	var testCases = map[string]struct {
+		a, b  containers.Value
+		equal bool
+	}{
+		"sameString":      {a: "abc", b: "abc", equal: true},
+		"differentString": {a: "abc", b: "abd"},
+		"stringAndBytes":  {a: "abc", b: []byte("abc"), equal: true},
+		"sameInt":         {a: 42, b: 42, equal: true},
+		"intSizes":        {a: int8(42), b: int64(42), equal: true},
+		"intAndUint":      {a: 42, b: uint(42)},
+		"intAndFloat":     {a: 1, b: 1.0},
+		"bools":           {a: true, b: false},
+		"signedZeros":     {a: 0.0, b: math.Copysign(0, -1), equal: true},
+		"signedZeros32":   {a: float32(0), b: float32(math.Copysign(0, -1)), equal: true},
+		"sameStruct":      {a: point{1, 2}, b: point{1, 2}, equal: true},
+		"differentStruct": {a: point{1, 2}, b: point{2, 1}},
+		"samePointer":     {a: shared, b: shared, equal: true},
+		"equalPointees":   {a: &point{1, 2}, b: &point{1, 2}},
+		"sameChan":        {a: ch, b: ch, equal: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.equal, Default(tc.a) == Default(tc.b); want != got {
+				t.Fatalf("want equal hashes= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestDefaultPointerIdentity checks that a pointer hashes the same after its target changes,
+// since it is still == to itself.
+func TestDefaultPointerIdentity(t *testing.T) {
+	p := &point{1, 2}
+	before := Default(p)
+	p.x = 3
+	if want, got := before, Default(p); want != got {
+		t.Fatalf("want= %x, got= %x", want, got)
+	}
+}
+
+// TestMixAvalanche checks that flipping one input bit flips about half of the output bits.
+func TestMixAvalanche(t *testing.T) {
+	const inputs = 1000
+
+	total := 0
+	for i := uint64(0); i < inputs; i++ {
+		for bit := uint(0); bit < 64; bit++ {
+			total += bits.OnesCount64(Mix(i) ^ Mix(i^(1<<bit)))
+		}
+	}
+
+	if avg := float64(total) / (inputs * 64); avg < 30 || avg > 34 {
+		t.Fatalf("want about 32 flipped bits, got= %.2f", avg)
+	}
+}
+
+func BenchmarkDefault(b *testing.B) {
+	for name, v := range map[string]containers.Value{
+		"int":    12345,
+		"string": "https://example.com/some/path",
+		"struct": point{1, 2},
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Default(v)
+			}
+		})
+	}
+}
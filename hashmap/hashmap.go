@@ -0,0 +1,365 @@
+// Package hashmap provides a hash map with open addressing, whose keys are compared with custom hash and equality
+// functions, so they can be slices or have their own notion of equality.
+// Collisions are resolved with Robin Hood hashing ("Robin Hood Hashing" - Celis 1986) and backward shift deletion.
+package hashmap
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// Map is a hash map.
+type Map interface {
+	// Len returns the number of keys.
+	Len() int
+	// Put sets the value of key. It returns whether key was already there, in which case its value is replaced.
+	Put(key, value containers.Value) bool
+	// Get returns the value of key, and whether key is there.
+	Get(key containers.Value) (containers.Value, bool)
+	// Delete removes key. It returns the removed value and whether key was there.
+	Delete(key containers.Value) (containers.Value, bool)
+	// Range calls fn on keys until fn returns false. fn must not change the map.
+	Range(fn func(key, value containers.Value) bool)
+	// Reset removes all keys.
+	Reset()
+	// Stats returns the load factor and probe lengths of the map.
+	Stats() Stats
+}
+
+// Options configures a hash map. Zero values use the defaults.
+type Options struct {
+	// Hash hashes keys, hasher.Default if nil. Keys which are equal must have equal hashes.
+	Hash hasher.Func
+	// Equal returns whether two keys are equal, == if nil. It must be set for keys which are not comparable, like slices.
+	Equal func(a, b containers.Value) bool
+	// InsertionOrder makes Range visit keys in the order they were first put, at the cost of slower deletions.
+	InsertionOrder bool
+	// Capacity is the number of keys the map can hold before growing.
+	Capacity int
+	// MaxLoad is the load factor in (0, 1) above which the map grows, 0.875 by default.
+	MaxLoad float64
+}
+
+// Stats describes how full a map is, and how far keys are from their home slot.
+// A key's probe length is the number of slots looked at to find it, 1 if it is in its home slot.
+type Stats struct {
+	Len             int
+	Slots           int
+	LoadFactor      float64
+	MaxProbeLength  int
+	MeanProbeLength float64
+}
+
+const (
+	defaultMaxLoad = 0.875
+	minSlots       = 8
+	// fibonacci is 2^64 / phi, which spreads the bits of hashes into the top bits used to pick home slots.
+	fibonacci = 0x9E3779B97F4A7C15
+)
+
+// entry is a key and its value. Entries are kept densely in a slice, and found through slots.
+type entry struct {
+	key     containers.Value
+	value   containers.Value
+	hash    uint64
+	deleted bool // a tombstone, only left in insertion order
+}
+
+// slot points to an entry. dist is its probe length, 0 for an empty slot.
+type slot struct {
+	hash  uint64
+	index int
+	dist  uint32
+}
+
+// hashMap is a Robin Hood hash map: a new key takes the slot of any key which is closer to its home slot,
+// which keeps probe lengths short and even. It is not safe for concurrent use.
+type hashMap struct {
+	hash    hasher.Func
+	equal   func(a, b containers.Value) bool
+	ordered bool
+	maxLoad float64
+
+	slots      []slot // a power of 2 of them
+	shift      uint   // 64 - log2(len(slots))
+	entries    []entry
+	n          int
+	tombstones int
+}
+
+// New returns an empty hash map.
+func New(opts Options) (*hashMap, error) {
+	if opts.Capacity < 0 {
+		return nil, errors.Errorf("capacity must not be negative, got %d", opts.Capacity)
+	}
+	if opts.MaxLoad == 0 {
+		opts.MaxLoad = defaultMaxLoad
+	}
+	if opts.MaxLoad <= 0 || opts.MaxLoad >= 1 {
+		return nil, errors.Errorf("max load must be in (0, 1), got %v", opts.MaxLoad)
+	}
+	if opts.Hash == nil {
+		opts.Hash = hasher.Default
+	}
+	if opts.Equal == nil {
+		opts.Equal = func(a, b containers.Value) bool { return a == b }
+	}
+
+	m := &hashMap{
+		hash:    opts.Hash,
+		equal:   opts.Equal,
+		ordered: opts.InsertionOrder,
+		maxLoad: opts.MaxLoad,
+	}
+	m.resize(m.slotsFor(opts.Capacity))
+	return m, nil
+}
+
+// slotsFor returns the number of slots needed to hold n keys.
+func (m *hashMap) slotsFor(n int) int {
+	slots := minSlots
+	for float64(n) > float64(slots)*m.maxLoad {
+		slots *= 2
+	}
+
+	return slots
+}
+
+// home returns the home slot of a hash.
+func (m *hashMap) home(hash uint64) int {
+	return int((hash * fibonacci) >> m.shift)
+}
+
+// resize rebuilds the slots with the given number of them, dropping tombstones.
+func (m *hashMap) resize(slots int) {
+	m.slots = make([]slot, slots)
+	m.shift = 64
+	for s := slots; s > 1; s >>= 1 {
+		m.shift--
+	}
+
+	if m.tombstones > 0 {
+		live := m.entries[:0]
+		for _, e := range m.entries {
+			if !e.deleted {
+				live = append(live, e)
+			}
+		}
+		for i := len(live); i < len(m.entries); i++ {
+			m.entries[i] = entry{}
+		}
+		m.entries, m.tombstones = live, 0
+	}
+	for i := range m.entries {
+		m.place(slot{hash: m.entries[i].hash, index: i, dist: 1})
+	}
+}
+
+// place puts s in the first slot from its home where it is further from home than the key there,
+// moving that key along in the same way.
+func (m *hashMap) place(s slot) {
+	mask := len(m.slots) - 1
+	for i := m.home(s.hash); ; i = (i + 1) & mask {
+		if m.slots[i].dist == 0 {
+			m.slots[i] = s
+			return
+		}
+		if m.slots[i].dist < s.dist {
+			m.slots[i], s = s, m.slots[i]
+		}
+		s.dist++
+	}
+}
+
+// find returns the slot of key, or -1 if key is not there.
+// The search stops at a key closer to its home than key would be, as Robin Hood placement would have put key there.
+func (m *hashMap) find(key containers.Value, hash uint64) int {
+	mask := len(m.slots) - 1
+	dist := uint32(1)
+	for i := m.home(hash); ; i = (i + 1) & mask {
+		s := m.slots[i]
+		if s.dist < dist {
+			return -1
+		}
+		if s.hash == hash && m.equal(m.entries[s.index].key, key) {
+			return i
+		}
+		dist++
+	}
+}
+
+// Len returns the number of keys.
+func (m *hashMap) Len() int {
+	return m.n
+}
+
+// Put sets the value of key. It returns whether key was already there, in which case its value is replaced.
+func (m *hashMap) Put(key, value containers.Value) bool {
+	hash := m.hash(key)
+	if i := m.find(key, hash); i >= 0 {
+		m.entries[m.slots[i].index].value = value
+		return true
+	}
+
+	if float64(m.n+1) > float64(len(m.slots))*m.maxLoad {
+		m.resize(2 * len(m.slots))
+	}
+	m.entries = append(m.entries, entry{key: key, value: value, hash: hash})
+	m.place(slot{hash: hash, index: len(m.entries) - 1, dist: 1})
+	m.n++
+
+	return false
+}
+
+// Get returns the value of key, and whether key is there.
+func (m *hashMap) Get(key containers.Value) (containers.Value, bool) {
+	i := m.find(key, m.hash(key))
+	if i < 0 {
+		return nil, false
+	}
+
+	return m.entries[m.slots[i].index].value, true
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (m *hashMap) Delete(key containers.Value) (containers.Value, bool) {
+	i := m.find(key, m.hash(key))
+	if i < 0 {
+		return nil, false
+	}
+
+	index := m.slots[i].index
+	value := m.entries[index].value
+	m.unlink(i)
+	m.removeEntry(index)
+	m.n--
+
+	return value, true
+}
+
+// unlink empties slot i, shifting the following keys back towards their home until one is already there.
+func (m *hashMap) unlink(i int) {
+	mask := len(m.slots) - 1
+	for next := (i + 1) & mask; m.slots[next].dist > 1; i, next = next, (next+1)&mask {
+		m.slots[i] = m.slots[next]
+		m.slots[i].dist--
+	}
+	m.slots[i] = slot{}
+}
+
+// removeEntry removes the entry at index, whose slot is already unlinked.
+// In insertion order, it leaves a tombstone, and compacts entries once they are mostly tombstones.
+// Otherwise, the last entry is moved into its place.
+func (m *hashMap) removeEntry(index int) {
+	if m.ordered {
+		m.entries[index] = entry{deleted: true}
+		m.tombstones++
+		if m.tombstones > len(m.entries)/2 {
+			m.resize(len(m.slots))
+		}
+		return
+	}
+
+	last := len(m.entries) - 1
+	if index != last {
+		m.slots[m.slotOf(last)].index = index
+		m.entries[index] = m.entries[last]
+	}
+	m.entries[last] = entry{}
+	m.entries = m.entries[:last]
+}
+
+// slotOf returns the slot pointing to the entry at index.
+func (m *hashMap) slotOf(index int) int {
+	mask := len(m.slots) - 1
+	for i := m.home(m.entries[index].hash); ; i = (i + 1) & mask {
+		if m.slots[i].index == index && m.slots[i].dist > 0 {
+			return i
+		}
+	}
+}
+
+// Range calls fn on keys until fn returns false. fn must not change the map.
+// Keys are visited in insertion order if the map keeps it, otherwise in no particular order.
+func (m *hashMap) Range(fn func(key, value containers.Value) bool) {
+	for _, e := range m.entries {
+		if e.deleted {
+			continue
+		}
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Reset removes all keys. The map keeps its slots.
+func (m *hashMap) Reset() {
+	for i := range m.slots {
+		m.slots[i] = slot{}
+	}
+	m.entries, m.n, m.tombstones = nil, 0, 0
+}
+
+// Stats returns the load factor and probe lengths of the map. It takes O(slots).
+func (m *hashMap) Stats() Stats {
+	stats := Stats{
+		Len:        m.n,
+		Slots:      len(m.slots),
+		LoadFactor: float64(m.n) / float64(len(m.slots)),
+	}
+
+	total := 0
+	for _, s := range m.slots {
+		if int(s.dist) > stats.MaxProbeLength {
+			stats.MaxProbeLength = int(s.dist)
+		}
+		total += int(s.dist)
+	}
+	if m.n > 0 {
+		stats.MeanProbeLength = float64(total) / float64(m.n)
+	}
+
+	return stats
+}
+
+// validate checks that every key is found from its home slot at its probe length,
+// that no key could be closer to home, and that slots and entries point to each other.
+func (m *hashMap) validate() error {
+	mask := len(m.slots) - 1
+	pointed := make([]bool, len(m.entries))
+	n := 0
+	for i, s := range m.slots {
+		if s.dist == 0 {
+			continue
+		}
+		n++
+
+		if s.index < 0 || s.index >= len(m.entries) || m.entries[s.index].deleted {
+			return errors.Errorf("slot %d points to no entry: %d", i, s.index)
+		}
+		if pointed[s.index] {
+			return errors.Errorf("entry %d has more than one slot", s.index)
+		}
+		pointed[s.index] = true
+		if e := m.entries[s.index]; e.hash != s.hash || m.hash(e.key) != s.hash {
+			return errors.Errorf("slot %d has hash %x, but its key %v has hash %x", i, s.hash, e.key, m.hash(e.key))
+		}
+		if want := uint32((i-m.home(s.hash))&mask) + 1; s.dist != want {
+			return errors.Errorf("slot %d has probe length %d, want %d", i, s.dist, want)
+		}
+		if prev := m.slots[(i-1)&mask]; s.dist > 1 && prev.dist+1 < s.dist {
+			return errors.Errorf("slot %d has probe length %d after one of %d", i, s.dist, prev.dist)
+		}
+	}
+
+	if n != m.n || len(m.entries)-m.tombstones != m.n {
+		return errors.Errorf("len is %d, but there are %d slots and %d entries", m.n, n, len(m.entries)-m.tombstones)
+	}
+	if !m.ordered && m.tombstones > 0 {
+		return errors.Errorf("%d tombstones without insertion order", m.tombstones)
+	}
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package hashmap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+var _ Map = (*hashMap)(nil)
+
+// intHash hashes int values without going through hasher.Default.
+func intHash(v containers.Value) uint64 {
+	return hasher.Mix(uint64(v.(int)))
+}
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		opts  Options
+		slots int
+		isErr bool
+	}{
+		"defaults":         {opts: Options{}, slots: 8},
+		"capacity":         {opts: Options{Capacity: 100}, slots: 128},
+		"capacityAtLoad":   {opts: Options{Capacity: 112}, slots: 128},
+		"capacityOverLoad": {opts: Options{Capacity: 113}, slots: 256},
+		"maxLoad":          {opts: Options{Capacity: 100, MaxLoad: 0.5}, slots: 256},
+		"negativeCapacity": {opts: Options{Capacity: -1}, isErr: true},
+		"negativeMaxLoad":  {opts: Options{MaxLoad: -0.5}, isErr: true},
+		"fullMaxLoad":      {opts: Options{MaxLoad: 1}, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			m, err := New(tc.opts)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if tc.isErr {
+				return
+			}
+			if want, got := tc.slots, m.Stats().Slots; want != got {
+				t.Fatalf("slots: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestRandom runs random operations on maps and the built-in map, checking that they agree.
+func TestRandom(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		for hashName, hash := range map[string]hasher.Func{"default": nil, "custom": intHash, "colliding": func(containers.Value) uint64 { return 0 }} {
+			t.Run(fmt.Sprintf("ordered=%v/%s", ordered, hashName), func(t *testing.T) {
+				rng := rand.New(rand.NewSource(1))
+				m, _ := New(Options{Hash: hash, InsertionOrder: ordered})
+				model := map[int]int{}
+				ops := 20000
+				if hashName == "colliding" {
+					ops = 2000
+				}
+
+				for op := 0; op < ops; op++ {
+					key := rng.Intn(500)
+					switch rng.Intn(3) {
+					case 0:
+						value, ok := m.Delete(key)
+						if want, had := model[key]; ok != had || (ok && value != want) {
+							t.Fatalf("delete %d: want= %v, got= %v, %v", key, want, value, ok)
+						}
+						delete(model, key)
+					case 1:
+						value, ok := m.Get(key)
+						if want, had := model[key]; ok != had || (ok && value != want) {
+							t.Fatalf("get %d: want= %v, got= %v, %v", key, want, value, ok)
+						}
+					default:
+						_, had := model[key]
+						if replaced := m.Put(key, op+1); replaced != had {
+							t.Fatalf("put %d: want= %v, got= %v", key, had, replaced)
+						}
+						model[key] = op + 1
+					}
+
+					if op%100 == 0 {
+						if err := m.validate(); err != nil {
+							t.Fatalf("invalid map after %d operations: %v", op, err)
+						}
+					}
+				}
+
+				got := map[int]int{}
+				m.Range(func(key, value containers.Value) bool {
+					got[key.(int)] = value.(int)
+					return true
+				})
+				if !cmp.Equal(model, got) {
+					t.Fatalf("want= %v, got= %v", model, got)
+				}
+				if want, got := len(model), m.Len(); want != got {
+					t.Fatalf("len: want= %v, got= %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestInsertionOrder(t *testing.T) {
+	m, _ := New(Options{InsertionOrder: true})
+	var want []int
+	for i := 0; i < 1000; i++ {
+		m.Put(i*7%1000, i)
+		want = append(want, i*7%1000)
+	}
+	for i := 0; i < 1000; i += 3 {
+		m.Delete(want[i])
+	}
+	m.Put(want[0], -1) // put again after its deletion, so it is last
+	m.Put(want[1], -1) // replaced, so it keeps its place
+
+	var kept []int
+	for i := 1; i < 1000; i++ {
+		if i%3 != 0 {
+			kept = append(kept, want[i])
+		}
+	}
+	kept = append(kept, want[0])
+
+	var got []int
+	m.Range(func(key, value containers.Value) bool {
+		got = append(got, key.(int))
+		return true
+	})
+	if !cmp.Equal(kept, got) {
+		t.Fatalf("want= %v, got= %v", kept, got)
+	}
+	if err := m.validate(); err != nil {
+		t.Fatalf("invalid map: %v", err)
+	}
+}
+
+func TestSliceKeys(t *testing.T) {
+	hash := func(v containers.Value) uint64 {
+		var h uint64
+		for _, x := range v.([]int) {
+			h = hasher.Mix(h ^ uint64(x))
+		}
+		return h
+	}
+	equal := func(a, b containers.Value) bool {
+		return cmp.Equal(a.([]int), b.([]int))
+	}
+
+	m, _ := New(Options{Hash: hash, Equal: equal})
+	for i := 0; i < 100; i++ {
+		m.Put([]int{i, i + 1}, i)
+	}
+	for i := 0; i < 100; i++ {
+		if value, ok := m.Get([]int{i, i + 1}); !ok || value != i {
+			t.Fatalf("get [%d %d]: want= %v, got= %v, %v", i, i+1, i, value, ok)
+		}
+	}
+	if _, ok := m.Get([]int{1, 1}); ok {
+		t.Fatalf("want [1 1] not found")
+	}
+	if replaced := m.Put([]int{0, 1}, -1); !replaced {
+		t.Fatalf("want [0 1] replaced")
+	}
+	if want, got := 100, m.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	m, _ := New(Options{Hash: intHash})
+	if want, got := (Stats{Slots: 8}), m.Stats(); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+
+	for i := 0; i < 100000; i++ {
+		m.Put(i, i)
+		if i%1000 != 0 {
+			continue
+		}
+		if stats := m.Stats(); stats.LoadFactor > defaultMaxLoad {
+			t.Fatalf("want load factor under %v, got= %+v", defaultMaxLoad, stats)
+		}
+	}
+	stats := m.Stats()
+	if stats.Len != 100000 || stats.LoadFactor < defaultMaxLoad/2 {
+		t.Fatalf("want 100000 keys at a load factor over %v, got= %+v", defaultMaxLoad/2, stats)
+	}
+	if stats.MeanProbeLength < 1 || stats.MeanProbeLength > 4 || stats.MaxProbeLength > 40 {
+		t.Fatalf("want short probes, got= %+v", stats)
+	}
+
+	m.Reset()
+	if stats := m.Stats(); stats.Len != 0 || stats.MaxProbeLength != 0 || m.Len() != 0 {
+		t.Fatalf("want no keys after reset, got= %+v", stats)
+	}
+	if _, ok := m.Get(1); ok {
+		t.Fatalf("want no keys after reset")
+	}
+}
+
+func TestRangeStops(t *testing.T) {
+	m, _ := New(Options{})
+	for i := 0; i < 10; i++ {
+		m.Put(i, i)
+	}
+
+	calls := 0
+	m.Range(func(key, value containers.Value) bool {
+		calls++
+		return calls < 3
+	})
+	if want, got := 3, calls; want != got {
+		t.Fatalf("calls: want= %v, got= %v", want, got)
+	}
+}
+
+func BenchmarkMap(b *testing.B) {
+	const keys = 1 << 16
+	strs := make([]string, keys)
+	for i := range strs {
+		strs[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		name := fmt.Sprintf("hashmap/ordered=%v", ordered)
+		b.Run(name+"/int/put", func(b *testing.B) {
+			m, _ := New(Options{Hash: intHash, InsertionOrder: ordered})
+			for i := 0; i < b.N; i++ {
+				m.Put(i%keys, i)
+			}
+		})
+		b.Run(name+"/int/get", func(b *testing.B) {
+			m, _ := New(Options{Hash: intHash, InsertionOrder: ordered})
+			for i := 0; i < keys; i++ {
+				m.Put(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Get(i % keys)
+			}
+		})
+		b.Run(name+"/int/churn", func(b *testing.B) {
+			m, _ := New(Options{Hash: intHash, InsertionOrder: ordered})
+			for i := 0; i < b.N; i++ {
+				m.Put(i%keys, i)
+				m.Delete((i + keys/2) % keys)
+			}
+		})
+		b.Run(name+"/string/get", func(b *testing.B) {
+			m, _ := New(Options{InsertionOrder: ordered})
+			for i, s := range strs {
+				m.Put(s, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Get(strs[i%keys])
+			}
+		})
+	}
+
+	b.Run("builtin/int/put", func(b *testing.B) {
+		m := map[containers.Value]containers.Value{}
+		for i := 0; i < b.N; i++ {
+			m[i%keys] = i
+		}
+	})
+	b.Run("builtin/int/get", func(b *testing.B) {
+		m := map[containers.Value]containers.Value{}
+		for i := 0; i < keys; i++ {
+			m[i] = i
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = m[i%keys]
+		}
+	})
+	b.Run("builtin/int/churn", func(b *testing.B) {
+		m := map[containers.Value]containers.Value{}
+		for i := 0; i < b.N; i++ {
+			m[i%keys] = i
+			delete(m, (i+keys/2)%keys)
+		}
+	})
+	b.Run("builtin/string/get", func(b *testing.B) {
+		m := map[containers.Value]containers.Value{}
+		for i, s := range strs {
+			m[s] = i
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = m[strs[i%keys]]
+		}
+	})
+}
@@ -0,0 +1,335 @@
+package list
+
+// Node is implemented by pointers to types embedding a Link, which can be put in an intrusive list.
+// A Node can be in at most one list at a time.
+type Node interface {
+	link() *Link
+}
+
+// Link is embedded in a type to put it in an intrusive list, without allocating an element for it:
+//
+//	type job struct {
+//		list.Link
+//		id int
+//	}
+//
+//	l := list.NewIntrusive()
+//	l.PushBack(&job{id: 1})
+type Link struct {
+	next, prev *Link
+	node       Node // the node embedding the link, while it is in a list
+	owner      *owner
+}
+
+func (l *Link) link() *Link {
+	return l
+}
+
+// nodeOf returns the node embedding l, or nil if l is nil.
+func nodeOf(l *Link) Node {
+	if l == nil {
+		return nil
+	}
+
+	return l.node
+}
+
+// owner identifies the list of a node. When a list is spliced into another one, its owner forwards to
+// the other's, so that splicing does not update every node ("A Class of Algorithms Which Require Nonlinear Time
+// to Maintain Disjoint Sets" - Tarjan 1979, for the path compression).
+type owner struct {
+	forward *owner
+}
+
+// resolve returns the owner of the list holding the node, compressing the path to it.
+func (o *owner) resolve() *owner {
+	root := o
+	for root.forward != nil {
+		root = root.forward
+	}
+	for o != root {
+		next := o.forward
+		o.forward = root
+		o = next
+	}
+
+	return root
+}
+
+// intrusive is a doubly linked list of nodes embedding a Link.
+// It is not safe for concurrent use.
+type intrusive struct {
+	head, tail *Link
+	len        int
+	owner      *owner
+}
+
+// NewIntrusive returns an empty intrusive list.
+func NewIntrusive() *intrusive {
+	return &intrusive{owner: &owner{}}
+}
+
+// Len returns the number of nodes in the list.
+func (l *intrusive) Len() int {
+	return l.len
+}
+
+// Front returns the first node of the list, or nil if it is empty.
+func (l *intrusive) Front() Node {
+	return nodeOf(l.head)
+}
+
+// Back returns the last node of the list, or nil if it is empty.
+func (l *intrusive) Back() Node {
+	return nodeOf(l.tail)
+}
+
+// Next returns the node after n, or nil if n is the last one.
+func (l *intrusive) Next(n Node) Node {
+	return nodeOf(n.link().next)
+}
+
+// Prev returns the node before n, or nil if n is the first one.
+func (l *intrusive) Prev(n Node) Node {
+	return nodeOf(n.link().prev)
+}
+
+// Contains returns whether n is in the list.
+func (l *intrusive) Contains(n Node) bool {
+	return l.contains(n.link())
+}
+
+func (l *intrusive) contains(link *Link) bool {
+	o := link.owner
+	return o == l.owner || o != nil && o.resolve() == l.owner
+}
+
+// PushFront adds n at the front of the list. n must not be in a list.
+func (l *intrusive) PushFront(n Node) {
+	l.insert(n, nil, l.head)
+}
+
+// PushBack adds n at the back of the list. n must not be in a list.
+func (l *intrusive) PushBack(n Node) {
+	l.insert(n, l.tail, nil)
+}
+
+// InsertBefore adds n before mark, and returns whether mark was in the list. n must not be in a list.
+func (l *intrusive) InsertBefore(n, mark Node) bool {
+	m := mark.link()
+	if !l.contains(m) {
+		return false
+	}
+
+	l.insert(n, m.prev, m)
+	return true
+}
+
+// InsertAfter adds n after mark, and returns whether mark was in the list. n must not be in a list.
+func (l *intrusive) InsertAfter(n, mark Node) bool {
+	m := mark.link()
+	if !l.contains(m) {
+		return false
+	}
+
+	l.insert(n, m, m.next)
+	return true
+}
+
+// Remove takes n out of the list, and returns whether it was there.
+func (l *intrusive) Remove(n Node) bool {
+	link := n.link()
+	if !l.contains(link) {
+		return false
+	}
+
+	l.unlink(link)
+	link.node, link.owner = nil, nil
+	return true
+}
+
+// MoveToFront moves n to the front of the list, if it is in the list.
+func (l *intrusive) MoveToFront(n Node) {
+	link := n.link()
+	if !l.contains(link) || l.head == link {
+		return
+	}
+
+	l.unlink(link)
+	l.link(link, nil, l.head)
+}
+
+// MoveToBack moves n to the back of the list, if it is in the list.
+func (l *intrusive) MoveToBack(n Node) {
+	link := n.link()
+	if !l.contains(link) || l.tail == link {
+		return
+	}
+
+	l.unlink(link)
+	l.link(link, l.tail, nil)
+}
+
+// MoveBefore moves n before mark, if both are in the list.
+func (l *intrusive) MoveBefore(n, mark Node) {
+	link, m := n.link(), mark.link()
+	if link == m || !l.contains(link) || !l.contains(m) {
+		return
+	}
+
+	l.unlink(link)
+	l.link(link, m.prev, m)
+}
+
+// MoveAfter moves n after mark, if both are in the list.
+func (l *intrusive) MoveAfter(n, mark Node) {
+	link, m := n.link(), mark.link()
+	if link == m || !l.contains(link) || !l.contains(m) {
+		return
+	}
+
+	l.unlink(link)
+	l.link(link, m, m.next)
+}
+
+// SpliceFront moves all nodes of other to the front of the list in O(1).
+func (l *intrusive) SpliceFront(other *intrusive) {
+	l.splice(other, nil, l.head)
+}
+
+// SpliceBack moves all nodes of other to the back of the list in O(1).
+func (l *intrusive) SpliceBack(other *intrusive) {
+	l.splice(other, l.tail, nil)
+}
+
+// SpliceBefore moves all nodes of other before mark in O(1), and returns whether mark was in the list.
+func (l *intrusive) SpliceBefore(mark Node, other *intrusive) bool {
+	m := mark.link()
+	if !l.contains(m) {
+		return false
+	}
+
+	l.splice(other, m.prev, m)
+	return true
+}
+
+// SpliceAfter moves all nodes of other after mark in O(1), and returns whether mark was in the list.
+func (l *intrusive) SpliceAfter(mark Node, other *intrusive) bool {
+	m := mark.link()
+	if !l.contains(m) {
+		return false
+	}
+
+	l.splice(other, m, m.next)
+	return true
+}
+
+// Reverse reverses the order of the nodes.
+func (l *intrusive) Reverse() {
+	for link := l.head; link != nil; {
+		link.next, link.prev = link.prev, link.next
+		link = link.prev
+	}
+	l.head, l.tail = l.tail, l.head
+}
+
+// Merge moves all nodes of other into the list, both being sorted by less, keeping the list sorted.
+// It is stable: nodes of the list come before equal nodes of other. It runs in O(Len() + other.Len()).
+func (l *intrusive) Merge(other *intrusive, less func(a, b Node) bool) {
+	if other == l || other.len == 0 {
+		return
+	}
+
+	var sentinel Link
+	tail := &sentinel
+	a, b := l.head, other.head
+	for a != nil && b != nil {
+		if less(b.node, a.node) {
+			tail.next, b.prev, b = b, tail, b.next
+		} else {
+			tail.next, a.prev, a = a, tail, a.next
+		}
+		tail = tail.next
+	}
+	if a != nil {
+		tail.next, a.prev = a, tail
+	} else {
+		tail.next, b.prev = b, tail
+		l.tail = other.tail
+	}
+
+	l.head = sentinel.next
+	l.head.prev = nil
+	l.len += other.len
+	other.owner.forward = l.owner
+	other.reset()
+}
+
+// insert links n between prev and next, which are adjacent.
+func (l *intrusive) insert(n Node, prev, next *Link) {
+	link := n.link()
+	link.node, link.owner = n, l.owner
+	l.link(link, prev, next)
+}
+
+// link links a link between prev and next, which are adjacent.
+func (l *intrusive) link(link, prev, next *Link) {
+	link.prev, link.next = prev, next
+	if prev == nil {
+		l.head = link
+	} else {
+		prev.next = link
+	}
+	if next == nil {
+		l.tail = link
+	} else {
+		next.prev = link
+	}
+	l.len++
+}
+
+// unlink takes link out of the list, leaving its node and owner as is.
+func (l *intrusive) unlink(link *Link) {
+	if link.prev == nil {
+		l.head = link.next
+	} else {
+		link.prev.next = link.next
+	}
+	if link.next == nil {
+		l.tail = link.prev
+	} else {
+		link.next.prev = link.prev
+	}
+	link.next, link.prev = nil, nil
+	l.len--
+}
+
+// splice links all nodes of other between prev and next, which are adjacent.
+func (l *intrusive) splice(other *intrusive, prev, next *Link) {
+	if other == l || other.len == 0 {
+		return
+	}
+
+	first, last := other.head, other.tail
+	first.prev, last.next = prev, next
+	if prev == nil {
+		l.head = first
+	} else {
+		prev.next = first
+	}
+	if next == nil {
+		l.tail = last
+	} else {
+		next.prev = last
+	}
+	l.len += other.len
+
+	other.owner.forward = l.owner
+	other.reset()
+}
+
+// reset empties the list without touching its former nodes, which now belong to another list.
+func (l *intrusive) reset() {
+	l.head, l.tail, l.len = nil, nil, 0
+	l.owner = &owner{}
+}
@@ -0,0 +1,118 @@
+package list
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// job is a user type embedding a Link.
+type job struct {
+	Link
+	id int
+}
+
+func ids(l *intrusive) []int {
+	var ids []int
+	for n := l.Front(); n != nil; n = l.Next(n) {
+		ids = append(ids, n.(*job).id)
+	}
+
+	return ids
+}
+
+func reversedIDs(l *intrusive) []int {
+	var ids []int
+	for n := l.Back(); n != nil; n = l.Prev(n) {
+		ids = append([]int{n.(*job).id}, ids...)
+	}
+
+	return ids
+}
+
+func TestIntrusive(t *testing.T) {
+	jobs := make([]*job, 5)
+	for i := range jobs {
+		jobs[i] = &job{id: i}
+	}
+
+	l := NewIntrusive()
+	l.PushBack(jobs[1])
+	l.PushBack(jobs[2])
+	l.PushFront(jobs[0])
+	if !l.InsertAfter(jobs[3], jobs[2]) || !l.InsertBefore(jobs[4], jobs[0]) {
+		t.Fatalf("want nodes inserted next to nodes of the list")
+	}
+	if want, got := []int{4, 0, 1, 2, 3}, ids(l); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+
+	l.MoveToBack(jobs[4])
+	l.MoveBefore(jobs[3], jobs[1])
+	l.Remove(jobs[2])
+	if want, got := []int{0, 3, 1, 4}, ids(l); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if want, got := ids(l), reversedIDs(l); !cmp.Equal(want, got) {
+		t.Fatalf("backward: want= %v, got= %v", want, got)
+	}
+	if l.Contains(jobs[2]) || l.Remove(jobs[2]) {
+		t.Fatalf("want removed node not in the list")
+	}
+
+	other := NewIntrusive()
+	other.PushBack(jobs[2])
+	if l.InsertAfter(&job{id: 9}, jobs[2]) {
+		t.Fatalf("want node of another list rejected as mark")
+	}
+	l.SpliceFront(other)
+	l.Reverse()
+	if want, got := []int{4, 1, 3, 0, 2}, ids(l); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if want, got := ids(l), reversedIDs(l); !cmp.Equal(want, got) {
+		t.Fatalf("backward: want= %v, got= %v", want, got)
+	}
+	if want, got := 5, l.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+func TestIntrusiveMerge(t *testing.T) {
+	l, other := NewIntrusive(), NewIntrusive()
+	for _, id := range []int{1, 4, 5} {
+		l.PushBack(&job{id: id})
+	}
+	for _, id := range []int{2, 3, 6, 7} {
+		other.PushBack(&job{id: id})
+	}
+	l.Merge(other, func(a, b Node) bool { return a.(*job).id < b.(*job).id })
+
+	if want, got := []int{1, 2, 3, 4, 5, 6, 7}, ids(l); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if want, got := ids(l), reversedIDs(l); !cmp.Equal(want, got) {
+		t.Fatalf("backward: want= %v, got= %v", want, got)
+	}
+	if want, got := 0, other.Len(); want != got {
+		t.Fatalf("other len: want= %v, got= %v", want, got)
+	}
+}
+
+// BenchmarkPushBack shows the allocation saved by embedding a Link.
+func BenchmarkPushBack(b *testing.B) {
+	b.Run("list", func(b *testing.B) {
+		b.ReportAllocs()
+		l := New()
+		for i := 0; i < b.N; i++ {
+			l.PushBack(&job{id: i})
+		}
+	})
+	b.Run("intrusive", func(b *testing.B) {
+		b.ReportAllocs()
+		l := NewIntrusive()
+		for i := 0; i < b.N; i++ {
+			l.PushBack(&job{id: i})
+		}
+	})
+}
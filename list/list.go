@@ -0,0 +1,178 @@
+// Package list provides doubly and singly linked lists, and an intrusive doubly linked list.
+package list
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// Element is an element of a doubly linked list.
+type Element struct {
+	Link
+	Value containers.Value
+}
+
+// Next returns the element after e, or nil if e is the last one.
+func (e *Element) Next() *Element {
+	return element(e.next)
+}
+
+// Prev returns the element before e, or nil if e is the first one.
+func (e *Element) Prev() *Element {
+	return element(e.prev)
+}
+
+// element returns the element embedding link, or nil if link is nil.
+func element(link *Link) *Element {
+	if link == nil {
+		return nil
+	}
+
+	return link.node.(*Element)
+}
+
+// list is a doubly linked list of containers.Value. Elements can be moved and spliced in O(1).
+// It is not safe for concurrent use.
+type list struct {
+	links intrusive
+}
+
+// New returns an empty doubly linked list.
+func New() *list {
+	return &list{links: intrusive{owner: &owner{}}}
+}
+
+// Len returns the number of elements in the list.
+func (l *list) Len() int {
+	return l.links.len
+}
+
+// Front returns the first element of the list, or nil if it is empty.
+func (l *list) Front() *Element {
+	return element(l.links.head)
+}
+
+// Back returns the last element of the list, or nil if it is empty.
+func (l *list) Back() *Element {
+	return element(l.links.tail)
+}
+
+// Contains returns whether e is in the list.
+func (l *list) Contains(e *Element) bool {
+	return e != nil && l.links.contains(&e.Link)
+}
+
+// PushFront adds a new containers.Value at the front of the list, and returns its element.
+func (l *list) PushFront(v containers.Value) *Element {
+	e := &Element{Value: v}
+	l.links.PushFront(e)
+	return e
+}
+
+// PushBack adds a new containers.Value at the back of the list, and returns its element.
+func (l *list) PushBack(v containers.Value) *Element {
+	e := &Element{Value: v}
+	l.links.PushBack(e)
+	return e
+}
+
+// InsertBefore adds a new containers.Value before mark, and returns its element, or nil if mark is not in the list.
+func (l *list) InsertBefore(v containers.Value, mark *Element) *Element {
+	if !l.Contains(mark) {
+		return nil
+	}
+
+	e := &Element{Value: v}
+	l.links.InsertBefore(e, mark)
+	return e
+}
+
+// InsertAfter adds a new containers.Value after mark, and returns its element, or nil if mark is not in the list.
+func (l *list) InsertAfter(v containers.Value, mark *Element) *Element {
+	if !l.Contains(mark) {
+		return nil
+	}
+
+	e := &Element{Value: v}
+	l.links.InsertAfter(e, mark)
+	return e
+}
+
+// Remove takes e out of the list if it is there, and returns its value.
+func (l *list) Remove(e *Element) containers.Value {
+	if l.Contains(e) {
+		l.links.Remove(e)
+	}
+
+	return e.Value
+}
+
+// MoveToFront moves e to the front of the list, if it is in the list.
+func (l *list) MoveToFront(e *Element) {
+	if l.Contains(e) {
+		l.links.MoveToFront(e)
+	}
+}
+
+// MoveToBack moves e to the back of the list, if it is in the list.
+func (l *list) MoveToBack(e *Element) {
+	if l.Contains(e) {
+		l.links.MoveToBack(e)
+	}
+}
+
+// MoveBefore moves e before mark, if both are in the list.
+func (l *list) MoveBefore(e, mark *Element) {
+	if l.Contains(e) && l.Contains(mark) {
+		l.links.MoveBefore(e, mark)
+	}
+}
+
+// MoveAfter moves e after mark, if both are in the list.
+func (l *list) MoveAfter(e, mark *Element) {
+	if l.Contains(e) && l.Contains(mark) {
+		l.links.MoveAfter(e, mark)
+	}
+}
+
+// SpliceFront moves all elements of other to the front of the list in O(1).
+func (l *list) SpliceFront(other *list) {
+	l.links.SpliceFront(&other.links)
+}
+
+// SpliceBack moves all elements of other to the back of the list in O(1).
+func (l *list) SpliceBack(other *list) {
+	l.links.SpliceBack(&other.links)
+}
+
+// SpliceBefore moves all elements of other before mark in O(1), and returns whether mark was in the list.
+func (l *list) SpliceBefore(mark *Element, other *list) bool {
+	return l.Contains(mark) && l.links.SpliceBefore(mark, &other.links)
+}
+
+// SpliceAfter moves all elements of other after mark in O(1), and returns whether mark was in the list.
+func (l *list) SpliceAfter(mark *Element, other *list) bool {
+	return l.Contains(mark) && l.links.SpliceAfter(mark, &other.links)
+}
+
+// Reverse reverses the order of the elements.
+func (l *list) Reverse() {
+	l.links.Reverse()
+}
+
+// Merge moves all elements of other into the list, both being sorted by less, keeping the list sorted.
+// It is stable: elements of the list come before equal elements of other.
+func (l *list) Merge(other *list, less func(a, b containers.Value) bool) {
+	l.links.Merge(&other.links, func(a, b Node) bool {
+		return less(a.(*Element).Value, b.(*Element).Value)
+	})
+}
+
+// Values returns the values of the list from front to back.
+func (l *list) Values() []containers.Value {
+	values := make([]containers.Value, 0, l.Len())
+	for e := l.Front(); e != nil; e = e.Next() {
+		values = append(values, e.Value)
+	}
+
+	return values
+}
@@ -0,0 +1,340 @@
+package list
+
+import (
+	stdlist "container/list"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func intLess(a, b containers.Value) bool {
+	return a.(int) < b.(int)
+}
+
+// fromValues returns a list holding values, and its elements.
+func fromValues(values ...containers.Value) (*list, []*Element) {
+	l := New()
+	var elems []*Element
+	for _, v := range values {
+		elems = append(elems, l.PushBack(v))
+	}
+
+	return l, elems
+}
+
+// validate checks links, ends and length of l against its values walked backward.
+func (l *list) validate() error {
+	n := 0
+	var prev *Element
+	for e := l.Front(); e != nil; e = e.Next() {
+		if e.Prev() != prev {
+			return fmt.Errorf("element %v: prev is %v, want %v", e.Value, e.Prev(), prev)
+		}
+		if !l.Contains(e) {
+			return fmt.Errorf("element %v is not owned by the list", e.Value)
+		}
+		prev = e
+		n++
+	}
+	if l.Back() != prev {
+		return fmt.Errorf("back is %v, want %v", l.Back(), prev)
+	}
+	if n != l.Len() {
+		return fmt.Errorf("len is %d, counted %d", l.Len(), n)
+	}
+
+	return nil
+}
+
+func TestListOps(t *testing.T) {
+	var testCases = map[string]struct {
+		op   func(l *list, e []*Element)
+		want []containers.Value
+	}{
+		"pushFront": {
+			op:   func(l *list, e []*Element) { l.PushFront(0) },
+			want: []containers.Value{0, 1, 2, 3},
+		},
+		"insertBefore": {
+			op:   func(l *list, e []*Element) { l.InsertBefore(9, e[1]) },
+			want: []containers.Value{1, 9, 2, 3},
+		},
+		"insertAfterBack": {
+			op:   func(l *list, e []*Element) { l.InsertAfter(9, e[2]) },
+			want: []containers.Value{1, 2, 3, 9},
+		},
+		"remove": {
+			op:   func(l *list, e []*Element) { l.Remove(e[1]) },
+			want: []containers.Value{1, 3},
+		},
+		"removeTwice": {
+			op:   func(l *list, e []*Element) { l.Remove(e[0]); l.Remove(e[0]) },
+			want: []containers.Value{2, 3},
+		},
+		"moveToFront": {
+			op:   func(l *list, e []*Element) { l.MoveToFront(e[2]) },
+			want: []containers.Value{3, 1, 2},
+		},
+		"moveToBack": {
+			op:   func(l *list, e []*Element) { l.MoveToBack(e[0]) },
+			want: []containers.Value{2, 3, 1},
+		},
+		"moveBefore": {
+			op:   func(l *list, e []*Element) { l.MoveBefore(e[2], e[0]) },
+			want: []containers.Value{3, 1, 2},
+		},
+		"moveAfter": {
+			op:   func(l *list, e []*Element) { l.MoveAfter(e[0], e[1]) },
+			want: []containers.Value{2, 1, 3},
+		},
+		"moveToItself": {
+			op:   func(l *list, e []*Element) { l.MoveAfter(e[1], e[1]) },
+			want: []containers.Value{1, 2, 3},
+		},
+		"reverse": {
+			op:   func(l *list, e []*Element) { l.Reverse() },
+			want: []containers.Value{3, 2, 1},
+		},
+		"elementOfOtherList": {
+			op: func(l *list, e []*Element) {
+				other, _ := fromValues(7)
+				l.MoveToFront(other.Front())
+				l.Remove(other.Front())
+				l.InsertAfter(9, other.Front())
+			},
+			want: []containers.Value{1, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, elems := fromValues(1, 2, 3)
+			tc.op(l, elems)
+
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if want, got := tc.want, l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestListSplice(t *testing.T) {
+	var testCases = map[string]struct {
+		op   func(l, other *list, e []*Element)
+		want []containers.Value
+	}{
+		"front": {
+			op:   func(l, other *list, e []*Element) { l.SpliceFront(other) },
+			want: []containers.Value{8, 9, 1, 2, 3},
+		},
+		"back": {
+			op:   func(l, other *list, e []*Element) { l.SpliceBack(other) },
+			want: []containers.Value{1, 2, 3, 8, 9},
+		},
+		"before": {
+			op:   func(l, other *list, e []*Element) { l.SpliceBefore(e[1], other) },
+			want: []containers.Value{1, 8, 9, 2, 3},
+		},
+		"after": {
+			op:   func(l, other *list, e []*Element) { l.SpliceAfter(e[1], other) },
+			want: []containers.Value{1, 2, 8, 9, 3},
+		},
+		"itself": {
+			op:   func(l, other *list, e []*Element) { l.SpliceBack(l) },
+			want: []containers.Value{1, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, elems := fromValues(1, 2, 3)
+			other, moved := fromValues(8, 9)
+			tc.op(l, other, elems)
+
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if want, got := tc.want, l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if want, got := 0, other.Len(); name != "itself" && want != got {
+				t.Fatalf("want spliced list empty, got len= %v", got)
+			}
+
+			// spliced elements belong to the list: they can be moved within it, but not within other
+			other.PushBack(10)
+			other.MoveToFront(moved[1])
+			if want, got := []containers.Value{10}, other.Values(); name != "itself" && !cmp.Equal(want, got) {
+				t.Fatalf("other: want= %v, got= %v", want, got)
+			}
+			l.MoveToFront(moved[1])
+			if want, got := containers.Value(9), l.Front().Value; name != "itself" && want != got {
+				t.Fatalf("front: want= %v, got= %v", want, got)
+			}
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+		})
+	}
+}
+
+// TestListSpliceChain checks ownership after a list is spliced into a list which is spliced in turn.
+func TestListSpliceChain(t *testing.T) {
+	a, aElems := fromValues(1)
+	b, _ := fromValues(2)
+	c, _ := fromValues(3)
+
+	b.SpliceBack(a)
+	c.SpliceBack(b)
+	if !c.Contains(aElems[0]) || a.Contains(aElems[0]) || b.Contains(aElems[0]) {
+		t.Fatalf("want element owned by the last list only")
+	}
+
+	c.Remove(aElems[0])
+	if c.Contains(aElems[0]) {
+		t.Fatalf("want removed element not owned")
+	}
+	if want, got := []containers.Value{3, 2}, c.Values(); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func TestListMerge(t *testing.T) {
+	var testCases = map[string]struct {
+		a, b []containers.Value
+	}{
+		"bothEmpty":  {},
+		"listEmpty":  {b: []containers.Value{1, 2}},
+		"otherEmpty": {a: []containers.Value{1, 2}},
+		"interleaved": {
+			a: []containers.Value{1, 3, 5, 7},
+			b: []containers.Value{2, 4, 6, 8, 10},
+		},
+		"duplicates": {
+			a: []containers.Value{1, 1, 2, 5},
+			b: []containers.Value{1, 2, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, _ := fromValues(tc.a...)
+			other, _ := fromValues(tc.b...)
+			l.Merge(other, intLess)
+
+			want := append(append([]containers.Value{}, tc.a...), tc.b...)
+			sort.SliceStable(want, func(i, j int) bool { return intLess(want[i], want[j]) })
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if got := l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestListMergeIsStable(t *testing.T) {
+	type item struct{ key, from int }
+	less := func(a, b containers.Value) bool { return a.(item).key < b.(item).key }
+
+	l, _ := fromValues(item{1, 0}, item{2, 0})
+	other, _ := fromValues(item{1, 1}, item{2, 1})
+	l.Merge(other, less)
+
+	want := []containers.Value{item{1, 0}, item{1, 1}, item{2, 0}, item{2, 1}}
+	if got := l.Values(); !cmp.Equal(want, got, cmp.AllowUnexported(item{})) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+// TestListAgainstSlice applies random operations to a list and to a slice, and compares them.
+func TestListAgainstSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	l := New()
+	var elems []*Element // in list order
+
+	for i := 0; i < 5000; i++ {
+		switch op := rng.Intn(6); {
+		case op == 0 || len(elems) == 0:
+			elems = append([]*Element{l.PushFront(i)}, elems...)
+		case op == 1:
+			elems = append(elems, l.PushBack(i))
+		case op == 2:
+			j := rng.Intn(len(elems))
+			l.Remove(elems[j])
+			elems = append(elems[:j], elems[j+1:]...)
+		case op == 3:
+			j := rng.Intn(len(elems))
+			e := elems[j]
+			l.MoveToFront(e)
+			elems = append([]*Element{e}, append(elems[:j:j], elems[j+1:]...)...)
+		case op == 4:
+			j, k := rng.Intn(len(elems)), rng.Intn(len(elems))
+			e, mark := elems[j], elems[k]
+			if j == k {
+				continue
+			}
+			l.MoveAfter(e, mark)
+			elems = append(elems[:j:j], elems[j+1:]...)
+			for k = range elems {
+				if elems[k] == mark {
+					break
+				}
+			}
+			elems = append(elems[:k+1:k+1], append([]*Element{e}, elems[k+1:]...)...)
+		case op == 5:
+			j := rng.Intn(len(elems))
+			elems = append(elems[:j+1:j+1], append([]*Element{l.InsertAfter(i, elems[j])}, elems[j+1:]...)...)
+		}
+	}
+
+	if err := l.validate(); err != nil {
+		t.Fatalf("invalid list: %v", err)
+	}
+	var want []containers.Value
+	for _, e := range elems {
+		want = append(want, e.Value)
+	}
+	if got := l.Values(); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+// BenchmarkMoveToFront compares moving elements with the standard library's list.
+func BenchmarkMoveToFront(b *testing.B) {
+	const n = 1000
+
+	b.Run("list", func(b *testing.B) {
+		l := New()
+		elems := make([]*Element, n)
+		for i := range elems {
+			elems[i] = l.PushBack(i)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.MoveToFront(elems[i%n])
+		}
+	})
+	b.Run("container/list", func(b *testing.B) {
+		l := stdlist.New()
+		elems := make([]*stdlist.Element, n)
+		for i := range elems {
+			elems[i] = l.PushBack(i)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.MoveToFront(elems[i%n])
+		}
+	})
+}
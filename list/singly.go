@@ -0,0 +1,230 @@
+package list
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+const listIsEmpty = "list is empty"
+
+// SElement is an element of a singly linked list.
+type SElement struct {
+	Value containers.Value
+	next  *SElement
+	owner *owner
+}
+
+// Next returns the element after e, or nil if e is the last one.
+func (e *SElement) Next() *SElement {
+	return e.next
+}
+
+// singly is a singly linked list of containers.Value, which uses less memory than a doubly linked list
+// but can only insert and remove after an element. It is not safe for concurrent use.
+type singly struct {
+	head, tail *SElement
+	len        int
+	owner      *owner
+}
+
+// NewSingly returns an empty singly linked list.
+func NewSingly() *singly {
+	return &singly{owner: &owner{}}
+}
+
+// Len returns the number of elements in the list.
+func (l *singly) Len() int {
+	return l.len
+}
+
+// Front returns the first element of the list, or nil if it is empty.
+func (l *singly) Front() *SElement {
+	return l.head
+}
+
+// Back returns the last element of the list, or nil if it is empty.
+func (l *singly) Back() *SElement {
+	return l.tail
+}
+
+// Contains returns whether e is in the list.
+func (l *singly) Contains(e *SElement) bool {
+	return e != nil && e.owner != nil && e.owner.resolve() == l.owner
+}
+
+// PushFront adds a new containers.Value at the front of the list, and returns its element.
+func (l *singly) PushFront(v containers.Value) *SElement {
+	e := &SElement{Value: v, next: l.head, owner: l.owner}
+	l.head = e
+	if l.tail == nil {
+		l.tail = e
+	}
+	l.len++
+
+	return e
+}
+
+// PushBack adds a new containers.Value at the back of the list, and returns its element.
+func (l *singly) PushBack(v containers.Value) *SElement {
+	if l.tail == nil {
+		return l.PushFront(v)
+	}
+
+	return l.insertAfter(v, l.tail)
+}
+
+// InsertAfter adds a new containers.Value after mark, and returns its element, or nil if mark is not in the list.
+func (l *singly) InsertAfter(v containers.Value, mark *SElement) *SElement {
+	if !l.Contains(mark) {
+		return nil
+	}
+
+	return l.insertAfter(v, mark)
+}
+
+func (l *singly) insertAfter(v containers.Value, mark *SElement) *SElement {
+	e := &SElement{Value: v, next: mark.next, owner: l.owner}
+	mark.next = e
+	if l.tail == mark {
+		l.tail = e
+	}
+	l.len++
+
+	return e
+}
+
+// PopFront removes the element at the front of the list and returns its value.
+func (l *singly) PopFront() (containers.Value, error) {
+	if l.head == nil {
+		return nil, errors.New(listIsEmpty)
+	}
+
+	e := l.head
+	l.head = e.next
+	if l.head == nil {
+		l.tail = nil
+	}
+	l.len--
+	e.next, e.owner = nil, nil
+
+	return e.Value, nil
+}
+
+// RemoveAfter removes the element after mark and returns its value.
+// It returns false if mark is not in the list or is the last element.
+func (l *singly) RemoveAfter(mark *SElement) (containers.Value, bool) {
+	if !l.Contains(mark) || mark.next == nil {
+		return nil, false
+	}
+
+	e := mark.next
+	mark.next = e.next
+	if l.tail == e {
+		l.tail = mark
+	}
+	l.len--
+	e.next, e.owner = nil, nil
+
+	return e.Value, true
+}
+
+// SpliceFront moves all elements of other to the front of the list in O(1).
+func (l *singly) SpliceFront(other *singly) {
+	if other == l || other.len == 0 {
+		return
+	}
+
+	other.tail.next = l.head
+	if l.tail == nil {
+		l.tail = other.tail
+	}
+	l.head = other.head
+	l.take(other)
+}
+
+// SpliceBack moves all elements of other to the back of the list in O(1).
+func (l *singly) SpliceBack(other *singly) {
+	if l.tail == nil {
+		l.SpliceFront(other)
+		return
+	}
+
+	l.SpliceAfter(l.tail, other)
+}
+
+// SpliceAfter moves all elements of other after mark in O(1), and returns whether mark was in the list.
+func (l *singly) SpliceAfter(mark *SElement, other *singly) bool {
+	if !l.Contains(mark) {
+		return false
+	}
+	if other == l || other.len == 0 {
+		return true
+	}
+
+	other.tail.next = mark.next
+	mark.next = other.head
+	if l.tail == mark {
+		l.tail = other.tail
+	}
+	l.take(other)
+	return true
+}
+
+// take adds the length and the elements of other, which were linked into the list, and empties other.
+func (l *singly) take(other *singly) {
+	l.len += other.len
+	other.owner.forward = l.owner
+	other.head, other.tail, other.len = nil, nil, 0
+	other.owner = &owner{}
+}
+
+// Reverse reverses the order of the elements.
+func (l *singly) Reverse() {
+	var prev *SElement
+	for e := l.head; e != nil; {
+		next := e.next
+		e.next = prev
+		prev, e = e, next
+	}
+	l.head, l.tail = l.tail, l.head
+}
+
+// Merge moves all elements of other into the list, both being sorted by less, keeping the list sorted.
+// It is stable: elements of the list come before equal elements of other. It runs in O(Len() + other.Len()).
+func (l *singly) Merge(other *singly, less func(a, b containers.Value) bool) {
+	if other == l || other.len == 0 {
+		return
+	}
+
+	var sentinel SElement
+	tail := &sentinel
+	a, b := l.head, other.head
+	for a != nil && b != nil {
+		if less(b.Value, a.Value) {
+			tail.next, b = b, b.next
+		} else {
+			tail.next, a = a, a.next
+		}
+		tail = tail.next
+	}
+	if a != nil {
+		tail.next = a
+	} else {
+		tail.next = b
+		l.tail = other.tail
+	}
+
+	l.head = sentinel.next
+	l.take(other)
+}
+
+// Values returns the values of the list from front to back.
+func (l *singly) Values() []containers.Value {
+	values := make([]containers.Value, 0, l.len)
+	for e := l.head; e != nil; e = e.next {
+		values = append(values, e.Value)
+	}
+
+	return values
+}
@@ -0,0 +1,203 @@
+package list
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// singlyFromValues returns a singly linked list holding values, and its elements.
+func singlyFromValues(values ...containers.Value) (*singly, []*SElement) {
+	l := NewSingly()
+	var elems []*SElement
+	for _, v := range values {
+		elems = append(elems, l.PushBack(v))
+	}
+
+	return l, elems
+}
+
+// validate checks the back and the length of l.
+func (l *singly) validate() error {
+	n := 0
+	var last *SElement
+	for e := l.Front(); e != nil; e = e.Next() {
+		if !l.Contains(e) {
+			return fmt.Errorf("element %v is not owned by the list", e.Value)
+		}
+		last = e
+		n++
+	}
+	if l.Back() != last {
+		return fmt.Errorf("back is %v, want %v", l.Back(), last)
+	}
+	if n != l.Len() {
+		return fmt.Errorf("len is %d, counted %d", l.Len(), n)
+	}
+
+	return nil
+}
+
+func TestSinglyOps(t *testing.T) {
+	var testCases = map[string]struct {
+		op   func(l *singly, e []*SElement)
+		want []containers.Value
+	}{
+		"pushFront": {
+			op:   func(l *singly, e []*SElement) { l.PushFront(0) },
+			want: []containers.Value{0, 1, 2, 3},
+		},
+		"insertAfter": {
+			op:   func(l *singly, e []*SElement) { l.InsertAfter(9, e[0]) },
+			want: []containers.Value{1, 9, 2, 3},
+		},
+		"insertAfterBack": {
+			op:   func(l *singly, e []*SElement) { l.InsertAfter(9, e[2]); l.PushBack(10) },
+			want: []containers.Value{1, 2, 3, 9, 10},
+		},
+		"popFront": {
+			op:   func(l *singly, e []*SElement) { l.PopFront() },
+			want: []containers.Value{2, 3},
+		},
+		"removeAfter": {
+			op:   func(l *singly, e []*SElement) { l.RemoveAfter(e[0]) },
+			want: []containers.Value{1, 3},
+		},
+		"removeAfterBack": {
+			op:   func(l *singly, e []*SElement) { l.RemoveAfter(e[2]) },
+			want: []containers.Value{1, 2, 3},
+		},
+		"removeBack": {
+			op:   func(l *singly, e []*SElement) { l.RemoveAfter(e[1]); l.PushBack(4) },
+			want: []containers.Value{1, 2, 4},
+		},
+		"reverse": {
+			op:   func(l *singly, e []*SElement) { l.Reverse(); l.PushBack(0) },
+			want: []containers.Value{3, 2, 1, 0},
+		},
+		"popAll": {
+			op: func(l *singly, e []*SElement) {
+				for l.Len() > 0 {
+					l.PopFront()
+				}
+				l.PushBack(4)
+			},
+			want: []containers.Value{4},
+		},
+		"elementOfOtherList": {
+			op: func(l *singly, e []*SElement) {
+				other, _ := singlyFromValues(7, 8)
+				l.InsertAfter(9, other.Front())
+				l.RemoveAfter(other.Front())
+			},
+			want: []containers.Value{1, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, elems := singlyFromValues(1, 2, 3)
+			tc.op(l, elems)
+
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if want, got := tc.want, l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestSinglyPopFrontEmpty(t *testing.T) {
+	l := NewSingly()
+	if _, err := l.PopFront(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+}
+
+func TestSinglySplice(t *testing.T) {
+	var testCases = map[string]struct {
+		values []containers.Value
+		op     func(l, other *singly, e []*SElement)
+		want   []containers.Value
+	}{
+		"front": {
+			values: []containers.Value{1, 2},
+			op:     func(l, other *singly, e []*SElement) { l.SpliceFront(other) },
+			want:   []containers.Value{8, 9, 1, 2},
+		},
+		"back": {
+			values: []containers.Value{1, 2},
+			op:     func(l, other *singly, e []*SElement) { l.SpliceBack(other) },
+			want:   []containers.Value{1, 2, 8, 9},
+		},
+		"backOfEmpty": {
+			op:   func(l, other *singly, e []*SElement) { l.SpliceBack(other) },
+			want: []containers.Value{8, 9},
+		},
+		"after": {
+			values: []containers.Value{1, 2},
+			op:     func(l, other *singly, e []*SElement) { l.SpliceAfter(e[0], other) },
+			want:   []containers.Value{1, 8, 9, 2},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, elems := singlyFromValues(tc.values...)
+			other, moved := singlyFromValues(8, 9)
+			tc.op(l, other, elems)
+			l.PushBack(10)
+
+			want := append(tc.want, 10)
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if got := l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if other.Len() != 0 || other.Contains(moved[0]) || !l.Contains(moved[0]) {
+				t.Fatalf("want spliced elements owned by the list only")
+			}
+		})
+	}
+}
+
+func TestSinglyMerge(t *testing.T) {
+	var testCases = map[string]struct {
+		a, b []containers.Value
+	}{
+		"bothEmpty":  {},
+		"listEmpty":  {b: []containers.Value{1, 2}},
+		"otherEmpty": {a: []containers.Value{1, 2}},
+		"otherLast":  {a: []containers.Value{1, 2}, b: []containers.Value{3, 4}},
+		"interleaved": {
+			a: []containers.Value{1, 3, 5, 7, 11},
+			b: []containers.Value{2, 4, 6, 8, 10},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			l, _ := singlyFromValues(tc.a...)
+			other, _ := singlyFromValues(tc.b...)
+			l.Merge(other, intLess)
+			l.PushBack(100)
+
+			want := append(append([]containers.Value{}, tc.a...), tc.b...)
+			sort.SliceStable(want, func(i, j int) bool { return intLess(want[i], want[j]) })
+			want = append(want, 100)
+			if err := l.validate(); err != nil {
+				t.Fatalf("invalid list: %v", err)
+			}
+			if got := l.Values(); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
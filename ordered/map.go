@@ -0,0 +1,224 @@
+package ordered
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// SortedMap is a map whose keys are kept sorted.
+type SortedMap interface {
+	// Len returns the number of keys.
+	Len() int
+	// Put sets the value of key, and returns whether key was already there.
+	// It returns ErrOutOfRange if key is outside of a sub map.
+	Put(key, value containers.Value) (bool, error)
+	// Get returns the value of key, and whether key is there.
+	Get(key containers.Value) (containers.Value, bool)
+	// Delete removes key. It returns the removed value and whether key was there.
+	Delete(key containers.Value) (containers.Value, bool)
+
+	// Floor returns the greatest key not greater than key, and its value.
+	Floor(key containers.Value) (containers.Value, containers.Value, bool)
+	// Ceiling returns the least key not less than key, and its value.
+	Ceiling(key containers.Value) (containers.Value, containers.Value, bool)
+	// Lower returns the greatest key less than key, and its value.
+	Lower(key containers.Value) (containers.Value, containers.Value, bool)
+	// Higher returns the least key greater than key, and its value.
+	Higher(key containers.Value) (containers.Value, containers.Value, bool)
+	// First returns the least key and its value.
+	First() (containers.Value, containers.Value, bool)
+	// Last returns the greatest key and its value.
+	Last() (containers.Value, containers.Value, bool)
+	// PollFirst removes the least key, and returns it with its value.
+	PollFirst() (containers.Value, containers.Value, bool)
+	// PollLast removes the greatest key, and returns it with its value.
+	PollLast() (containers.Value, containers.Value, bool)
+
+	// Ascend calls fn on keys in order, until fn returns false.
+	Ascend(fn func(key, value containers.Value) bool)
+	// Range calls fn on keys in [from, to) in order, until fn returns false.
+	Range(from, to containers.Value, fn func(key, value containers.Value) bool)
+	// SubMap returns a view of the keys in [from, to). Changes to the view are made to the map, and the other way around.
+	SubMap(from, to containers.Value) SortedMap
+}
+
+// sortedMap is a concrete implementation of SortedMap over a Store, or a view of the keys in [lo, hi) of one.
+// It is not safe for concurrent use.
+type sortedMap struct {
+	less    func(a, b containers.Value) bool
+	store   Store
+	bounded bool // whether the map is a sub map, otherwise lo and hi are not used
+	lo, hi  containers.Value
+}
+
+// NewMap returns an empty SortedMap whose keys are ordered by less, and stored in a Store from backend.
+// A nil backend uses AVLTree.
+func NewMap(less func(a, b containers.Value) bool, backend Backend) *sortedMap {
+	if backend == nil {
+		backend = AVLTree
+	}
+
+	return &sortedMap{less: less, store: backend(less)}
+}
+
+// inRange returns whether key is in [lo, hi).
+func (m *sortedMap) inRange(key containers.Value) bool {
+	return !m.bounded || (!m.less(key, m.lo) && m.less(key, m.hi))
+}
+
+// within returns the entry found in the store, unless it is out of range.
+func (m *sortedMap) within(key, value containers.Value, ok bool) (containers.Value, containers.Value, bool) {
+	if !ok || !m.inRange(key) {
+		return nil, nil, false
+	}
+
+	return key, value, true
+}
+
+// Len returns the number of keys. It takes O(n) for sub maps, which count their keys.
+func (m *sortedMap) Len() int {
+	if !m.bounded {
+		return m.store.Len()
+	}
+
+	n := 0
+	m.Ascend(func(key, value containers.Value) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Put sets the value of key, and returns whether key was already there.
+// It returns ErrOutOfRange if key is outside of a sub map.
+func (m *sortedMap) Put(key, value containers.Value) (bool, error) {
+	if !m.inRange(key) {
+		return false, ErrOutOfRange
+	}
+
+	return m.store.Insert(key, value), nil
+}
+
+// Get returns the value of key, and whether key is there.
+func (m *sortedMap) Get(key containers.Value) (containers.Value, bool) {
+	if !m.inRange(key) {
+		return nil, false
+	}
+
+	return m.store.Get(key)
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (m *sortedMap) Delete(key containers.Value) (containers.Value, bool) {
+	if !m.inRange(key) {
+		return nil, false
+	}
+
+	return m.store.Delete(key)
+}
+
+// Floor returns the greatest key not greater than key, and its value.
+func (m *sortedMap) Floor(key containers.Value) (containers.Value, containers.Value, bool) {
+	if m.bounded && !m.less(key, m.hi) {
+		return m.within(m.store.Lower(m.hi))
+	}
+
+	return m.within(m.store.Floor(key))
+}
+
+// Ceiling returns the least key not less than key, and its value.
+func (m *sortedMap) Ceiling(key containers.Value) (containers.Value, containers.Value, bool) {
+	if m.bounded && m.less(key, m.lo) {
+		return m.within(m.store.Ceiling(m.lo))
+	}
+
+	return m.within(m.store.Ceiling(key))
+}
+
+// Lower returns the greatest key less than key, and its value.
+func (m *sortedMap) Lower(key containers.Value) (containers.Value, containers.Value, bool) {
+	if m.bounded && !m.less(key, m.hi) {
+		return m.within(m.store.Lower(m.hi))
+	}
+
+	return m.within(m.store.Lower(key))
+}
+
+// Higher returns the least key greater than key, and its value.
+func (m *sortedMap) Higher(key containers.Value) (containers.Value, containers.Value, bool) {
+	if m.bounded && m.less(key, m.lo) {
+		return m.within(m.store.Ceiling(m.lo))
+	}
+
+	return m.within(m.store.Higher(key))
+}
+
+// First returns the least key and its value.
+func (m *sortedMap) First() (containers.Value, containers.Value, bool) {
+	if m.bounded {
+		return m.within(m.store.Ceiling(m.lo))
+	}
+
+	return m.within(m.store.First())
+}
+
+// Last returns the greatest key and its value.
+func (m *sortedMap) Last() (containers.Value, containers.Value, bool) {
+	if m.bounded {
+		return m.within(m.store.Lower(m.hi))
+	}
+
+	return m.within(m.store.Last())
+}
+
+// PollFirst removes the least key, and returns it with its value.
+func (m *sortedMap) PollFirst() (containers.Value, containers.Value, bool) {
+	return m.poll(m.First())
+}
+
+// PollLast removes the greatest key, and returns it with its value.
+func (m *sortedMap) PollLast() (containers.Value, containers.Value, bool) {
+	return m.poll(m.Last())
+}
+
+func (m *sortedMap) poll(key, value containers.Value, ok bool) (containers.Value, containers.Value, bool) {
+	if ok {
+		m.store.Delete(key)
+	}
+
+	return key, value, ok
+}
+
+// Ascend calls fn on keys in order, until fn returns false.
+func (m *sortedMap) Ascend(fn func(key, value containers.Value) bool) {
+	if !m.bounded {
+		m.store.Ascend(fn)
+		return
+	}
+
+	m.Range(m.lo, m.hi, fn)
+}
+
+// Range calls fn on keys in [from, to) in order, until fn returns false.
+func (m *sortedMap) Range(from, to containers.Value, fn func(key, value containers.Value) bool) {
+	if from, to = m.narrow(from, to); m.less(from, to) {
+		m.store.Range(from, to, fn)
+	}
+}
+
+// narrow returns the bounds of the keys in both [from, to) and the map.
+func (m *sortedMap) narrow(from, to containers.Value) (containers.Value, containers.Value) {
+	if m.bounded && m.less(from, m.lo) {
+		from = m.lo
+	}
+	if m.bounded && m.less(m.hi, to) {
+		to = m.hi
+	}
+
+	return from, to
+}
+
+// SubMap returns a view of the keys in [from, to). Changes to the view are made to the map, and the other way around.
+func (m *sortedMap) SubMap(from, to containers.Value) SortedMap {
+	lo, hi := m.narrow(from, to)
+	return &sortedMap{less: m.less, store: m.store, bounded: true, lo: lo, hi: hi}
+}
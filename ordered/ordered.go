@@ -0,0 +1,52 @@
+// Package ordered provides sorted maps and sets, backed by the ordered maps of the skiplist and btree packages.
+package ordered
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/btree"
+	"github.com/bitsgofer/containers/skiplist"
+)
+
+// Store is an ordered map of unique keys, which holds the entries of sorted maps and sets.
+type Store interface {
+	Len() int
+	// Insert sets the value of key. It returns whether key was already there.
+	Insert(key, value containers.Value) bool
+	// Delete removes key. It returns the removed value and whether key was there.
+	Delete(key containers.Value) (containers.Value, bool)
+	Get(key containers.Value) (containers.Value, bool)
+	Floor(key containers.Value) (containers.Value, containers.Value, bool)
+	Ceiling(key containers.Value) (containers.Value, containers.Value, bool)
+	Lower(key containers.Value) (containers.Value, containers.Value, bool)
+	Higher(key containers.Value) (containers.Value, containers.Value, bool)
+	First() (containers.Value, containers.Value, bool)
+	Last() (containers.Value, containers.Value, bool)
+	// Ascend calls fn on keys in order, until fn returns false.
+	Ascend(fn func(key, value containers.Value) bool)
+	// Range calls fn on keys in [from, to) in order, until fn returns false.
+	Range(from, to containers.Value, fn func(key, value containers.Value) bool)
+}
+
+// Backend returns an empty Store whose keys are ordered by less.
+type Backend func(less func(a, b containers.Value) bool) Store
+
+// SkipList is a Backend using a skip list.
+func SkipList(less func(a, b containers.Value) bool) Store {
+	return skiplist.New(less, nil)
+}
+
+// ConcurrentSkipList is a Backend using a skip list which is safe for concurrent use.
+// Sorted maps and sets are still not, as some of their operations take more than one call to the Store.
+func ConcurrentSkipList(less func(a, b containers.Value) bool) Store {
+	return skiplist.NewConcurrent(less, nil)
+}
+
+// AVLTree is a Backend using an AVL tree.
+func AVLTree(less func(a, b containers.Value) bool) Store {
+	return btree.NewAVLTree(less)
+}
+
+// ErrOutOfRange is returned when adding a key outside of the range of a sub map or sub set.
+var ErrOutOfRange = errors.New("key is out of range")
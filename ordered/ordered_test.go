@@ -0,0 +1,305 @@
+package ordered
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+var (
+	_ SortedMap = (*sortedMap)(nil)
+	_ SortedSet = (*sortedSet)(nil)
+)
+
+// backends are all the Backends the conformance tests run against.
+var backends = map[string]Backend{
+	"skipList":           SkipList,
+	"concurrentSkipList": ConcurrentSkipList,
+	"avlTree":            AVLTree,
+}
+
+func intLess(a, b containers.Value) bool {
+	return a.(int) < b.(int)
+}
+
+// model is a map with int keys in [lo, hi), to check sorted maps and their views against.
+type model struct {
+	values map[int]int
+	lo, hi int
+}
+
+func (m model) keys() []int {
+	var keys []int
+	for k := range m.values {
+		if m.lo <= k && k < m.hi {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+
+	return keys
+}
+
+// search returns the key of keys picked by found, scanning from the end if backward.
+func search(keys []int, backward bool, found func(k int) bool) (containers.Value, bool) {
+	for i := range keys {
+		if backward {
+			i = len(keys) - 1 - i
+		}
+		if found(keys[i]) {
+			return keys[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// checkMap compares every query of m, for keys in [-1, max], to the model.
+func checkMap(t *testing.T, m SortedMap, want model, max int) {
+	t.Helper()
+
+	keys := want.keys()
+	if got := m.Len(); got != len(keys) {
+		t.Fatalf("len: want= %v, got= %v", len(keys), got)
+	}
+	var ascended []int
+	m.Ascend(func(key, value containers.Value) bool {
+		if value != want.values[key.(int)] {
+			t.Fatalf("value of %v: want= %v, got= %v", key, want.values[key.(int)], value)
+		}
+		ascended = append(ascended, key.(int))
+		return true
+	})
+	if !cmp.Equal(keys, ascended) {
+		t.Fatalf("ascend: want= %v, got= %v", keys, ascended)
+	}
+
+	first, firstOK := search(keys, false, func(int) bool { return true })
+	last, lastOK := search(keys, true, func(int) bool { return true })
+	for name, q := range map[string]struct {
+		query  func() (containers.Value, containers.Value, bool)
+		want   containers.Value
+		wantOK bool
+	}{
+		"first": {query: m.First, want: first, wantOK: firstOK},
+		"last":  {query: m.Last, want: last, wantOK: lastOK},
+	} {
+		if key, _, ok := q.query(); ok != q.wantOK || key != q.want {
+			t.Fatalf("%s: want= %v, %v, got= %v, %v", name, q.want, q.wantOK, key, ok)
+		}
+	}
+
+	for k := -1; k <= max; k++ {
+		value, ok := m.Get(k)
+		wantValue, wantOK := want.values[k]
+		wantOK = wantOK && want.lo <= k && k < want.hi
+		if ok != wantOK || (ok && value != wantValue) {
+			t.Fatalf("get %d: want= %v, %v, got= %v, %v", k, wantValue, wantOK, value, ok)
+		}
+
+		k := k
+		for name, q := range map[string]struct {
+			query    func(key containers.Value) (containers.Value, containers.Value, bool)
+			backward bool
+			found    func(key int) bool
+		}{
+			"floor":   {query: m.Floor, backward: true, found: func(key int) bool { return key <= k }},
+			"ceiling": {query: m.Ceiling, found: func(key int) bool { return key >= k }},
+			"lower":   {query: m.Lower, backward: true, found: func(key int) bool { return key < k }},
+			"higher":  {query: m.Higher, found: func(key int) bool { return key > k }},
+		} {
+			wantKey, wantOK := search(keys, q.backward, q.found)
+			if key, _, ok := q.query(k); ok != wantOK || key != wantKey {
+				t.Fatalf("%s %d: want= %v, %v, got= %v, %v", name, k, wantKey, wantOK, key, ok)
+			}
+		}
+	}
+}
+
+// TestMapConformance runs random operations on a map and on a view of it, and checks all queries on both.
+func TestMapConformance(t *testing.T) {
+	const max = 300
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			m := NewMap(intLess, backend)
+			view := m.SubMap(100, 200)
+			values := map[int]int{}
+			all, viewed := model{values, -1 << 31, 1 << 31}, model{values, 100, 200}
+
+			for op := 0; op < 2000; op++ {
+				target, targetModel := SortedMap(m), all
+				if rng.Intn(2) == 0 {
+					target, targetModel = view, viewed
+				}
+				key := rng.Intn(max)
+				inRange := targetModel.lo <= key && key < targetModel.hi
+
+				switch rng.Intn(10) {
+				case 0:
+					wantKey, wantOK := search(targetModel.keys(), false, func(int) bool { return true })
+					if key, _, ok := target.PollFirst(); ok != wantOK || key != wantKey {
+						t.Fatalf("poll first: want= %v, %v, got= %v, %v", wantKey, wantOK, key, ok)
+					}
+					if wantOK {
+						delete(values, wantKey.(int))
+					}
+				case 1:
+					wantKey, wantOK := search(targetModel.keys(), true, func(int) bool { return true })
+					if key, _, ok := target.PollLast(); ok != wantOK || key != wantKey {
+						t.Fatalf("poll last: want= %v, %v, got= %v, %v", wantKey, wantOK, key, ok)
+					}
+					if wantOK {
+						delete(values, wantKey.(int))
+					}
+				case 2, 3, 4:
+					wantValue, wantOK := values[key]
+					wantOK = wantOK && inRange
+					if value, ok := target.Delete(key); ok != wantOK || (ok && value != wantValue) {
+						t.Fatalf("delete %d: want= %v, %v, got= %v, %v", key, wantValue, wantOK, value, ok)
+					}
+					if wantOK {
+						delete(values, key)
+					}
+				default:
+					_, wantReplaced := values[key]
+					replaced, err := target.Put(key, op)
+					if !inRange {
+						if err != ErrOutOfRange {
+							t.Fatalf("put %d: want= %v, got= %v", key, ErrOutOfRange, err)
+						}
+						continue
+					}
+					if err != nil || replaced != wantReplaced {
+						t.Fatalf("put %d: want replaced= %v, got= %v, %v", key, wantReplaced, replaced, err)
+					}
+					values[key] = op
+				}
+			}
+
+			checkMap(t, m, all, max)
+			checkMap(t, view, viewed, max)
+		})
+	}
+}
+
+func TestSubMap(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap(intLess, backend)
+			for k := 0; k < 10; k++ {
+				m.Put(k, k)
+			}
+
+			var testCases = map[string]struct {
+				view SortedMap
+				want []int
+			}{
+				"view":        {view: m.SubMap(2, 8), want: []int{2, 3, 4, 5, 6, 7}},
+				"nested":      {view: m.SubMap(2, 8).SubMap(5, 20), want: []int{5, 6, 7}},
+				"nestedWider": {view: m.SubMap(2, 8).SubMap(-5, 4), want: []int{2, 3}},
+				"empty":       {view: m.SubMap(5, 5), want: nil},
+				"reversed":    {view: m.SubMap(8, 2), want: nil},
+			}
+			for name, tc := range testCases {
+				t.Run(name, func(t *testing.T) {
+					if want, got := tc.want, keysOf(tc.view); !cmp.Equal(want, got) {
+						t.Fatalf("want= %v, got= %v", want, got)
+					}
+
+					var ranged []int
+					tc.view.Range(3, 7, func(key, value containers.Value) bool {
+						ranged = append(ranged, key.(int))
+						return true
+					})
+					var want []int
+					for _, k := range tc.want {
+						if 3 <= k && k < 7 {
+							want = append(want, k)
+						}
+					}
+					if !cmp.Equal(want, ranged) {
+						t.Fatalf("range: want= %v, got= %v", want, ranged)
+					}
+				})
+			}
+
+			// views see changes to the map, and the other way around
+			view := m.SubMap(2, 8)
+			m.Delete(3)
+			view.Delete(4)
+			if _, err := view.Put(20, 20); err != ErrOutOfRange {
+				t.Fatalf("want= %v, got= %v", ErrOutOfRange, err)
+			}
+			if want, got := []int{2, 5, 6, 7}, keysOf(view); !cmp.Equal(want, got) {
+				t.Fatalf("view: want= %v, got= %v", want, got)
+			}
+			if want, got := []int{0, 1, 2, 5, 6, 7, 8, 9}, keysOf(m); !cmp.Equal(want, got) {
+				t.Fatalf("map: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestRangeStops(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap(intLess, backend)
+			for k := 0; k < 10; k++ {
+				m.Put(k, k)
+			}
+
+			for viewName, view := range map[string]SortedMap{
+				"map":  m,
+				"view": m.SubMap(2, 8),
+			} {
+				var keys []int
+				view.Ascend(func(key, value containers.Value) bool {
+					keys = append(keys, key.(int))
+					return len(keys) < 3
+				})
+				if len(keys) != 3 {
+					t.Fatalf("%s: want 3 keys, got= %v", viewName, keys)
+				}
+			}
+		})
+	}
+}
+
+func keysOf(m SortedMap) []int {
+	var keys []int
+	m.Ascend(func(key, value containers.Value) bool {
+		keys = append(keys, key.(int))
+		return true
+	})
+
+	return keys
+}
+
+func BenchmarkMap(b *testing.B) {
+	const n = 1 << 16
+	for name, backend := range backends {
+		b.Run(fmt.Sprintf("%s/put", name), func(b *testing.B) {
+			m := NewMap(intLess, backend)
+			for i := 0; i < b.N; i++ {
+				m.Put(i*7919%n, i)
+			}
+		})
+		b.Run(fmt.Sprintf("%s/floor", name), func(b *testing.B) {
+			m := NewMap(intLess, backend)
+			for i := 0; i < n; i += 2 {
+				m.Put(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Floor(i % n)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package ordered
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// SortedSet is a set whose values are kept sorted.
+type SortedSet interface {
+	// Len returns the number of values.
+	Len() int
+	// Add adds v, and returns whether it was not there yet.
+	// It returns ErrOutOfRange if v is outside of a sub set.
+	Add(v containers.Value) (bool, error)
+	// Contains returns whether v is there.
+	Contains(v containers.Value) bool
+	// Remove removes v, and returns whether it was there.
+	Remove(v containers.Value) bool
+
+	// Floor returns the greatest value not greater than v.
+	Floor(v containers.Value) (containers.Value, bool)
+	// Ceiling returns the least value not less than v.
+	Ceiling(v containers.Value) (containers.Value, bool)
+	// Lower returns the greatest value less than v.
+	Lower(v containers.Value) (containers.Value, bool)
+	// Higher returns the least value greater than v.
+	Higher(v containers.Value) (containers.Value, bool)
+	// First returns the least value.
+	First() (containers.Value, bool)
+	// Last returns the greatest value.
+	Last() (containers.Value, bool)
+	// PollFirst removes the least value and returns it.
+	PollFirst() (containers.Value, bool)
+	// PollLast removes the greatest value and returns it.
+	PollLast() (containers.Value, bool)
+
+	// Ascend calls fn on values in order, until fn returns false.
+	Ascend(fn func(v containers.Value) bool)
+	// Range calls fn on values in [from, to) in order, until fn returns false.
+	Range(from, to containers.Value, fn func(v containers.Value) bool)
+	// SubSet returns a view of the values in [from, to). Changes to the view are made to the set, and the other way around.
+	SubSet(from, to containers.Value) SortedSet
+}
+
+// sortedSet is a concrete implementation of SortedSet, as the keys of a sorted map.
+// It is not safe for concurrent use.
+type sortedSet struct {
+	m *sortedMap
+}
+
+// NewSet returns an empty SortedSet whose values are ordered by less, and stored in a Store from backend.
+// A nil backend uses AVLTree.
+func NewSet(less func(a, b containers.Value) bool, backend Backend) *sortedSet {
+	return &sortedSet{m: NewMap(less, backend)}
+}
+
+// key returns the key found in the map, dropping its value.
+func key(k, _ containers.Value, ok bool) (containers.Value, bool) {
+	return k, ok
+}
+
+// Len returns the number of values. It takes O(n) for sub sets, which count their values.
+func (s *sortedSet) Len() int {
+	return s.m.Len()
+}
+
+// Add adds v, and returns whether it was not there yet.
+// It returns ErrOutOfRange if v is outside of a sub set.
+func (s *sortedSet) Add(v containers.Value) (bool, error) {
+	replaced, err := s.m.Put(v, nil)
+	return !replaced && err == nil, err
+}
+
+// Contains returns whether v is there.
+func (s *sortedSet) Contains(v containers.Value) bool {
+	_, ok := s.m.Get(v)
+	return ok
+}
+
+// Remove removes v, and returns whether it was there.
+func (s *sortedSet) Remove(v containers.Value) bool {
+	_, ok := s.m.Delete(v)
+	return ok
+}
+
+// Floor returns the greatest value not greater than v.
+func (s *sortedSet) Floor(v containers.Value) (containers.Value, bool) {
+	return key(s.m.Floor(v))
+}
+
+// Ceiling returns the least value not less than v.
+func (s *sortedSet) Ceiling(v containers.Value) (containers.Value, bool) {
+	return key(s.m.Ceiling(v))
+}
+
+// Lower returns the greatest value less than v.
+func (s *sortedSet) Lower(v containers.Value) (containers.Value, bool) {
+	return key(s.m.Lower(v))
+}
+
+// Higher returns the least value greater than v.
+func (s *sortedSet) Higher(v containers.Value) (containers.Value, bool) {
+	return key(s.m.Higher(v))
+}
+
+// First returns the least value.
+func (s *sortedSet) First() (containers.Value, bool) {
+	return key(s.m.First())
+}
+
+// Last returns the greatest value.
+func (s *sortedSet) Last() (containers.Value, bool) {
+	return key(s.m.Last())
+}
+
+// PollFirst removes the least value and returns it.
+func (s *sortedSet) PollFirst() (containers.Value, bool) {
+	return key(s.m.PollFirst())
+}
+
+// PollLast removes the greatest value and returns it.
+func (s *sortedSet) PollLast() (containers.Value, bool) {
+	return key(s.m.PollLast())
+}
+
+// Ascend calls fn on values in order, until fn returns false.
+func (s *sortedSet) Ascend(fn func(v containers.Value) bool) {
+	s.m.Ascend(func(key, _ containers.Value) bool { return fn(key) })
+}
+
+// Range calls fn on values in [from, to) in order, until fn returns false.
+func (s *sortedSet) Range(from, to containers.Value, fn func(v containers.Value) bool) {
+	s.m.Range(from, to, func(key, _ containers.Value) bool { return fn(key) })
+}
+
+// SubSet returns a view of the values in [from, to). Changes to the view are made to the set, and the other way around.
+func (s *sortedSet) SubSet(from, to containers.Value) SortedSet {
+	return &sortedSet{m: s.m.SubMap(from, to).(*sortedMap)}
+}
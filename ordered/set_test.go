@@ -0,0 +1,147 @@
+package ordered
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func valuesOf(s SortedSet) []int {
+	var values []int
+	s.Ascend(func(v containers.Value) bool {
+		values = append(values, v.(int))
+		return true
+	})
+
+	return values
+}
+
+// TestSetConformance runs random operations on a set and a view of it, and checks them against a map.
+func TestSetConformance(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			s := NewSet(intLess, backend)
+			view := s.SubSet(10, 20)
+			model := map[int]bool{}
+
+			for op := 0; op < 1000; op++ {
+				v := rng.Intn(30)
+				inView := 10 <= v && v < 20
+				switch rng.Intn(4) {
+				case 0:
+					if removed := s.Remove(v); removed != model[v] {
+						t.Fatalf("remove %d: want= %v, got= %v", v, model[v], removed)
+					}
+					delete(model, v)
+				case 1:
+					if removed := view.Remove(v); removed != (model[v] && inView) {
+						t.Fatalf("view remove %d: want= %v, got= %v", v, model[v] && inView, removed)
+					}
+					if inView {
+						delete(model, v)
+					}
+				case 2:
+					added, err := view.Add(v)
+					if !inView {
+						if err != ErrOutOfRange || added {
+							t.Fatalf("view add %d: want= %v, got= %v, %v", v, ErrOutOfRange, added, err)
+						}
+						continue
+					}
+					if err != nil || added == model[v] {
+						t.Fatalf("view add %d: want added= %v, got= %v, %v", v, !model[v], added, err)
+					}
+					model[v] = true
+				default:
+					if added, err := s.Add(v); err != nil || added == model[v] {
+						t.Fatalf("add %d: want added= %v, got= %v, %v", v, !model[v], added, err)
+					}
+					model[v] = true
+				}
+			}
+
+			var all, viewed []int
+			for v := range model {
+				all = append(all, v)
+				if 10 <= v && v < 20 {
+					viewed = append(viewed, v)
+				}
+			}
+			sort.Ints(all)
+			sort.Ints(viewed)
+			if got := valuesOf(s); !cmp.Equal(all, got) {
+				t.Fatalf("set: want= %v, got= %v", all, got)
+			}
+			if got := valuesOf(view); !cmp.Equal(viewed, got) {
+				t.Fatalf("view: want= %v, got= %v", viewed, got)
+			}
+			if s.Len() != len(all) || view.Len() != len(viewed) {
+				t.Fatalf("len: want= %d, %d, got= %d, %d", len(all), len(viewed), s.Len(), view.Len())
+			}
+			for v := 0; v < 30; v++ {
+				if s.Contains(v) != model[v] || view.Contains(v) != (model[v] && 10 <= v && v < 20) {
+					t.Fatalf("contains %d: want= %v", v, model[v])
+				}
+			}
+		})
+	}
+}
+
+func TestSetQueries(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := NewSet(intLess, backend)
+			for _, v := range []int{10, 20, 30, 40, 50} {
+				s.Add(v)
+			}
+			view := s.SubSet(15, 45)
+
+			var testCases = map[string]struct {
+				query  func() (containers.Value, bool)
+				want   containers.Value
+				wantOK bool
+			}{
+				"floor":        {query: func() (containers.Value, bool) { return s.Floor(25) }, want: 20, wantOK: true},
+				"ceiling":      {query: func() (containers.Value, bool) { return s.Ceiling(25) }, want: 30, wantOK: true},
+				"lower":        {query: func() (containers.Value, bool) { return s.Lower(20) }, want: 10, wantOK: true},
+				"higher":       {query: func() (containers.Value, bool) { return s.Higher(20) }, want: 30, wantOK: true},
+				"first":        {query: s.First, want: 10, wantOK: true},
+				"last":         {query: s.Last, want: 50, wantOK: true},
+				"viewFloor":    {query: func() (containers.Value, bool) { return view.Floor(100) }, want: 40, wantOK: true},
+				"viewCeiling":  {query: func() (containers.Value, bool) { return view.Ceiling(0) }, want: 20, wantOK: true},
+				"viewLower":    {query: func() (containers.Value, bool) { return view.Lower(20) }, wantOK: false},
+				"viewHigher":   {query: func() (containers.Value, bool) { return view.Higher(40) }, wantOK: false},
+				"viewFirst":    {query: view.First, want: 20, wantOK: true},
+				"viewLast":     {query: view.Last, want: 40, wantOK: true},
+				"nestedSubSet": {query: view.SubSet(0, 25).Last, want: 20, wantOK: true},
+			}
+			for name, tc := range testCases {
+				t.Run(name, func(t *testing.T) {
+					if v, ok := tc.query(); ok != tc.wantOK || v != tc.want {
+						t.Fatalf("want= %v, %v, got= %v, %v", tc.want, tc.wantOK, v, ok)
+					}
+				})
+			}
+
+			if v, ok := view.PollFirst(); !ok || v != 20 {
+				t.Fatalf("poll first: want= 20, got= %v, %v", v, ok)
+			}
+			if v, ok := view.PollLast(); !ok || v != 40 {
+				t.Fatalf("poll last: want= 40, got= %v, %v", v, ok)
+			}
+			var ranged []int
+			s.Range(0, 45, func(v containers.Value) bool {
+				ranged = append(ranged, v.(int))
+				return true
+			})
+			if want := []int{10, 30}; !cmp.Equal(want, ranged) {
+				t.Fatalf("range: want= %v, got= %v", want, ranged)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/stack"
+)
+
+// CombineFunc is an associative binary operator used to aggregate values.
+// It does not need to be commutative: combine(a, b) is always called with a older than b.
+type CombineFunc func(a, b containers.Value) containers.Value
+
+// AggregatingQueueable provides FIFO APIs and the aggregate over the current contents.
+type AggregatingQueueable interface {
+	Queueable
+	Aggregate() (containers.Value, error)
+}
+
+// aggregatedItem is an item on the front stack,
+// together with the aggregate of itself and every item newer than it on that stack.
+type aggregatedItem struct {
+	value containers.Value
+	agg   containers.Value
+}
+
+// aggregatingQueue is a concrete implementation of AggregatingQueueable using two stacks
+// (a.k.a. sliding window aggregation). Each operation is amortized O(1) calls to combine.
+//
+// New items are pushed onto back. When front is empty, all items of back are moved to front,
+// so front's top is the oldest item. Each item on front stores the aggregate of itself and all items
+// below it (newer ones), which gives us: aggregate = front.Top().agg + backAgg.
+type aggregatingQueue struct {
+	combine CombineFunc
+
+	front stack.Stackable // items are aggregatedItem
+	back  stack.Stackable // items are containers.Value
+
+	backAgg containers.Value // aggregate of items on back, only valid when back is not empty
+	newest  containers.Value // last enqueued item, only valid when queue is not empty
+}
+
+// NewAggregating returns AggregatingQueueable which aggregates its contents with combine.
+func NewAggregating(combine CombineFunc) *aggregatingQueue {
+	return &aggregatingQueue{
+		combine: combine,
+		front:   stack.New(),
+		back:    stack.New(),
+	}
+}
+
+// Enqueue adds a new containers.Value at the queue's back.
+func (q *aggregatingQueue) Enqueue(item containers.Value) {
+	if q.back.Size() == 0 {
+		q.backAgg = item
+	} else {
+		q.backAgg = q.combine(q.backAgg, item)
+	}
+
+	q.back.Push(item)
+	q.newest = item
+}
+
+// Size returns the current number of elements in the queue.
+func (q *aggregatingQueue) Size() int {
+	return q.front.Size() + q.back.Size()
+}
+
+// Clear empties the whole queue.
+func (q *aggregatingQueue) Clear() {
+	q.front.Clear()
+	q.back.Clear()
+	q.backAgg = nil
+	q.newest = nil
+}
+
+// Front returns the oldest containers.Value in the queue.
+func (q *aggregatingQueue) Front() (containers.Value, error) {
+	if q.Size() == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	q.refillFront()
+	top, err := q.front.Top()
+	if err != nil {
+		return nil, err
+	}
+
+	return top.(aggregatedItem).value, nil
+}
+
+// Back returns the newest containers.Value in the queue.
+func (q *aggregatingQueue) Back() (containers.Value, error) {
+	if q.Size() == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	return q.newest, nil
+}
+
+// Dequeue removes the oldest containers.Value in the queue and returns it.
+func (q *aggregatingQueue) Dequeue() (containers.Value, error) {
+	if q.Size() == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	q.refillFront()
+	top, err := q.front.Pop()
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Size() == 0 {
+		q.newest = nil
+	}
+	return top.(aggregatedItem).value, nil
+}
+
+// Aggregate returns combine() folded over all items in the queue, from oldest to newest.
+func (q *aggregatingQueue) Aggregate() (containers.Value, error) {
+	frontTop, frontErr := q.front.Top()
+	switch {
+	case frontErr != nil && q.back.Size() == 0:
+		return nil, errors.New(queueIsEmpty)
+	case frontErr != nil:
+		return q.backAgg, nil
+	case q.back.Size() == 0:
+		return frontTop.(aggregatedItem).agg, nil
+	default:
+		return q.combine(frontTop.(aggregatedItem).agg, q.backAgg), nil
+	}
+}
+
+// refillFront moves all items from back to front if front is empty.
+func (q *aggregatingQueue) refillFront() {
+	if q.front.Size() > 0 {
+		return
+	}
+
+	var agg containers.Value
+	for i := 0; q.back.Size() > 0; i++ {
+		item, _ := q.back.Pop() // items are popped from newest to oldest
+		if i == 0 {
+			agg = item
+		} else {
+			agg = q.combine(item, agg)
+		}
+
+		q.front.Push(aggregatedItem{value: item, agg: agg})
+	}
+	q.backAgg = nil
+}
@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+var (
+	sumInts = func(a, b containers.Value) containers.Value {
+		return a.(int) + b.(int)
+	}
+	gcdInts = func(a, b containers.Value) containers.Value {
+		x, y := a.(int), b.(int)
+		for y != 0 {
+			x, y = y, x%y
+		}
+		return x
+	}
+	concatStrings = func(a, b containers.Value) containers.Value {
+		return a.(string) + b.(string)
+	}
+	mulMatrices = func(a, b containers.Value) containers.Value {
+		x, y := a.(matrix2x2), b.(matrix2x2)
+		return matrix2x2{
+			x[0]*y[0] + x[1]*y[2], x[0]*y[1] + x[1]*y[3],
+			x[2]*y[0] + x[3]*y[2], x[2]*y[1] + x[3]*y[3],
+		}
+	}
+)
+
+// matrix2x2 is a row-major 2x2 matrix, whose product is not commutative.
+type matrix2x2 [4]int
+
+// aggregateOp is an operation on an aggregating queue: enqueue val, or dequeue if val is nil.
+type aggregateOp struct {
+	val containers.Value
+}
+
+func TestAggregatingQueueAggregate(t *testing.T) {
+	var testCases = map[string]struct {
+		combine CombineFunc
+		ops     []aggregateOp
+		isErr   bool
+		agg     containers.Value
+	}{
+		"empty": {
+			combine: sumInts,
+			isErr:   true,
+		},
+		"emptyAfterDequeue": {
+			combine: sumInts,
+			ops:     []aggregateOp{{1}, {2}, {nil}, {nil}},
+			isErr:   true,
+		},
+		"sumOne": {
+			combine: sumInts,
+			ops:     []aggregateOp{{1}},
+			agg:     1,
+		},
+		"sumMany": {
+			combine: sumInts,
+			ops:     []aggregateOp{{1}, {2}, {3}, {4}},
+			agg:     10,
+		},
+		"sumSlidingWindow": {
+			combine: sumInts,
+			ops:     []aggregateOp{{1}, {2}, {3}, {nil}, {4}, {nil}, {5}},
+			agg:     12,
+		},
+		"gcd": {
+			combine: gcdInts,
+			ops:     []aggregateOp{{7}, {12}, {18}, {nil}, {30}},
+			agg:     6,
+		},
+		"concatOnlyBack": {
+			combine: concatStrings,
+			ops:     []aggregateOp{{"a"}, {"b"}, {"c"}},
+			agg:     "abc",
+		},
+		"concatOnlyFront": {
+			combine: concatStrings,
+			ops:     []aggregateOp{{"a"}, {"b"}, {"c"}, {"d"}, {nil}},
+			agg:     "bcd",
+		},
+		"concatFrontAndBack": {
+			combine: concatStrings,
+			ops:     []aggregateOp{{"a"}, {"b"}, {"c"}, {nil}, {"d"}, {"e"}},
+			agg:     "bcde",
+		},
+		"matrixProduct": {
+			combine: mulMatrices,
+			ops: []aggregateOp{
+				{matrix2x2{1, 1, 0, 1}},
+				{matrix2x2{0, 1, 1, 0}},
+				{nil},
+				{matrix2x2{2, 0, 0, 1}},
+				{matrix2x2{1, 0, 3, 1}},
+			},
+			agg: matrix2x2{0, 1, 1, 0}.mul(matrix2x2{2, 0, 0, 1}).mul(matrix2x2{1, 0, 3, 1}),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q := NewAggregating(tc.combine)
+			for _, op := range tc.ops {
+				if op.val == nil {
+					q.Dequeue()
+					continue
+				}
+				q.Enqueue(op.val)
+			}
+
+			agg, err := q.Aggregate()
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			default:
+				if want, got := tc.agg, agg; !cmp.Equal(want, got) {
+					t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+				}
+			}
+		})
+	}
+}
+
+func (m matrix2x2) mul(other matrix2x2) matrix2x2 {
+	return mulMatrices(m, other).(matrix2x2)
+}
+
+func TestAggregatingQueueOrder(t *testing.T) {
+	var testCases = map[string]struct {
+		enqueued []containers.Value
+		dequeued []containers.Value
+		front    containers.Value
+		back     containers.Value
+	}{
+		"one": {
+			enqueued: []containers.Value{"a"},
+			front:    "a",
+			back:     "a",
+		},
+		"many": {
+			enqueued: []containers.Value{"a", "b", "c"},
+			front:    "a",
+			back:     "c",
+		},
+		"dequeued": {
+			enqueued: []containers.Value{"a", "b", "c"},
+			dequeued: []containers.Value{"a", "b"},
+			front:    "c",
+			back:     "c",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q := NewAggregating(concatStrings)
+			for _, v := range tc.enqueued {
+				q.Enqueue(v)
+			}
+
+			var dequeued []containers.Value
+			for range tc.dequeued {
+				val, err := q.Dequeue()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				dequeued = append(dequeued, val)
+			}
+			if want, got := tc.dequeued, dequeued; !cmp.Equal(want, got) {
+				t.Fatalf("dequeued: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+
+			if want, got := len(tc.enqueued)-len(tc.dequeued), q.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			if front, err := q.Front(); err != nil || !cmp.Equal(tc.front, front) {
+				t.Fatalf("front: want= %v, got= %v, err= %v", tc.front, front, err)
+			}
+			if back, err := q.Back(); err != nil || !cmp.Equal(tc.back, back) {
+				t.Fatalf("back: want= %v, got= %v, err= %v", tc.back, back, err)
+			}
+		})
+	}
+}
+
+func TestAggregatingQueueEmpty(t *testing.T) {
+	q := NewAggregating(sumInts)
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Clear()
+
+	if want, got := 0, q.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	if _, err := q.Front(); err == nil {
+		t.Fatalf("front: want error, got none")
+	}
+	if _, err := q.Back(); err == nil {
+		t.Fatalf("back: want error, got none")
+	}
+	if _, err := q.Dequeue(); err == nil {
+		t.Fatalf("dequeue: want error, got none")
+	}
+	if _, err := q.Aggregate(); err == nil {
+		t.Fatalf("aggregate: want error, got none")
+	}
+}
+
+func BenchmarkAggregatingQueueSlidingWindow(b *testing.B) {
+	q := NewAggregating(sumInts)
+	for i := 0; i < 100; i++ {
+		q.Enqueue(i)
+	}
+
+	for i := 0; i < b.N; i++ {
+		q.Enqueue(i)
+		q.Dequeue()
+		q.Aggregate()
+	}
+}
@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+// DedupPolicy decides what happens when an item's key is already pending in a dedup queue.
+type DedupPolicy int
+
+const (
+	// RejectDuplicates keeps the pending item and drops the new one.
+	RejectDuplicates DedupPolicy = iota
+	// MergeDuplicates replaces the pending item with Merge(pending, new), keeping its position.
+	MergeDuplicates
+)
+
+// DedupOptions configures a dedup queue.
+type DedupOptions struct {
+	Policy DedupPolicy
+	// Merge combines a pending item with a new one with the same key. It is required by MergeDuplicates.
+	Merge func(pending, item containers.Value) containers.Value
+	// RememberFor rejects items whose key was dequeued less than RememberFor ago. 0 disables it.
+	RememberFor time.Duration
+	// Clock tells the time for RememberFor, clock.Real if nil.
+	Clock clock.Clock
+}
+
+// dedupItem is an item in the queue with its key.
+type dedupItem struct {
+	key   containers.Value
+	value containers.Value
+}
+
+// dequeuedKey is a key remembered until expiresAt.
+type dequeuedKey struct {
+	key       containers.Value
+	expiresAt time.Time
+}
+
+// dedupQueue is a concrete implementation of Queueable which holds at most one item per key.
+type dedupQueue struct {
+	key  func(item containers.Value) containers.Value
+	opts DedupOptions
+
+	items   *queue // items are *dedupItem
+	pending map[containers.Value]*dedupItem
+
+	dequeued     map[containers.Value]time.Time // key => when it is forgotten
+	dequeuedKeys *queue                         // items are dequeuedKey, ordered by expiresAt
+}
+
+// NewDedup returns Queueable which does not accept an item if another one with the same key(item) is pending.
+// Keys must be comparable.
+func NewDedup(key func(item containers.Value) containers.Value, opts DedupOptions) (*dedupQueue, error) {
+	switch opts.Policy {
+	case RejectDuplicates:
+	case MergeDuplicates:
+		if opts.Merge == nil {
+			return nil, errors.New("merge function is required to merge duplicates")
+		}
+	default:
+		return nil, errors.Errorf("unknown dedup policy %d", opts.Policy)
+	}
+	if opts.RememberFor < 0 {
+		return nil, errors.Errorf("remember duration must not be negative, got %v", opts.RememberFor)
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+
+	return &dedupQueue{
+		key:          key,
+		opts:         opts,
+		items:        newZeroValueQueue(),
+		pending:      map[containers.Value]*dedupItem{},
+		dequeued:     map[containers.Value]time.Time{},
+		dequeuedKeys: newZeroValueQueue(),
+	}, nil
+}
+
+// Enqueue adds a new containers.Value at the queue's back, unless its key is pending or recently dequeued.
+func (q *dedupQueue) Enqueue(item containers.Value) {
+	q.Offer(item)
+}
+
+// Offer adds a new containers.Value at the queue's back, unless its key is pending or recently dequeued.
+// It returns whether the item was accepted, i.e. added or merged into the pending item.
+func (q *dedupQueue) Offer(item containers.Value) bool {
+	key := q.key(item)
+	if pending, ok := q.pending[key]; ok {
+		if q.opts.Policy != MergeDuplicates {
+			return false
+		}
+
+		pending.value = q.opts.Merge(pending.value, item)
+		return true
+	}
+
+	if q.opts.RememberFor > 0 {
+		q.forgetExpired()
+		if _, ok := q.dequeued[key]; ok {
+			return false
+		}
+	}
+
+	di := &dedupItem{key: key, value: item}
+	q.items.Enqueue(di)
+	q.pending[key] = di
+	return true
+}
+
+// forgetExpired removes dequeued keys which are not remembered anymore.
+func (q *dedupQueue) forgetExpired() {
+	now := q.opts.Clock.Now()
+	for q.dequeuedKeys.Size() > 0 {
+		front, _ := q.dequeuedKeys.Front()
+		dk := front.(dequeuedKey)
+		if dk.expiresAt.After(now) {
+			return
+		}
+
+		q.dequeuedKeys.Dequeue()
+		if expiresAt, ok := q.dequeued[dk.key]; ok && !expiresAt.After(dk.expiresAt) {
+			delete(q.dequeued, dk.key)
+		}
+	}
+}
+
+// Size returns the current number of elements in the queue.
+func (q *dedupQueue) Size() int {
+	return q.items.Size()
+}
+
+// Clear empties the whole queue and forgets dequeued keys.
+func (q *dedupQueue) Clear() {
+	q.items.Clear()
+	q.pending = map[containers.Value]*dedupItem{}
+	q.dequeued = map[containers.Value]time.Time{}
+	q.dequeuedKeys.Clear()
+}
+
+// Front returns the containers.Value at the queue's front.
+func (q *dedupQueue) Front() (containers.Value, error) {
+	front, err := q.items.Front()
+	if err != nil {
+		return nil, err
+	}
+
+	return front.(*dedupItem).value, nil
+}
+
+// Back returns the containers.Value at the queue's back.
+func (q *dedupQueue) Back() (containers.Value, error) {
+	back, err := q.items.Back()
+	if err != nil {
+		return nil, err
+	}
+
+	return back.(*dedupItem).value, nil
+}
+
+// Dequeue removes the containers.Value at the queue's front and returns it.
+// Its key is remembered for RememberFor.
+func (q *dedupQueue) Dequeue() (containers.Value, error) {
+	front, err := q.items.Dequeue()
+	if err != nil {
+		return nil, err
+	}
+
+	di := front.(*dedupItem)
+	delete(q.pending, di.key)
+	if q.opts.RememberFor > 0 {
+		q.forgetExpired()
+		expiresAt := q.opts.Clock.Now().Add(q.opts.RememberFor)
+		q.dequeued[di.key] = expiresAt
+		q.dequeuedKeys.Enqueue(dequeuedKey{key: di.key, expiresAt: expiresAt})
+	}
+
+	return di.value, nil
+}
@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+var _ Queueable = (*dedupQueue)(nil)
+
+// urlJob is a crawling job, keyed by URL.
+type urlJob struct {
+	url      string
+	priority int
+}
+
+func urlOf(item containers.Value) containers.Value {
+	return item.(urlJob).url
+}
+
+func maxPriority(pending, item containers.Value) containers.Value {
+	p, i := pending.(urlJob), item.(urlJob)
+	if i.priority > p.priority {
+		p.priority = i.priority
+	}
+
+	return p
+}
+
+func TestNewDedup(t *testing.T) {
+	var testCases = map[string]struct {
+		opts  DedupOptions
+		isErr bool
+	}{
+		"reject": {
+			opts: DedupOptions{Policy: RejectDuplicates},
+		},
+		"merge": {
+			opts: DedupOptions{Policy: MergeDuplicates, Merge: maxPriority},
+		},
+		"mergeWithoutFunc": {
+			opts:  DedupOptions{Policy: MergeDuplicates},
+			isErr: true,
+		},
+		"unknownPolicy": {
+			opts:  DedupOptions{Policy: DedupPolicy(42)},
+			isErr: true,
+		},
+		"negativeRemember": {
+			opts:  DedupOptions{RememberFor: -time.Second},
+			isErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewDedup(urlOf, tc.opts)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestDedupOffer(t *testing.T) {
+	var testCases = map[string]struct {
+		opts     DedupOptions
+		offered  []urlJob
+		accepted []bool
+		dequeued []containers.Value
+	}{
+		"noDuplicates": {
+			opts:     DedupOptions{Policy: RejectDuplicates},
+			offered:  []urlJob{{"a", 1}, {"b", 1}},
+			accepted: []bool{true, true},
+			dequeued: []containers.Value{urlJob{"a", 1}, urlJob{"b", 1}},
+		},
+		"rejectDuplicates": {
+			opts:     DedupOptions{Policy: RejectDuplicates},
+			offered:  []urlJob{{"a", 1}, {"b", 1}, {"a", 2}},
+			accepted: []bool{true, true, false},
+			dequeued: []containers.Value{urlJob{"a", 1}, urlJob{"b", 1}},
+		},
+		"mergeDuplicatesKeepsPosition": {
+			opts:     DedupOptions{Policy: MergeDuplicates, Merge: maxPriority},
+			offered:  []urlJob{{"a", 1}, {"b", 1}, {"a", 5}, {"a", 3}},
+			accepted: []bool{true, true, true, true},
+			dequeued: []containers.Value{urlJob{"a", 5}, urlJob{"b", 1}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q, err := NewDedup(urlOf, tc.opts)
+			if err != nil {
+				t.Fatalf("need a valid queue to test, got %q", err)
+			}
+
+			var accepted []bool
+			for _, job := range tc.offered {
+				accepted = append(accepted, q.Offer(job))
+			}
+			if want, got := tc.accepted, accepted; !cmp.Equal(want, got) {
+				t.Fatalf("accepted: want= %v, got= %v", want, got)
+			}
+
+			if want, got := tc.dequeued, dequeueAll(t, q); !cmp.Equal(want, got, cmp.AllowUnexported(urlJob{})) {
+				t.Fatalf("dequeued: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDedupRememberDequeued(t *testing.T) {
+	c := clock.NewFake(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	q, err := NewDedup(urlOf, DedupOptions{RememberFor: time.Minute, Clock: c})
+	if err != nil {
+		t.Fatalf("need a valid queue to test, got %q", err)
+	}
+
+	q.Offer(urlJob{"a", 1})
+	q.Dequeue()
+	if q.Offer(urlJob{"a", 2}) {
+		t.Fatalf("want recently dequeued key rejected")
+	}
+	if !q.Offer(urlJob{"b", 1}) {
+		t.Fatalf("want other keys accepted")
+	}
+
+	c.Advance(time.Minute - time.Second)
+	if q.Offer(urlJob{"a", 3}) {
+		t.Fatalf("want recently dequeued key rejected until it expires")
+	}
+
+	c.Advance(time.Second)
+	if !q.Offer(urlJob{"a", 4}) {
+		t.Fatalf("want expired key accepted")
+	}
+	q.Dequeue() // b
+	q.Dequeue() // a, remembered again
+
+	c.Advance(30 * time.Second)
+	if q.Offer(urlJob{"a", 5}) {
+		t.Fatalf("want key dequeued again rejected")
+	}
+}
+
+func TestDedupOps(t *testing.T) {
+	q, _ := NewDedup(urlOf, DedupOptions{})
+	if _, err := q.Front(); err == nil {
+		t.Fatalf("front: want error, got none")
+	}
+	if _, err := q.Back(); err == nil {
+		t.Fatalf("back: want error, got none")
+	}
+
+	q.Enqueue(urlJob{"a", 1})
+	q.Enqueue(urlJob{"b", 1})
+	q.Enqueue(urlJob{"a", 1})
+	if want, got := 2, q.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	if front, err := q.Front(); err != nil || front != (urlJob{"a", 1}) {
+		t.Fatalf("front: want a, got= %v, err= %v", front, err)
+	}
+	if back, err := q.Back(); err != nil || back != (urlJob{"b", 1}) {
+		t.Fatalf("back: want b, got= %v, err= %v", back, err)
+	}
+
+	q.Clear()
+	if want, got := 0, q.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	if !q.Offer(urlJob{"a", 1}) {
+		t.Fatalf("want key accepted after clear")
+	}
+}
+
+func dequeueAll(t *testing.T, q Queueable) []containers.Value {
+	var vals []containers.Value
+	for q.Size() > 0 {
+		val, err := q.Dequeue()
+		if err != nil {
+			t.Fatalf("cannot dequeue: %v", err)
+		}
+		vals = append(vals, val)
+	}
+
+	return vals
+}
+
+// BenchmarkDedupVersusPlain shows the overhead of hashing keys compared to the plain queue.
+// Values are all distinct, so the dedup queues accept them like the plain queue does.
+func BenchmarkDedupVersusPlain(b *testing.B) {
+	identity := func(item containers.Value) containers.Value { return item }
+
+	for name, bm := range map[string]struct {
+		newValue func(i int) containers.Value
+	}{
+		"int": {
+			newValue: func(i int) containers.Value { return i },
+		},
+		"string": {
+			newValue: func(i int) containers.Value { return "https://example.com/some/path/" + strconv.Itoa(i) },
+		},
+	} {
+		b.Run(name+"/plain", func(b *testing.B) {
+			q := newZeroValueQueue()
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(bm.newValue(i))
+				if i%2 == 1 {
+					q.Dequeue()
+				}
+			}
+		})
+		b.Run(name+"/dedup", func(b *testing.B) {
+			q, _ := NewDedup(identity, DedupOptions{})
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(bm.newValue(i))
+				if i%2 == 1 {
+					q.Dequeue()
+				}
+			}
+		})
+		b.Run(name+"/dedupRemembered", func(b *testing.B) {
+			q, _ := NewDedup(identity, DedupOptions{RememberFor: time.Millisecond})
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(bm.newValue(i))
+				if i%2 == 1 {
+					q.Dequeue()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package delay provides queues whose items can only be dequeued after their deadline.
+package delay
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+// Queueable provides APIs for a queue whose items become ready at a deadline.
+type Queueable interface {
+	Enqueue(item containers.Value, readyAt time.Time)
+	Take(ctx context.Context) (containers.Value, error)
+	Poll() (containers.Value, error)
+	Size() int
+	Clear()
+}
+
+const noItemIsReady = "no item is ready"
+
+// entry is an item waiting for its deadline.
+type entry struct {
+	value   containers.Value
+	readyAt time.Time
+	seq     uint64 // order of Enqueue(), so items with the same deadline are FIFO
+	tick    int64  // deadline in ticks, only used by timingWheel
+}
+
+// entryHeap is a min-heap of entries by deadline, implementing heap.Interface.
+type entryHeap []*entry
+
+func (h entryHeap) Len() int { return len(h) }
+
+func (h entryHeap) Less(i, j int) bool {
+	if h[i].readyAt.Equal(h[j].readyAt) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].readyAt.Before(h[j].readyAt)
+}
+
+func (h entryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap) Push(x interface{}) { *h = append(*h, x.(*entry)) }
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// notifier lets goroutines wait until the next change of a queue.
+type notifier struct {
+	changed chan struct{}
+}
+
+func newNotifier() notifier {
+	return notifier{changed: make(chan struct{})}
+}
+
+// wait returns a channel which is closed on the next notify().
+func (n *notifier) wait() <-chan struct{} {
+	return n.changed
+}
+
+func (n *notifier) notify() {
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// delayQueue is a concrete implementation of Queueable on a heap.
+// Items are returned in deadline order. It is safe for concurrent use.
+type delayQueue struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	items    entryHeap
+	seq      uint64
+	notifier notifier
+}
+
+// New returns Queueable using c to tell whether items are ready.
+func New(c clock.Clock) *delayQueue {
+	return &delayQueue{
+		clock:    c,
+		notifier: newNotifier(),
+	}
+}
+
+// Enqueue adds a new containers.Value, which can be dequeued from readyAt.
+func (q *delayQueue) Enqueue(item containers.Value, readyAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e := &entry{value: item, readyAt: readyAt, seq: q.seq}
+	q.seq++
+	heap.Push(&q.items, e)
+	if q.items[0] == e { // waiting goroutines need to wait for a new deadline
+		q.notifier.notify()
+	}
+}
+
+// Size returns the current number of elements in the queue, ready or not.
+func (q *delayQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// Clear empties the whole queue.
+func (q *delayQueue) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.items = nil
+	q.notifier.notify()
+}
+
+// Poll removes the ready containers.Value with the earliest deadline and returns it.
+// It returns an error if no item is ready.
+func (q *delayQueue) Poll() (containers.Value, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	val, _, ok := q.poll()
+	if !ok {
+		return nil, errors.New(noItemIsReady)
+	}
+
+	return val, nil
+}
+
+// poll returns the next ready value, or how long to wait for one (negative if the queue is empty).
+func (q *delayQueue) poll() (containers.Value, time.Duration, bool) {
+	if len(q.items) == 0 {
+		return nil, -1, false
+	}
+
+	wait := q.items[0].readyAt.Sub(q.clock.Now())
+	if wait > 0 {
+		return nil, wait, false
+	}
+
+	return heap.Pop(&q.items).(*entry).value, 0, true
+}
+
+// Take removes the ready containers.Value with the earliest deadline and returns it,
+// waiting for one to be ready if needed. It returns ctx.Err() if ctx is done first.
+func (q *delayQueue) Take(ctx context.Context) (containers.Value, error) {
+	for {
+		q.mu.Lock()
+		val, wait, ok := q.poll()
+		changed := q.notifier.wait()
+		q.mu.Unlock()
+
+		if ok {
+			return val, nil
+		}
+		if err := waitFor(ctx, q.clock, wait, changed); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitFor blocks until d passed (forever if d is negative), changed is closed or ctx is done.
+func waitFor(ctx context.Context, c clock.Clock, d time.Duration, changed <-chan struct{}) error {
+	var timeout <-chan time.Time
+	if d >= 0 {
+		timer := c.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+	case <-timeout:
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package delay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+var epoch = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// implementations creates each Queueable on a clock.
+var implementations = map[string]func(c clock.Clock) Queueable{
+	"heap": func(c clock.Clock) Queueable {
+		return New(c)
+	},
+	"timingWheel": func(c clock.Clock) Queueable {
+		w, _ := NewTimingWheel(c, time.Millisecond, 4)
+		return w
+	},
+}
+
+func TestPoll(t *testing.T) {
+	var testCases = map[string]struct {
+		delays  []time.Duration
+		advance time.Duration
+		polled  []containers.Value
+	}{
+		"empty": {
+			advance: time.Second,
+		},
+		"notReady": {
+			delays:  []time.Duration{time.Second},
+			advance: time.Second - time.Millisecond,
+		},
+		"readyAtDeadline": {
+			delays:  []time.Duration{time.Second},
+			advance: time.Second,
+			polled:  []containers.Value{0},
+		},
+		"alreadyPassed": {
+			delays: []time.Duration{-time.Second, 0},
+			polled: []containers.Value{0, 1},
+		},
+		"deadlineOrder": {
+			delays:  []time.Duration{3 * time.Second, time.Second, time.Hour, 2 * time.Second},
+			advance: 3 * time.Second,
+			polled:  []containers.Value{1, 3, 0},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for impl, newQueue := range implementations {
+				c := clock.NewFake(epoch)
+				q := newQueue(c)
+
+				for i, d := range tc.delays {
+					q.Enqueue(i, epoch.Add(d))
+				}
+				c.Advance(tc.advance)
+
+				var polled []containers.Value
+				for {
+					val, err := q.Poll()
+					if err != nil {
+						break
+					}
+					polled = append(polled, val)
+				}
+
+				if want, got := tc.polled, polled; !cmp.Equal(want, got) {
+					t.Fatalf("%s: want= %v, got= %v, diff= %v", impl, want, got, cmp.Diff(want, got))
+				}
+				if want, got := len(tc.delays)-len(tc.polled), q.Size(); want != got {
+					t.Fatalf("%s: size: want= %v, got= %v", impl, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTake(t *testing.T) {
+	for impl, newQueue := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c := clock.NewFake(epoch)
+			q := newQueue(c)
+			q.Enqueue("later", epoch.Add(time.Hour))
+
+			taken := make(chan containers.Value)
+			go func() {
+				val, err := q.Take(context.Background())
+				if err != nil {
+					t.Errorf("want no error, got %q", err)
+				}
+				taken <- val
+			}()
+
+			// an earlier item wakes up Take(), which waits for the new deadline
+			c.WaitForTimers(1)
+			q.Enqueue("sooner", epoch.Add(time.Minute))
+			c.Advance(time.Minute - time.Millisecond)
+			select {
+			case val := <-taken:
+				t.Fatalf("want Take() to block, got %v", val)
+			default:
+			}
+
+			c.WaitForTimers(1)
+			c.Advance(time.Millisecond)
+			if want, got := containers.Value("sooner"), <-taken; want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestTakeEmpty(t *testing.T) {
+	for impl, newQueue := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c := clock.NewFake(epoch)
+			q := newQueue(c)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error)
+			go func() {
+				_, err := q.Take(ctx)
+				errs <- err
+			}()
+			cancel()
+
+			if want, got := context.Canceled, <-errs; want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	for impl, newQueue := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			c := clock.NewFake(epoch)
+			q := newQueue(c)
+			q.Enqueue(1, epoch)
+			q.Enqueue(2, epoch.Add(time.Hour))
+			q.Clear()
+
+			if want, got := 0, q.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			c.Advance(time.Hour)
+			if val, err := q.Poll(); err == nil {
+				t.Fatalf("want error, got %v", val)
+			}
+		})
+	}
+}
+
+func TestSameDeadlineIsFIFO(t *testing.T) {
+	c := clock.NewFake(epoch)
+	q := New(c)
+	for i := 0; i < 10; i++ {
+		q.Enqueue(i, epoch.Add(time.Second))
+	}
+	c.Advance(time.Second)
+
+	for i := 0; i < 10; i++ {
+		if val, err := q.Poll(); err != nil || val != i {
+			t.Fatalf("want= %v, got= %v, err= %v", i, val, err)
+		}
+	}
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	for impl, newQueue := range implementations {
+		b.Run(impl, func(b *testing.B) {
+			q := newQueue(clock.NewFake(epoch))
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(i, epoch.Add(time.Duration(i%100000)*time.Millisecond))
+			}
+		})
+	}
+}
+
+func BenchmarkEnqueuePoll(b *testing.B) {
+	const timers = 1 << 20
+
+	for impl, newQueue := range implementations {
+		b.Run(impl, func(b *testing.B) {
+			c := clock.NewFake(epoch)
+			q := newQueue(c)
+			for i := 0; i < timers; i++ {
+				q.Enqueue(i, epoch.Add(time.Duration(i)*time.Millisecond))
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(i, c.Now().Add(timers*time.Millisecond))
+				c.Advance(time.Millisecond)
+				q.Poll()
+			}
+		})
+	}
+}
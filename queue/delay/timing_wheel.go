@@ -0,0 +1,269 @@
+package delay
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+	"github.com/bitsgofer/containers/queue"
+)
+
+// timingWheel is a concrete implementation of Queueable on a hierarchical timing wheel,
+// with O(1) Enqueue() regardless of the number of pending items.
+//
+// Time is counted in ticks since the wheel was created, and deadlines are rounded up to a tick.
+// Level i of the wheel has wheelSize slots of wheelSize^i ticks each. An item goes into the lowest level
+// which spans its deadline, and is moved to lower levels (cascaded) as the current tick gets closer to it.
+// Items becoming ready in the same tick are returned in no particular order.
+// It is safe for concurrent use.
+type timingWheel struct {
+	clock  clock.Clock
+	tick   time.Duration
+	size   int64
+	origin time.Time
+
+	mu       sync.Mutex
+	levels   [][][]*entry // levels[i][slot] holds entries
+	spans    []int64      // spans[i] is the number of ticks in a slot of level i
+	counts   []int        // counts[i] is the number of entries in level i
+	pending  int          // number of entries in all levels
+	cur      int64        // entries with deadlines up to this tick are ready
+	ready    queue.Queueable
+	notifier notifier
+}
+
+// NewTimingWheel returns Queueable on a hierarchical timing wheel, using c to tell whether items are ready.
+// Deadlines are rounded up to a multiple of tick, and each level of the wheel has wheelSize slots.
+func NewTimingWheel(c clock.Clock, tick time.Duration, wheelSize int) (*timingWheel, error) {
+	if tick <= 0 {
+		return nil, errors.Errorf("tick must be positive, got %v", tick)
+	}
+	if wheelSize < 2 {
+		return nil, errors.Errorf("wheel size must be at least 2, got %d", wheelSize)
+	}
+
+	return &timingWheel{
+		clock:    c,
+		tick:     tick,
+		size:     int64(wheelSize),
+		origin:   c.Now(),
+		ready:    queue.New(),
+		notifier: newNotifier(),
+	}, nil
+}
+
+// Enqueue adds a new containers.Value, which can be dequeued from readyAt.
+func (w *timingWheel) Enqueue(item containers.Value, readyAt time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.advance(w.ticksSince(w.clock.Now()))
+	w.add(&entry{value: item, readyAt: readyAt, tick: w.deadlineTick(readyAt)})
+	w.notifier.notify()
+}
+
+// Size returns the current number of elements in the queue, ready or not.
+func (w *timingWheel) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.pending + w.ready.Size()
+}
+
+// Clear empties the whole queue.
+func (w *timingWheel) Clear() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.levels, w.spans, w.counts = nil, nil, nil
+	w.pending = 0
+	w.ready.Clear()
+	w.notifier.notify()
+}
+
+// Poll removes a ready containers.Value and returns it.
+// It returns an error if no item is ready.
+func (w *timingWheel) Poll() (containers.Value, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.advance(w.ticksSince(w.clock.Now()))
+	if w.ready.Size() == 0 {
+		return nil, errors.New(noItemIsReady)
+	}
+
+	return w.ready.Dequeue()
+}
+
+// Take removes a ready containers.Value and returns it, waiting for one to be ready if needed.
+// It returns ctx.Err() if ctx is done first.
+func (w *timingWheel) Take(ctx context.Context) (containers.Value, error) {
+	for {
+		w.mu.Lock()
+		now := w.clock.Now()
+		w.advance(w.ticksSince(now))
+		if w.ready.Size() > 0 {
+			val, err := w.ready.Dequeue()
+			w.mu.Unlock()
+			return val, err
+		}
+
+		wait := time.Duration(-1)
+		if w.pending > 0 {
+			wait = w.origin.Add(time.Duration(w.nextEvent()) * w.tick).Sub(now)
+		}
+		changed := w.notifier.wait()
+		w.mu.Unlock()
+
+		if err := waitFor(ctx, w.clock, wait, changed); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ticksSince returns the number of whole ticks between the wheel's creation and t.
+func (w *timingWheel) ticksSince(t time.Time) int64 {
+	d := t.Sub(w.origin)
+	if d < 0 {
+		return 0
+	}
+
+	return int64(d / w.tick)
+}
+
+// deadlineTick returns the first tick at which readyAt has passed.
+func (w *timingWheel) deadlineTick(readyAt time.Time) int64 {
+	d := readyAt.Sub(w.origin)
+	if d <= 0 {
+		return 0
+	}
+
+	ticks := int64(d / w.tick)
+	if d%w.tick != 0 {
+		ticks++
+	}
+	return ticks
+}
+
+// add puts e into the lowest level spanning its deadline, or the ready queue if it's due.
+func (w *timingWheel) add(e *entry) {
+	if e.tick <= w.cur {
+		w.ready.Enqueue(e.value)
+		return
+	}
+
+	d := e.tick - w.cur
+	for i := 0; ; i++ {
+		if i == len(w.levels) {
+			w.addLevel()
+		}
+
+		span := w.spans[i]
+		if d < span*w.size || span > math.MaxInt64/w.size {
+			slot := (e.tick / span) % w.size
+			w.levels[i][slot] = append(w.levels[i][slot], e)
+			w.counts[i]++
+			w.pending++
+			return
+		}
+	}
+}
+
+func (w *timingWheel) addLevel() {
+	span := int64(1)
+	if n := len(w.spans); n > 0 {
+		span = w.spans[n-1] * w.size
+	}
+
+	w.levels = append(w.levels, make([][]*entry, w.size))
+	w.spans = append(w.spans, span)
+	w.counts = append(w.counts, 0)
+}
+
+// advance moves the current tick to t, cascading entries down and moving due ones to the ready queue.
+func (w *timingWheel) advance(t int64) {
+	for w.cur < t {
+		if w.pending == 0 {
+			w.cur = t
+			return
+		}
+
+		// skip ticks in which nothing happens: if levels below L are empty,
+		// nothing happens until level L cascades at the next multiple of its span.
+		if lowest := w.lowestLevel(); lowest > 0 {
+			span := w.spans[lowest]
+			if next := (w.cur/span+1)*span - 1; next > w.cur {
+				if next >= t {
+					w.cur = t
+					return
+				}
+				w.cur = next
+			}
+		}
+
+		w.cur++
+		top := 0
+		for i := 1; i < len(w.levels) && w.cur%w.spans[i] == 0; i++ {
+			top = i
+		}
+		for i := top; i >= 1; i-- {
+			w.cascade(i, (w.cur/w.spans[i])%w.size)
+		}
+
+		slot := w.cur % w.size
+		for _, e := range w.levels[0][slot] {
+			w.ready.Enqueue(e.value)
+		}
+		w.counts[0] -= len(w.levels[0][slot])
+		w.pending -= len(w.levels[0][slot])
+		w.levels[0][slot] = nil
+	}
+}
+
+// cascade moves entries of a slot in level i to lower levels.
+func (w *timingWheel) cascade(i int, slot int64) {
+	entries := w.levels[i][slot]
+	w.levels[i][slot] = nil
+	w.counts[i] -= len(entries)
+	w.pending -= len(entries)
+
+	for _, e := range entries {
+		w.add(e)
+	}
+}
+
+// lowestLevel returns the lowest level holding entries, which must exist.
+func (w *timingWheel) lowestLevel() int {
+	for i, n := range w.counts {
+		if n > 0 {
+			return i
+		}
+	}
+
+	panic("timing wheel has no pending entries")
+}
+
+// nextEvent returns the next tick at which an entry becomes ready or is cascaded.
+func (w *timingWheel) nextEvent() int64 {
+	next := int64(math.MaxInt64)
+	for i := len(w.levels) - 1; i >= 1; i-- {
+		if w.counts[i] > 0 {
+			next = (w.cur/w.spans[i] + 1) * w.spans[i]
+		}
+	}
+	if w.counts[0] == 0 {
+		return next
+	}
+
+	for t := w.cur + 1; t < next; t++ {
+		if len(w.levels[0][t%w.size]) > 0 {
+			return t
+		}
+	}
+	return next
+}
@@ -0,0 +1,175 @@
+package delay
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/clock"
+)
+
+func TestNewTimingWheel(t *testing.T) {
+	var testCases = map[string]struct {
+		tick      time.Duration
+		wheelSize int
+		isErr     bool
+	}{
+		"valid": {
+			tick:      time.Millisecond,
+			wheelSize: 64,
+		},
+		"zeroTick": {
+			tick:      0,
+			wheelSize: 64,
+			isErr:     true,
+		},
+		"wheelTooSmall": {
+			tick:      time.Millisecond,
+			wheelSize: 1,
+			isErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTimingWheel(clock.NewFake(epoch), tc.tick, tc.wheelSize)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+// TestTimingWheelCascade checks that items in all levels become ready exactly at the tick of their deadline.
+func TestTimingWheelCascade(t *testing.T) {
+	var testCases = map[string]struct {
+		wheelSize int
+		items     int
+		maxTicks  int
+		step      int // ticks to advance at a time
+	}{
+		"oneLevel": {
+			wheelSize: 8,
+			items:     100,
+			maxTicks:  8,
+			step:      1,
+		},
+		"manyLevels": {
+			wheelSize: 4,
+			items:     1000,
+			maxTicks:  1000,
+			step:      1,
+		},
+		"manyLevelsBigSteps": {
+			wheelSize: 4,
+			items:     1000,
+			maxTicks:  1000,
+			step:      7,
+		},
+		"binaryWheel": {
+			wheelSize: 2,
+			items:     1000,
+			maxTicks:  5000,
+			step:      3,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(42))
+			c := clock.NewFake(epoch)
+			w, err := NewTimingWheel(c, time.Second, tc.wheelSize)
+			if err != nil {
+				t.Fatalf("need a valid timing wheel to test, got %q", err)
+			}
+
+			readyAt := map[int][]containers.Value{} // tick => items
+			for i := 0; i < tc.items; i++ {
+				d := time.Duration(rng.Int63n(int64(tc.maxTicks) * int64(time.Second)))
+				tick := int((d + time.Second - 1) / time.Second)
+				readyAt[tick] = append(readyAt[tick], i)
+				w.Enqueue(i, epoch.Add(d))
+			}
+
+			for tick := 0; tick <= tc.maxTicks+tc.step; tick += tc.step {
+				var want []containers.Value
+				for i := tick - tc.step + 1; i <= tick; i++ {
+					want = append(want, readyAt[i]...)
+				}
+
+				var got []containers.Value
+				for {
+					val, err := w.Poll()
+					if err != nil {
+						break
+					}
+					got = append(got, val)
+				}
+				if !cmp.Equal(sortedInts(want), sortedInts(got)) {
+					t.Fatalf("tick %d: want= %v, got= %v", tick, sortedInts(want), sortedInts(got))
+				}
+
+				c.Advance(time.Duration(tc.step) * time.Second)
+			}
+
+			if want, got := 0, w.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestTimingWheelTakeSleepsUntilCascade checks that Take() wakes up for items in higher levels.
+func TestTimingWheelTakeSleepsUntilCascade(t *testing.T) {
+	c := clock.NewFake(epoch)
+	w, _ := NewTimingWheel(c, time.Second, 2)
+	w.Enqueue("far", epoch.Add(100*time.Second))
+
+	done := make(chan struct{})
+	go func() {
+		val, err := w.Take(context.Background())
+		if err != nil || val != "far" {
+			t.Errorf("want far, got= %v, err= %v", val, err)
+		}
+		close(done)
+	}()
+
+	var elapsed time.Duration
+	for elapsed < 100*time.Second {
+		select {
+		case <-done:
+			t.Fatalf("want Take() to block until 100s, returned after %v", elapsed)
+		default:
+		}
+
+		c.WaitForTimers(1)
+		next := c.NextTimer()
+		c.Advance(next)
+		elapsed += next
+	}
+
+	<-done
+	if want, got := 100*time.Second, elapsed; want != got {
+		t.Fatalf("want Take() to return after %v, got %v", want, got)
+	}
+}
+
+// sortedInts returns vals as sorted ints, for comparing items ready in the same tick.
+func sortedInts(vals []containers.Value) []int {
+	ints := make([]int, 0, len(vals))
+	for _, v := range vals {
+		ints = append(ints, v.(int))
+	}
+	sort.Ints(ints)
+
+	return ints
+}
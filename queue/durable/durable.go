@@ -0,0 +1,518 @@
+// Package durable provides a disk-backed queue that survives process restarts.
+//
+// Enqueued values are appended to segment files (a write-ahead log) through a codec.Codec.
+// Dequeued values are only forgotten once they are acknowledged with Ack(),
+// so values that were dequeued but not acknowledged before a crash are delivered again.
+package durable
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+)
+
+const (
+	offsetFileName = "consumer.offset"
+	offsetFileSize = 12 // sequence number (8 bytes) | CRC-32C (4 bytes)
+)
+
+const queueIsEmpty = "queue is empty"
+
+// Options tunes a durable queue.
+type Options struct {
+	// MaxSegmentSize is the size in bytes after which a new segment file is started.
+	MaxSegmentSize int64
+	// SyncOnEnqueue makes each Enqueue() wait until the value is flushed to disk.
+	SyncOnEnqueue bool
+}
+
+// DefaultOptions are used by Open() for zero fields in Options.
+var DefaultOptions = Options{
+	MaxSegmentSize: 64 << 20,
+}
+
+// durableQueue is a concrete implementation of queue.Queueable on segment files.
+// Each value gets a sequence number, which increases by 1 for every enqueued value:
+//
+//	 acked            dequeued, not acked        not dequeued
+//	[firstSeq, ackSeq) [ackSeq, readSeq)          [readSeq, nextSeq)
+type durableQueue struct {
+	dir   string
+	codec codec.Codec
+	opts  Options
+
+	segments []*segment // ordered by firstSeq, the last one is being written to
+	w        *os.File   // last segment
+
+	nextSeq uint64
+	readSeq uint64
+	ackSeq  uint64
+
+	r        *os.File // segments[rSeg]
+	rSeg     int
+	rPos     int64
+	peeked   containers.Value
+	peekSize int64 // size of the peeked record, 0 if nothing was peeked
+
+	newest containers.Value // last enqueued value, only valid if the queue is not empty
+
+	err error // first error from Enqueue() or Clear(), which do not return errors
+}
+
+// Open returns a durable queue.Queueable storing its data in dir, creating dir if needed.
+// If dir holds data from a previous run, the queue recovers it,
+// dropping records that were only partially written before a crash.
+func Open(dir string, c codec.Codec, opts Options) (*durableQueue, error) {
+	if opts.MaxSegmentSize <= 0 {
+		opts.MaxSegmentSize = DefaultOptions.MaxSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "cannot create %s", dir)
+	}
+
+	q := &durableQueue{
+		dir:   dir,
+		codec: c,
+		opts:  opts,
+	}
+	if err := q.recover(); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// recover loads segments and the consumer offset from disk.
+func (q *durableQueue) recover() error {
+	ackSeq, err := readOffset(q.dir)
+	if err != nil {
+		return err
+	}
+
+	segments, err := listSegments(q.dir)
+	if err != nil {
+		return err
+	}
+	for i, seg := range segments {
+		isLast := i == len(segments)-1
+		if err := recoverSegment(seg, isLast); err != nil {
+			return err
+		}
+		if !isLast && seg.firstSeq+seg.n != segments[i+1].firstSeq {
+			return errors.Errorf("segment %s is corrupted: want %d records, got %d", seg.path, segments[i+1].firstSeq-seg.firstSeq, seg.n)
+		}
+	}
+	if len(segments) == 0 {
+		if err := q.createSegment(ackSeq); err != nil {
+			return err
+		}
+		segments = q.segments
+	}
+	q.segments = segments
+
+	first, last := segments[0], segments[len(segments)-1]
+	q.nextSeq = last.firstSeq + last.n
+
+	// the offset might point outside of the log if we lost its tail.
+	// Persist the corrected offset, so new values are not mistaken as acknowledged after another restart.
+	switch {
+	case ackSeq < first.firstSeq:
+		ackSeq = first.firstSeq
+	case ackSeq > q.nextSeq:
+		ackSeq = q.nextSeq
+		if err := writeOffset(q.dir, ackSeq); err != nil {
+			return err
+		}
+	}
+	q.ackSeq, q.readSeq = ackSeq, ackSeq
+
+	if q.w == nil {
+		if q.w, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0); err != nil {
+			return errors.Wrapf(err, "cannot open segment %s", last.path)
+		}
+	}
+	if err := q.seekReader(); err != nil {
+		return err
+	}
+	if q.Size() > 0 {
+		return q.loadNewest()
+	}
+
+	return nil
+}
+
+// seekReader positions the reader at readSeq.
+func (q *durableQueue) seekReader() error {
+	q.rSeg = len(q.segments) - 1
+	for i, seg := range q.segments {
+		if q.readSeq < seg.firstSeq+seg.n {
+			q.rSeg = i
+			break
+		}
+	}
+	if err := q.openReader(); err != nil {
+		return err
+	}
+
+	seg := q.segments[q.rSeg]
+	pos, err := skipRecords(q.r, q.readSeq-seg.firstSeq, seg.size)
+	if err != nil {
+		return errors.Wrapf(err, "cannot find record %d in segment %s", q.readSeq, seg.path)
+	}
+	q.rPos = pos
+
+	return nil
+}
+
+func (q *durableQueue) openReader() error {
+	if q.r != nil {
+		q.r.Close()
+	}
+
+	path := q.segments[q.rSeg].path
+	r, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open segment %s", path)
+	}
+	q.r, q.rPos = r, 0
+
+	return nil
+}
+
+// loadNewest reads the last enqueued value from disk.
+func (q *durableQueue) loadNewest() error {
+	for i := len(q.segments) - 1; i >= 0; i-- {
+		seg := q.segments[i]
+		if seg.n == 0 {
+			continue
+		}
+
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return errors.Wrapf(err, "cannot open segment %s", seg.path)
+		}
+		defer f.Close()
+
+		payload, _, err := readRecordAt(f, seg.lastOffset, seg.size)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read last record of segment %s", seg.path)
+		}
+		q.newest, err = q.codec.Decode(payload)
+		return err
+	}
+
+	return nil
+}
+
+// createSegment starts a new segment file for values from firstSeq, and writes to it from now on.
+// The previous segment is synced first: only the last segment can be torn by a crash, and truncated on recovery.
+func (q *durableQueue) createSegment(firstSeq uint64) error {
+	if q.w != nil {
+		if err := q.w.Sync(); err != nil {
+			return errors.Wrap(err, "cannot sync segment")
+		}
+	}
+
+	path := segmentPath(q.dir, firstSeq)
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "cannot create segment %s", path)
+	}
+	if err := syncDir(q.dir); err != nil {
+		w.Close()
+		return err
+	}
+
+	if q.w != nil {
+		if err := q.w.Close(); err != nil {
+			w.Close()
+			return errors.Wrap(err, "cannot close segment")
+		}
+	}
+	q.w = w
+	q.segments = append(q.segments, &segment{firstSeq: firstSeq, path: path})
+
+	return nil
+}
+
+// Enqueue adds a new containers.Value at the queue's back.
+// If it cannot be written, the queue stops working and Err() returns the reason.
+func (q *durableQueue) Enqueue(item containers.Value) {
+	if q.err != nil {
+		return
+	}
+
+	q.err = q.append(item)
+}
+
+func (q *durableQueue) append(item containers.Value) error {
+	payload, err := q.codec.Encode(item)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode value")
+	}
+
+	record := encodeRecord(payload)
+	if _, err := q.w.Write(record); err != nil {
+		return errors.Wrap(err, "cannot write record")
+	}
+	if q.opts.SyncOnEnqueue {
+		if err := q.w.Sync(); err != nil {
+			return errors.Wrap(err, "cannot sync segment")
+		}
+	}
+
+	seg := q.segments[len(q.segments)-1]
+	seg.lastOffset = seg.size
+	seg.size += int64(len(record))
+	seg.n++
+	q.nextSeq++
+	q.newest = item
+
+	if seg.size >= q.opts.MaxSegmentSize {
+		return q.createSegment(q.nextSeq)
+	}
+
+	return nil
+}
+
+// Size returns the current number of elements in the queue, i.e. not dequeued yet.
+func (q *durableQueue) Size() int {
+	return int(q.nextSeq - q.readSeq)
+}
+
+// Clear empties the whole queue, including values that were dequeued but not acknowledged.
+// If it fails, the queue stops working and Err() returns the reason.
+func (q *durableQueue) Clear() {
+	if q.err != nil {
+		return
+	}
+
+	q.err = q.clear()
+}
+
+func (q *durableQueue) clear() error {
+	old := q.segments
+	q.segments = nil
+	if err := q.createSegment(q.nextSeq); err != nil {
+		return err
+	}
+	q.readSeq, q.ackSeq = q.nextSeq, q.nextSeq
+	q.peekSize, q.peeked, q.newest = 0, nil, nil
+	if err := writeOffset(q.dir, q.ackSeq); err != nil {
+		return err
+	}
+
+	q.rSeg = 0
+	if err := q.openReader(); err != nil {
+		return err
+	}
+	for _, seg := range old {
+		if seg.path == q.segments[0].path { // an empty last segment was recreated in place
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return errors.Wrapf(err, "cannot remove segment %s", seg.path)
+		}
+	}
+
+	return nil
+}
+
+// Front returns the containers.Value at the queue's front.
+func (q *durableQueue) Front() (containers.Value, error) {
+	if err := q.peek(); err != nil {
+		return nil, err
+	}
+
+	return q.peeked, nil
+}
+
+// Back returns the containers.Value at the queue's back.
+func (q *durableQueue) Back() (containers.Value, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.Size() == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	return q.newest, nil
+}
+
+// Dequeue removes the containers.Value at the queue's front and returns it.
+// The value is delivered again after a restart unless Ack() is called.
+func (q *durableQueue) Dequeue() (containers.Value, error) {
+	if err := q.peek(); err != nil {
+		return nil, err
+	}
+
+	front := q.peeked
+	q.rPos += q.peekSize
+	q.readSeq++
+	q.peeked, q.peekSize = nil, 0
+	if q.Size() == 0 {
+		q.newest = nil
+	}
+
+	return front, nil
+}
+
+// peek reads the value at readSeq.
+func (q *durableQueue) peek() error {
+	if q.err != nil {
+		return q.err
+	}
+	if q.Size() == 0 {
+		return errors.New(queueIsEmpty)
+	}
+	if q.peekSize > 0 {
+		return nil
+	}
+
+	for q.rPos >= q.segments[q.rSeg].size {
+		q.rSeg++
+		if err := q.openReader(); err != nil {
+			return err
+		}
+	}
+
+	seg := q.segments[q.rSeg]
+	payload, size, err := readRecordAt(q.r, q.rPos, seg.size)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read record %d in segment %s", q.readSeq, seg.path)
+	}
+	val, err := q.codec.Decode(payload)
+	if err != nil {
+		return errors.Wrapf(err, "cannot decode record %d", q.readSeq)
+	}
+
+	q.peeked, q.peekSize = val, size
+	return nil
+}
+
+// Ack acknowledges all dequeued values, so they are not delivered again after a restart.
+// Segments whose values are all acknowledged are removed.
+func (q *durableQueue) Ack() error {
+	if q.err != nil {
+		return q.err
+	}
+	if q.ackSeq == q.readSeq {
+		return nil
+	}
+
+	if err := writeOffset(q.dir, q.readSeq); err != nil {
+		return err
+	}
+	q.ackSeq = q.readSeq
+
+	return q.compact()
+}
+
+// compact removes segments whose values are all acknowledged, except the one being written to.
+func (q *durableQueue) compact() error {
+	n := 0
+	for n < len(q.segments)-1 && q.segments[n+1].firstSeq <= q.ackSeq {
+		if err := os.Remove(q.segments[n].path); err != nil {
+			return errors.Wrapf(err, "cannot remove segment %s", q.segments[n].path)
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	q.segments = q.segments[n:]
+	if q.rSeg < n { // reader was at the end of a removed segment
+		q.rSeg = 0
+		return q.openReader()
+	}
+	q.rSeg -= n
+
+	return nil
+}
+
+// Err returns the error which stopped the queue from working, if any.
+func (q *durableQueue) Err() error {
+	return q.err
+}
+
+// Close releases the files used by the queue.
+func (q *durableQueue) Close() error {
+	var err error
+	if q.w != nil {
+		err = q.w.Close()
+		q.w = nil
+	}
+	if q.r != nil {
+		q.r.Close()
+		q.r = nil
+	}
+
+	return errors.Wrap(err, "cannot close segment")
+}
+
+// readOffset returns the acknowledged sequence number stored in dir, or 0 if there is none.
+func readOffset(dir string) (uint64, error) {
+	path := filepath.Join(dir, offsetFileName)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot read %s", path)
+	}
+
+	if len(data) != offsetFileSize || crc32.Checksum(data[:8], crcTable) != binary.BigEndian.Uint32(data[8:]) {
+		return 0, errors.Errorf("%s is corrupted", path)
+	}
+	return binary.BigEndian.Uint64(data[:8]), nil
+}
+
+// writeOffset atomically replaces the acknowledged sequence number stored in dir.
+func writeOffset(dir string, seq uint64) error {
+	var data [offsetFileSize]byte
+	binary.BigEndian.PutUint64(data[:8], seq)
+	binary.BigEndian.PutUint32(data[8:], crc32.Checksum(data[:8], crcTable))
+
+	path := filepath.Join(dir, offsetFileName)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "cannot create %s", tmp)
+	}
+	if _, err := f.Write(data[:]); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "cannot write %s", tmp)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "cannot sync %s", tmp)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "cannot close %s", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrapf(err, "cannot rename %s", tmp)
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir flushes changes to dir's entries (i.e. created, renamed files) to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open %s", dir)
+	}
+	defer d.Close()
+
+	return errors.Wrapf(d.Sync(), "cannot sync %s", dir)
+}
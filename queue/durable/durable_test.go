@@ -0,0 +1,394 @@
+package durable
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+	"github.com/bitsgofer/containers/queue"
+)
+
+var _ queue.Queueable = (*durableQueue)(nil)
+
+func TestQueueOps(t *testing.T) {
+	var testCases = map[string]struct {
+		enqueued []containers.Value
+		dequeue  int
+		dequeued []containers.Value
+		front    containers.Value
+		back     containers.Value
+		isErr    bool
+	}{
+		"empty": {
+			isErr: true,
+		},
+		"emptyAfterDequeue": {
+			enqueued: []containers.Value{"a", "b"},
+			dequeue:  2,
+			dequeued: []containers.Value{"a", "b"},
+			isErr:    true,
+		},
+		"filled": {
+			enqueued: []containers.Value{"a", "b", "c"},
+			front:    "a",
+			back:     "c",
+		},
+		"dequeuedAcrossSegments": {
+			enqueued: []containers.Value{"a", "b", "c", "d", "e", "f"},
+			dequeue:  4,
+			dequeued: []containers.Value{"a", "b", "c", "d"},
+			front:    "e",
+			back:     "f",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+
+			q, err := Open(dir, codec.String{}, Options{MaxSegmentSize: 20})
+			if err != nil {
+				t.Fatalf("cannot open queue: %v", err)
+			}
+			defer q.Close()
+
+			for _, v := range tc.enqueued {
+				q.Enqueue(v)
+			}
+			if want, got := len(tc.enqueued), q.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			if want, got := tc.dequeued, dequeueN(t, q, tc.dequeue); !cmp.Equal(want, got) {
+				t.Fatalf("dequeued: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+
+			front, frontErr := q.Front()
+			back, backErr := q.Back()
+			switch {
+			case tc.isErr && (frontErr == nil || backErr == nil):
+				t.Fatalf("want errors, got front= %v, back= %v", frontErr, backErr)
+			case !tc.isErr && (frontErr != nil || backErr != nil):
+				t.Fatalf("want no errors, got front= %v, back= %v", frontErr, backErr)
+			case !tc.isErr:
+				if !cmp.Equal(tc.front, front) || !cmp.Equal(tc.back, back) {
+					t.Fatalf("want front= %v, back= %v, got front= %v, back= %v", tc.front, tc.back, front, back)
+				}
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	var testCases = map[string]struct {
+		enqueued  []containers.Value
+		dequeue   int
+		ack       bool
+		recovered []containers.Value
+	}{
+		"nothingDequeued": {
+			enqueued:  []containers.Value{"a", "b", "c"},
+			recovered: []containers.Value{"a", "b", "c"},
+		},
+		"dequeuedWithoutAck": {
+			enqueued:  []containers.Value{"a", "b", "c"},
+			dequeue:   2,
+			recovered: []containers.Value{"a", "b", "c"},
+		},
+		"dequeuedWithAck": {
+			enqueued:  []containers.Value{"a", "b", "c"},
+			dequeue:   2,
+			ack:       true,
+			recovered: []containers.Value{"c"},
+		},
+		"allAcked": {
+			enqueued: []containers.Value{"a", "b", "c"},
+			dequeue:  3,
+			ack:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+
+			q, err := Open(dir, codec.String{}, Options{MaxSegmentSize: 20})
+			if err != nil {
+				t.Fatalf("cannot open queue: %v", err)
+			}
+			for _, v := range tc.enqueued {
+				q.Enqueue(v)
+			}
+			dequeueN(t, q, tc.dequeue)
+			if tc.ack {
+				if err := q.Ack(); err != nil {
+					t.Fatalf("cannot ack: %v", err)
+				}
+			}
+			if err := q.Close(); err != nil {
+				t.Fatalf("cannot close queue: %v", err)
+			}
+
+			q, err = Open(dir, codec.String{}, Options{MaxSegmentSize: 20})
+			if err != nil {
+				t.Fatalf("cannot reopen queue: %v", err)
+			}
+			defer q.Close()
+
+			if want, got := tc.recovered, dequeueN(t, q, q.Size()); !cmp.Equal(want, got) {
+				t.Fatalf("recovered: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+
+			// continue using the queue after recovering
+			q.Enqueue("z")
+			if val, err := q.Dequeue(); err != nil || val != "z" {
+				t.Fatalf("want z, got= %v, err= %v", val, err)
+			}
+		})
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, codec.String{}, Options{MaxSegmentSize: 1}) // one record per segment
+	if err != nil {
+		t.Fatalf("cannot open queue: %v", err)
+	}
+	defer q.Close()
+
+	for _, v := range []string{"a", "b", "c", "d"} {
+		q.Enqueue(v)
+	}
+	if want, got := 5, countSegments(t, dir); want != got {
+		t.Fatalf("segments before ack: want= %v, got= %v", want, got)
+	}
+
+	dequeueN(t, q, 2)
+	if err := q.Ack(); err != nil {
+		t.Fatalf("cannot ack: %v", err)
+	}
+	if want, got := 3, countSegments(t, dir); want != got {
+		t.Fatalf("segments after ack: want= %v, got= %v", want, got)
+	}
+	if want, got := []containers.Value{"c", "d"}, dequeueN(t, q, 2); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+	}
+
+	if err := q.Ack(); err != nil {
+		t.Fatalf("cannot ack: %v", err)
+	}
+	if want, got := 1, countSegments(t, dir); want != got {
+		t.Fatalf("segments after acking all: want= %v, got= %v", want, got)
+	}
+}
+
+// TestClear checks that a cleared queue keeps what is enqueued afterwards,
+// including when its last segment was empty, and gets recreated by Clear.
+func TestClear(t *testing.T) {
+	var testCases = map[string]struct {
+		enqueued []string
+		dequeued int
+		clears   int
+	}{
+		"values":         {enqueued: []string{"a", "b", "c", "d", "e"}, dequeued: 1, clears: 1},
+		"fresh":          {clears: 1},
+		"twice":          {enqueued: []string{"a", "b"}, clears: 2},
+		"justRolledOver": {enqueued: []string{"a value longer than a segment"}, clears: 1},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+
+			q, err := Open(dir, codec.String{}, Options{MaxSegmentSize: 20})
+			if err != nil {
+				t.Fatalf("cannot open queue: %v", err)
+			}
+			for _, v := range tc.enqueued {
+				q.Enqueue(v)
+			}
+			dequeueN(t, q, tc.dequeued)
+			for i := 0; i < tc.clears; i++ {
+				q.Clear()
+			}
+			if err := q.Err(); err != nil {
+				t.Fatalf("cannot clear: %v", err)
+			}
+			if want, got := 0, q.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			if want, got := 1, countSegments(t, dir); want != got {
+				t.Fatalf("segments: want= %v, got= %v", want, got)
+			}
+
+			q.Enqueue("f")
+			q.Close()
+
+			q, err = Open(dir, codec.String{}, Options{MaxSegmentSize: 20})
+			if err != nil {
+				t.Fatalf("cannot reopen queue: %v", err)
+			}
+			defer q.Close()
+			if want, got := []containers.Value{"f"}, dequeueN(t, q, q.Size()); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, codec.String{}, Options{})
+	if err != nil {
+		t.Fatalf("cannot open queue: %v", err)
+	}
+	defer q.Close()
+
+	q.Enqueue(42)
+	if q.Err() == nil {
+		t.Fatalf("want error, got none")
+	}
+	if _, err := q.Dequeue(); err == nil {
+		t.Fatalf("want dequeue to fail after an error, got none")
+	}
+}
+
+// TestCrashRecovery simulates crashes by truncating the last segment at random offsets.
+// The recovered queue must hold a prefix of what was enqueued, and keep working.
+// Other segments are synced before the next one is created, so a crash cannot tear them:
+// if their tail is corrupted anyway, recovery must fail rather than drop the records after it.
+func TestCrashRecovery(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	for round := 0; round < 50; round++ {
+		dir := newTempDir(t)
+
+		q, err := Open(dir, codec.Gob{}, Options{MaxSegmentSize: 512})
+		if err != nil {
+			t.Fatalf("cannot open queue: %v", err)
+		}
+		var enqueued []containers.Value
+		for i := rng.Intn(100) + 1; i > 0; i-- {
+			v := fmt.Sprintf("item-%d", rng.Int())
+			q.Enqueue(v)
+			enqueued = append(enqueued, v)
+		}
+		acked := rng.Intn(len(enqueued) + 1)
+		dequeueN(t, q, acked)
+		if err := q.Ack(); err != nil {
+			t.Fatalf("cannot ack: %v", err)
+		}
+		q.Close()
+
+		if len(q.segments) > 1 && round%2 == 0 {
+			seg := q.segments[rng.Intn(len(q.segments)-1)]
+			if err := os.Truncate(seg.path, seg.size-1); err != nil {
+				t.Fatalf("cannot truncate: %v", err)
+			}
+			if _, err := Open(dir, codec.Gob{}, Options{MaxSegmentSize: 512}); err == nil {
+				t.Fatalf("round %d: want error after corrupting segment %s, got none", round, seg.path)
+			}
+			if info, err := os.Stat(seg.path); err != nil || info.Size() != seg.size-1 {
+				t.Fatalf("round %d: want corrupted segment left as is, got= %v, %v", round, info, err)
+			}
+			os.RemoveAll(dir)
+			continue
+		}
+
+		last := q.segments[len(q.segments)-1]
+		committed := int(last.firstSeq) // records in other segments are never truncated
+		truncateAt := rng.Int63n(last.size + 1)
+		if err := os.Truncate(last.path, truncateAt); err != nil {
+			t.Fatalf("cannot truncate: %v", err)
+		}
+
+		q, err = Open(dir, codec.Gob{}, Options{MaxSegmentSize: 512})
+		if err != nil {
+			t.Fatalf("round %d: cannot recover queue after truncating at %d: %v", round, truncateAt, err)
+		}
+		recovered := dequeueN(t, q, q.Size())
+		if committed > acked && len(recovered) < committed-acked {
+			t.Fatalf("round %d: lost records in committed segments: want at least %d, got %d", round, committed-acked, len(recovered))
+		}
+		if want, got := enqueued[acked:acked+len(recovered)], recovered; len(got) > 0 && !cmp.Equal(want, got) {
+			t.Fatalf("round %d: want a prefix of the queue= %v, got= %v", round, want, got)
+		}
+
+		q.Enqueue("after crash")
+		q.Close()
+		q, err = Open(dir, codec.Gob{}, Options{MaxSegmentSize: 512})
+		if err != nil {
+			t.Fatalf("round %d: cannot reopen queue: %v", round, err)
+		}
+		if want, got := append(recovered, "after crash"), dequeueN(t, q, q.Size()); !cmp.Equal(want, got) {
+			t.Fatalf("round %d: want= %v, got= %v, diff= %v", round, want, got, cmp.Diff(want, got))
+		}
+		q.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "durable-queue")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+
+	return dir
+}
+
+func dequeueN(t *testing.T, q *durableQueue, n int) []containers.Value {
+	var vals []containers.Value
+	for i := 0; i < n; i++ {
+		val, err := q.Dequeue()
+		if err != nil {
+			t.Fatalf("cannot dequeue: %v", err)
+		}
+		vals = append(vals, val)
+	}
+
+	return vals
+}
+
+func countSegments(t *testing.T, dir string) int {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatalf("cannot list segments: %v", err)
+	}
+
+	return len(matches)
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	dir, err := ioutil.TempDir("", "durable-queue")
+	if err != nil {
+		b.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, codec.String{}, Options{})
+	if err != nil {
+		b.Fatalf("cannot open queue: %v", err)
+	}
+	defer q.Close()
+
+	for i := 0; i < b.N; i++ {
+		q.Enqueue("this is a string")
+	}
+}
@@ -0,0 +1,165 @@
+package durable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A record on disk is: payload length (4 bytes) | CRC-32C of payload (4 bytes) | payload.
+const recordHeaderSize = 8
+
+const segmentExt = ".seg"
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// errCorruptRecord means a record is truncated or does not match its checksum.
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// encodeRecord returns the on-disk representation of payload.
+func encodeRecord(payload []byte) []byte {
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[recordHeaderSize:], payload)
+
+	return buf
+}
+
+// readRecordAt reads the record at offset off of a file whose size is limit.
+// It returns the payload and the size of the whole record,
+// io.EOF if there is no record at off and errCorruptRecord if the record is not valid.
+func readRecordAt(r io.ReaderAt, off, limit int64) ([]byte, int64, error) {
+	if off >= limit {
+		return nil, 0, io.EOF
+	}
+	if limit-off < recordHeaderSize {
+		return nil, 0, errCorruptRecord
+	}
+
+	var header [recordHeaderSize]byte
+	if _, err := r.ReadAt(header[:], off); err != nil {
+		return nil, 0, errors.Wrap(err, "cannot read record header")
+	}
+	n := int64(binary.BigEndian.Uint32(header[0:4]))
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if limit-off-recordHeaderSize < n {
+		return nil, 0, errCorruptRecord
+	}
+
+	payload := make([]byte, n)
+	if _, err := r.ReadAt(payload, off+recordHeaderSize); err != nil {
+		return nil, 0, errors.Wrap(err, "cannot read record payload")
+	}
+	if crc32.Checksum(payload, crcTable) != sum {
+		return nil, 0, errCorruptRecord
+	}
+
+	return payload, recordHeaderSize + n, nil
+}
+
+// segment is a log file holding consecutive records, starting with sequence number firstSeq.
+type segment struct {
+	firstSeq   uint64
+	path       string
+	size       int64  // bytes of valid records
+	n          uint64 // number of records
+	lastOffset int64  // offset of the last record, only valid if n > 0
+}
+
+func segmentPath(dir string, firstSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", firstSeq, segmentExt))
+}
+
+// listSegments returns segments in dir, ordered by their first sequence number.
+// Their sizes are not known until they are recovered.
+func listSegments(dir string) ([]*segment, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot list %s", dir)
+	}
+
+	var segments []*segment
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unexpected segment name %s", name)
+		}
+		segments = append(segments, &segment{
+			firstSeq: firstSeq,
+			path:     filepath.Join(dir, name),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].firstSeq < segments[j].firstSeq
+	})
+
+	return segments, nil
+}
+
+// recoverSegment validates all records of seg, counting them.
+// If a record is corrupt and truncate is true, the segment is truncated right before it
+// (i.e. we lost the tail of the log during a crash). Otherwise, an error is returned.
+func recoverSegment(seg *segment, truncate bool) error {
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open segment %s", seg.path)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "cannot stat segment %s", seg.path)
+	}
+	limit := info.Size()
+
+	seg.size, seg.n = 0, 0
+	for {
+		_, size, err := readRecordAt(f, seg.size, limit)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err == errCorruptRecord && truncate:
+			if err := f.Truncate(seg.size); err != nil {
+				return errors.Wrapf(err, "cannot truncate segment %s", seg.path)
+			}
+			return errors.Wrapf(f.Sync(), "cannot sync segment %s", seg.path)
+		case err != nil:
+			return errors.Wrapf(err, "cannot recover segment %s at offset %d", seg.path, seg.size)
+		}
+
+		seg.lastOffset = seg.size
+		seg.size += size
+		seg.n++
+	}
+}
+
+// skipRecords returns the offset of the n-th record in f, whose valid records span limit bytes.
+func skipRecords(f io.ReaderAt, n uint64, limit int64) (int64, error) {
+	var off int64
+	for i := uint64(0); i < n; i++ {
+		_, size, err := readRecordAt(f, off, limit)
+		if err != nil {
+			return 0, err
+		}
+		off += size
+	}
+
+	return off, nil
+}
@@ -0,0 +1,133 @@
+package durable
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadRecordAt(t *testing.T) {
+	valid := append(encodeRecord([]byte("hello")), encodeRecord([]byte("world"))...)
+	corrupt := append([]byte(nil), valid...)
+	corrupt[recordHeaderSize] ^= 0xff
+
+	var testCases = map[string]struct {
+		data     []byte
+		off      int64
+		payloads [][]byte
+		err      error
+	}{
+		"empty": {
+			data: nil,
+			err:  io.EOF,
+		},
+		"valid": {
+			data:     valid,
+			payloads: [][]byte{[]byte("hello"), []byte("world")},
+			err:      io.EOF,
+		},
+		"truncatedHeader": {
+			data:     valid[:len(valid)/2+3],
+			payloads: [][]byte{[]byte("hello")},
+			err:      errCorruptRecord,
+		},
+		"truncatedPayload": {
+			data:     valid[:len(valid)-1],
+			payloads: [][]byte{[]byte("hello")},
+			err:      errCorruptRecord,
+		},
+		"checksumMismatch": {
+			data: corrupt,
+			err:  errCorruptRecord,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.data)
+
+			var payloads [][]byte
+			var off int64
+			for {
+				payload, size, err := readRecordAt(r, off, int64(len(tc.data)))
+				if err != nil {
+					if want, got := tc.err, err; want != got {
+						t.Fatalf("want error= %v, got= %v", want, got)
+					}
+					break
+				}
+				payloads = append(payloads, payload)
+				off += size
+			}
+
+			if want, got := tc.payloads, payloads; !cmp.Equal(want, got) {
+				t.Fatalf("want= %q, got= %q", want, got)
+			}
+		})
+	}
+}
+
+func TestRecoverSegment(t *testing.T) {
+	var testCases = map[string]struct {
+		data     []byte
+		truncate bool
+		isErr    bool
+		size     int64
+		n        uint64
+	}{
+		"valid": {
+			data:     append(encodeRecord([]byte("a")), encodeRecord([]byte("b"))...),
+			truncate: true,
+			size:     2 * (recordHeaderSize + 1),
+			n:        2,
+		},
+		"truncatedTail": {
+			data:     append(encodeRecord([]byte("a")), encodeRecord([]byte("b"))[:5]...),
+			truncate: true,
+			size:     recordHeaderSize + 1,
+			n:        1,
+		},
+		"truncatedTailNotAllowed": {
+			data:  append(encodeRecord([]byte("a")), encodeRecord([]byte("b"))[:5]...),
+			isErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+
+			seg := &segment{path: filepath.Join(dir, "0"+segmentExt)}
+			if err := ioutil.WriteFile(seg.path, tc.data, 0644); err != nil {
+				t.Fatalf("cannot write segment: %v", err)
+			}
+
+			err := recoverSegment(seg, tc.truncate)
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			case tc.isErr:
+				return
+			}
+
+			if seg.size != tc.size || seg.n != tc.n {
+				t.Fatalf("want size= %v, n= %v, got size= %v, n= %v", tc.size, tc.n, seg.size, seg.n)
+			}
+			info, err := os.Stat(seg.path)
+			if err != nil {
+				t.Fatalf("cannot stat segment: %v", err)
+			}
+			if want, got := tc.size, info.Size(); want != got {
+				t.Fatalf("file size: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
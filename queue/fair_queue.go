@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// FlowConfig configures a flow (e.g. a tenant) of a fair queue.
+type FlowConfig struct {
+	// Weight is the number of items dequeued from the flow in each round. It must be positive.
+	Weight int
+	// Limit is the maximum number of pending items in the flow, 0 means unlimited.
+	Limit int
+}
+
+// FlowStats counts items of a flow.
+type FlowStats struct {
+	Pending  int
+	Enqueued int
+	Dequeued int
+	Rejected int
+}
+
+// ErrFlowIsFull is returned by EnqueueFlow when the flow reached its limit.
+var ErrFlowIsFull = errors.New("flow is full")
+
+// fairItem is an item in a flow, with its position among all enqueued items.
+type fairItem struct {
+	value containers.Value
+	seq   uint64
+}
+
+// flow is a sub-queue for items sharing a key.
+type flow struct {
+	key        containers.Value
+	config     FlowConfig
+	configured bool   // whether config was set by SetFlow, so the flow is kept while idle
+	items      *queue // items are fairItem
+	deficit    int    // number of items the flow can still dequeue in its turn
+	active     bool
+	stats      FlowStats
+}
+
+// fairQueue is a concrete implementation of Queueable, which dequeues from per-key flows
+// with deficit round robin: flows take turns, and each turn dequeues up to the flow's weight.
+// A busy flow cannot starve others, and each backlogged flow gets a share proportional to its weight.
+type fairQueue struct {
+	key      func(item containers.Value) containers.Value
+	defaults FlowConfig
+
+	flows  map[containers.Value]*flow
+	active []*flow // flows with pending items, in round robin order
+	cur    int     // index in active of the flow whose turn it is
+	fresh  bool    // whether the turn of active[cur] has not started yet
+	size   int
+	seq    uint64
+}
+
+// NewFair returns Queueable whose items are put into flows by key(item), which must be comparable.
+// Flows use the defaults config unless configured with SetFlow(). Flows using the defaults are forgotten once
+// they have no pending items, so short-lived keys do not accumulate.
+func NewFair(key func(item containers.Value) containers.Value, defaults FlowConfig) (*fairQueue, error) {
+	if err := validateFlowConfig(defaults); err != nil {
+		return nil, err
+	}
+
+	return &fairQueue{
+		key:      key,
+		defaults: defaults,
+		flows:    map[containers.Value]*flow{},
+		fresh:    true,
+	}, nil
+}
+
+func validateFlowConfig(config FlowConfig) error {
+	if config.Weight <= 0 {
+		return errors.Errorf("weight must be positive, got %d", config.Weight)
+	}
+	if config.Limit < 0 {
+		return errors.Errorf("limit must not be negative, got %d", config.Limit)
+	}
+
+	return nil
+}
+
+// SetFlow changes the config of the flow with key. The flow is kept, with its stats, until the queue is dropped.
+func (q *fairQueue) SetFlow(key containers.Value, config FlowConfig) error {
+	if err := validateFlowConfig(config); err != nil {
+		return err
+	}
+
+	f := q.flow(key)
+	f.config, f.configured = config, true
+	return nil
+}
+
+// Stats returns the counters of the flow with key.
+// The counters of a flow using the defaults restart after it has no pending items, as the flow is forgotten.
+func (q *fairQueue) Stats(key containers.Value) FlowStats {
+	f, ok := q.flows[key]
+	if !ok {
+		return FlowStats{}
+	}
+
+	stats := f.stats
+	stats.Pending = f.items.Size()
+	return stats
+}
+
+func (q *fairQueue) flow(key containers.Value) *flow {
+	f, ok := q.flows[key]
+	if !ok {
+		f = &flow{
+			key:    key,
+			config: q.defaults,
+			items:  newZeroValueQueue(),
+		}
+		q.flows[key] = f
+	}
+
+	return f
+}
+
+// Enqueue adds a new containers.Value at the back of its flow.
+// The item is dropped if the flow is full.
+func (q *fairQueue) Enqueue(item containers.Value) {
+	q.EnqueueFlow(q.key(item), item)
+}
+
+// EnqueueFlow adds a new containers.Value at the back of the flow with key.
+// It returns ErrFlowIsFull if the flow reached its limit.
+func (q *fairQueue) EnqueueFlow(key containers.Value, item containers.Value) error {
+	f := q.flow(key)
+	if f.config.Limit > 0 && f.items.Size() >= f.config.Limit {
+		f.stats.Rejected++
+		return ErrFlowIsFull
+	}
+
+	f.items.Enqueue(fairItem{value: item, seq: q.seq})
+	f.stats.Enqueued++
+	q.seq++
+	q.size++
+	if !f.active {
+		f.active = true
+		q.active = append(q.active, f)
+	}
+
+	return nil
+}
+
+// Size returns the current number of elements in all flows.
+func (q *fairQueue) Size() int {
+	return q.size
+}
+
+// Clear empties all flows. Configs and stats of flows configured by SetFlow are kept.
+func (q *fairQueue) Clear() {
+	for _, f := range q.active {
+		f.items.Clear()
+		f.deficit = 0
+		f.active = false
+		q.forgetIdle(f)
+	}
+	q.active = q.active[:0]
+	q.cur, q.fresh = 0, true
+	q.size = 0
+}
+
+// Front returns the containers.Value which would be dequeued next.
+func (q *fairQueue) Front() (containers.Value, error) {
+	if q.size == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	front, err := q.schedule().items.Front()
+	if err != nil {
+		return nil, err
+	}
+
+	return front.(fairItem).value, nil
+}
+
+// Back returns the most recently enqueued containers.Value which is still in the queue.
+func (q *fairQueue) Back() (containers.Value, error) {
+	if q.size == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	var newest fairItem
+	for i, f := range q.active {
+		back, err := f.items.Back()
+		if err != nil {
+			return nil, err
+		}
+		if item := back.(fairItem); i == 0 || item.seq > newest.seq {
+			newest = item
+		}
+	}
+
+	return newest.value, nil
+}
+
+// Dequeue removes the containers.Value at the front of the flow whose turn it is and returns it.
+func (q *fairQueue) Dequeue() (containers.Value, error) {
+	if q.size == 0 {
+		return nil, errors.New(queueIsEmpty)
+	}
+
+	f := q.schedule()
+	front, err := f.items.Dequeue()
+	if err != nil {
+		return nil, err
+	}
+
+	f.deficit--
+	f.stats.Dequeued++
+	q.size--
+	if f.items.Size() == 0 { // an idle flow does not keep its deficit
+		f.deficit = 0
+		f.active = false
+		q.active = append(q.active[:q.cur], q.active[q.cur+1:]...)
+		if q.cur == len(q.active) {
+			q.cur = 0
+		}
+		q.fresh = true
+		q.forgetIdle(f)
+	}
+
+	return front.(fairItem).value, nil
+}
+
+// forgetIdle drops f, which has no pending items, unless it was configured by SetFlow.
+func (q *fairQueue) forgetIdle(f *flow) {
+	if !f.configured {
+		delete(q.flows, f.key)
+	}
+}
+
+// schedule returns the flow to dequeue from, starting new turns as needed. The queue must not be empty.
+func (q *fairQueue) schedule() *flow {
+	for {
+		f := q.active[q.cur]
+		if f.deficit > 0 {
+			return f
+		}
+		if q.fresh {
+			f.deficit += f.config.Weight
+			q.fresh = false
+			continue
+		}
+
+		q.cur = (q.cur + 1) % len(q.active)
+		q.fresh = true
+	}
+}
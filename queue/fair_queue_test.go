@@ -0,0 +1,286 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+var _ Queueable = (*fairQueue)(nil)
+
+// tenantOf returns the tenant of items like "tenant/job".
+func tenantOf(item containers.Value) containers.Value {
+	s := item.(string)
+	for i := range s {
+		if s[i] == '/' {
+			return s[:i]
+		}
+	}
+
+	return s
+}
+
+func TestNewFair(t *testing.T) {
+	var testCases = map[string]struct {
+		defaults FlowConfig
+		isErr    bool
+	}{
+		"valid": {
+			defaults: FlowConfig{Weight: 1},
+		},
+		"withLimit": {
+			defaults: FlowConfig{Weight: 2, Limit: 10},
+		},
+		"zeroWeight": {
+			defaults: FlowConfig{},
+			isErr:    true,
+		},
+		"negativeLimit": {
+			defaults: FlowConfig{Weight: 1, Limit: -1},
+			isErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFair(tenantOf, tc.defaults)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestFairDequeueOrder(t *testing.T) {
+	var testCases = map[string]struct {
+		weights  map[string]int
+		enqueued []containers.Value
+		dequeued []containers.Value
+	}{
+		"oneFlowIsFIFO": {
+			enqueued: []containers.Value{"a/1", "a/2", "a/3"},
+			dequeued: []containers.Value{"a/1", "a/2", "a/3"},
+		},
+		"roundRobin": {
+			enqueued: []containers.Value{"a/1", "a/2", "a/3", "b/1", "b/2", "c/1"},
+			dequeued: []containers.Value{"a/1", "b/1", "c/1", "a/2", "b/2", "a/3"},
+		},
+		"weighted": {
+			weights:  map[string]int{"a": 2, "b": 1},
+			enqueued: []containers.Value{"a/1", "a/2", "a/3", "a/4", "b/1", "b/2"},
+			dequeued: []containers.Value{"a/1", "a/2", "b/1", "a/3", "a/4", "b/2"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q, _ := NewFair(tenantOf, FlowConfig{Weight: 1})
+			for key, weight := range tc.weights {
+				if err := q.SetFlow(key, FlowConfig{Weight: weight}); err != nil {
+					t.Fatalf("cannot set flow: %v", err)
+				}
+			}
+			for _, v := range tc.enqueued {
+				q.Enqueue(v)
+			}
+
+			var dequeued []containers.Value
+			for q.Size() > 0 {
+				front, err := q.Front()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				val, err := q.Dequeue()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				if front != val {
+					t.Fatalf("want Dequeue() to return Front()= %v, got= %v", front, val)
+				}
+				dequeued = append(dequeued, val)
+			}
+
+			if want, got := tc.dequeued, dequeued; !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+// TestFairProportionalShare checks that backlogged flows share the queue by their weights,
+// no matter how many items each of them enqueued.
+func TestFairProportionalShare(t *testing.T) {
+	var testCases = map[string]struct {
+		weights  map[string]int
+		backlog  map[string]int
+		dequeue  int
+		dequeued map[string]int
+	}{
+		"equalWeightsSkewedLoad": {
+			weights:  map[string]int{"noisy": 1, "quiet": 1},
+			backlog:  map[string]int{"noisy": 10000, "quiet": 100},
+			dequeue:  200,
+			dequeued: map[string]int{"noisy": 100, "quiet": 100},
+		},
+		"weightedSkewedLoad": {
+			weights:  map[string]int{"a": 3, "b": 1, "c": 1},
+			backlog:  map[string]int{"a": 1000, "b": 10000, "c": 1000},
+			dequeue:  500,
+			dequeued: map[string]int{"a": 300, "b": 100, "c": 100},
+		},
+		"idleFlowDoesNotCount": {
+			weights:  map[string]int{"a": 1, "b": 1, "c": 1},
+			backlog:  map[string]int{"a": 1000, "b": 1000, "c": 10},
+			dequeue:  510,
+			dequeued: map[string]int{"a": 250, "b": 250, "c": 10},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			q, _ := NewFair(tenantOf, FlowConfig{Weight: 1})
+			for key, weight := range tc.weights {
+				q.SetFlow(key, FlowConfig{Weight: weight})
+			}
+
+			// the biggest flows enqueue everything first, to make sure arrival order does not matter
+			for _, key := range []string{"noisy", "b", "a", "c", "quiet"} {
+				for i := 0; i < tc.backlog[key]; i++ {
+					q.Enqueue(fmt.Sprintf("%s/%d", key, i))
+				}
+			}
+
+			dequeued := map[string]int{}
+			for i := 0; i < tc.dequeue; i++ {
+				val, err := q.Dequeue()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				dequeued[tenantOf(val).(string)]++
+			}
+
+			if want, got := tc.dequeued, dequeued; !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestFairLimitAndStats(t *testing.T) {
+	q, _ := NewFair(tenantOf, FlowConfig{Weight: 1, Limit: 2})
+	for _, v := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		q.Enqueue(v)
+	}
+	if want, got := ErrFlowIsFull, q.EnqueueFlow("a", "a/4"); want != got {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	q.Dequeue()
+
+	if want, got := (FlowStats{Pending: 1, Enqueued: 2, Dequeued: 1, Rejected: 2}), q.Stats("a"); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+	if want, got := (FlowStats{Pending: 1, Enqueued: 1}), q.Stats("b"); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+	if want, got := (FlowStats{}), q.Stats("unknown"); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+}
+
+// TestFairForgetsIdleFlows checks that short-lived flows do not accumulate, unless they were configured.
+func TestFairForgetsIdleFlows(t *testing.T) {
+	q, _ := NewFair(tenantOf, FlowConfig{Weight: 1})
+	q.SetFlow("configured", FlowConfig{Weight: 2})
+	for i := 0; i < 1000; i++ {
+		q.Enqueue(fmt.Sprintf("%d/1", i))
+		q.Enqueue(fmt.Sprintf("%d/2", i))
+		q.Enqueue(fmt.Sprintf("configured/%d", i))
+		for q.Size() > 0 {
+			q.Dequeue()
+		}
+	}
+	q.Enqueue("short/1")
+	q.Clear()
+
+	if want, got := 1, len(q.flows); want != got {
+		t.Fatalf("flows: want= %v, got= %v", want, got)
+	}
+	if want, got := (FlowStats{Enqueued: 1000, Dequeued: 1000}), q.Stats("configured"); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+	if want, got := (FlowStats{}), q.Stats("0"); want != got {
+		t.Fatalf("want forgotten flow to restart its stats, got= %+v", got)
+	}
+
+	q.Enqueue("0/3")
+	if want, got := (FlowStats{Pending: 1, Enqueued: 1}), q.Stats("0"); want != got {
+		t.Fatalf("want= %+v, got= %+v", want, got)
+	}
+}
+
+func TestFairBack(t *testing.T) {
+	q, _ := NewFair(tenantOf, FlowConfig{Weight: 1})
+	if _, err := q.Back(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+
+	for _, v := range []string{"a/1", "b/1", "a/2"} {
+		q.Enqueue(v)
+	}
+	if back, err := q.Back(); err != nil || back != "a/2" {
+		t.Fatalf("want a/2, got= %v, err= %v", back, err)
+	}
+
+	q.Dequeue() // a/1
+	q.Dequeue() // b/1
+	q.Dequeue() // a/2
+	q.Enqueue("b/2")
+	if back, err := q.Back(); err != nil || back != "b/2" {
+		t.Fatalf("want b/2, got= %v, err= %v", back, err)
+	}
+}
+
+func TestFairClear(t *testing.T) {
+	q, _ := NewFair(tenantOf, FlowConfig{Weight: 1})
+	for _, v := range []string{"a/1", "b/1", "a/2"} {
+		q.Enqueue(v)
+	}
+	q.Dequeue()
+	q.Clear()
+
+	if want, got := 0, q.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	if _, err := q.Dequeue(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+
+	q.Enqueue("b/2")
+	if val, err := q.Dequeue(); err != nil || val != "b/2" {
+		t.Fatalf("want b/2, got= %v, err= %v", val, err)
+	}
+}
+
+func BenchmarkFairEnqueueDequeue(b *testing.B) {
+	for _, flows := range []int{1, 10, 1000} {
+		b.Run(fmt.Sprintf("flows=%d", flows), func(b *testing.B) {
+			q, _ := NewFair(func(item containers.Value) containers.Value {
+				return item.(int) % flows
+			}, FlowConfig{Weight: 1})
+
+			for i := 0; i < b.N; i++ {
+				q.Enqueue(i)
+				if i%2 == 1 {
+					q.Dequeue()
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package queue
 
 import (
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,3 +45,45 @@ func TestFuzzOps(t *testing.T) {
 		}
 	}
 }
+
+// TestFuzzAggregatingQueue performs N random operations and compares the aggregate with a naive fold.
+func TestFuzzAggregatingQueue(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	concat := func(a, b containers.Value) containers.Value {
+		return a.(string) + b.(string)
+	}
+	q := NewAggregating(concat)
+	var naive []string
+
+	steps := rng.Intn(10000) + 2000
+	for i := 0; i < steps; i++ {
+		switch rng.Intn(4) {
+		case 0, 1:
+			s := string(rune('a' + rng.Intn(26)))
+			q.Enqueue(s)
+			naive = append(naive, s)
+		case 2:
+			q.Dequeue()
+			if len(naive) > 0 {
+				naive = naive[1:]
+			}
+		default:
+			q.Clear()
+			naive = naive[:0]
+		}
+
+		agg, err := q.Aggregate()
+		if len(naive) == 0 {
+			if err == nil {
+				t.Fatalf("step %d: want error, got none", i)
+			}
+			continue
+		}
+		if want, got := strings.Join(naive, ""), agg; err != nil || want != got {
+			t.Fatalf("step %d: want= %v, got= %v, err= %v", i, want, got, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package spsc provides a bounded ring buffer for exactly one producer and one consumer goroutine.
+package spsc
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+var (
+	// ErrFull is returned by TryEnqueue when the ring has no free slot.
+	ErrFull = errors.New("ring is full")
+	// ErrEmpty is returned when there is nothing to dequeue.
+	ErrEmpty = errors.New("ring is empty")
+)
+
+const cacheLineSize = 64
+
+// ring is a wait-free single-producer single-consumer queue.
+// Only one goroutine may enqueue and only one goroutine may dequeue, which can be different goroutines.
+//
+// head and tail grow forever and wrap around as uint64, slots are picked by masking them.
+// Each side keeps its own index and a cached copy of the other side's index on a separate cache line,
+// so they only read each other's line when the cached copy says the ring is full or empty.
+type ring struct {
+	// consumer's cache line
+	head       uint64 // next slot to dequeue, accessed atomically. First in struct for 64-bit alignment
+	cachedTail uint64 // only used by the consumer
+	_          [cacheLineSize - 16]byte
+
+	// producer's cache line
+	tail       uint64 // next slot to enqueue, accessed atomically
+	cachedHead uint64 // only used by the producer
+	_          [cacheLineSize - 16]byte
+
+	mask  uint64
+	slots []containers.Value
+}
+
+// New returns an empty ring buffer. capacity must be a power of two.
+func New(capacity int) (*ring, error) {
+	return newWithStart(capacity, 0)
+}
+
+// newWithStart returns an empty ring buffer whose indices start at start, to test wraparound.
+func newWithStart(capacity int, start uint64) (*ring, error) {
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		return nil, errors.Errorf("capacity must be a positive power of two, got %d", capacity)
+	}
+
+	return &ring{
+		head:       start,
+		cachedTail: start,
+		tail:       start,
+		cachedHead: start,
+		mask:       uint64(capacity - 1),
+		slots:      make([]containers.Value, capacity),
+	}, nil
+}
+
+// Capacity returns the maximum number of items in the ring.
+func (r *ring) Capacity() int {
+	return len(r.slots)
+}
+
+// Size returns the current number of items in the ring. It may be stale if the other side is active.
+func (r *ring) Size() int {
+	head := atomic.LoadUint64(&r.head)
+	tail := atomic.LoadUint64(&r.tail)
+	return int(tail - head)
+}
+
+// free returns the number of free slots seen by the producer, reloading head only if needed.
+func (r *ring) free(tail uint64, want int) int {
+	free := len(r.slots) - int(tail-r.cachedHead)
+	if free < want {
+		r.cachedHead = atomic.LoadUint64(&r.head)
+		free = len(r.slots) - int(tail-r.cachedHead)
+	}
+
+	return free
+}
+
+// available returns the number of items seen by the consumer, reloading tail only if needed.
+func (r *ring) available(head uint64, want int) int {
+	available := int(r.cachedTail - head)
+	if available < want {
+		r.cachedTail = atomic.LoadUint64(&r.tail)
+		available = int(r.cachedTail - head)
+	}
+
+	return available
+}
+
+// TryEnqueue adds a new containers.Value at the ring's back. Only the producer may call it.
+// It returns ErrFull if there is no free slot.
+func (r *ring) TryEnqueue(item containers.Value) error {
+	tail := r.tail // only the producer writes tail
+	if r.free(tail, 1) == 0 {
+		return ErrFull
+	}
+
+	r.slots[tail&r.mask] = item
+	atomic.StoreUint64(&r.tail, tail+1) // publishes the slot to the consumer
+	return nil
+}
+
+// EnqueueBatch adds as many items as there are free slots, in order. Only the producer may call it.
+// It returns the number of items added.
+func (r *ring) EnqueueBatch(items []containers.Value) int {
+	tail := r.tail
+	n := r.free(tail, len(items))
+	if n > len(items) {
+		n = len(items)
+	}
+
+	for i := 0; i < n; i++ {
+		r.slots[(tail+uint64(i))&r.mask] = items[i]
+	}
+	atomic.StoreUint64(&r.tail, tail+uint64(n))
+	return n
+}
+
+// TryDequeue removes the containers.Value at the ring's front and returns it. Only the consumer may call it.
+// It returns ErrEmpty if there is nothing to dequeue.
+func (r *ring) TryDequeue() (containers.Value, error) {
+	head := r.head // only the consumer writes head
+	if r.available(head, 1) == 0 {
+		return nil, ErrEmpty
+	}
+
+	slot := &r.slots[head&r.mask]
+	item := *slot
+	*slot = nil                         // let the item be garbage collected
+	atomic.StoreUint64(&r.head, head+1) // gives the slot back to the producer
+	return item, nil
+}
+
+// DequeueBatch removes up to len(dst) items from the ring's front into dst. Only the consumer may call it.
+// It returns the number of items removed.
+func (r *ring) DequeueBatch(dst []containers.Value) int {
+	head := r.head
+	n := r.available(head, len(dst))
+	if n > len(dst) {
+		n = len(dst)
+	}
+
+	for i := 0; i < n; i++ {
+		slot := &r.slots[(head+uint64(i))&r.mask]
+		dst[i] = *slot
+		*slot = nil
+	}
+	atomic.StoreUint64(&r.head, head+uint64(n))
+	return n
+}
+
+// front returns the item at head without removing it. Only the consumer may call it.
+func (r *ring) front() (containers.Value, error) {
+	head := r.head
+	if r.available(head, 1) == 0 {
+		return nil, ErrEmpty
+	}
+
+	return r.slots[head&r.mask], nil
+}
+
+// back returns the most recently enqueued item. Only the consumer may call it.
+func (r *ring) back() (containers.Value, error) {
+	head := r.head
+	r.cachedTail = atomic.LoadUint64(&r.tail) // the back moves with every enqueue
+	if r.cachedTail == head {
+		return nil, ErrEmpty
+	}
+
+	// the slot before cachedTail cannot be reused by the producer until the consumer moves head past it
+	return r.slots[(r.cachedTail-1)&r.mask], nil
+}
+
+// clear removes all items seen by the consumer. Only the consumer may call it.
+func (r *ring) clear() {
+	head := r.head
+	r.cachedTail = atomic.LoadUint64(&r.tail)
+	for i := head; i != r.cachedTail; i++ {
+		r.slots[i&r.mask] = nil
+	}
+	atomic.StoreUint64(&r.head, r.cachedTail)
+}
+
+// Queue returns a view of the ring implementing queue.Queueable.
+// Its Enqueue must only be called by the producer, and its other methods by the consumer.
+func (r *ring) Queue() *view {
+	return &view{ring: r}
+}
+
+// view implements queue.Queueable on a ring.
+type view struct {
+	ring *ring
+}
+
+// Enqueue adds a new containers.Value at the queue's back, yielding the processor until a slot is free.
+func (v *view) Enqueue(item containers.Value) {
+	for v.ring.TryEnqueue(item) != nil {
+		runtime.Gosched()
+	}
+}
+
+// Dequeue removes the containers.Value at the queue's front and returns it.
+func (v *view) Dequeue() (containers.Value, error) {
+	return v.ring.TryDequeue()
+}
+
+// Front returns the containers.Value at the queue's front.
+func (v *view) Front() (containers.Value, error) {
+	return v.ring.front()
+}
+
+// Back returns the containers.Value at the queue's back.
+func (v *view) Back() (containers.Value, error) {
+	return v.ring.back()
+}
+
+// Size returns the current number of elements in the queue.
+func (v *view) Size() int {
+	return v.ring.Size()
+}
+
+// Clear empties the whole queue.
+func (v *view) Clear() {
+	v.ring.clear()
+}
@@ -0,0 +1,299 @@
+package spsc
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/queue"
+)
+
+var _ queue.Queueable = (*view)(nil)
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		capacity int
+		isErr    bool
+	}{
+		"one":           {capacity: 1},
+		"powerOfTwo":    {capacity: 1024},
+		"zero":          {capacity: 0, isErr: true},
+		"negative":      {capacity: -4, isErr: true},
+		"notPowerOfTwo": {capacity: 12, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(tc.capacity)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestRingOps(t *testing.T) {
+	starts := map[string]uint64{
+		"zero":        0,
+		"beyondInt32": 3e9,
+		"wrapsAround": math.MaxUint64 - 2,
+	}
+
+	for name, start := range starts {
+		t.Run(name, func(t *testing.T) {
+			r, _ := newWithStart(4, start)
+			if _, err := r.TryDequeue(); err != ErrEmpty {
+				t.Fatalf("want= %v, got= %v", ErrEmpty, err)
+			}
+
+			for i := 0; i < 4; i++ {
+				if err := r.TryEnqueue(i); err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+			}
+			if err := r.TryEnqueue(4); err != ErrFull {
+				t.Fatalf("want= %v, got= %v", ErrFull, err)
+			}
+			if want, got := 4, r.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+
+			var dequeued []containers.Value
+			for i := 0; i < 2; i++ {
+				val, _ := r.TryDequeue()
+				dequeued = append(dequeued, val)
+			}
+			r.TryEnqueue(4)
+			r.TryEnqueue(5)
+			for r.Size() > 0 {
+				val, _ := r.TryDequeue()
+				dequeued = append(dequeued, val)
+			}
+
+			if want, got := []containers.Value{0, 1, 2, 3, 4, 5}, dequeued; !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestRingBatch(t *testing.T) {
+	var testCases = map[string]struct {
+		capacity  int
+		enqueue   []containers.Value
+		enqueued  int
+		dequeueTo int
+		dequeued  []containers.Value
+	}{
+		"fits": {
+			capacity:  8,
+			enqueue:   []containers.Value{1, 2, 3},
+			enqueued:  3,
+			dequeueTo: 8,
+			dequeued:  []containers.Value{1, 2, 3},
+		},
+		"partialEnqueue": {
+			capacity:  2,
+			enqueue:   []containers.Value{1, 2, 3},
+			enqueued:  2,
+			dequeueTo: 8,
+			dequeued:  []containers.Value{1, 2},
+		},
+		"partialDequeue": {
+			capacity:  8,
+			enqueue:   []containers.Value{1, 2, 3},
+			enqueued:  3,
+			dequeueTo: 2,
+			dequeued:  []containers.Value{1, 2},
+		},
+		"empty": {
+			capacity:  8,
+			dequeueTo: 8,
+			dequeued:  []containers.Value{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, _ := newWithStart(tc.capacity, math.MaxUint64-1)
+			if want, got := tc.enqueued, r.EnqueueBatch(tc.enqueue); want != got {
+				t.Fatalf("enqueued: want= %v, got= %v", want, got)
+			}
+
+			dst := make([]containers.Value, tc.dequeueTo)
+			n := r.DequeueBatch(dst)
+			if want, got := tc.dequeued, dst[:n]; !cmp.Equal(want, got) {
+				t.Fatalf("dequeued: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	r, _ := New(4)
+	q := r.Queue()
+	if _, err := q.Front(); err == nil {
+		t.Fatalf("front: want error, got none")
+	}
+	if _, err := q.Back(); err == nil {
+		t.Fatalf("back: want error, got none")
+	}
+
+	q.Enqueue("a")
+	q.Enqueue("b")
+	q.Enqueue("c")
+	if front, err := q.Front(); err != nil || front != "a" {
+		t.Fatalf("front: want a, got= %v, err= %v", front, err)
+	}
+	if back, err := q.Back(); err != nil || back != "c" {
+		t.Fatalf("back: want c, got= %v, err= %v", back, err)
+	}
+	if val, err := q.Dequeue(); err != nil || val != "a" {
+		t.Fatalf("dequeue: want a, got= %v, err= %v", val, err)
+	}
+
+	q.Clear()
+	if want, got := 0, q.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	q.Enqueue("d")
+	if val, err := q.Dequeue(); err != nil || val != "d" {
+		t.Fatalf("dequeue: want d, got= %v, err= %v", val, err)
+	}
+}
+
+// TestStress has a producer and a consumer goroutine pass sequence numbers through a small ring,
+// with indices which wrap around during the test. The consumer must see every number in order.
+// Run it with -race.
+func TestStress(t *testing.T) {
+	const (
+		firstSeq = uint64(3e9)
+		items    = 1 << 20
+		batch    = 7
+	)
+
+	r, _ := newWithStart(64, math.MaxUint64-items/2)
+	go func() {
+		buf := make([]containers.Value, 0, batch)
+		for seq := firstSeq; seq < firstSeq+items; {
+			if seq%2 == 0 { // alternate single and batch enqueues
+				if r.TryEnqueue(seq) == nil {
+					seq++
+				} else {
+					runtime.Gosched()
+				}
+				continue
+			}
+
+			buf = buf[:0]
+			for i := uint64(0); i < batch && seq+i < firstSeq+items; i++ {
+				buf = append(buf, seq+i)
+			}
+			n := r.EnqueueBatch(buf)
+			if n == 0 {
+				runtime.Gosched()
+			}
+			seq += uint64(n)
+		}
+	}()
+
+	want := firstSeq
+	buf := make([]containers.Value, batch)
+	for want < firstSeq+items {
+		var got []containers.Value
+		if want%3 == 0 {
+			if val, err := r.TryDequeue(); err == nil {
+				got = append(got, val)
+			}
+		} else {
+			got = buf[:r.DequeueBatch(buf)]
+		}
+		if len(got) == 0 {
+			runtime.Gosched() // the producer may need this processor
+		}
+
+		for _, val := range got {
+			if val.(uint64) != want {
+				t.Fatalf("want= %v, got= %v", want, val)
+			}
+			want++
+		}
+	}
+
+	if want, got := 0, r.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+}
+
+func BenchmarkThroughput(b *testing.B) {
+	for _, capacity := range []int{64, 1024} {
+		b.Run(fmt.Sprintf("single/capacity=%d", capacity), func(b *testing.B) {
+			r, _ := New(capacity)
+			go func() {
+				for i := 0; i < b.N; {
+					if r.TryEnqueue(i) == nil {
+						i++
+					} else {
+						runtime.Gosched()
+					}
+				}
+			}()
+
+			for i := 0; i < b.N; {
+				if _, err := r.TryDequeue(); err == nil {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch/capacity=%d", capacity), func(b *testing.B) {
+			r, _ := New(capacity)
+			go func() {
+				items := make([]containers.Value, 32)
+				for i := range items {
+					items[i] = i
+				}
+				for i := 0; i < b.N; {
+					n := len(items)
+					if b.N-i < n {
+						n = b.N - i
+					}
+					if n = r.EnqueueBatch(items[:n]); n == 0 {
+						runtime.Gosched()
+					}
+					i += n
+				}
+			}()
+
+			dst := make([]containers.Value, 32)
+			for i := 0; i < b.N; {
+				n := r.DequeueBatch(dst)
+				if n == 0 {
+					runtime.Gosched()
+				}
+				i += n
+			}
+		})
+		b.Run(fmt.Sprintf("channel/capacity=%d", capacity), func(b *testing.B) {
+			ch := make(chan containers.Value, capacity)
+			go func() {
+				for i := 0; i < b.N; i++ {
+					ch <- i
+				}
+			}()
+
+			for i := 0; i < b.N; i++ {
+				<-ch
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Package segtree provides a segment tree for range queries over an associative operation, with lazy range updates.
+package segtree
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Monoid is an associative Combine with an Identity, i.e. Combine(Identity, a) == Combine(a, Identity) == a.
+type Monoid struct {
+	Combine  func(a, b containers.Value) containers.Value
+	Identity containers.Value
+}
+
+// Lazy describes range updates which can be applied to aggregates without visiting every value.
+type Lazy struct {
+	// Apply returns the aggregate of n values after applying update to each of them.
+	Apply func(update, aggregate containers.Value, n int) containers.Value
+	// Compose returns the update equivalent to applying first, then second.
+	Compose func(second, first containers.Value) containers.Value
+}
+
+var (
+	// SumInt64 adds int64 values.
+	SumInt64 = Monoid{
+		Combine:  func(a, b containers.Value) containers.Value { return a.(int64) + b.(int64) },
+		Identity: int64(0),
+	}
+	// MinInt64 takes the least of int64 values.
+	MinInt64 = Monoid{
+		Combine: func(a, b containers.Value) containers.Value {
+			if b.(int64) < a.(int64) {
+				return b
+			}
+			return a
+		},
+		Identity: int64(math.MaxInt64),
+	}
+	// MaxInt64 takes the greatest of int64 values.
+	MaxInt64 = Monoid{
+		Combine: func(a, b containers.Value) containers.Value {
+			if b.(int64) > a.(int64) {
+				return b
+			}
+			return a
+		},
+		Identity: int64(math.MinInt64),
+	}
+
+	// AddToSumInt64 adds an int64 to each value of a range, for SumInt64.
+	AddToSumInt64 = Lazy{
+		Apply: func(update, aggregate containers.Value, n int) containers.Value {
+			return aggregate.(int64) + update.(int64)*int64(n)
+		},
+		Compose: func(second, first containers.Value) containers.Value { return second.(int64) + first.(int64) },
+	}
+	// AddToExtremumInt64 adds an int64 to each value of a range, for MinInt64 and MaxInt64.
+	AddToExtremumInt64 = Lazy{
+		Apply: func(update, aggregate containers.Value, n int) containers.Value {
+			return aggregate.(int64) + update.(int64)
+		},
+		Compose: func(second, first containers.Value) containers.Value { return second.(int64) + first.(int64) },
+	}
+)
+
+// segmentTree keeps the aggregate of every range [lo, hi) halved from [0, n).
+// Node 1 is the root, and node i has children 2i and 2i+1.
+type segmentTree struct {
+	monoid Monoid
+	lazy   *Lazy // nil if range updates are not supported
+
+	n          int
+	aggregates []containers.Value
+	pending    []containers.Value // updates not applied to children yet
+	hasPending []bool
+}
+
+// New returns a segment tree over a copy of values, combined with m. It only supports point updates.
+func New(values []containers.Value, m Monoid) (*segmentTree, error) {
+	if m.Combine == nil {
+		return nil, errors.New("monoid must have a combine function")
+	}
+
+	t := &segmentTree{
+		monoid:     m,
+		n:          len(values),
+		aggregates: make([]containers.Value, 4*len(values)+1),
+	}
+	if t.n > 0 {
+		t.build(1, 0, t.n, values)
+	}
+
+	return t, nil
+}
+
+// NewLazy returns a segment tree over a copy of values, combined with m, which also supports range updates.
+func NewLazy(values []containers.Value, m Monoid, lazy Lazy) (*segmentTree, error) {
+	if lazy.Apply == nil || lazy.Compose == nil {
+		return nil, errors.New("lazy updates must have apply and compose functions")
+	}
+
+	t, err := New(values, m)
+	if err != nil {
+		return nil, err
+	}
+	t.lazy = &lazy
+	t.pending = make([]containers.Value, len(t.aggregates))
+	t.hasPending = make([]bool, len(t.aggregates))
+
+	return t, nil
+}
+
+func (t *segmentTree) build(node, lo, hi int, values []containers.Value) {
+	if hi-lo == 1 {
+		t.aggregates[node] = values[lo]
+		return
+	}
+
+	mid := (lo + hi) / 2
+	t.build(2*node, lo, mid, values)
+	t.build(2*node+1, mid, hi, values)
+	t.aggregates[node] = t.monoid.Combine(t.aggregates[2*node], t.aggregates[2*node+1])
+}
+
+// Len returns the number of values.
+func (t *segmentTree) Len() int {
+	return t.n
+}
+
+func (t *segmentTree) checkRange(l, r int) error {
+	if l < 0 || r > t.n || l > r {
+		return errors.Errorf("range [%d, %d) is out of [0, %d)", l, r, t.n)
+	}
+
+	return nil
+}
+
+// Query returns the combination of values in [l, r), or the identity if the range is empty.
+func (t *segmentTree) Query(l, r int) (containers.Value, error) {
+	if err := t.checkRange(l, r); err != nil {
+		return nil, err
+	}
+	if l == r {
+		return t.monoid.Identity, nil
+	}
+
+	return t.query(1, 0, t.n, l, r), nil
+}
+
+func (t *segmentTree) query(node, lo, hi, l, r int) containers.Value {
+	if l <= lo && hi <= r {
+		return t.aggregates[node]
+	}
+
+	t.push(node, lo, hi)
+	mid := (lo + hi) / 2
+	switch {
+	case r <= mid:
+		return t.query(2*node, lo, mid, l, r)
+	case mid <= l:
+		return t.query(2*node+1, mid, hi, l, r)
+	}
+
+	return t.monoid.Combine(t.query(2*node, lo, mid, l, r), t.query(2*node+1, mid, hi, l, r))
+}
+
+// Set replaces the value at i.
+func (t *segmentTree) Set(i int, value containers.Value) error {
+	if i < 0 || i >= t.n {
+		return errors.Errorf("index %d is out of [0, %d)", i, t.n)
+	}
+
+	t.set(1, 0, t.n, i, value)
+	return nil
+}
+
+func (t *segmentTree) set(node, lo, hi, i int, value containers.Value) {
+	if hi-lo == 1 {
+		t.aggregates[node] = value
+		return
+	}
+
+	t.push(node, lo, hi)
+	mid := (lo + hi) / 2
+	if i < mid {
+		t.set(2*node, lo, mid, i, value)
+	} else {
+		t.set(2*node+1, mid, hi, i, value)
+	}
+	t.aggregates[node] = t.monoid.Combine(t.aggregates[2*node], t.aggregates[2*node+1])
+}
+
+// Update applies update to every value in [l, r). The tree must be created with NewLazy().
+func (t *segmentTree) Update(l, r int, update containers.Value) error {
+	if t.lazy == nil {
+		return errors.New("range updates need a tree created with NewLazy()")
+	}
+	if err := t.checkRange(l, r); err != nil {
+		return err
+	}
+	if l == r {
+		return nil
+	}
+
+	t.update(1, 0, t.n, l, r, update)
+	return nil
+}
+
+func (t *segmentTree) update(node, lo, hi, l, r int, update containers.Value) {
+	if l <= lo && hi <= r {
+		t.apply(node, lo, hi, update)
+		return
+	}
+
+	t.push(node, lo, hi)
+	mid := (lo + hi) / 2
+	if l < mid {
+		t.update(2*node, lo, mid, l, r, update)
+	}
+	if mid < r {
+		t.update(2*node+1, mid, hi, l, r, update)
+	}
+	t.aggregates[node] = t.monoid.Combine(t.aggregates[2*node], t.aggregates[2*node+1])
+}
+
+// apply updates the aggregate of node, and remembers the update for its children.
+func (t *segmentTree) apply(node, lo, hi int, update containers.Value) {
+	t.aggregates[node] = t.lazy.Apply(update, t.aggregates[node], hi-lo)
+	if hi-lo == 1 {
+		return
+	}
+
+	if t.hasPending[node] {
+		update = t.lazy.Compose(update, t.pending[node])
+	}
+	t.pending[node], t.hasPending[node] = update, true
+}
+
+// push applies the pending update of node to its children.
+func (t *segmentTree) push(node, lo, hi int) {
+	if t.lazy == nil || !t.hasPending[node] {
+		return
+	}
+
+	mid := (lo + hi) / 2
+	t.apply(2*node, lo, mid, t.pending[node])
+	t.apply(2*node+1, mid, hi, t.pending[node])
+	t.pending[node], t.hasPending[node] = nil, false
+}
@@ -0,0 +1,73 @@
+// +build fuzz
+
+package segtree
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bitsgofer/containers"
+)
+
+// TestFuzzAgainstNaive performs N random updates and queries, and compares the tree with loops over a slice.
+func TestFuzzAgainstNaive(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	for name, tc := range map[string]struct {
+		monoid Monoid
+		lazy   Lazy
+	}{
+		"sum": {monoid: SumInt64, lazy: AddToSumInt64},
+		"min": {monoid: MinInt64, lazy: AddToExtremumInt64},
+		"max": {monoid: MaxInt64, lazy: AddToExtremumInt64},
+	} {
+		n := rng.Intn(200) + 1
+		naive := make([]int64, n)
+		values := make([]containers.Value, n)
+		for i := range naive {
+			naive[i] = rng.Int63n(2000) - 1000
+			values[i] = naive[i]
+		}
+		tree, err := NewLazy(values, tc.monoid, tc.lazy)
+		if err != nil {
+			t.Fatalf("%s: need a valid tree to test, got %q", name, err)
+		}
+
+		steps := rng.Intn(10000) + 2000
+		for i := 0; i < steps; i++ {
+			l := rng.Intn(n + 1)
+			r := l + rng.Intn(n-l+1)
+
+			switch rng.Intn(3) {
+			case 0:
+				if l == n {
+					continue
+				}
+				v := rng.Int63n(2000) - 1000
+				tree.Set(l, v)
+				naive[l] = v
+			case 1:
+				delta := rng.Int63n(200) - 100
+				tree.Update(l, r, delta)
+				for j := l; j < r; j++ {
+					naive[j] += delta
+				}
+			default:
+				want := tc.monoid.Identity
+				for j := l; j < r; j++ {
+					want = tc.monoid.Combine(want, naive[j])
+				}
+				got, err := tree.Query(l, r)
+				if err != nil {
+					t.Fatalf("%s: step %d: want no error, got %q", name, i, err)
+				}
+				if want != got {
+					t.Fatalf("%s: step %d: query [%d, %d): want= %v, got= %v", name, i, l, r, want, got)
+				}
+			}
+		}
+	}
+}
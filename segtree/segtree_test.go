@@ -0,0 +1,195 @@
+package segtree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bitsgofer/containers"
+)
+
+func int64s(vals ...int64) []containers.Value {
+	values := make([]containers.Value, len(vals))
+	for i, v := range vals {
+		values[i] = v
+	}
+
+	return values
+}
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		monoid Monoid
+		lazy   *Lazy
+		isErr  bool
+	}{
+		"monoid": {
+			monoid: SumInt64,
+		},
+		"lazy": {
+			monoid: SumInt64,
+			lazy:   &AddToSumInt64,
+		},
+		"noCombine": {
+			monoid: Monoid{Identity: 0},
+			isErr:  true,
+		},
+		"noCompose": {
+			monoid: SumInt64,
+			lazy:   &Lazy{Apply: AddToSumInt64.Apply},
+			isErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var err error
+			if tc.lazy == nil {
+				_, err = New(int64s(1, 2, 3), tc.monoid)
+			} else {
+				_, err = NewLazy(int64s(1, 2, 3), tc.monoid, *tc.lazy)
+			}
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	values := int64s(5, -2, 7, 3, 0, 9, -4)
+
+	var testCases = map[string]struct {
+		monoid Monoid
+		l, r   int
+		want   containers.Value
+		isErr  bool
+	}{
+		"sumAll":     {monoid: SumInt64, l: 0, r: 7, want: int64(18)},
+		"sumMiddle":  {monoid: SumInt64, l: 2, r: 5, want: int64(10)},
+		"sumOne":     {monoid: SumInt64, l: 6, r: 7, want: int64(-4)},
+		"sumEmpty":   {monoid: SumInt64, l: 3, r: 3, want: int64(0)},
+		"minMiddle":  {monoid: MinInt64, l: 1, r: 4, want: int64(-2)},
+		"maxSuffix":  {monoid: MaxInt64, l: 3, r: 7, want: int64(9)},
+		"outOfRange": {monoid: SumInt64, l: 0, r: 8, isErr: true},
+		"negative":   {monoid: SumInt64, l: -1, r: 2, isErr: true},
+		"reversed":   {monoid: SumInt64, l: 4, r: 2, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tree, _ := New(values, tc.monoid)
+			got, err := tree.Query(tc.l, tc.r)
+
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if want := tc.want; want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestSetAndUpdate(t *testing.T) {
+	tree, _ := NewLazy(int64s(1, 2, 3, 4, 5, 6, 7, 8), SumInt64, AddToSumInt64)
+	if err := tree.Update(2, 6, int64(10)); err != nil { // 1 2 13 14 15 16 7 8
+		t.Fatalf("want no error, got %q", err)
+	}
+	if err := tree.Set(3, int64(0)); err != nil { // 1 2 13 0 15 16 7 8
+		t.Fatalf("want no error, got %q", err)
+	}
+	if err := tree.Update(0, 4, int64(-1)); err != nil { // 0 1 12 -1 15 16 7 8
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	for _, q := range []struct {
+		l, r int
+		want int64
+	}{
+		{0, 8, 58},
+		{0, 1, 0},
+		{2, 4, 11},
+		{3, 5, 14},
+		{5, 8, 31},
+	} {
+		if got, _ := tree.Query(q.l, q.r); got != q.want {
+			t.Fatalf("query [%d, %d): want= %v, got= %v", q.l, q.r, q.want, got)
+		}
+	}
+
+	if err := tree.Set(8, int64(0)); err == nil {
+		t.Fatalf("set out of range: want error, got none")
+	}
+	notLazy, _ := New(int64s(1, 2), SumInt64)
+	if err := notLazy.Update(0, 1, int64(1)); err == nil {
+		t.Fatalf("update without lazy: want error, got none")
+	}
+}
+
+func TestMinWithRangeAdd(t *testing.T) {
+	tree, _ := NewLazy(int64s(4, 8, 1, 6), MinInt64, AddToExtremumInt64)
+	tree.Update(2, 3, int64(10)) // 4 8 11 6
+	tree.Update(0, 2, int64(5))  // 9 13 11 6
+
+	if got, _ := tree.Query(0, 3); got != int64(9) {
+		t.Fatalf("want= 9, got= %v", got)
+	}
+	if got, _ := tree.Query(0, 4); got != int64(6) {
+		t.Fatalf("want= 6, got= %v", got)
+	}
+}
+
+// TestNonCommutative checks that the tree keeps the order of values, e.g. for string concatenation.
+func TestNonCommutative(t *testing.T) {
+	concat := Monoid{
+		Combine:  func(a, b containers.Value) containers.Value { return a.(string) + b.(string) },
+		Identity: "",
+	}
+	tree, _ := New([]containers.Value{"a", "b", "c", "d", "e"}, concat)
+	tree.Set(2, "C")
+
+	if got, _ := tree.Query(1, 5); got != "bCde" {
+		t.Fatalf("want= bCde, got= %v", got)
+	}
+}
+
+func BenchmarkQueryAndUpdate(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		values := make([]containers.Value, n)
+		for i := range values {
+			values[i] = int64(i)
+		}
+
+		b.Run(fmt.Sprintf("segtree/n=%d", n), func(b *testing.B) {
+			tree, _ := NewLazy(values, SumInt64, AddToSumInt64)
+			for i := 0; i < b.N; i++ {
+				l := i % (n / 2)
+				tree.Update(l, l+n/2, int64(1))
+				tree.Query(l/2, l+n/4)
+			}
+		})
+		b.Run(fmt.Sprintf("naive/n=%d", n), func(b *testing.B) {
+			naive := make([]int64, n)
+			for i := 0; i < b.N; i++ {
+				l := i % (n / 2)
+				for j := l; j < l+n/2; j++ {
+					naive[j]++
+				}
+				var sum int64
+				for j := l / 2; j < l+n/4; j++ {
+					sum += naive[j]
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// countMin is a Count-Min sketch: depth rows of width counters, where each value adds to one counter per row.
+// Estimates never undercount, and with probability 1 - delta overcount by at most epsilon * Total().
+// It is not safe for concurrent use.
+type countMin struct {
+	width, depth uint64
+	counters     []uint64 // row after row
+	total        uint64
+	hash         hasher.Func
+}
+
+// NewCountMin returns a Count-Min sketch which overcounts by at most epsilon * Total() with probability 1 - delta.
+// A nil hash uses hasher.Default.
+func NewCountMin(epsilon, delta float64, hash hasher.Func) (*countMin, error) {
+	if epsilon <= 0 || epsilon >= 1 {
+		return nil, errors.Errorf("epsilon must be in (0, 1), got %v", epsilon)
+	}
+	if delta <= 0 || delta >= 1 {
+		return nil, errors.Errorf("delta must be in (0, 1), got %v", delta)
+	}
+
+	return NewCountMinWithSize(int(math.Ceil(math.E/epsilon)), int(math.Ceil(math.Log(1/delta))), hash)
+}
+
+// NewCountMinWithSize returns a Count-Min sketch with depth rows of width counters.
+func NewCountMinWithSize(width, depth int, hash hasher.Func) (*countMin, error) {
+	if width <= 0 {
+		return nil, errors.Errorf("width must be positive, got %d", width)
+	}
+	if depth <= 0 {
+		return nil, errors.Errorf("depth must be positive, got %d", depth)
+	}
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	return &countMin{
+		width:    uint64(width),
+		depth:    uint64(depth),
+		counters: make([]uint64, width*depth),
+		hash:     hash,
+	}, nil
+}
+
+// Width returns the number of counters per row.
+func (s *countMin) Width() int {
+	return int(s.width)
+}
+
+// Depth returns the number of rows.
+func (s *countMin) Depth() int {
+	return int(s.depth)
+}
+
+// Total returns the sum of all counts added.
+func (s *countMin) Total() uint64 {
+	return s.total
+}
+
+// cells returns the index of v's counter in each row, by double hashing like bloom filters.
+func (s *countMin) cells(v containers.Value, cells []uint64) []uint64 {
+	h1 := s.hash(v)
+	h2 := hasher.Mix(h1) | 1
+	for i := uint64(0); i < s.depth; i++ {
+		cells = append(cells, i*s.width+(h1+i*h2)%s.width)
+	}
+
+	return cells
+}
+
+// Add counts v count times.
+func (s *countMin) Add(v containers.Value, count uint64) {
+	var buf [16]uint64
+	for _, c := range s.cells(v, buf[:0]) {
+		s.counters[c] += count
+	}
+	s.total += count
+}
+
+// AddConservative counts v count times with conservative update: counters are only raised as far as
+// the new estimate of v, which makes estimates of other values more accurate. Sketches updated this way
+// cannot handle negative counts, but are still merged with Merge.
+func (s *countMin) AddConservative(v containers.Value, count uint64) {
+	var buf [16]uint64
+	cells := s.cells(v, buf[:0])
+	estimate := s.min(cells) + count
+	for _, c := range cells {
+		if s.counters[c] < estimate {
+			s.counters[c] = estimate
+		}
+	}
+	s.total += count
+}
+
+// Estimate returns how many times v was added, or more.
+func (s *countMin) Estimate(v containers.Value) uint64 {
+	var buf [16]uint64
+	return s.min(s.cells(v, buf[:0]))
+}
+
+func (s *countMin) min(cells []uint64) uint64 {
+	min := uint64(math.MaxUint64)
+	for _, c := range cells {
+		if s.counters[c] < min {
+			min = s.counters[c]
+		}
+	}
+
+	return min
+}
+
+// Merge adds the counts of other, which must have the same size and hash function.
+// It returns ErrIncompatible if the sizes differ.
+func (s *countMin) Merge(other *countMin) error {
+	if s.width != other.width || s.depth != other.depth {
+		return ErrIncompatible
+	}
+
+	for i, c := range other.counters {
+		s.counters[i] += c
+	}
+	s.total += other.total
+	return nil
+}
+
+const countMinHeaderSize = 1 + 1 + 8 + 8 + 8 // version, kind, width, depth, total
+
+// MarshalBinary encodes the sketch, without its hash function.
+func (s *countMin) MarshalBinary() ([]byte, error) {
+	data := make([]byte, countMinHeaderSize+8*len(s.counters))
+	data[0], data[1] = formatVersion, kindCountMin
+	binary.LittleEndian.PutUint64(data[2:], s.width)
+	binary.LittleEndian.PutUint64(data[10:], s.depth)
+	binary.LittleEndian.PutUint64(data[18:], s.total)
+	for i, c := range s.counters {
+		binary.LittleEndian.PutUint64(data[countMinHeaderSize+8*i:], c)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary replaces the sketch with one encoded by MarshalBinary, keeping its hash function.
+func (s *countMin) UnmarshalBinary(data []byte) error {
+	if err := checkHeader(data, kindCountMin, countMinHeaderSize); err != nil {
+		return err
+	}
+	width, depth := binary.LittleEndian.Uint64(data[2:]), binary.LittleEndian.Uint64(data[10:])
+	size := uint64(len(data) - countMinHeaderSize)
+	if width == 0 || depth == 0 || size%8 != 0 || size/8%depth != 0 || size/8/depth != width {
+		return errors.Errorf("invalid sketch of %d rows of %d counters in %d bytes", depth, width, len(data))
+	}
+
+	s.width, s.depth = width, depth
+	s.total = binary.LittleEndian.Uint64(data[18:])
+	s.counters = make([]uint64, width*depth)
+	for i := range s.counters {
+		s.counters[i] = binary.LittleEndian.Uint64(data[countMinHeaderSize+8*i:])
+	}
+	return nil
+}
+
+// CountMinFromBinary returns a sketch encoded by MarshalBinary, with the hash function it was created with.
+func CountMinFromBinary(data []byte, hash hasher.Func) (*countMin, error) {
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	s := &countMin{hash: hash}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
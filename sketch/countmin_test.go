@@ -0,0 +1,180 @@
+package sketch
+
+import (
+	"testing"
+)
+
+func TestNewCountMin(t *testing.T) {
+	var testCases = map[string]struct {
+		epsilon, delta float64
+		width, depth   int
+		isErr          bool
+	}{
+		"onePercent":    {epsilon: 0.01, delta: 0.01, width: 272, depth: 5},
+		"tenthPercent":  {epsilon: 0.001, delta: 0.001, width: 2719, depth: 7},
+		"zeroEpsilon":   {epsilon: 0, delta: 0.01, isErr: true},
+		"zeroDelta":     {epsilon: 0.01, delta: 0, isErr: true},
+		"certainErrors": {epsilon: 0.01, delta: 1, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewCountMin(tc.epsilon, tc.delta, nil)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if err == nil && (s.Width() != tc.width || s.Depth() != tc.depth) {
+				t.Fatalf("want %d x %d counters, got= %d x %d", tc.depth, tc.width, s.Depth(), s.Width())
+			}
+		})
+	}
+
+	if _, err := NewCountMinWithSize(0, 1, nil); err == nil {
+		t.Fatalf("zero width: want error, got none")
+	}
+	if _, err := NewCountMinWithSize(1, 0, nil); err == nil {
+		t.Fatalf("zero depth: want error, got none")
+	}
+}
+
+// TestCountMinAccuracy checks the error bounds on a Zipfian stream,
+// and that conservative update overcounts less.
+func TestCountMinAccuracy(t *testing.T) {
+	const (
+		n       = 200000
+		epsilon = 0.001
+		delta   = 0.01
+	)
+	stream, counts := zipfStream(n, 1.1, 100000)
+
+	overcounts := map[bool]uint64{}
+	for _, conservative := range []bool{false, true} {
+		s, _ := NewCountMin(epsilon, delta, intHash)
+		for _, v := range stream {
+			if conservative {
+				s.AddConservative(v, 1)
+			} else {
+				s.Add(v, 1)
+			}
+		}
+		if want, got := uint64(n), s.Total(); want != got {
+			t.Fatalf("total: want= %v, got= %v", want, got)
+		}
+
+		beyondBound := 0
+		for v, count := range counts {
+			estimate := s.Estimate(v)
+			if estimate < count {
+				t.Fatalf("conservative=%v: want estimate of %d at least %d, got= %d", conservative, v, count, estimate)
+			}
+			if float64(estimate-count) > epsilon*n {
+				beyondBound++
+			}
+			overcounts[conservative] += estimate - count
+		}
+		if rate := float64(beyondBound) / float64(len(counts)); rate > delta {
+			t.Fatalf("conservative=%v: want at most %v of estimates beyond the bound, got= %v", conservative, delta, rate)
+		}
+	}
+
+	if overcounts[true] > overcounts[false]*2/3 {
+		t.Fatalf("want conservative update to overcount much less, got= %d, standard= %d", overcounts[true], overcounts[false])
+	}
+}
+
+func TestCountMinMerge(t *testing.T) {
+	a, _ := NewCountMinWithSize(100, 4, intHash)
+	b, _ := NewCountMinWithSize(100, 4, intHash)
+	for i := 0; i < 50; i++ {
+		a.Add(i, 2)
+		b.AddConservative(i+25, 3)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := uint64(250), a.Total(); want != got {
+		t.Fatalf("total: want= %v, got= %v", want, got)
+	}
+	for i := 0; i < 75; i++ {
+		want := uint64(0)
+		if i < 50 {
+			want += 2
+		}
+		if i >= 25 {
+			want += 3
+		}
+		if got := a.Estimate(i); got < want {
+			t.Fatalf("estimate of %d: want at least %d, got= %d", i, want, got)
+		}
+	}
+
+	other, _ := NewCountMinWithSize(100, 5, intHash)
+	if err := a.Merge(other); err != ErrIncompatible {
+		t.Fatalf("want= %v, got= %v", ErrIncompatible, err)
+	}
+}
+
+func TestCountMinMarshalBinary(t *testing.T) {
+	s, _ := NewCountMinWithSize(100, 4, intHash)
+	for i := 0; i < 1000; i++ {
+		s.Add(i%300, uint64(i))
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	decoded, err := CountMinFromBinary(data, intHash)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if decoded.Width() != s.Width() || decoded.Depth() != s.Depth() || decoded.Total() != s.Total() {
+		t.Fatalf("want same params, got= %d x %d, total %d", decoded.Depth(), decoded.Width(), decoded.Total())
+	}
+	for i := 0; i < 600; i++ {
+		if want, got := s.Estimate(i), decoded.Estimate(i); want != got {
+			t.Fatalf("estimate of %d: want= %v, got= %v", i, want, got)
+		}
+	}
+
+	hll, _ := NewHyperLogLog(4, nil)
+	hllData, _ := hll.MarshalBinary()
+	var testCases = map[string][]byte{
+		"empty":        nil,
+		"truncated":    data[:len(data)-1],
+		"wrongVersion": append([]byte{9}, data[1:]...),
+		"wrongKind":    hllData,
+		"zeroWidth":    append(append([]byte{}, data[:2]...), make([]byte, len(data)-2)...),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := CountMinFromBinary(data, intHash); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+}
+
+func BenchmarkCountMin(b *testing.B) {
+	s, _ := NewCountMin(0.001, 0.01, nil)
+	b.Run("add", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.Add(i, 1)
+		}
+	})
+	b.Run("addConservative", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.AddConservative(i, 1)
+		}
+	})
+	b.Run("estimate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.Estimate(i)
+		}
+	})
+}
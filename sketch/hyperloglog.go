@@ -0,0 +1,346 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+const (
+	minPrecision    = 4
+	maxPrecision    = 18
+	sparsePrecision = 25 // precision of the sparse representation
+	sparseRhoBits   = 6  // bits of a sparse entry holding its rho, which is at most 64 - sparsePrecision + 1
+)
+
+// hyperLogLog is a HyperLogLog++ sketch ("HyperLogLog in Practice" - Heule et al. 2013), which counts distinct values.
+// It starts with a sparse representation, a sorted list of hashes at a higher precision which is exact enough
+// for small cardinalities, and switches to 2^precision registers once those take less memory.
+// Instead of the empirical bias correction tables of HyperLogLog++, dense estimates use the improved estimator
+// of "New cardinality estimation algorithms for HyperLogLog sketches" - Ertl 2017, which is unbiased across all
+// cardinalities. It is not safe for concurrent use.
+type hyperLogLog struct {
+	p    uint
+	hash hasher.Func
+
+	sparse bool
+	list   []uint32 // sorted sparse entries, index<<sparseRhoBits | rho, one per index with the highest rho
+	buffer []uint32 // sparse entries added since the list was last merged
+
+	registers []uint8 // the highest rho of each index, when dense
+}
+
+// NewHyperLogLog returns a sketch with 2^precision registers, whose relative error is about 1.04 / sqrt(2^precision).
+// The precision must be in [4, 18]. A nil hash uses hasher.Default.
+func NewHyperLogLog(precision int, hash hasher.Func) (*hyperLogLog, error) {
+	if precision < minPrecision || precision > maxPrecision {
+		return nil, errors.Errorf("precision must be in [%d, %d], got %d", minPrecision, maxPrecision, precision)
+	}
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	return &hyperLogLog{p: uint(precision), hash: hash, sparse: true}, nil
+}
+
+// Precision returns the log2 of the number of registers.
+func (s *hyperLogLog) Precision() int {
+	return int(s.p)
+}
+
+// Sparse returns whether the sketch still uses the sparse representation.
+func (s *hyperLogLog) Sparse() bool {
+	return s.sparse
+}
+
+// Add counts v.
+func (s *hyperLogLog) Add(v containers.Value) {
+	h := s.hash(v)
+	if !s.sparse {
+		s.setRegister(h>>(64-s.p), rho(h, s.p))
+		return
+	}
+
+	s.buffer = append(s.buffer, uint32(h>>(64-sparsePrecision))<<sparseRhoBits|uint32(rho(h, sparsePrecision)))
+	if len(s.buffer) > s.maxSparse()/4 {
+		s.flush()
+	}
+}
+
+// rho returns the position of the first 1 bit of h after its first p bits, counting from 1.
+func rho(h uint64, p uint) uint8 {
+	return uint8(bits.LeadingZeros64(h<<p|1<<(p-1)) + 1)
+}
+
+// maxSparse returns the number of sparse entries which take as much memory as the registers.
+func (s *hyperLogLog) maxSparse() int {
+	return 1 << s.p / 4
+}
+
+func (s *hyperLogLog) setRegister(index uint64, rho uint8) {
+	if s.registers[index] < rho {
+		s.registers[index] = rho
+	}
+}
+
+// flush merges the buffer into the list, and switches to registers if the list got too big.
+func (s *hyperLogLog) flush() {
+	if len(s.buffer) == 0 {
+		return
+	}
+
+	s.list = mergeSparse(s.list, s.buffer)
+	s.buffer = s.buffer[:0]
+	if len(s.list) > s.maxSparse() {
+		s.toDense()
+	}
+}
+
+// mergeSparse returns the sorted entries of list and entries, with the highest rho per index.
+// list must be sorted with one entry per index.
+func mergeSparse(list, entries []uint32) []uint32 {
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+
+	merged := make([]uint32, 0, len(list)+len(entries))
+	for len(list) > 0 || len(entries) > 0 {
+		var e uint32
+		if len(entries) == 0 || (len(list) > 0 && list[0] < entries[0]) {
+			e, list = list[0], list[1:]
+		} else {
+			e, entries = entries[0], entries[1:]
+		}
+
+		// entries are sorted by index then rho, so the last entry of an index has the highest rho
+		if n := len(merged); n > 0 && merged[n-1]>>sparseRhoBits == e>>sparseRhoBits {
+			merged[n-1] = e
+			continue
+		}
+		merged = append(merged, e)
+	}
+
+	return merged
+}
+
+// toDense moves the sparse entries to registers.
+func (s *hyperLogLog) toDense() {
+	s.registers = make([]uint8, 1<<s.p)
+	s.sparse = false
+	s.addSparse(s.list)
+	s.addSparse(s.buffer)
+	s.list, s.buffer = nil, nil
+}
+
+// addSparse sets registers from sparse entries.
+func (s *hyperLogLog) addSparse(entries []uint32) {
+	for _, e := range entries {
+		index, r := uint64(e>>sparseRhoBits), uint8(e&(1<<sparseRhoBits-1))
+
+		// the sparse index holds the register index, followed by the first bits counted by the register's rho
+		extra := sparsePrecision - s.p
+		if rest := index & (1<<extra - 1); rest != 0 {
+			r = uint8(bits.LeadingZeros64(rest<<(64-extra)) + 1)
+		} else {
+			r += uint8(extra)
+		}
+		s.setRegister(index>>extra, r)
+	}
+}
+
+// Count returns the estimated number of distinct values added.
+func (s *hyperLogLog) Count() uint64 {
+	return uint64(math.Round(s.estimate()))
+}
+
+func (s *hyperLogLog) estimate() float64 {
+	if s.sparse {
+		s.flush()
+	}
+	if s.sparse { // linear counting is almost exact with so many registers
+		m := float64(uint64(1) << sparsePrecision)
+		return m * math.Log(m/(m-float64(len(s.list))))
+	}
+
+	q := 64 - s.p
+	counts := make([]int, q+2)
+	for _, r := range s.registers {
+		counts[r]++
+	}
+
+	m := float64(len(s.registers))
+	z := m * tau(1-float64(counts[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(counts[k]))
+	}
+	z += m * sigma(float64(counts[0])/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+// sigma and tau are the series of Ertl's estimator, which correct for empty and saturated registers.
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
+
+// Merge adds the values counted by other, which must have the same precision and hash function.
+// It returns ErrIncompatible if the precisions differ.
+func (s *hyperLogLog) Merge(other *hyperLogLog) error {
+	if s.p != other.p {
+		return ErrIncompatible
+	}
+
+	if !other.sparse {
+		if s.sparse {
+			s.toDense()
+		}
+		for i, r := range other.registers {
+			s.setRegister(uint64(i), r)
+		}
+		return nil
+	}
+
+	if !s.sparse {
+		s.addSparse(other.list)
+		s.addSparse(other.buffer)
+		return nil
+	}
+	s.buffer = append(s.buffer, other.list...)
+	s.buffer = append(s.buffer, other.buffer...)
+	s.flush()
+	return nil
+}
+
+const hyperLogLogHeaderSize = 1 + 1 + 1 + 1 // version, kind, precision, sparse
+
+// MarshalBinary encodes the sketch, without its hash function.
+// Sparse entries are encoded as varints of their difference with the previous one.
+func (s *hyperLogLog) MarshalBinary() ([]byte, error) {
+	data := []byte{formatVersion, kindHyperLogLog, byte(s.p), 0}
+	if !s.sparse {
+		return append(data, s.registers...), nil
+	}
+
+	s.flush()
+	if !s.sparse {
+		return s.MarshalBinary()
+	}
+	data[3] = 1
+	var buf [binary.MaxVarintLen32]byte
+	data = append(data, buf[:binary.PutUvarint(buf[:], uint64(len(s.list)))]...)
+	prev := uint32(0)
+	for _, e := range s.list {
+		data = append(data, buf[:binary.PutUvarint(buf[:], uint64(e-prev))]...)
+		prev = e
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary replaces the sketch with one encoded by MarshalBinary, keeping its hash function.
+func (s *hyperLogLog) UnmarshalBinary(data []byte) error {
+	if err := checkHeader(data, kindHyperLogLog, hyperLogLogHeaderSize); err != nil {
+		return err
+	}
+	p := uint(data[2])
+	if p < minPrecision || p > maxPrecision {
+		return errors.Errorf("invalid precision %d", p)
+	}
+	decoded := hyperLogLog{p: p, hash: s.hash, sparse: data[3] == 1}
+	data = data[hyperLogLogHeaderSize:]
+
+	if !decoded.sparse {
+		if len(data) != 1<<p {
+			return errors.Errorf("want %d registers, got %d", 1<<p, len(data))
+		}
+		for _, r := range data {
+			if uint(r) > 64-p+1 {
+				return errors.Errorf("invalid register %d", r)
+			}
+		}
+		decoded.registers = append([]uint8{}, data...)
+		*s = decoded
+		return nil
+	}
+
+	n, read := binary.Uvarint(data)
+	if read <= 0 || n > uint64(decoded.maxSparse()) {
+		return errors.New("invalid number of sparse entries")
+	}
+	data = data[read:]
+	decoded.list = make([]uint32, 0, n)
+	prev := uint64(0)
+	for i := uint64(0); i < n; i++ {
+		delta, read := binary.Uvarint(data)
+		if read <= 0 {
+			return errors.Errorf("cannot read sparse entry %d", i)
+		}
+		data = data[read:]
+		if delta > 1<<31 {
+			return errors.Errorf("invalid sparse entry delta %d", delta)
+		}
+
+		e := prev + delta
+		r := e & (1<<sparseRhoBits - 1)
+		if e >= 1<<(sparsePrecision+sparseRhoBits) || r == 0 || r > 64-sparsePrecision+1 || (i > 0 && delta == 0) {
+			return errors.Errorf("invalid sparse entry %d", e)
+		}
+		if i > 0 && e>>sparseRhoBits == prev>>sparseRhoBits {
+			return errors.Errorf("duplicate sparse index %d", e>>sparseRhoBits)
+		}
+		decoded.list = append(decoded.list, uint32(e))
+		prev = e
+	}
+	if len(data) != 0 {
+		return errors.Errorf("%d bytes left after sparse entries", len(data))
+	}
+
+	*s = decoded
+	return nil
+}
+
+// HyperLogLogFromBinary returns a sketch encoded by MarshalBinary, with the hash function it was created with.
+func HyperLogLogFromBinary(data []byte, hash hasher.Func) (*hyperLogLog, error) {
+	if hash == nil {
+		hash = hasher.Default
+	}
+
+	s := &hyperLogLog{hash: hash}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
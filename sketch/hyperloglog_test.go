@@ -0,0 +1,184 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNewHyperLogLog(t *testing.T) {
+	var testCases = map[string]struct {
+		precision int
+		isErr     bool
+	}{
+		"min":      {precision: 4},
+		"max":      {precision: 18},
+		"tooSmall": {precision: 3, isErr: true},
+		"tooBig":   {precision: 19, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewHyperLogLog(tc.precision, nil)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+// TestHyperLogLogAccuracy counts distinct values of Zipfian streams, where most values repeat a lot.
+func TestHyperLogLogAccuracy(t *testing.T) {
+	for _, precision := range []int{10, 14} {
+		for _, max := range []uint64{10, 1000, 100000, 2000000} {
+			t.Run(fmt.Sprintf("precision=%d/max=%d", precision, max), func(t *testing.T) {
+				stream, counts := zipfStream(1000000, 1.01, max)
+				s, _ := NewHyperLogLog(precision, intHash)
+				for _, v := range stream {
+					s.Add(v)
+				}
+
+				// sparse sketches are almost exact, dense ones are within a few standard errors
+				bound := 0.01
+				if !s.Sparse() {
+					bound = 3 * 1.04 / math.Sqrt(float64(uint64(1)<<uint(precision)))
+				}
+				distinct := float64(len(counts))
+				if got := float64(s.Count()); math.Abs(got-distinct) > bound*distinct {
+					t.Fatalf("want %v distinct values within %v, got= %v", distinct, bound, got)
+				}
+			})
+		}
+	}
+}
+
+// TestHyperLogLogSparse checks the switch to registers, which must not change estimates much.
+func TestHyperLogLogSparse(t *testing.T) {
+	s, _ := NewHyperLogLog(12, intHash)
+	maxSparse := s.maxSparse()
+
+	i := 0
+	for ; s.Sparse(); i++ {
+		s.Add(i)
+		if i < maxSparse && s.Count() != uint64(i+1) {
+			t.Fatalf("want sparse count exact, got= %d for %d values", s.Count(), i+1)
+		}
+	}
+	if i < maxSparse {
+		t.Fatalf("want sketch sparse up to %d values, got dense at %d", maxSparse, i)
+	}
+	if got := float64(s.Count()); math.Abs(got-float64(i)) > 0.05*float64(i) {
+		t.Fatalf("want dense count near %d, got= %v", i, got)
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	// a sketch counting 10 values stays sparse, one counting 100000 goes dense
+	for _, sizes := range [][2]int{{10, 10}, {10, 100000}, {100000, 10}, {100000, 100000}} {
+		t.Run(fmt.Sprintf("%d+%d", sizes[0], sizes[1]), func(t *testing.T) {
+			a, _ := NewHyperLogLog(10, intHash)
+			b, _ := NewHyperLogLog(10, intHash)
+			all, _ := NewHyperLogLog(10, intHash)
+			for i := 0; i < sizes[0]; i++ {
+				a.Add(i)
+				all.Add(i)
+			}
+			for i := sizes[0] / 2; i < sizes[0]/2+sizes[1]; i++ {
+				b.Add(i)
+				all.Add(i)
+			}
+			bCount := b.Count()
+
+			if err := a.Merge(b); err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if want, got := all.Count(), a.Count(); want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if want, got := bCount, b.Count(); want != got {
+				t.Fatalf("want merged sketch unchanged, want= %v, got= %v", want, got)
+			}
+		})
+	}
+
+	a, _ := NewHyperLogLog(10, intHash)
+	other, _ := NewHyperLogLog(11, intHash)
+	if err := a.Merge(other); err != ErrIncompatible {
+		t.Fatalf("want= %v, got= %v", ErrIncompatible, err)
+	}
+}
+
+func TestHyperLogLogMarshalBinary(t *testing.T) {
+	for _, n := range []int{0, 100, 100000} {
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			s, _ := NewHyperLogLog(10, intHash)
+			for i := 0; i < n; i++ {
+				s.Add(i)
+			}
+			data, err := s.MarshalBinary()
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+
+			decoded, err := HyperLogLogFromBinary(data, intHash)
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if decoded.Precision() != s.Precision() || decoded.Sparse() != s.Sparse() || decoded.Count() != s.Count() {
+				t.Fatalf("want same sketch, got= precision %d, sparse %v, count %d",
+					decoded.Precision(), decoded.Sparse(), decoded.Count())
+			}
+		})
+	}
+
+	sparse, _ := NewHyperLogLog(10, intHash)
+	dense, _ := NewHyperLogLog(10, intHash)
+	for i := 0; i < 100000; i++ {
+		if i < 100 {
+			sparse.Add(i)
+		}
+		dense.Add(i)
+	}
+	sparseData, _ := sparse.MarshalBinary()
+	denseData, _ := dense.MarshalBinary()
+	var testCases = map[string][]byte{
+		"empty":           nil,
+		"wrongPrecision":  append([]byte{sparseData[0], sparseData[1], 30}, sparseData[3:]...),
+		"truncatedSparse": sparseData[:len(sparseData)-1],
+		"trailingSparse":  append(append([]byte{}, sparseData...), 0),
+		"zeroSparseEntry": append([]byte{sparseData[0], sparseData[1], sparseData[2], 1}, 1, 0),
+		"truncatedDense":  denseData[:len(denseData)-1],
+		"invalidRegister": append(append([]byte{}, denseData[:len(denseData)-1]...), 64),
+		"tooManyEntries":  append([]byte{sparseData[0], sparseData[1], sparseData[2], 1}, 0xff, 0xff, 0x01),
+		"duplicateSparse": append([]byte{sparseData[0], sparseData[1], sparseData[2], 1}, 2, 1<<sparseRhoBits|1, 1),
+		"wrappingSparse":  append([]byte{sparseData[0], sparseData[1], sparseData[2], 1, 2, 1<<sparseRhoBits | 1}, uvarint(math.MaxUint64-63)...),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := HyperLogLogFromBinary(data, intHash); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+}
+
+func uvarint(x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return buf[:binary.PutUvarint(buf[:], x)]
+}
+
+func BenchmarkHyperLogLog(b *testing.B) {
+	for _, precision := range []int{10, 14} {
+		b.Run(fmt.Sprintf("precision=%d/add", precision), func(b *testing.B) {
+			s, _ := NewHyperLogLog(precision, nil)
+			for i := 0; i < b.N; i++ {
+				s.Add(i)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Package sketch provides streaming sketches, which summarize huge streams of containers.Value in little memory:
+// Count-Min for frequencies, HyperLogLog for cardinality and Space-Saving for the most frequent values.
+package sketch
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrIncompatible is returned when merging sketches which were created with different parameters.
+var ErrIncompatible = errors.New("sketches are incompatible")
+
+const formatVersion = 1
+
+// kinds of sketches in their binary encoding
+const (
+	kindCountMin = iota + 1
+	kindHyperLogLog
+	kindTopK
+)
+
+// checkHeader checks the version and kind at the start of an encoded sketch, which must be at least size bytes long.
+func checkHeader(data []byte, kind byte, size int) error {
+	if len(data) < size {
+		return errors.Errorf("data is too short: %d bytes", len(data))
+	}
+	if data[0] != formatVersion {
+		return errors.Errorf("unknown format version %d", data[0])
+	}
+	if data[1] != kind {
+		return errors.Errorf("want sketch kind %d, got %d", kind, data[1])
+	}
+
+	return nil
+}
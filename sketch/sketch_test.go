@@ -0,0 +1,28 @@
+package sketch
+
+import (
+	"math/rand"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/hasher"
+)
+
+// intHash hashes int values without going through hasher.Default.
+func intHash(v containers.Value) uint64 {
+	return hasher.Mix(uint64(v.(int)))
+}
+
+// zipfStream returns n values in [0, max), where value i is about (i+1)^s times rarer than 0,
+// and how many times each value occurs.
+func zipfStream(n int, s float64, max uint64) ([]int, map[int]uint64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), s, 1, max-1)
+	stream := make([]int, n)
+	counts := map[int]uint64{}
+	for i := range stream {
+		v := int(z.Uint64())
+		stream[i] = v
+		counts[v]++
+	}
+
+	return stream, counts
+}
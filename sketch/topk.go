@@ -0,0 +1,265 @@
+package sketch
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+)
+
+// Item is a value tracked by a top-K sketch.
+type Item struct {
+	Value containers.Value
+	// Count is at least the number of times Value was added.
+	Count uint64
+	// Error is how much Count may overcount: Value was added at least Count - Error times.
+	Error uint64
+}
+
+// counter is an item in the heap of a top-K sketch.
+type counter struct {
+	Item
+	index int // in the heap
+}
+
+// counterHeap is a min-heap of counters by count, implementing heap.Interface.
+type counterHeap []*counter
+
+func (h counterHeap) Len() int { return len(h) }
+
+func (h counterHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+
+func (h counterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *counterHeap) Push(x interface{}) {
+	c := x.(*counter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *counterHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return c
+}
+
+// MaxK is the largest number of counters of a top-K sketch.
+const MaxK = 1 << 24
+
+// topK tracks the most frequent values of a stream with k counters, using Space-Saving
+// ("Efficient Computation of Frequent and Top-k Elements in Data Streams" - Metwally et al. 2005):
+// a new value replaces the value with the lowest count, and inherits its count as error.
+// Any value added more than Total() / k times is tracked. It is not safe for concurrent use.
+type topK struct {
+	k        int
+	codec    codec.Codec
+	counters map[containers.Value]*counter
+	heap     counterHeap
+	total    uint64
+}
+
+// NewTopK returns a top-K sketch with k counters. Values must be comparable,
+// and are serialized with c, or codec.Gob if nil.
+func NewTopK(k int, c codec.Codec) (*topK, error) {
+	if k <= 0 || k > MaxK {
+		return nil, errors.Errorf("k must be in [1, %d], got %d", MaxK, k)
+	}
+	if c == nil {
+		c = codec.Gob{}
+	}
+
+	return newTopK(k, k, c), nil
+}
+
+// newTopK returns a top-K sketch with room for size values.
+func newTopK(k, size int, c codec.Codec) *topK {
+	return &topK{
+		k:        k,
+		codec:    c,
+		counters: make(map[containers.Value]*counter, size),
+		heap:     make(counterHeap, 0, size),
+	}
+}
+
+// K returns the number of counters.
+func (s *topK) K() int {
+	return s.k
+}
+
+// Len returns the number of values tracked.
+func (s *topK) Len() int {
+	return len(s.heap)
+}
+
+// Total returns the sum of all counts added.
+func (s *topK) Total() uint64 {
+	return s.total
+}
+
+// Add counts v count times.
+func (s *topK) Add(v containers.Value, count uint64) {
+	s.total += count
+	if c, ok := s.counters[v]; ok {
+		c.Count += count
+		heap.Fix(&s.heap, c.index)
+		return
+	}
+
+	if len(s.heap) < s.k {
+		c := &counter{Item: Item{Value: v, Count: count}}
+		heap.Push(&s.heap, c)
+		s.counters[v] = c
+		return
+	}
+
+	min := s.heap[0]
+	delete(s.counters, min.Value)
+	min.Value, min.Error = v, min.Count
+	min.Count += count
+	s.counters[v] = min
+	heap.Fix(&s.heap, 0)
+}
+
+// Lookup returns the item of v, and false if v is not tracked.
+func (s *topK) Lookup(v containers.Value) (Item, bool) {
+	c, ok := s.counters[v]
+	if !ok {
+		return Item{}, false
+	}
+
+	return c.Item, true
+}
+
+// Top returns the n items with the highest counts, from the highest. Items with equal counts are ordered
+// from the lowest error.
+func (s *topK) Top(n int) []Item {
+	items := make([]Item, len(s.heap))
+	for i, c := range s.heap {
+		items[i] = c.Item
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count == items[j].Count {
+			return items[i].Error < items[j].Error
+		}
+		return items[i].Count > items[j].Count
+	})
+
+	if n < 0 {
+		n = 0
+	}
+	if n < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+// tracked returns whether v has a counter, or an error if v is not comparable, e.g. when decoded by a codec.
+func (s *topK) tracked(v containers.Value) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("value %v is not comparable", v)
+		}
+	}()
+
+	_, ok = s.counters[v]
+	return ok, nil
+}
+
+const topKHeaderSize = 1 + 1 + 4 + 8 + 4 // version, kind, k, total, number of items
+
+// MarshalBinary encodes the sketch, with values encoded by its codec.
+func (s *topK) MarshalBinary() ([]byte, error) {
+	data := make([]byte, topKHeaderSize)
+	data[0], data[1] = formatVersion, kindTopK
+	binary.LittleEndian.PutUint32(data[2:], uint32(s.k))
+	binary.LittleEndian.PutUint64(data[6:], s.total)
+	binary.LittleEndian.PutUint32(data[14:], uint32(len(s.heap)))
+
+	var buf [8 + 8 + 4]byte // count, error, length of the value
+	for _, c := range s.heap {
+		value, err := s.codec.Encode(c.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot encode %v", c.Value)
+		}
+
+		binary.LittleEndian.PutUint64(buf[0:], c.Count)
+		binary.LittleEndian.PutUint64(buf[8:], c.Error)
+		binary.LittleEndian.PutUint32(buf[16:], uint32(len(value)))
+		data = append(data, buf[:]...)
+		data = append(data, value...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary replaces the sketch with one encoded by MarshalBinary, keeping its codec.
+func (s *topK) UnmarshalBinary(data []byte) error {
+	if err := checkHeader(data, kindTopK, topKHeaderSize); err != nil {
+		return err
+	}
+	k, n := int(binary.LittleEndian.Uint32(data[2:])), int(binary.LittleEndian.Uint32(data[14:]))
+	if k == 0 || k > MaxK || n > k {
+		return errors.Errorf("invalid sketch with %d counters and %d items", k, n)
+	}
+	if itemSize := 8 + 8 + 4; n > (len(data)-topKHeaderSize)/itemSize { // before allocating for n items
+		return errors.Errorf("want %d items, got %d bytes", n, len(data)-topKHeaderSize)
+	}
+
+	decoded := newTopK(k, n, s.codec)
+	decoded.total = binary.LittleEndian.Uint64(data[6:])
+	data = data[topKHeaderSize:]
+	for i := 0; i < n; i++ {
+		if len(data) < 8+8+4 {
+			return errors.Errorf("cannot read item %d", i)
+		}
+		count, errBound, size := binary.LittleEndian.Uint64(data[0:]), binary.LittleEndian.Uint64(data[8:]),
+			binary.LittleEndian.Uint32(data[16:])
+		data = data[8+8+4:]
+		if uint64(len(data)) < uint64(size) {
+			return errors.Errorf("cannot read value of item %d", i)
+		}
+		v, err := s.codec.Decode(data[:size])
+		if err != nil {
+			return errors.Wrapf(err, "cannot decode value of item %d", i)
+		}
+		data = data[size:]
+
+		if tracked, err := decoded.tracked(v); err != nil {
+			return errors.Wrapf(err, "cannot track value of item %d", i)
+		} else if tracked {
+			return errors.Errorf("duplicate value %v", v)
+		}
+		c := &counter{Item: Item{Value: v, Count: count, Error: errBound}}
+		heap.Push(&decoded.heap, c)
+		decoded.counters[v] = c
+	}
+	if len(data) != 0 {
+		return errors.Errorf("%d bytes left after items", len(data))
+	}
+
+	*s = *decoded
+	return nil
+}
+
+// TopKFromBinary returns a sketch encoded by MarshalBinary, decoding values with c, or codec.Gob if nil.
+func TopKFromBinary(data []byte, c codec.Codec) (*topK, error) {
+	if c == nil {
+		c = codec.Gob{}
+	}
+
+	s := &topK{codec: c}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,168 @@
+package sketch
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+)
+
+func TestNewTopK(t *testing.T) {
+	if _, err := NewTopK(0, nil); err == nil {
+		t.Fatalf("want error, got none")
+	}
+	if _, err := NewTopK(MaxK+1, nil); err == nil {
+		t.Fatalf("want error for more than MaxK counters, got none")
+	}
+	if _, err := NewTopK(1, nil); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+}
+
+func TestTopKReplacesLowestCount(t *testing.T) {
+	s, _ := NewTopK(3, nil)
+	for _, v := range []string{"a", "a", "b", "a", "b", "c", "d"} {
+		s.Add(v, 1)
+	}
+
+	want := []Item{{"a", 3, 0}, {"b", 2, 0}, {"d", 2, 1}}
+	if got := s.Top(5); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if _, ok := s.Lookup("c"); ok {
+		t.Fatalf("want replaced value untracked")
+	}
+	if item, ok := s.Lookup("a"); !ok || item != want[0] {
+		t.Fatalf("want= %v, got= %v, %v", want[0], item, ok)
+	}
+	if got := s.Top(-1); len(got) != 0 {
+		t.Fatalf("want no items for a negative n, got= %v", got)
+	}
+	if want, got := 2, len(s.Top(2)); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+// TestTopKAccuracy checks the guarantees of Space-Saving on a Zipfian stream.
+func TestTopKAccuracy(t *testing.T) {
+	const (
+		n = 200000
+		k = 100
+	)
+	stream, counts := zipfStream(n, 1.1, 100000)
+	s, _ := NewTopK(k, nil)
+	for _, v := range stream {
+		s.Add(v, 1)
+	}
+	if want, got := uint64(n), s.Total(); want != got {
+		t.Fatalf("total: want= %v, got= %v", want, got)
+	}
+
+	for _, item := range s.Top(k) {
+		count := counts[item.Value.(int)]
+		if item.Count < count || item.Count-item.Error > count {
+			t.Fatalf("want count of %v in [%d, %d], got= %d", item.Value, item.Count-item.Error, item.Count, count)
+		}
+	}
+	for v, count := range counts {
+		if _, ok := s.Lookup(v); count > n/k && !ok {
+			t.Fatalf("want value %d added %d times tracked", v, count)
+		}
+	}
+
+	values := make([]int, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return counts[values[i]] > counts[values[j]] })
+	var top []int
+	for _, item := range s.Top(10) {
+		top = append(top, item.Value.(int))
+	}
+	if want, got := values[:10], top; !cmp.Equal(want, got) {
+		t.Fatalf("top 10: want= %v, got= %v", want, got)
+	}
+}
+
+func TestTopKMarshalBinary(t *testing.T) {
+	s, _ := NewTopK(3, codec.String{})
+	for _, v := range []string{"a", "a", "b", "a", "b", "c", "d"} {
+		s.Add(v, 1)
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+
+	decoded, err := TopKFromBinary(data, codec.String{})
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if decoded.K() != s.K() || decoded.Total() != s.Total() {
+		t.Fatalf("want same params, got= k %d, total %d", decoded.K(), decoded.Total())
+	}
+	if want, got := s.Top(3), decoded.Top(3); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	decoded.Add("e", 1)
+	if item, _ := decoded.Lookup("e"); item != (Item{"e", 3, 2}) {
+		t.Fatalf("want decoded heap to replace the lowest count, got= %v", item)
+	}
+
+	ints, _ := NewTopK(2, nil)
+	ints.Add(42, 1)
+	if _, err := ints.MarshalBinary(); err != nil {
+		t.Fatalf("gob: want no error, got %q", err)
+	}
+	s.Add(42, 10) // not a string
+	if _, err := s.MarshalBinary(); err == nil {
+		t.Fatalf("want error encoding an int as a string, got none")
+	}
+
+	cm, _ := NewCountMinWithSize(1, 1, nil)
+	cmData, _ := cm.MarshalBinary()
+	var testCases = map[string][]byte{
+		"empty":        nil,
+		"truncated":    data[:len(data)-1],
+		"trailing":     append(append([]byte{}, data...), 0),
+		"wrongVersion": append([]byte{9}, data[1:]...),
+		"wrongKind":    cmData,
+		"tooManyItems": append(append(append([]byte{}, data[:14]...), 4, 0, 0, 0), data[18:]...),
+		"hugeK":        append(append(append([]byte{}, data[:2]...), 0xff, 0xff, 0xff, 0xff), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0),
+		"hugeItems":    append(append([]byte{}, data[:2]...), 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := TopKFromBinary(data, codec.String{}); err == nil {
+				t.Fatalf("want error, got none")
+			}
+		})
+	}
+
+	if _, err := TopKFromBinary(data, bytesCodec{}); err == nil {
+		t.Fatalf("want error for values which are not comparable, got none")
+	}
+}
+
+// bytesCodec decodes values as byte slices, which are not comparable.
+type bytesCodec struct{}
+
+func (bytesCodec) Encode(v containers.Value) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (bytesCodec) Decode(data []byte) (containers.Value, error) {
+	return append([]byte{}, data...), nil
+}
+
+func BenchmarkTopK(b *testing.B) {
+	stream, _ := zipfStream(1<<16, 1.1, 1<<20)
+	s, _ := NewTopK(100, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(stream[i&(1<<16-1)], 1)
+	}
+}
@@ -0,0 +1,233 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/bitsgofer/containers"
+)
+
+// concurrentNode is a key in a concurrent skip list. next[i] (*concurrentNode) and value (*containers.Value)
+// are read atomically, so readers never need a lock.
+type concurrentNode struct {
+	key   containers.Value
+	value unsafe.Pointer
+	next  []unsafe.Pointer
+}
+
+func newConcurrentNode(key, value containers.Value, level int) *concurrentNode {
+	return &concurrentNode{
+		key:   key,
+		value: unsafe.Pointer(&value),
+		next:  make([]unsafe.Pointer, level),
+	}
+}
+
+func (n *concurrentNode) loadNext(i int) *concurrentNode {
+	return (*concurrentNode)(atomic.LoadPointer(&n.next[i]))
+}
+
+func (n *concurrentNode) storeNext(i int, next *concurrentNode) {
+	atomic.StorePointer(&n.next[i], unsafe.Pointer(next))
+}
+
+func (n *concurrentNode) loadValue() containers.Value {
+	return *(*containers.Value)(atomic.LoadPointer(&n.value))
+}
+
+// concurrentSkipList is an ordered map on a skip list for one writer and many readers at a time.
+// Writers take a lock, readers never block: a node is linked bottom-up, so it is visible to all readers
+// once it is at level 0, and a deleted node keeps its links, so readers standing on it can move on.
+//
+// Iterations are weakly consistent: they see each key at most once in order, and may or may not see
+// changes made after they started. There are no spans, so it has no rank queries.
+type concurrentSkipList struct {
+	less func(a, b containers.Value) bool
+
+	mu     sync.Mutex // serializes writers
+	levels levelGenerator
+
+	head   *concurrentNode
+	level  int32 // accessed atomically
+	length int64 // accessed atomically
+}
+
+// NewConcurrent returns an empty skip list whose keys are ordered by less, safe for concurrent use.
+// Node levels are picked with src, which can be seeded for reproducible layouts. A nil src is seeded with the time.
+func NewConcurrent(less func(a, b containers.Value) bool, src rand.Source) *concurrentSkipList {
+	return &concurrentSkipList{
+		less:   less,
+		levels: newLevelGenerator(src),
+		head:   newConcurrentNode(nil, nil, MaxLevel),
+		level:  1,
+	}
+}
+
+// Len returns the number of keys.
+func (s *concurrentSkipList) Len() int {
+	return int(atomic.LoadInt64(&s.length))
+}
+
+func (s *concurrentSkipList) equal(n *concurrentNode, key containers.Value) bool {
+	return n != nil && !s.less(key, n.key)
+}
+
+// findLess returns the last node whose key is less than key (the head if there is none),
+// and the predecessor at each level if update is not nil.
+func (s *concurrentSkipList) findLess(key containers.Value, update *[MaxLevel]*concurrentNode) *concurrentNode {
+	x := s.head
+	for i := int(atomic.LoadInt32(&s.level)) - 1; i >= 0; i-- {
+		for next := x.loadNext(i); next != nil && s.less(next.key, key); next = x.loadNext(i) {
+			x = next
+		}
+		if update != nil {
+			update[i] = x
+		}
+	}
+
+	return x
+}
+
+// findLessOrEqual returns the last node whose key is not greater than key, or the head if there is none.
+func (s *concurrentSkipList) findLessOrEqual(key containers.Value) *concurrentNode {
+	x := s.head
+	for i := int(atomic.LoadInt32(&s.level)) - 1; i >= 0; i-- {
+		for next := x.loadNext(i); next != nil && !s.less(key, next.key); next = x.loadNext(i) {
+			x = next
+		}
+	}
+
+	return x
+}
+
+// Insert sets the value of key. It returns whether key was already there, in which case its value is replaced.
+func (s *concurrentSkipList) Insert(key, value containers.Value) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var update [MaxLevel]*concurrentNode
+	x := s.findLess(key, &update)
+	if next := x.loadNext(0); s.equal(next, key) {
+		atomic.StorePointer(&next.value, unsafe.Pointer(&value))
+		return true
+	}
+
+	level := s.levels.next()
+	current := int(s.level) // only writers change it
+	for i := current; i < level; i++ {
+		update[i] = s.head
+	}
+
+	n := newConcurrentNode(key, value, level)
+	for i := 0; i < level; i++ {
+		n.next[i] = update[i].next[i] // n is not visible yet
+		update[i].storeNext(i, n)
+	}
+	if level > current {
+		atomic.StoreInt32(&s.level, int32(level))
+	}
+	atomic.AddInt64(&s.length, 1)
+
+	return false
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (s *concurrentSkipList) Delete(key containers.Value) (containers.Value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var update [MaxLevel]*concurrentNode
+	x := s.findLess(key, &update).loadNext(0)
+	if !s.equal(x, key) {
+		return nil, false
+	}
+
+	// unlink top-down, so x disappears from level 0 last
+	for i := len(x.next) - 1; i >= 0; i-- {
+		update[i].storeNext(i, x.loadNext(i))
+	}
+	level := s.level
+	for level > 1 && s.head.loadNext(int(level)-1) == nil {
+		level--
+	}
+	atomic.StoreInt32(&s.level, level)
+	atomic.AddInt64(&s.length, -1)
+
+	return x.loadValue(), true
+}
+
+// Get returns the value of key, and whether key is there.
+func (s *concurrentSkipList) Get(key containers.Value) (containers.Value, bool) {
+	x := s.findLess(key, nil).loadNext(0)
+	if !s.equal(x, key) {
+		return nil, false
+	}
+
+	return x.loadValue(), true
+}
+
+// entry returns the key and value of n, unless it is nil or the head.
+func (s *concurrentSkipList) entry(n *concurrentNode) (containers.Value, containers.Value, bool) {
+	if n == nil || n == s.head {
+		return nil, nil, false
+	}
+
+	return n.key, n.loadValue(), true
+}
+
+// Floor returns the greatest key not greater than key, and its value.
+func (s *concurrentSkipList) Floor(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLessOrEqual(key))
+}
+
+// Ceiling returns the least key not less than key, and its value.
+func (s *concurrentSkipList) Ceiling(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLess(key, nil).loadNext(0))
+}
+
+// Lower returns the greatest key less than key, and its value.
+func (s *concurrentSkipList) Lower(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLess(key, nil))
+}
+
+// Higher returns the least key greater than key, and its value.
+func (s *concurrentSkipList) Higher(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLessOrEqual(key).loadNext(0))
+}
+
+// First returns the least key and its value.
+func (s *concurrentSkipList) First() (containers.Value, containers.Value, bool) {
+	return s.entry(s.head.loadNext(0))
+}
+
+// Last returns the greatest key and its value.
+func (s *concurrentSkipList) Last() (containers.Value, containers.Value, bool) {
+	x := s.head
+	for i := int(atomic.LoadInt32(&s.level)) - 1; i >= 0; i-- {
+		for next := x.loadNext(i); next != nil; next = x.loadNext(i) {
+			x = next
+		}
+	}
+
+	return s.entry(x)
+}
+
+// Ascend calls fn on keys in order, until fn returns false.
+func (s *concurrentSkipList) Ascend(fn func(key, value containers.Value) bool) {
+	for x := s.head.loadNext(0); x != nil; x = x.loadNext(0) {
+		if !fn(x.key, x.loadValue()) {
+			return
+		}
+	}
+}
+
+// Range calls fn on keys in [from, to) in order, until fn returns false.
+func (s *concurrentSkipList) Range(from, to containers.Value, fn func(key, value containers.Value) bool) {
+	for x := s.findLess(from, nil).loadNext(0); x != nil && s.less(x.key, to); x = x.loadNext(0) {
+		if !fn(x.key, x.loadValue()) {
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package skiplist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bitsgofer/containers"
+)
+
+// TestConcurrentReadersAndWriter has readers look up and iterate while a writer inserts and deletes.
+// Even keys are never deleted, so readers must always find them. Run it with -race.
+func TestConcurrentReadersAndWriter(t *testing.T) {
+	const (
+		keys    = 2000
+		readers = 4
+		writes  = 20000
+	)
+
+	s := NewConcurrent(intLess, rand.NewSource(1))
+	for k := 0; k < keys; k += 2 {
+		s.Insert(k, k)
+	}
+
+	var (
+		done     int32
+		wg       sync.WaitGroup
+		failures = make(chan string, readers)
+	)
+	wg.Add(readers)
+	for r := 0; r < readers; r++ {
+		go func(r int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(r)))
+			for atomic.LoadInt32(&done) == 0 {
+				k := rng.Intn(keys/2) * 2
+				if val, ok := s.Get(k); !ok || val != k {
+					failures <- "missing even key"
+					return
+				}
+				if key, _, ok := s.Floor(k + 1); !ok || key.(int) < k {
+					failures <- "floor is less than an even key"
+					return
+				}
+
+				prev, seen, ordered := -1, 0, true
+				s.Range(k, k+100, func(key, value containers.Value) bool {
+					if key.(int) <= prev {
+						ordered = false
+						return false
+					}
+					if key.(int)%2 == 0 {
+						seen++
+					}
+					prev = key.(int)
+					return true
+				})
+				if !ordered {
+					failures <- "keys not in order"
+					return
+				}
+				if k+100 <= keys && seen != 50 { // 50 even keys in [k, k+100)
+					failures <- "range missed even keys"
+					return
+				}
+				runtime.Gosched()
+			}
+		}(r)
+	}
+
+	rng := rand.New(rand.NewSource(99))
+	for i := 0; i < writes; i++ {
+		k := rng.Intn(keys/2)*2 + 1
+		if rng.Intn(2) == 0 {
+			s.Insert(k, k)
+		} else {
+			s.Delete(k)
+		}
+		if i%100 == 0 {
+			runtime.Gosched()
+		}
+	}
+	atomic.StoreInt32(&done, 1)
+	wg.Wait()
+	close(failures)
+
+	for err := range failures {
+		t.Fatalf("reader: %s", err)
+	}
+
+	odd := 0
+	s.Ascend(func(key, value containers.Value) bool {
+		if key.(int)%2 == 1 {
+			odd++
+		}
+		return true
+	})
+	if want, got := keys/2+odd, s.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
@@ -0,0 +1,283 @@
+// Package skiplist provides ordered maps on probabilistic skip lists
+// ("Skip Lists: A Probabilistic Alternative to Balanced Trees" - Pugh 1990).
+package skiplist
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/bitsgofer/containers"
+)
+
+const (
+	// MaxLevel is the maximum number of levels of a skip list, enough for 4^MaxLevel keys.
+	MaxLevel = 32
+	// a node is promoted to the next level with probability 1/branching.
+	branching = 4
+)
+
+// levelGenerator picks random levels for new nodes.
+type levelGenerator struct {
+	rng *rand.Rand
+}
+
+// newLevelGenerator returns a levelGenerator using src, or a time-seeded source if src is nil.
+func newLevelGenerator(src rand.Source) levelGenerator {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+
+	return levelGenerator{rng: rand.New(src)}
+}
+
+// next returns a level in [1, MaxLevel], where level k+1 is branching times less likely than k.
+func (g levelGenerator) next() int {
+	level := 1
+	for level < MaxLevel && g.rng.Int63()%branching == 0 {
+		level++
+	}
+
+	return level
+}
+
+// node is a key in a skip list. span[i] is the number of level 0 links between the node and next[i].
+type node struct {
+	key   containers.Value
+	value containers.Value
+	next  []*node
+	span  []int
+}
+
+func newNode(key, value containers.Value, level int) *node {
+	return &node{
+		key:   key,
+		value: value,
+		next:  make([]*node, level),
+		span:  make([]int, level),
+	}
+}
+
+// skipList is an ordered map on a skip list. It is not safe for concurrent use.
+type skipList struct {
+	less   func(a, b containers.Value) bool
+	levels levelGenerator
+
+	head   *node // sentinel before the first key, with MaxLevel levels
+	level  int   // number of levels in use
+	length int
+}
+
+// New returns an empty skip list whose keys are ordered by less.
+// Node levels are picked with src, which can be seeded for reproducible layouts. A nil src is seeded with the time.
+func New(less func(a, b containers.Value) bool, src rand.Source) *skipList {
+	return &skipList{
+		less:   less,
+		levels: newLevelGenerator(src),
+		head:   newNode(nil, nil, MaxLevel),
+		level:  1,
+	}
+}
+
+// Len returns the number of keys.
+func (s *skipList) Len() int {
+	return s.length
+}
+
+// equal returns whether n holds key. n may be nil.
+func (s *skipList) equal(n *node, key containers.Value) bool {
+	return n != nil && !s.less(key, n.key)
+}
+
+// findLess returns the last node whose key is less than key (the head if there is none),
+// the predecessor at each level, and the rank of each predecessor (the head has rank 0).
+func (s *skipList) findLess(key containers.Value, update *[MaxLevel]*node, rank *[MaxLevel]int) *node {
+	x, r := s.head, 0
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && s.less(x.next[i].key, key) {
+			r += x.span[i]
+			x = x.next[i]
+		}
+		if update != nil {
+			update[i], rank[i] = x, r
+		}
+	}
+
+	return x
+}
+
+// findLessOrEqual returns the last node whose key is not greater than key, or the head if there is none.
+func (s *skipList) findLessOrEqual(key containers.Value) *node {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && !s.less(key, x.next[i].key) {
+			x = x.next[i]
+		}
+	}
+
+	return x
+}
+
+// Insert sets the value of key. It returns whether key was already there, in which case its value is replaced.
+func (s *skipList) Insert(key, value containers.Value) bool {
+	var update [MaxLevel]*node
+	var rank [MaxLevel]int
+	x := s.findLess(key, &update, &rank)
+	if next := x.next[0]; s.equal(next, key) {
+		next.value = value
+		return true
+	}
+
+	level := s.levels.next()
+	for i := s.level; i < level; i++ {
+		update[i], rank[i] = s.head, 0
+		s.head.span[i] = s.length
+	}
+	if level > s.level {
+		s.level = level
+	}
+
+	n := newNode(key, value, level)
+	for i := 0; i < level; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+
+		// update[i] -> n is rank[0]-rank[i]+1 links, n -> next takes the rest
+		n.span[i] = update[i].span[i] - (rank[0] - rank[i])
+		update[i].span[i] = rank[0] - rank[i] + 1
+	}
+	for i := level; i < s.level; i++ {
+		update[i].span[i]++
+	}
+	s.length++
+
+	return false
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+func (s *skipList) Delete(key containers.Value) (containers.Value, bool) {
+	var update [MaxLevel]*node
+	var rank [MaxLevel]int
+	x := s.findLess(key, &update, &rank).next[0]
+	if !s.equal(x, key) {
+		return nil, false
+	}
+
+	for i := 0; i < s.level; i++ {
+		if update[i].next[i] == x {
+			update[i].span[i] += x.span[i] - 1
+			update[i].next[i] = x.next[i]
+		} else {
+			update[i].span[i]--
+		}
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.head.span[s.level-1] = 0
+		s.level--
+	}
+	s.length--
+
+	return x.value, true
+}
+
+// Get returns the value of key, and whether key is there.
+func (s *skipList) Get(key containers.Value) (containers.Value, bool) {
+	x := s.findLess(key, nil, nil).next[0]
+	if !s.equal(x, key) {
+		return nil, false
+	}
+
+	return x.value, true
+}
+
+// entry returns the key and value of n, unless it is nil or the head.
+func (s *skipList) entry(n *node) (containers.Value, containers.Value, bool) {
+	if n == nil || n == s.head {
+		return nil, nil, false
+	}
+
+	return n.key, n.value, true
+}
+
+// Floor returns the greatest key not greater than key, and its value.
+func (s *skipList) Floor(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLessOrEqual(key))
+}
+
+// Ceiling returns the least key not less than key, and its value.
+func (s *skipList) Ceiling(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLess(key, nil, nil).next[0])
+}
+
+// Lower returns the greatest key less than key, and its value.
+func (s *skipList) Lower(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLess(key, nil, nil))
+}
+
+// Higher returns the least key greater than key, and its value.
+func (s *skipList) Higher(key containers.Value) (containers.Value, containers.Value, bool) {
+	return s.entry(s.findLessOrEqual(key).next[0])
+}
+
+// First returns the least key and its value.
+func (s *skipList) First() (containers.Value, containers.Value, bool) {
+	return s.entry(s.head.next[0])
+}
+
+// Last returns the greatest key and its value.
+func (s *skipList) Last() (containers.Value, containers.Value, bool) {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil {
+			x = x.next[i]
+		}
+	}
+
+	return s.entry(x)
+}
+
+// Rank returns the number of keys less than key, and whether key is there.
+func (s *skipList) Rank(key containers.Value) (int, bool) {
+	var update [MaxLevel]*node
+	var rank [MaxLevel]int
+	x := s.findLess(key, &update, &rank)
+
+	return rank[0], s.equal(x.next[0], key)
+}
+
+// At returns the key with rank i (i.e. the i-th least key, from 0) and its value.
+func (s *skipList) At(i int) (containers.Value, containers.Value, bool) {
+	if i < 0 || i >= s.length {
+		return nil, nil, false
+	}
+
+	x, traversed := s.head, 0
+	for l := s.level - 1; l >= 0; l-- {
+		for x.next[l] != nil && traversed+x.span[l] <= i+1 {
+			traversed += x.span[l]
+			x = x.next[l]
+		}
+		if traversed == i+1 {
+			break
+		}
+	}
+
+	return s.entry(x)
+}
+
+// Ascend calls fn on keys in order, until fn returns false.
+func (s *skipList) Ascend(fn func(key, value containers.Value) bool) {
+	for x := s.head.next[0]; x != nil; x = x.next[0] {
+		if !fn(x.key, x.value) {
+			return
+		}
+	}
+}
+
+// Range calls fn on keys in [from, to) in order, until fn returns false.
+func (s *skipList) Range(from, to containers.Value, fn func(key, value containers.Value) bool) {
+	for x := s.findLess(from, nil, nil).next[0]; x != nil && s.less(x.key, to); x = x.next[0] {
+		if !fn(x.key, x.value) {
+			return
+		}
+	}
+}
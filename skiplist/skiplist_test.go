@@ -0,0 +1,393 @@
+package skiplist
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// orderedMap is implemented by both skip lists, so they share tests.
+type orderedMap interface {
+	Len() int
+	Insert(key, value containers.Value) bool
+	Delete(key containers.Value) (containers.Value, bool)
+	Get(key containers.Value) (containers.Value, bool)
+	Floor(key containers.Value) (containers.Value, containers.Value, bool)
+	Ceiling(key containers.Value) (containers.Value, containers.Value, bool)
+	Lower(key containers.Value) (containers.Value, containers.Value, bool)
+	Higher(key containers.Value) (containers.Value, containers.Value, bool)
+	First() (containers.Value, containers.Value, bool)
+	Last() (containers.Value, containers.Value, bool)
+	Ascend(fn func(key, value containers.Value) bool)
+	Range(from, to containers.Value, fn func(key, value containers.Value) bool)
+}
+
+var implementations = map[string]func(src rand.Source) orderedMap{
+	"skipList": func(src rand.Source) orderedMap {
+		return New(intLess, src)
+	},
+	"concurrentSkipList": func(src rand.Source) orderedMap {
+		return NewConcurrent(intLess, src)
+	},
+}
+
+func intLess(a, b containers.Value) bool {
+	return a.(int) < b.(int)
+}
+
+// keys returns all keys of m in iteration order.
+func keys(m orderedMap) []containers.Value {
+	var ks []containers.Value
+	m.Ascend(func(key, value containers.Value) bool {
+		ks = append(ks, key)
+		return true
+	})
+
+	return ks
+}
+
+func TestInsertGetDelete(t *testing.T) {
+	for name, newMap := range implementations {
+		t.Run(name, func(t *testing.T) {
+			m := newMap(rand.NewSource(1))
+			for _, k := range []int{5, 1, 9, 3, 7} {
+				if m.Insert(k, k*10) {
+					t.Fatalf("insert %d: want new key", k)
+				}
+			}
+			if !m.Insert(3, "three") {
+				t.Fatalf("insert 3 again: want existing key")
+			}
+
+			if want, got := []containers.Value{1, 3, 5, 7, 9}, keys(m); !cmp.Equal(want, got) {
+				t.Fatalf("keys: want= %v, got= %v", want, got)
+			}
+			if val, ok := m.Get(3); !ok || val != "three" {
+				t.Fatalf("get 3: want three, got= %v, %v", val, ok)
+			}
+			if _, ok := m.Get(4); ok {
+				t.Fatalf("get 4: want missing")
+			}
+
+			if val, ok := m.Delete(5); !ok || val != 50 {
+				t.Fatalf("delete 5: want 50, got= %v, %v", val, ok)
+			}
+			if _, ok := m.Delete(5); ok {
+				t.Fatalf("delete 5 again: want missing")
+			}
+			if want, got := 4, m.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+			if want, got := []containers.Value{1, 3, 7, 9}, keys(m); !cmp.Equal(want, got) {
+				t.Fatalf("keys: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	type result struct {
+		Key containers.Value
+		OK  bool
+	}
+	var testCases = map[string]struct {
+		key                           int
+		floor, ceiling, lower, higher result
+	}{
+		"beforeFirst": {
+			key:     0,
+			ceiling: result{10, true},
+			higher:  result{10, true},
+		},
+		"first": {
+			key:     10,
+			floor:   result{10, true},
+			ceiling: result{10, true},
+			higher:  result{20, true},
+		},
+		"between": {
+			key:     25,
+			floor:   result{20, true},
+			ceiling: result{30, true},
+			lower:   result{20, true},
+			higher:  result{30, true},
+		},
+		"present": {
+			key:     20,
+			floor:   result{20, true},
+			ceiling: result{20, true},
+			lower:   result{10, true},
+			higher:  result{30, true},
+		},
+		"afterLast": {
+			key:   99,
+			floor: result{30, true},
+			lower: result{30, true},
+		},
+	}
+
+	for implName, newMap := range implementations {
+		m := newMap(rand.NewSource(1))
+		for _, k := range []int{30, 10, 20} {
+			m.Insert(k, k)
+		}
+
+		for name, tc := range testCases {
+			t.Run(implName+"/"+name, func(t *testing.T) {
+				for op, fn := range map[string]func(key containers.Value) (containers.Value, containers.Value, bool){
+					"floor":   m.Floor,
+					"ceiling": m.Ceiling,
+					"lower":   m.Lower,
+					"higher":  m.Higher,
+				} {
+					want := map[string]result{"floor": tc.floor, "ceiling": tc.ceiling, "lower": tc.lower, "higher": tc.higher}[op]
+					key, _, ok := fn(tc.key)
+					if got := (result{key, ok}); want != got {
+						t.Fatalf("%s: want= %v, got= %v", op, want, got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFirstLastRange(t *testing.T) {
+	for name, newMap := range implementations {
+		t.Run(name, func(t *testing.T) {
+			m := newMap(rand.NewSource(1))
+			if _, _, ok := m.First(); ok {
+				t.Fatalf("first: want none")
+			}
+			if _, _, ok := m.Last(); ok {
+				t.Fatalf("last: want none")
+			}
+
+			for i := 0; i < 100; i++ {
+				m.Insert(i*2, i)
+			}
+			if key, _, _ := m.First(); key != 0 {
+				t.Fatalf("first: want 0, got= %v", key)
+			}
+			if key, _, _ := m.Last(); key != 198 {
+				t.Fatalf("last: want 198, got= %v", key)
+			}
+
+			var got []containers.Value
+			m.Range(9, 17, func(key, value containers.Value) bool {
+				got = append(got, key)
+				return true
+			})
+			if want := []containers.Value{10, 12, 14, 16}; !cmp.Equal(want, got) {
+				t.Fatalf("range: want= %v, got= %v", want, got)
+			}
+
+			got = nil
+			m.Range(0, 100, func(key, value containers.Value) bool {
+				got = append(got, key)
+				return len(got) < 3
+			})
+			if want := []containers.Value{0, 2, 4}; !cmp.Equal(want, got) {
+				t.Fatalf("stopped range: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestAgainstSortedSlice performs random operations and compares each implementation with a sorted slice.
+func TestAgainstSortedSlice(t *testing.T) {
+	for name, newMap := range implementations {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(42))
+			m := newMap(rand.NewSource(42))
+			var naive []int
+
+			for step := 0; step < 5000; step++ {
+				k := rng.Intn(500)
+				i := sort.SearchInts(naive, k)
+				found := i < len(naive) && naive[i] == k
+
+				if rng.Intn(3) == 0 {
+					if _, ok := m.Delete(k); ok != found {
+						t.Fatalf("step %d: delete %d: want= %v, got= %v", step, k, found, ok)
+					}
+					if found {
+						naive = append(naive[:i], naive[i+1:]...)
+					}
+				} else {
+					if existed := m.Insert(k, k); existed != found {
+						t.Fatalf("step %d: insert %d: want= %v, got= %v", step, k, found, existed)
+					}
+					if !found {
+						naive = append(naive, 0)
+						copy(naive[i+1:], naive[i:])
+						naive[i] = k
+					}
+				}
+			}
+
+			var want []containers.Value
+			for _, k := range naive {
+				want = append(want, k)
+			}
+			if got := keys(m); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if s, ok := m.(*skipList); ok {
+				validate(t, s)
+			}
+		})
+	}
+}
+
+func TestRankAndAt(t *testing.T) {
+	s := New(intLess, rand.NewSource(7))
+	for i := 0; i < 1000; i++ {
+		s.Insert(i*3, nil)
+	}
+	for i := 0; i < 1000; i += 2 {
+		s.Delete(i * 3)
+	}
+	validate(t, s)
+
+	var testCases = map[string]struct {
+		key   int
+		rank  int
+		found bool
+	}{
+		"first":     {key: 3, rank: 0, found: true},
+		"beforeAll": {key: 0, rank: 0},
+		"middle":    {key: 1503, rank: 250, found: true},
+		"missing":   {key: 1504, rank: 251},
+		"last":      {key: 2997, rank: 499, found: true},
+		"afterAll":  {key: 5000, rank: 500},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rank, found := s.Rank(tc.key)
+			if want, got := tc.rank, rank; want != got {
+				t.Fatalf("rank: want= %v, got= %v", want, got)
+			}
+			if want, got := tc.found, found; want != got {
+				t.Fatalf("found: want= %v, got= %v", want, got)
+			}
+		})
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		key, _, ok := s.At(i)
+		if want := i*6 + 3; !ok || key != want {
+			t.Fatalf("at %d: want= %v, got= %v, %v", i, want, key, ok)
+		}
+	}
+	if _, _, ok := s.At(s.Len()); ok {
+		t.Fatalf("at len: want none")
+	}
+	if _, _, ok := s.At(-1); ok {
+		t.Fatalf("at -1: want none")
+	}
+}
+
+func TestSeedIsReproducible(t *testing.T) {
+	layout := func(seed int64) []int {
+		s := New(intLess, rand.NewSource(seed))
+		for i := 0; i < 100; i++ {
+			s.Insert(i, nil)
+		}
+
+		var levels []int
+		for x := s.head.next[0]; x != nil; x = x.next[0] {
+			levels = append(levels, len(x.next))
+		}
+		return levels
+	}
+
+	if want, got := layout(1), layout(1); !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+	if a, b := layout(1), layout(2); cmp.Equal(a, b) {
+		t.Fatalf("want different layouts for different seeds, got= %v", a)
+	}
+}
+
+// validate checks that keys are ordered at every level and that spans match level 0.
+func validate(t *testing.T, s *skipList) {
+	rank := map[*node]int{s.head: 0}
+	i := 0
+	for x := s.head.next[0]; x != nil; x = x.next[0] {
+		i++
+		rank[x] = i
+	}
+	if want, got := i, s.length; want != got {
+		t.Fatalf("length: want= %v, got= %v", want, got)
+	}
+
+	for l := 0; l < s.level; l++ {
+		for x := s.head; x != nil; x = x.next[l] {
+			next := x.next[l]
+			want := s.length - rank[x]
+			if next != nil {
+				if x != s.head && !s.less(x.key, next.key) {
+					t.Fatalf("level %d: keys not in order: %v, %v", l, x.key, next.key)
+				}
+				want = rank[next] - rank[x]
+			}
+			if got := x.span[l]; want != got {
+				t.Fatalf("level %d: span of %v: want= %v, got= %v", l, x.key, want, got)
+			}
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		for name, newMap := range implementations {
+			b.Run(fmt.Sprintf("%s/n=%d", name, n), func(b *testing.B) {
+				keys := rand.New(rand.NewSource(1)).Perm(n)
+				var m orderedMap
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if i%n == 0 { // start over, so every insert is into a map of less than n keys
+						b.StopTimer()
+						m = newMap(rand.NewSource(1))
+						b.StartTimer()
+					}
+					m.Insert(keys[i%n], nil)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		for name, newMap := range implementations {
+			b.Run(fmt.Sprintf("%s/n=%d", name, n), func(b *testing.B) {
+				m := newMap(rand.NewSource(1))
+				for i := 0; i < n; i++ {
+					m.Insert(i, nil)
+				}
+				keys := rand.New(rand.NewSource(1)).Perm(n)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					m.Get(keys[i%n])
+				}
+			})
+		}
+		b.Run(fmt.Sprintf("map/n=%d", n), func(b *testing.B) {
+			m := map[containers.Value]containers.Value{}
+			for i := 0; i < n; i++ {
+				m[i] = nil
+			}
+			keys := rand.New(rand.NewSource(1)).Perm(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = m[keys[i%n]]
+			}
+		})
+	}
+}
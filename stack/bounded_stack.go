@@ -0,0 +1,155 @@
+package stack
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// BoundedStackable provides LIFO APIs on a stack with limited capacity.
+// Push applies the stack's OverflowPolicy silently, TryPush reports when an item is rejected.
+type BoundedStackable interface {
+	Stackable
+	TryPush(item containers.Value) error
+	Capacity() int
+}
+
+// OverflowPolicy decides what happens when pushing onto a full bounded stack.
+type OverflowPolicy int
+
+const (
+	// RejectOnOverflow drops the new item. TryPush returns ErrOverflow.
+	RejectOnOverflow OverflowPolicy = iota
+	// DropBottomOnOverflow drops the bottom-most item to make space for the new one.
+	DropBottomOnOverflow
+	// BlockOnOverflow waits until another goroutine pops an item or clears the stack.
+	BlockOnOverflow
+)
+
+const stackIsFull = "stack is full"
+
+// ErrOverflow is returned by TryPush when a RejectOnOverflow stack is full.
+var ErrOverflow = errors.New(stackIsFull)
+
+// boundedStack is a concrete implementation of BoundedStackable on a ring buffer.
+// It is safe for concurrent use.
+type boundedStack struct {
+	policy OverflowPolicy
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	items   []containers.Value // ring buffer with fixed length = capacity
+	bottom  int                // index of the bottom-most item
+	n       int                // number of items
+}
+
+// NewBounded returns BoundedStackable which holds at most capacity items.
+func NewBounded(capacity int, policy OverflowPolicy) (*boundedStack, error) {
+	if capacity <= 0 {
+		return nil, errors.Errorf("capacity must be positive, got %d", capacity)
+	}
+	switch policy {
+	case RejectOnOverflow, DropBottomOnOverflow, BlockOnOverflow:
+	default:
+		return nil, errors.Errorf("unknown overflow policy %d", policy)
+	}
+
+	s := &boundedStack{
+		policy: policy,
+		items:  make([]containers.Value, capacity),
+	}
+	s.notFull = sync.NewCond(&s.mu)
+	return s, nil
+}
+
+// Push adds a new containers.Value on the stack's top, applying the overflow policy if the stack is full.
+func (s *boundedStack) Push(item containers.Value) {
+	s.TryPush(item)
+}
+
+// TryPush adds a new containers.Value on the stack's top, applying the overflow policy if the stack is full.
+// It returns ErrOverflow if the item was rejected.
+func (s *boundedStack) TryPush(item containers.Value) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.n == len(s.items) {
+		switch s.policy {
+		case RejectOnOverflow:
+			return ErrOverflow
+		case DropBottomOnOverflow:
+			s.items[s.bottom] = nil
+			s.bottom = (s.bottom + 1) % len(s.items)
+			s.n--
+		case BlockOnOverflow:
+			for s.n == len(s.items) {
+				s.notFull.Wait()
+			}
+		}
+	}
+
+	s.items[s.index(s.n)] = item
+	s.n++
+	return nil
+}
+
+// Capacity returns the maximum number of elements in the stack.
+func (s *boundedStack) Capacity() int {
+	return len(s.items)
+}
+
+// Size returns the current number of elements in the stack.
+func (s *boundedStack) Size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.n
+}
+
+// Clear empties the whole stack.
+func (s *boundedStack) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < s.n; i++ {
+		s.items[s.index(i)] = nil
+	}
+	s.bottom, s.n = 0, 0
+	s.notFull.Broadcast()
+}
+
+// Top returns the containers.Value on top of the stack.
+func (s *boundedStack) Top() (containers.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.n == 0 {
+		return nil, errors.New(stackIsEmpty)
+	}
+
+	return s.items[s.index(s.n-1)], nil
+}
+
+// Pop removes the containers.Value on top of the stack and returns it.
+func (s *boundedStack) Pop() (containers.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.n == 0 {
+		return nil, errors.New(stackIsEmpty)
+	}
+
+	i := s.index(s.n - 1)
+	top := s.items[i]
+	s.items[i] = nil // don't hold on to popped items
+	s.n--
+	s.notFull.Signal()
+	return top, nil
+}
+
+// index returns the position in the ring buffer of the i-th item from the bottom.
+func (s *boundedStack) index(i int) int {
+	return (s.bottom + i) % len(s.items)
+}
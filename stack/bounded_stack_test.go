@@ -0,0 +1,241 @@
+package stack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func TestNewBounded(t *testing.T) {
+	var testCases = map[string]struct {
+		capacity int
+		policy   OverflowPolicy
+		isErr    bool
+	}{
+		"valid": {
+			capacity: 3,
+			policy:   DropBottomOnOverflow,
+		},
+		"zeroCapacity": {
+			capacity: 0,
+			policy:   RejectOnOverflow,
+			isErr:    true,
+		},
+		"negativeCapacity": {
+			capacity: -1,
+			policy:   RejectOnOverflow,
+			isErr:    true,
+		},
+		"unknownPolicy": {
+			capacity: 3,
+			policy:   OverflowPolicy(42),
+			isErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewBounded(tc.capacity, tc.policy)
+
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			case !tc.isErr:
+				if want, got := tc.capacity, s.Capacity(); want != got {
+					t.Fatalf("want capacity= %v, got= %v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBoundedTryPush(t *testing.T) {
+	var testCases = map[string]struct {
+		policy   OverflowPolicy
+		capacity int
+		pushed   []containers.Value
+		errs     []error
+		values   []containers.Value
+	}{
+		"rejectNotFull": {
+			policy:   RejectOnOverflow,
+			capacity: 3,
+			pushed:   []containers.Value{1, 2},
+			errs:     []error{nil, nil},
+			values:   []containers.Value{1, 2},
+		},
+		"rejectFull": {
+			policy:   RejectOnOverflow,
+			capacity: 3,
+			pushed:   []containers.Value{1, 2, 3, 4, 5},
+			errs:     []error{nil, nil, nil, ErrOverflow, ErrOverflow},
+			values:   []containers.Value{1, 2, 3},
+		},
+		"dropBottomNotFull": {
+			policy:   DropBottomOnOverflow,
+			capacity: 3,
+			pushed:   []containers.Value{1, 2},
+			errs:     []error{nil, nil},
+			values:   []containers.Value{1, 2},
+		},
+		"dropBottomFull": {
+			policy:   DropBottomOnOverflow,
+			capacity: 3,
+			pushed:   []containers.Value{1, 2, 3, 4, 5},
+			errs:     []error{nil, nil, nil, nil, nil},
+			values:   []containers.Value{3, 4, 5},
+		},
+		"dropBottomWrapAroundManyTimes": {
+			policy:   DropBottomOnOverflow,
+			capacity: 2,
+			pushed:   []containers.Value{1, 2, 3, 4, 5, 6, 7},
+			errs:     []error{nil, nil, nil, nil, nil, nil, nil},
+			values:   []containers.Value{6, 7},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewBounded(tc.capacity, tc.policy)
+			if err != nil {
+				t.Fatalf("need a valid bounded stack to test, got %q", err)
+			}
+
+			var errs []error
+			for _, v := range tc.pushed {
+				errs = append(errs, s.TryPush(v))
+			}
+
+			if want, got := tc.errs, errs; !cmp.Equal(want, got, cmp.Comparer(func(a, b error) bool { return a == b })) {
+				t.Fatalf("errors: want= %v, got= %v", want, got)
+			}
+			if want, got := tc.values, boundedStackValues(s); !cmp.Equal(want, got) {
+				t.Fatalf("values: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestBoundedPop(t *testing.T) {
+	var testCases = map[string]struct {
+		pushed []containers.Value
+		isErr  bool
+		val    containers.Value
+		values []containers.Value
+	}{
+		"empty": {
+			isErr: true,
+		},
+		"oneElement": {
+			pushed: []containers.Value{1},
+			val:    1,
+		},
+		"wrappedAround": {
+			pushed: []containers.Value{1, 2, 3, 4},
+			val:    4,
+			values: []containers.Value{2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, _ := NewBounded(3, DropBottomOnOverflow)
+			for _, v := range tc.pushed {
+				s.Push(v)
+			}
+
+			val, err := s.Pop()
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			default:
+				if want, got := tc.val, val; !cmp.Equal(want, got) {
+					t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+				}
+			}
+
+			if want, got := tc.values, boundedStackValues(s); !cmp.Equal(want, got) {
+				t.Fatalf("values: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestBoundedBlockOnOverflow(t *testing.T) {
+	s, _ := NewBounded(2, BlockOnOverflow)
+	s.Push(1)
+	s.Push(2)
+
+	pushed := make(chan struct{})
+	go func() {
+		s.Push(3)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatalf("want Push() to block on a full stack")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if val, err := s.Pop(); err != nil || val != 2 {
+		t.Fatalf("want pop= 2, got= %v, err= %v", val, err)
+	}
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatalf("want Push() to finish after Pop()")
+	}
+
+	if want, got := []containers.Value{1, 3}, boundedStackValues(s); !cmp.Equal(want, got) {
+		t.Fatalf("values: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+	}
+}
+
+func TestBoundedClear(t *testing.T) {
+	s, _ := NewBounded(3, DropBottomOnOverflow)
+	for i := 0; i < 5; i++ {
+		s.Push(i)
+	}
+	s.Clear()
+
+	if want, got := 0, s.Size(); want != got {
+		t.Fatalf("want size= %v, got= %v", want, got)
+	}
+	if _, err := s.Top(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+
+	s.Push(42)
+	if want, got := []containers.Value{42}, boundedStackValues(s); !cmp.Equal(want, got) {
+		t.Fatalf("values: want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+	}
+}
+
+// boundedStackValues returns items of the stack from bottom to top.
+func boundedStackValues(s *boundedStack) []containers.Value {
+	var vals []containers.Value
+	for i := 0; i < s.n; i++ {
+		vals = append(vals, s.items[s.index(i)])
+	}
+
+	return vals
+}
+
+func BenchmarkBoundedPush(b *testing.B) {
+	for name, bm := range benchmarkTypes {
+		b.Run(name, func(b *testing.B) {
+			s, _ := NewBounded(1024, DropBottomOnOverflow)
+			for i := 0; i < b.N; i++ {
+				s.Push(bm.newValue())
+			}
+		})
+	}
+}
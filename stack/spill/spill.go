@@ -0,0 +1,210 @@
+// Package spill provides a stack whose depth is not limited by memory.
+//
+// Only the top-most values are kept in memory. Older values are spilled to a temporary file
+// in chunks, and read back when Pop() drains the in-memory part.
+package spill
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+)
+
+const stackIsEmpty = "stack is empty"
+
+// chunk is a group of values spilled together, stored in the file at [offset, end of file).
+type chunk struct {
+	offset int64
+	n      int
+}
+
+// spillStack is a concrete implementation of stack.Stackable,
+// keeping up to window values in memory and the rest in a temporary file.
+type spillStack struct {
+	codec     codec.Codec
+	window    int
+	chunkSize int
+
+	mem     []containers.Value // top-most values, the last one is the top
+	f       *os.File
+	chunks  []chunk // spilled chunks, the last one is next to mem
+	end     int64   // end of the last chunk in f
+	spilled int     // number of values in f
+
+	err error // first error from Push() or Clear(), which do not return errors
+}
+
+// New returns stack.Stackable keeping at most window values in memory.
+// Older values are encoded with c and stored in a temporary file in dir (os.TempDir() if dir is empty).
+// Close() must be called to remove the file.
+func New(dir string, window int, c codec.Codec) (*spillStack, error) {
+	if window <= 0 {
+		return nil, errors.Errorf("window must be positive, got %d", window)
+	}
+
+	f, err := ioutil.TempFile(dir, "spill-stack")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create spill file")
+	}
+
+	chunkSize := window / 2 // spill half of the window, so alternating Push() and Pop() won't hit the disk
+	if chunkSize == 0 {
+		chunkSize = 1
+	}
+	return &spillStack{
+		codec:     c,
+		window:    window,
+		chunkSize: chunkSize,
+		mem:       make([]containers.Value, 0, window+1),
+		f:         f,
+	}, nil
+}
+
+// Push adds a new containers.Value on the stack's top.
+// If it cannot be spilled to disk, the stack stops working and Err() returns the reason.
+func (s *spillStack) Push(item containers.Value) {
+	if s.err != nil {
+		return
+	}
+
+	s.mem = append(s.mem, item)
+	if len(s.mem) > s.window {
+		s.err = s.spill()
+	}
+}
+
+// spill writes the oldest chunkSize values in memory to the file.
+func (s *spillStack) spill() error {
+	var buf []byte
+	var header [4]byte
+	for _, v := range s.mem[:s.chunkSize] {
+		data, err := s.codec.Encode(v)
+		if err != nil {
+			return errors.Wrap(err, "cannot encode value")
+		}
+
+		binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+		buf = append(buf, header[:]...)
+		buf = append(buf, data...)
+	}
+	if _, err := s.f.WriteAt(buf, s.end); err != nil {
+		return errors.Wrap(err, "cannot write spill file")
+	}
+
+	s.chunks = append(s.chunks, chunk{offset: s.end, n: s.chunkSize})
+	s.end += int64(len(buf))
+	s.spilled += s.chunkSize
+
+	n := copy(s.mem, s.mem[s.chunkSize:])
+	for i := n; i < len(s.mem); i++ {
+		s.mem[i] = nil // don't hold on to spilled values
+	}
+	s.mem = s.mem[:n]
+
+	return nil
+}
+
+// load reads the last spilled chunk back into memory, which must be empty.
+func (s *spillStack) load() error {
+	c := s.chunks[len(s.chunks)-1]
+	buf := make([]byte, s.end-c.offset)
+	if _, err := s.f.ReadAt(buf, c.offset); err != nil {
+		return errors.Wrap(err, "cannot read spill file")
+	}
+
+	for i := 0; i < c.n; i++ {
+		if len(buf) < 4 {
+			return errors.New("spill file is corrupted")
+		}
+		n := binary.BigEndian.Uint32(buf)
+		if uint32(len(buf)-4) < n {
+			return errors.New("spill file is corrupted")
+		}
+
+		v, err := s.codec.Decode(buf[4 : 4+n])
+		if err != nil {
+			return errors.Wrap(err, "cannot decode value")
+		}
+		s.mem = append(s.mem, v)
+		buf = buf[4+n:]
+	}
+
+	if err := s.f.Truncate(c.offset); err != nil {
+		return errors.Wrap(err, "cannot truncate spill file")
+	}
+	s.chunks = s.chunks[:len(s.chunks)-1]
+	s.end = c.offset
+	s.spilled -= c.n
+
+	return nil
+}
+
+// Size returns the current number of elements in the stack.
+func (s *spillStack) Size() int {
+	return len(s.mem) + s.spilled
+}
+
+// Clear empties the whole stack.
+// If the spill file cannot be emptied, the stack stops working and Err() returns the reason.
+func (s *spillStack) Clear() {
+	if s.err != nil {
+		return
+	}
+
+	for i := range s.mem {
+		s.mem[i] = nil
+	}
+	s.mem = s.mem[:0]
+	s.chunks = nil
+	s.end, s.spilled = 0, 0
+	s.err = errors.Wrap(s.f.Truncate(0), "cannot truncate spill file")
+}
+
+// Top returns the containers.Value on top of the stack.
+func (s *spillStack) Top() (containers.Value, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.mem) == 0 {
+		if len(s.chunks) == 0 {
+			return nil, errors.New(stackIsEmpty)
+		}
+		if s.err = s.load(); s.err != nil {
+			return nil, s.err
+		}
+	}
+
+	return s.mem[len(s.mem)-1], nil
+}
+
+// Pop removes the containers.Value on top of the stack and returns it.
+func (s *spillStack) Pop() (containers.Value, error) {
+	top, err := s.Top()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mem[len(s.mem)-1] = nil
+	s.mem = s.mem[:len(s.mem)-1]
+	return top, nil
+}
+
+// Err returns the error which stopped the stack from working, if any.
+func (s *spillStack) Err() error {
+	return s.err
+}
+
+// Close removes the spill file.
+func (s *spillStack) Close() error {
+	path := s.f.Name()
+	if err := s.f.Close(); err != nil {
+		return errors.Wrap(err, "cannot close spill file")
+	}
+
+	return errors.Wrap(os.Remove(path), "cannot remove spill file")
+}
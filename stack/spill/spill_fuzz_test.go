@@ -0,0 +1,55 @@
+// +build fuzz
+
+package spill
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+)
+
+// TestFuzzOps performs N random operations and compares the stack with a naive slice.
+func TestFuzzOps(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	s, err := New("", rng.Intn(16)+1, codec.Gob{})
+	if err != nil {
+		t.Fatalf("need a valid stack to test, got %q", err)
+	}
+	defer s.Close()
+	var naive []containers.Value
+
+	steps := rng.Intn(10000) + 2000
+	for i := 0; i < steps; i++ {
+		switch rng.Intn(10) {
+		case 0, 1, 2, 3, 4:
+			v := containers.Value(rng.Int())
+			s.Push(v)
+			naive = append(naive, v)
+		case 5, 6, 7, 8:
+			val, err := s.Pop()
+			if len(naive) == 0 {
+				if err == nil {
+					t.Fatalf("step %d: want error, got none", i)
+				}
+				continue
+			}
+			if want, got := naive[len(naive)-1], val; err != nil || want != got {
+				t.Fatalf("step %d: want= %v, got= %v, err= %v", i, want, got, err)
+			}
+			naive = naive[:len(naive)-1]
+		default:
+			s.Clear()
+			naive = naive[:0]
+		}
+
+		if want, got := len(naive), s.Size(); want != got {
+			t.Fatalf("step %d: want size= %v, got= %v", i, want, got)
+		}
+	}
+}
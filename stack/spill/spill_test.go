@@ -0,0 +1,219 @@
+package spill
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/codec"
+	"github.com/bitsgofer/containers/stack"
+)
+
+var _ stack.Stackable = (*spillStack)(nil)
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		window int
+		isErr  bool
+	}{
+		"one": {
+			window: 1,
+		},
+		"many": {
+			window: 64,
+		},
+		"zero": {
+			window: 0,
+			isErr:  true,
+		},
+		"negative": {
+			window: -1,
+			isErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := New("", tc.window, codec.Gob{})
+
+			switch {
+			case tc.isErr && err == nil:
+				t.Fatalf("want error, got none")
+			case !tc.isErr && err != nil:
+				t.Fatalf("want no error, got %q", err)
+			case !tc.isErr:
+				s.Close()
+			}
+		})
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	var testCases = map[string]struct {
+		window  int
+		pushed  int
+		popped  int
+		inMem   int
+		spilled int
+	}{
+		"fitsInMemory": {
+			window:  4,
+			pushed:  4,
+			popped:  2,
+			inMem:   2,
+			spilled: 0,
+		},
+		"spilled": {
+			window:  4,
+			pushed:  11,
+			inMem:   3,
+			spilled: 8,
+		},
+		"spilledThenLoaded": {
+			window:  4,
+			pushed:  11,
+			popped:  5,
+			inMem:   0,
+			spilled: 6,
+		},
+		"windowOfOne": {
+			window:  1,
+			pushed:  5,
+			popped:  2,
+			inMem:   0,
+			spilled: 3,
+		},
+		"allPopped": {
+			window:  4,
+			pushed:  11,
+			popped:  11,
+			inMem:   0,
+			spilled: 0,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := New("", tc.window, codec.Gob{})
+			if err != nil {
+				t.Fatalf("need a valid stack to test, got %q", err)
+			}
+			defer s.Close()
+
+			for i := 0; i < tc.pushed; i++ {
+				s.Push(i)
+			}
+			for i := 0; i < tc.popped; i++ {
+				val, err := s.Pop()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				if want, got := containers.Value(tc.pushed-1-i), val; want != got {
+					t.Fatalf("pop #%d: want= %v, got= %v", i, want, got)
+				}
+			}
+
+			if want, got := tc.pushed-tc.popped, s.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			if len(s.mem) != tc.inMem || s.spilled != tc.spilled {
+				t.Fatalf("want inMem= %v, spilled= %v, got inMem= %v, spilled= %v", tc.inMem, tc.spilled, len(s.mem), s.spilled)
+			}
+			if _, err := s.Top(); (s.Size() == 0) != (err != nil) {
+				t.Fatalf("top: size= %v, err= %v", s.Size(), err)
+			}
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	s, err := New("", 4, codec.Gob{})
+	if err != nil {
+		t.Fatalf("need a valid stack to test, got %q", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		s.Push(i)
+	}
+	s.Clear()
+	if err := s.Err(); err != nil {
+		t.Fatalf("cannot clear: %v", err)
+	}
+	if want, got := 0, s.Size(); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+	if _, err := s.Pop(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+
+	for i := 0; i < 10; i++ {
+		s.Push(i)
+	}
+	var popped []containers.Value
+	for s.Size() > 0 {
+		val, _ := s.Pop()
+		popped = append(popped, val)
+	}
+	if want, got := []containers.Value{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, popped; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	s, err := New("", 1, codec.String{})
+	if err != nil {
+		t.Fatalf("need a valid stack to test, got %q", err)
+	}
+	defer s.Close()
+
+	s.Push(1)
+	s.Push(2) // spills 1, which is not a string
+	if s.Err() == nil {
+		t.Fatalf("want error, got none")
+	}
+	if _, err := s.Top(); err == nil {
+		t.Fatalf("want error, got none")
+	}
+}
+
+func TestClose(t *testing.T) {
+	s, err := New("", 1, codec.Gob{})
+	if err != nil {
+		t.Fatalf("need a valid stack to test, got %q", err)
+	}
+	path := s.f.Name()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("want spill file removed, got %v", err)
+	}
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	const depth = 1 << 14
+
+	for _, window := range []int{16, 256, 4096, depth} {
+		b.Run(fmt.Sprintf("window=%d", window), func(b *testing.B) {
+			s, err := New("", window, codec.Gob{})
+			if err != nil {
+				b.Fatalf("need a valid stack to test, got %q", err)
+			}
+			defer s.Close()
+
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < depth; j++ {
+					s.Push(j)
+				}
+				for j := 0; j < depth; j++ {
+					s.Pop()
+				}
+			}
+		})
+	}
+}
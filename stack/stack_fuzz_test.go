@@ -41,3 +41,45 @@ func TestFuzzOps(t *testing.T) {
 		}
 	}
 }
+
+// TestFuzzBoundedOps performs N random operations and compares the bounded stack with a naive slice.
+func TestFuzzBoundedOps(t *testing.T) {
+	randSeed := time.Now().Unix()
+	rng := rand.New(rand.NewSource(randSeed))
+	t.Logf("running with random seed= %v", randSeed)
+
+	capacity := rng.Intn(16) + 1
+	s, err := NewBounded(capacity, DropBottomOnOverflow)
+	if err != nil {
+		t.Fatalf("need a valid bounded stack to test, got %q", err)
+	}
+	var naive []containers.Value
+
+	steps := rng.Intn(10000) + 2000
+	for i := 0; i < steps; i++ {
+		switch rng.Intn(4) {
+		case 0, 1:
+			v := containers.Value(rng.Int())
+			s.Push(v)
+			naive = append(naive, v)
+			if len(naive) > capacity {
+				naive = naive[1:]
+			}
+		case 2:
+			s.Pop()
+			if len(naive) > 0 {
+				naive = naive[:len(naive)-1]
+			}
+		default:
+			s.Clear()
+			naive = naive[:0]
+		}
+
+		if want, got := len(naive), s.Size(); want != got {
+			t.Fatalf("step %d: want size= %v, got= %v", i, want, got)
+		}
+		if top, err := s.Top(); len(naive) > 0 && (err != nil || top != naive[len(naive)-1]) {
+			t.Fatalf("step %d: want top= %v, got= %v, err= %v", i, naive[len(naive)-1], top, err)
+		}
+	}
+}
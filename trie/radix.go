@@ -0,0 +1,273 @@
+// Package trie provides a compressed radix tree for string keys.
+package trie
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Mode decides where keys can be split between nodes.
+type Mode int
+
+const (
+	// Runes splits keys only between runes, so prefixes are matched rune by rune. Keys should be valid UTF-8.
+	Runes Mode = iota
+	// Bytes splits keys between any bytes, for arbitrary byte strings.
+	Bytes
+)
+
+// node holds the part of keys after its parent's, in label.
+// Children are sorted by label, and no two of them start with the same rune (or byte, in Bytes mode).
+type node struct {
+	label    string
+	value    containers.Value
+	hasValue bool
+	children []*node
+}
+
+// radixTree is a trie where each node without a value has at least two children,
+// so chains of single children are compressed into one label.
+type radixTree struct {
+	mode Mode
+	root *node
+	size int
+}
+
+// New returns an empty radix tree.
+func New(mode Mode) (*radixTree, error) {
+	if mode != Runes && mode != Bytes {
+		return nil, errors.Errorf("unknown mode %d", mode)
+	}
+
+	return &radixTree{
+		mode: mode,
+		root: &node{},
+	}, nil
+}
+
+// Len returns the number of keys.
+func (t *radixTree) Len() int {
+	return t.size
+}
+
+// firstUnit returns the length of the first rune (or byte, in Bytes mode) of s, which must not be empty.
+func (t *radixTree) firstUnit(s string) int {
+	if t.mode == Bytes {
+		return 1
+	}
+
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b, which ends between runes in Runes mode.
+func (t *radixTree) commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	if t.mode == Runes {
+		for n > 0 && (n < len(a) && !utf8.RuneStart(a[n]) || n < len(b) && !utf8.RuneStart(b[n])) {
+			n--
+		}
+	}
+
+	return n
+}
+
+// child returns the index of the child of n whose label shares a prefix with key,
+// or where one would be inserted, and whether it exists.
+func (t *radixTree) child(n *node, key string) (int, bool) {
+	unit := key[:t.firstUnit(key)]
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].label >= unit
+	})
+
+	return i, i < len(n.children) && t.commonPrefix(n.children[i].label, key) > 0
+}
+
+// Insert sets the value of key. It returns whether key was already there, in which case its value is replaced.
+func (t *radixTree) Insert(key string, value containers.Value) bool {
+	n := t.root
+	for key != "" {
+		i, ok := t.child(n, key)
+		if !ok {
+			leaf := &node{label: key, value: value, hasValue: true}
+			i = sort.Search(len(n.children), func(i int) bool {
+				return n.children[i].label >= key
+			})
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = leaf
+			t.size++
+			return false
+		}
+
+		c := n.children[i]
+		common := t.commonPrefix(c.label, key)
+		if common < len(c.label) { // split c at common
+			split := &node{label: c.label[:common], children: []*node{c}}
+			c.label = c.label[common:]
+			n.children[i] = split
+			c = split
+		}
+
+		n, key = c, key[common:]
+	}
+
+	existed := n.hasValue
+	n.value, n.hasValue = value, true
+	if !existed {
+		t.size++
+	}
+	return existed
+}
+
+// find returns the node holding exactly key, if any.
+func (t *radixTree) find(key string) *node {
+	n := t.root
+	for key != "" {
+		i, ok := t.child(n, key)
+		if !ok || !strings.HasPrefix(key, n.children[i].label) {
+			return nil
+		}
+
+		n, key = n.children[i], key[len(n.children[i].label):]
+	}
+
+	return n
+}
+
+// Get returns the value of key, and whether key is there.
+func (t *radixTree) Get(key string) (containers.Value, bool) {
+	n := t.find(key)
+	if n == nil || !n.hasValue {
+		return nil, false
+	}
+
+	return n.value, true
+}
+
+// Delete removes key. It returns the removed value and whether key was there.
+// Nodes left with no value and a single child are merged with it.
+func (t *radixTree) Delete(key string) (containers.Value, bool) {
+	var parent *node
+	n := t.root
+	for key != "" {
+		i, ok := t.child(n, key)
+		if !ok || !strings.HasPrefix(key, n.children[i].label) {
+			return nil, false
+		}
+
+		parent, n, key = n, n.children[i], key[len(n.children[i].label):]
+	}
+	if !n.hasValue {
+		return nil, false
+	}
+
+	value := n.value
+	n.value, n.hasValue = nil, false
+	t.size--
+
+	switch {
+	case n == t.root:
+	case len(n.children) == 1:
+		t.merge(n)
+	case len(n.children) == 0:
+		i, _ := t.child(parent, n.label)
+		parent.children = append(parent.children[:i], parent.children[i+1:]...)
+		if parent != t.root && !parent.hasValue && len(parent.children) == 1 {
+			t.merge(parent)
+		}
+	}
+
+	return value, true
+}
+
+// merge appends the only child of n into n.
+func (t *radixTree) merge(n *node) {
+	c := n.children[0]
+	n.label += c.label
+	n.value, n.hasValue = c.value, c.hasValue
+	n.children = c.children
+}
+
+// LongestPrefix returns the longest key which is a prefix of s, and its value.
+func (t *radixTree) LongestPrefix(s string) (string, containers.Value, bool) {
+	var (
+		key      string
+		value    containers.Value
+		found    = t.root.hasValue
+		n        = t.root
+		consumed = 0
+	)
+	if found {
+		value = t.root.value
+	}
+
+	for rest := s; rest != ""; {
+		i, ok := t.child(n, rest)
+		if !ok || !strings.HasPrefix(rest, n.children[i].label) {
+			break
+		}
+
+		n = n.children[i]
+		consumed += len(n.label)
+		rest = rest[len(n.label):]
+		if n.hasValue {
+			key, value, found = s[:consumed], n.value, true
+		}
+	}
+
+	return key, value, found
+}
+
+// WalkPrefix calls fn on keys starting with prefix in lexicographic order, until fn returns false.
+func (t *radixTree) WalkPrefix(prefix string, fn func(key string, value containers.Value) bool) {
+	n, path := t.root, ""
+	for rest := prefix; rest != ""; {
+		i, ok := t.child(n, rest)
+		if !ok {
+			return
+		}
+
+		c := n.children[i]
+		common := t.commonPrefix(c.label, rest)
+		if common < len(rest) && common < len(c.label) {
+			return
+		}
+
+		n, path = c, path+c.label
+		rest = rest[common:]
+		if common == len(c.label) {
+			continue
+		}
+		break // the prefix ends inside c's label, so all of c's keys match
+	}
+
+	walk(n, path, fn)
+}
+
+// Walk calls fn on all keys in lexicographic order, until fn returns false.
+func (t *radixTree) Walk(fn func(key string, value containers.Value) bool) {
+	walk(t.root, "", fn)
+}
+
+// walk calls fn on keys in the subtree at n, whose key is path, in pre-order. It returns false if fn did.
+func walk(n *node, path string, fn func(key string, value containers.Value) bool) bool {
+	if n.hasValue && !fn(path, n.value) {
+		return false
+	}
+	for _, c := range n.children {
+		if !walk(c, path+c.label, fn) {
+			return false
+		}
+	}
+
+	return true
+}
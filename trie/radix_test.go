@@ -0,0 +1,422 @@
+package trie
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func TestNew(t *testing.T) {
+	var testCases = map[string]struct {
+		mode  Mode
+		isErr bool
+	}{
+		"runes":   {mode: Runes},
+		"bytes":   {mode: Bytes},
+		"unknown": {mode: Mode(42), isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(tc.mode)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+		})
+	}
+}
+
+// newTree returns a tree with each key's value being the key itself.
+func newTree(t *testing.T, mode Mode, keys ...string) *radixTree {
+	tree, err := New(mode)
+	if err != nil {
+		t.Fatalf("need a valid tree to test, got %q", err)
+	}
+	for _, k := range keys {
+		tree.Insert(k, k)
+	}
+
+	return tree
+}
+
+// walkPrefix returns keys found by WalkPrefix(prefix).
+func walkPrefix(tree *radixTree, prefix string) []string {
+	var keys []string
+	tree.WalkPrefix(prefix, func(key string, value containers.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	return keys
+}
+
+// nodes returns the number of nodes in the tree, including the root.
+func nodes(n *node) int {
+	count := 1
+	for _, c := range n.children {
+		count += nodes(c)
+	}
+
+	return count
+}
+
+func TestInsertGetDelete(t *testing.T) {
+	for _, mode := range []Mode{Runes, Bytes} {
+		t.Run(fmt.Sprintf("mode=%d", mode), func(t *testing.T) {
+			tree := newTree(t, mode, "romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus")
+			if !tree.Insert("ruber", "red") {
+				t.Fatalf("insert ruber again: want existing key")
+			}
+			if tree.Insert("", "empty") {
+				t.Fatalf("insert empty key: want new key")
+			}
+
+			var testCases = map[string]struct {
+				key   string
+				value containers.Value
+				found bool
+			}{
+				"leaf":         {key: "romulus", value: "romulus", found: true},
+				"replaced":     {key: "ruber", value: "red", found: true},
+				"empty":        {key: "", value: "empty", found: true},
+				"innerNode":    {key: "rom"},
+				"prefixOfLeaf": {key: "rubicund"},
+				"longer":       {key: "romanesque"},
+				"missing":      {key: "x"},
+			}
+			for name, tc := range testCases {
+				value, found := tree.Get(tc.key)
+				if found != tc.found || value != tc.value {
+					t.Fatalf("%s: want= %v, %v, got= %v, %v", name, tc.value, tc.found, value, found)
+				}
+			}
+
+			if want, got := 8, tree.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+			if _, ok := tree.Delete("rom"); ok {
+				t.Fatalf("delete inner node: want missing")
+			}
+			if value, ok := tree.Delete("romulus"); !ok || value != "romulus" {
+				t.Fatalf("delete romulus: want romulus, got= %v, %v", value, ok)
+			}
+			if _, ok := tree.Get("romulus"); ok {
+				t.Fatalf("get deleted key: want missing")
+			}
+			if want, got := 7, tree.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDeleteMergesNodes(t *testing.T) {
+	tree := newTree(t, Runes, "team", "test", "toast")
+	// root -> t -> {e -> {am, st}, oast}
+	if want, got := 6, nodes(tree.root); want != got {
+		t.Fatalf("nodes: want= %v, got= %v", want, got)
+	}
+
+	tree.Delete("team") // "e" is left with one child, and merged into "est"
+	if want, got := 4, nodes(tree.root); want != got {
+		t.Fatalf("nodes: want= %v, got= %v", want, got)
+	}
+
+	tree.Insert("te", "te")
+	tree.Delete("te") // a node with a value and one child loses its value, and is merged
+	if want, got := 4, nodes(tree.root); want != got {
+		t.Fatalf("nodes: want= %v, got= %v", want, got)
+	}
+
+	tree.Delete("test")
+	tree.Delete("toast")
+	if want, got := 1, nodes(tree.root); want != got {
+		t.Fatalf("nodes: want= %v, got= %v", want, got)
+	}
+	if want, got := []string(nil), walkPrefix(tree, ""); !cmp.Equal(want, got) {
+		t.Fatalf("keys: want= %v, got= %v", want, got)
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+	routes := newTree(t, Runes, "/", "/api", "/api/v1/", "/api/v1/users", "/static/")
+
+	var testCases = map[string]struct {
+		path  string
+		route string
+		found bool
+	}{
+		"exact":       {path: "/api/v1/users", route: "/api/v1/users", found: true},
+		"child":       {path: "/api/v1/users/42", route: "/api/v1/users", found: true},
+		"innerPrefix": {path: "/api/v1/orders", route: "/api/v1/", found: true},
+		"notAtSlash":  {path: "/apis", route: "/api", found: true},
+		"root":        {path: "/index.html", route: "/", found: true},
+		"none":        {path: "index.html"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			route, value, found := routes.LongestPrefix(tc.path)
+			if found != tc.found || route != tc.route {
+				t.Fatalf("want= %q, %v, got= %q, %v", tc.route, tc.found, route, found)
+			}
+			if found && value != route {
+				t.Fatalf("value: want= %v, got= %v", route, value)
+			}
+		})
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+	tree := newTree(t, Runes, "romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus", "rom")
+
+	var testCases = map[string]struct {
+		prefix string
+		keys   []string
+	}{
+		"all": {
+			prefix: "",
+			keys:   []string{"rom", "romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus"},
+		},
+		"endsAtNode": {
+			prefix: "rom",
+			keys:   []string{"rom", "romane", "romanus", "romulus"},
+		},
+		"endsInsideLabel": {
+			prefix: "rubic",
+			keys:   []string{"rubicon", "rubicundus"},
+		},
+		"wholeKey": {
+			prefix: "ruber",
+			keys:   []string{"ruber"},
+		},
+		"diverges": {
+			prefix: "rubx",
+		},
+		"longerThanKeys": {
+			prefix: "rubiconia",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.keys, walkPrefix(tree, tc.prefix); !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+
+	var first []string
+	tree.Walk(func(key string, value containers.Value) bool {
+		first = append(first, key)
+		return len(first) < 2
+	})
+	if want := []string{"rom", "romane"}; !cmp.Equal(want, first) {
+		t.Fatalf("stopped walk: want= %v, got= %v", want, first)
+	}
+}
+
+// TestModes checks where keys sharing the first bytes of a rune are split.
+func TestModes(t *testing.T) {
+	keys := []string{"café", "cafè", "cafe"} // é and è share their first byte in UTF-8
+
+	var testCases = map[string]struct {
+		mode   Mode
+		prefix string
+		keys   []string
+	}{
+		"runesWholeRune": {
+			mode:   Runes,
+			prefix: "café",
+			keys:   []string{"café"},
+		},
+		"runesPartialRune": {
+			mode:   Runes,
+			prefix: "caf\xc3",
+		},
+		"bytesPartialRune": {
+			mode:   Bytes,
+			prefix: "caf\xc3",
+			keys:   []string{"cafè", "café"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tree := newTree(t, tc.mode, keys...)
+			if want, got := tc.keys, walkPrefix(tree, tc.prefix); !cmp.Equal(want, got) {
+				t.Fatalf("want= %q, got= %q", want, got)
+			}
+
+			labelsAreRunes := true
+			walkNodes(tree.root, func(n *node) {
+				if !utf8.ValidString(n.label) {
+					labelsAreRunes = false
+				}
+			})
+			if want, got := tc.mode == Runes, labelsAreRunes; want != got {
+				t.Fatalf("labels are valid UTF-8: want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+func walkNodes(n *node, fn func(*node)) {
+	fn(n)
+	for _, c := range n.children {
+		walkNodes(c, fn)
+	}
+}
+
+// TestAgainstMap performs random operations and compares the tree with a map.
+func TestAgainstMap(t *testing.T) {
+	for _, mode := range []Mode{Runes, Bytes} {
+		t.Run(fmt.Sprintf("mode=%d", mode), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(42))
+			alphabet := []string{"a", "b", "é", "è", "/"}
+			randomKey := func() string {
+				var b strings.Builder
+				for i := rng.Intn(6); i > 0; i-- {
+					b.WriteString(alphabet[rng.Intn(len(alphabet))])
+				}
+				return b.String()
+			}
+
+			tree := newTree(t, mode)
+			naive := map[string]bool{}
+			for step := 0; step < 5000; step++ {
+				key := randomKey()
+				if rng.Intn(3) == 0 {
+					_, ok := tree.Delete(key)
+					if want := naive[key]; want != ok {
+						t.Fatalf("step %d: delete %q: want= %v, got= %v", step, key, want, ok)
+					}
+					delete(naive, key)
+				} else {
+					existed := tree.Insert(key, key)
+					if want := naive[key]; want != existed {
+						t.Fatalf("step %d: insert %q: want= %v, got= %v", step, key, want, existed)
+					}
+					naive[key] = true
+				}
+
+				prefix := randomKey()
+				var want []string
+				longest, found := "", false
+				for k := range naive {
+					if strings.HasPrefix(k, prefix) {
+						want = append(want, k)
+					}
+					if strings.HasPrefix(prefix, k) && (!found || len(k) > len(longest)) {
+						longest, found = k, true
+					}
+				}
+				sort.Strings(want)
+				if got := walkPrefix(tree, prefix); !cmp.Equal(want, got) {
+					t.Fatalf("step %d: walk %q: want= %q, got= %q", step, prefix, want, got)
+				}
+				if got, _, ok := tree.LongestPrefix(prefix); ok != found || got != longest {
+					t.Fatalf("step %d: longest prefix of %q: want= %q, got= %q", step, prefix, longest, got)
+				}
+			}
+
+			// every node but the root has a value or at least two children
+			walkNodes(tree.root, func(n *node) {
+				if n != tree.root && !n.hasValue && len(n.children) < 2 {
+					t.Fatalf("node %q is not compressed", n.label)
+				}
+			})
+		})
+	}
+}
+
+// urls returns n URLs which look like the routes of a web service.
+func urls(n int) []string {
+	rng := rand.New(rand.NewSource(1))
+	hosts := []string{"api.example.com", "static.example.com", "www.example.com", "auth.example.org"}
+	resources := []string{"users", "orders", "products", "carts", "payments", "reviews", "search", "images"}
+	actions := []string{"", "/edit", "/history", "/items", "/thumbnail.png", "/comments"}
+
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://%s/v%d/%s/%d%s",
+			hosts[rng.Intn(len(hosts))],
+			rng.Intn(3)+1,
+			resources[rng.Intn(len(resources))],
+			rng.Intn(100000),
+			actions[rng.Intn(len(actions))])
+	}
+
+	return urls
+}
+
+// idPrefix returns url up to the first 2 digits of its resource ID.
+func idPrefix(url string) string {
+	i := 0
+	for slashes := 0; slashes < 5; i++ {
+		if url[i] == '/' {
+			slashes++
+		}
+	}
+
+	return url[:i+2]
+}
+
+func BenchmarkURLs(b *testing.B) {
+	const n = 100000
+	keys := urls(n)
+
+	for _, mode := range []Mode{Runes, Bytes} {
+		tree, _ := New(mode)
+		for _, k := range keys {
+			tree.Insert(k, nil)
+		}
+
+		b.Run(fmt.Sprintf("insert/mode=%d", mode), func(b *testing.B) {
+			var tree *radixTree
+			for i := 0; i < b.N; i++ {
+				if i%n == 0 {
+					tree, _ = New(mode)
+				}
+				tree.Insert(keys[i%n], nil)
+			}
+		})
+		b.Run(fmt.Sprintf("get/mode=%d", mode), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tree.Get(keys[i%n])
+			}
+		})
+		b.Run(fmt.Sprintf("longestPrefix/mode=%d", mode), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tree.LongestPrefix(keys[i%n] + "/deeper/path")
+			}
+		})
+		b.Run(fmt.Sprintf("walkPrefix/mode=%d", mode), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tree.WalkPrefix(idPrefix(keys[i%n]), func(key string, value containers.Value) bool { return true })
+			}
+		})
+	}
+
+	b.Run("get/map", func(b *testing.B) {
+		m := map[string]containers.Value{}
+		for _, k := range keys {
+			m[k] = nil
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = m[keys[i%n]]
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package unionfind
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+	"github.com/bitsgofer/containers/stack"
+)
+
+const nothingToUndo = "nothing to undo"
+
+// change is an operation recorded on the undo stack.
+type change struct {
+	added      bool // key with the last id was added, otherwise child was linked below root
+	child      int
+	root       int
+	rankRaised bool
+}
+
+// rollbackUnionFind is a disjoint-set forest with union by rank and without path compression,
+// so that every operation can be undone in O(1). Find runs in O(log n).
+// It is not safe for concurrent use.
+type rollbackUnionFind struct {
+	keys
+	parent []int
+	rank   []int
+	size   []int
+	next   []int
+	count  int
+
+	undo stack.Stackable // items are change
+}
+
+// NewRollback returns an empty union-find whose operations can be rolled back, e.g. for offline dynamic connectivity.
+// Keys must be comparable.
+func NewRollback() *rollbackUnionFind {
+	return &rollbackUnionFind{
+		keys: newKeys(),
+		undo: stack.New(),
+	}
+}
+
+// Add adds key as a set of its own, and returns whether it was new.
+func (u *rollbackUnionFind) Add(key containers.Value) bool {
+	id, added := u.add(key)
+	if added {
+		u.parent = append(u.parent, id)
+		u.rank = append(u.rank, 0)
+		u.size = append(u.size, 1)
+		u.next = append(u.next, id)
+		u.count++
+		u.undo.Push(change{added: true})
+	}
+
+	return added
+}
+
+// Len returns the number of keys.
+func (u *rollbackUnionFind) Len() int {
+	return len(u.keys.keys)
+}
+
+// Count returns the number of sets.
+func (u *rollbackUnionFind) Count() int {
+	return u.count
+}
+
+// Find returns the representative key of the set of key, and whether key exists.
+func (u *rollbackUnionFind) Find(key containers.Value) (containers.Value, bool) {
+	id, ok := u.id(key)
+	if !ok {
+		return nil, false
+	}
+
+	return u.keys.keys[u.root(id)], true
+}
+
+func (u *rollbackUnionFind) root(id int) int {
+	for u.parent[id] != id {
+		id = u.parent[id]
+	}
+
+	return id
+}
+
+// Union merges the sets of a and b, adding them if needed, and returns whether they were disjoint.
+// Nothing is recorded if they were not.
+func (u *rollbackUnionFind) Union(a, b containers.Value) bool {
+	u.Add(a)
+	u.Add(b)
+	ida, _ := u.id(a)
+	idb, _ := u.id(b)
+
+	ra, rb := u.root(ida), u.root(idb)
+	if ra == rb {
+		return false
+	}
+	if u.rank[ra] < u.rank[rb] {
+		ra, rb = rb, ra
+	}
+
+	c := change{child: rb, root: ra, rankRaised: u.rank[ra] == u.rank[rb]}
+	u.parent[rb] = ra
+	u.size[ra] += u.size[rb]
+	if c.rankRaised {
+		u.rank[ra]++
+	}
+	u.next[ra], u.next[rb] = u.next[rb], u.next[ra]
+	u.count--
+	u.undo.Push(c)
+	return true
+}
+
+// Connected returns whether a and b exist and are in the same set.
+func (u *rollbackUnionFind) Connected(a, b containers.Value) bool {
+	ida, oka := u.id(a)
+	idb, okb := u.id(b)
+
+	return oka && okb && u.root(ida) == u.root(idb)
+}
+
+// Size returns the size of the set of key, or 0 if key does not exist.
+func (u *rollbackUnionFind) Size(key containers.Value) int {
+	id, ok := u.id(key)
+	if !ok {
+		return 0
+	}
+
+	return u.size[u.root(id)]
+}
+
+// Members returns the keys in the set of key, or nil if key does not exist. It runs in O(Size(key)).
+func (u *rollbackUnionFind) Members(key containers.Value) []containers.Value {
+	id, ok := u.id(key)
+	if !ok {
+		return nil
+	}
+
+	return u.members(id, u.next)
+}
+
+// Checkpoint returns the number of recorded operations, to be given to Rollback later.
+func (u *rollbackUnionFind) Checkpoint() int {
+	return u.undo.Size()
+}
+
+// Undo reverts the last operation which changed the sets, i.e. adding a key or merging two sets.
+func (u *rollbackUnionFind) Undo() error {
+	top, err := u.undo.Pop()
+	if err != nil {
+		return errors.New(nothingToUndo)
+	}
+
+	c := top.(change)
+	if c.added {
+		last := len(u.keys.keys) - 1
+		delete(u.ids, u.keys.keys[last])
+		u.keys.keys[last] = nil
+		u.keys.keys = u.keys.keys[:last]
+		u.parent = u.parent[:last]
+		u.rank = u.rank[:last]
+		u.size = u.size[:last]
+		u.next = u.next[:last]
+		u.count--
+		return nil
+	}
+
+	u.next[c.root], u.next[c.child] = u.next[c.child], u.next[c.root]
+	if c.rankRaised {
+		u.rank[c.root]--
+	}
+	u.size[c.root] -= u.size[c.child]
+	u.parent[c.child] = c.child
+	u.count++
+	return nil
+}
+
+// Rollback reverts operations until the state of the given checkpoint.
+func (u *rollbackUnionFind) Rollback(checkpoint int) error {
+	if checkpoint < 0 || checkpoint > u.undo.Size() {
+		return errors.Errorf("checkpoint must be in [0, %d], got %d", u.undo.Size(), checkpoint)
+	}
+
+	for u.undo.Size() > checkpoint {
+		if err := u.Undo(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package unionfind
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+func TestRollback(t *testing.T) {
+	u := NewRollback()
+	u.Union("a", "b")
+	base := u.Checkpoint()
+
+	u.Union("c", "d")
+	u.Union("a", "c")
+	u.Union("a", "d") // already connected, not recorded
+	if want, got := 4, u.Size("a"); want != got {
+		t.Fatalf("size: want= %v, got= %v", want, got)
+	}
+
+	if err := u.Undo(); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if u.Connected("a", "c") || !u.Connected("c", "d") {
+		t.Fatalf("want only the last union undone")
+	}
+	if want, got := []containers.Value{"c", "d"}, sorted(u.Members("d")); !cmp.Equal(want, got) {
+		t.Fatalf("members: want= %v, got= %v", want, got)
+	}
+
+	if err := u.Rollback(base); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want, got := 2, u.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+	if want, got := 1, u.Count(); want != got {
+		t.Fatalf("count: want= %v, got= %v", want, got)
+	}
+	if _, ok := u.Find("c"); ok {
+		t.Fatalf("want keys added after the checkpoint removed")
+	}
+	if !u.Connected("a", "b") {
+		t.Fatalf("want unions before the checkpoint kept")
+	}
+
+	if err := u.Rollback(base + 1); err == nil {
+		t.Fatalf("want error rolling back to a future checkpoint, got none")
+	}
+	if err := u.Rollback(0); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if err := u.Undo(); err == nil {
+		t.Fatalf("want error with nothing to undo, got none")
+	}
+	if want, got := 0, u.Len(); want != got {
+		t.Fatalf("len: want= %v, got= %v", want, got)
+	}
+}
+
+// TestOfflineDynamicConnectivity counts components of a graph whose edges are added and removed over time:
+// each edge is alive during an interval of time, which is split over a segment tree of times.
+// A depth-first walk of the tree adds the edges of a node, and rolls them back when leaving it.
+func TestOfflineDynamicConnectivity(t *testing.T) {
+	const (
+		n     = 30
+		steps = 400
+	)
+	rng := rand.New(rand.NewSource(1))
+
+	type interval struct{ from, to, a, b int } // edge (a, b) alive during [from, to)
+	var intervals []interval
+	alive := map[[2]int]int{} // edge => since when
+	want := make([]int, steps)
+	for time := 0; time < steps; time++ {
+		e := [2]int{rng.Intn(n), rng.Intn(n)}
+		if since, ok := alive[e]; ok {
+			intervals = append(intervals, interval{since, time, e[0], e[1]})
+			delete(alive, e)
+		} else {
+			alive[e] = time
+		}
+
+		var edges [][2]int
+		for e := range alive {
+			edges = append(edges, e)
+		}
+		components := map[int]bool{}
+		for _, l := range labels(n, edges) {
+			components[l] = true
+		}
+		want[time] = len(components)
+	}
+	for e, since := range alive {
+		intervals = append(intervals, interval{since, steps, e[0], e[1]})
+	}
+
+	edgesOf := make([][][2]int, 4*steps) // segment tree over [0, steps)
+	var insert func(node, lo, hi int, iv interval)
+	insert = func(node, lo, hi int, iv interval) {
+		if iv.to <= lo || hi <= iv.from {
+			return
+		}
+		if iv.from <= lo && hi <= iv.to {
+			edgesOf[node] = append(edgesOf[node], [2]int{iv.a, iv.b})
+			return
+		}
+		mid := (lo + hi) / 2
+		insert(2*node, lo, mid, iv)
+		insert(2*node+1, mid, hi, iv)
+	}
+	for _, iv := range intervals {
+		insert(1, 0, steps, iv)
+	}
+
+	u := NewRollback()
+	for i := 0; i < n; i++ {
+		u.Add(i)
+	}
+	got := make([]int, steps)
+	var walk func(node, lo, hi int)
+	walk = func(node, lo, hi int) {
+		checkpoint := u.Checkpoint()
+		for _, e := range edgesOf[node] {
+			u.Union(e[0], e[1])
+		}
+		if hi-lo == 1 {
+			got[lo] = u.Count()
+		} else {
+			mid := (lo + hi) / 2
+			walk(2*node, lo, mid)
+			walk(2*node+1, mid, hi)
+		}
+		if err := u.Rollback(checkpoint); err != nil {
+			t.Fatalf("cannot rollback: %v", err)
+		}
+	}
+	walk(1, 0, steps)
+
+	if !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v, diff= %v", want, got, cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,174 @@
+// Package unionfind provides disjoint sets of containers.Value keys.
+package unionfind
+
+import (
+	"github.com/bitsgofer/containers"
+)
+
+// keys maps comparable keys to dense ids, in the order they were added.
+type keys struct {
+	ids  map[containers.Value]int
+	keys []containers.Value
+}
+
+func newKeys() keys {
+	return keys{ids: map[containers.Value]int{}}
+}
+
+// id returns the id of key, and whether it exists.
+func (k *keys) id(key containers.Value) (int, bool) {
+	id, ok := k.ids[key]
+	return id, ok
+}
+
+// add returns the id of key, adding it if needed, and whether it was added.
+func (k *keys) add(key containers.Value) (int, bool) {
+	if id, ok := k.ids[key]; ok {
+		return id, false
+	}
+
+	id := len(k.keys)
+	k.ids[key] = id
+	k.keys = append(k.keys, key)
+	return id, true
+}
+
+// members returns the keys of the ring of ids starting at id.
+func (k *keys) members(id int, next []int) []containers.Value {
+	members := []containers.Value{k.keys[id]}
+	for i := next[id]; i != id; i = next[i] {
+		members = append(members, k.keys[i])
+	}
+
+	return members
+}
+
+// unionFind is a disjoint-set forest with path compression and union by size,
+// so that operations run in amortized O(α(n)) ("Efficiency of a Good But Not Linear Set Union Algorithm" - Tarjan 1975).
+// It is not safe for concurrent use.
+type unionFind struct {
+	keys
+	parent []int
+	size   []int // size of the set, for roots
+	next   []int // ring of the members of each set, to enumerate them
+	count  int
+}
+
+// New returns an empty union-find. Keys must be comparable.
+func New() *unionFind {
+	return &unionFind{keys: newKeys()}
+}
+
+// Add adds key as a set of its own, and returns whether it was new.
+func (u *unionFind) Add(key containers.Value) bool {
+	id, added := u.add(key)
+	if added {
+		u.parent = append(u.parent, id)
+		u.size = append(u.size, 1)
+		u.next = append(u.next, id)
+		u.count++
+	}
+
+	return added
+}
+
+// Len returns the number of keys.
+func (u *unionFind) Len() int {
+	return len(u.keys.keys)
+}
+
+// Count returns the number of sets.
+func (u *unionFind) Count() int {
+	return u.count
+}
+
+// Find returns the representative key of the set of key, and whether key exists.
+func (u *unionFind) Find(key containers.Value) (containers.Value, bool) {
+	id, ok := u.id(key)
+	if !ok {
+		return nil, false
+	}
+
+	return u.keys.keys[u.root(id)], true
+}
+
+func (u *unionFind) root(id int) int {
+	root := id
+	for u.parent[root] != root {
+		root = u.parent[root]
+	}
+	for id != root {
+		next := u.parent[id]
+		u.parent[id] = root
+		id = next
+	}
+
+	return root
+}
+
+// Union merges the sets of a and b, adding them if needed, and returns whether they were disjoint.
+func (u *unionFind) Union(a, b containers.Value) bool {
+	u.Add(a)
+	u.Add(b)
+	ida, _ := u.id(a)
+	idb, _ := u.id(b)
+
+	ra, rb := u.root(ida), u.root(idb)
+	if ra == rb {
+		return false
+	}
+	if u.size[ra] < u.size[rb] {
+		ra, rb = rb, ra
+	}
+
+	u.parent[rb] = ra
+	u.size[ra] += u.size[rb]
+	u.next[ra], u.next[rb] = u.next[rb], u.next[ra]
+	u.count--
+	return true
+}
+
+// Connected returns whether a and b exist and are in the same set.
+func (u *unionFind) Connected(a, b containers.Value) bool {
+	ida, oka := u.id(a)
+	idb, okb := u.id(b)
+
+	return oka && okb && u.root(ida) == u.root(idb)
+}
+
+// Size returns the size of the set of key, or 0 if key does not exist.
+func (u *unionFind) Size(key containers.Value) int {
+	id, ok := u.id(key)
+	if !ok {
+		return 0
+	}
+
+	return u.size[u.root(id)]
+}
+
+// Members returns the keys in the set of key, or nil if key does not exist. It runs in O(Size(key)).
+func (u *unionFind) Members(key containers.Value) []containers.Value {
+	id, ok := u.id(key)
+	if !ok {
+		return nil
+	}
+
+	return u.members(id, u.next)
+}
+
+// Sets returns all sets, in the order their first key was added.
+func (u *unionFind) Sets() [][]containers.Value {
+	sets := make([][]containers.Value, 0, u.count)
+	seen := make([]bool, len(u.parent))
+	for id := range u.parent {
+		root := u.root(id)
+		if seen[root] {
+			continue
+		}
+
+		seen[root] = true
+		sets = append(sets, u.members(id, u.next))
+	}
+
+	return sets
+}
@@ -0,0 +1,207 @@
+package unionfind
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+// disjointSets is implemented by both union-finds.
+type disjointSets interface {
+	Add(key containers.Value) bool
+	Len() int
+	Count() int
+	Find(key containers.Value) (containers.Value, bool)
+	Union(a, b containers.Value) bool
+	Connected(a, b containers.Value) bool
+	Size(key containers.Value) int
+	Members(key containers.Value) []containers.Value
+}
+
+var implementations = map[string]func() disjointSets{
+	"unionFind": func() disjointSets { return New() },
+	"rollback":  func() disjointSets { return NewRollback() },
+}
+
+func sorted(keys []containers.Value) []containers.Value {
+	sorted := append([]containers.Value{}, keys...)
+	sort.Slice(sorted, func(i, j int) bool { return fmt.Sprint(sorted[i]) < fmt.Sprint(sorted[j]) })
+
+	return sorted
+}
+
+func TestUnion(t *testing.T) {
+	for impl, newSets := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			u := newSets()
+			if !u.Add("a") || u.Add("a") {
+				t.Fatalf("want key added once")
+			}
+			if _, ok := u.Find("x"); ok {
+				t.Fatalf("want missing key not found")
+			}
+
+			if !u.Union("a", "b") || !u.Union("c", "d") || !u.Union("b", "d") {
+				t.Fatalf("want disjoint sets merged")
+			}
+			if u.Union("a", "c") {
+				t.Fatalf("want connected keys not merged")
+			}
+			u.Add("e")
+
+			if want, got := 5, u.Len(); want != got {
+				t.Fatalf("len: want= %v, got= %v", want, got)
+			}
+			if want, got := 2, u.Count(); want != got {
+				t.Fatalf("count: want= %v, got= %v", want, got)
+			}
+			if want, got := 4, u.Size("c"); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+			if want, got := 0, u.Size("x"); want != got {
+				t.Fatalf("size of missing key: want= %v, got= %v", want, got)
+			}
+			if !u.Connected("a", "d") || u.Connected("a", "e") || u.Connected("a", "x") {
+				t.Fatalf("want only keys of the same set connected")
+			}
+
+			ra, _ := u.Find("a")
+			rd, _ := u.Find("d")
+			if ra != rd {
+				t.Fatalf("want same representative, got= %v, %v", ra, rd)
+			}
+
+			if want, got := []containers.Value{"a", "b", "c", "d"}, sorted(u.Members("b")); !cmp.Equal(want, got) {
+				t.Fatalf("members: want= %v, got= %v", want, got)
+			}
+			if want, got := []containers.Value{"e"}, u.Members("e"); !cmp.Equal(want, got) {
+				t.Fatalf("members: want= %v, got= %v", want, got)
+			}
+			if got := u.Members("x"); got != nil {
+				t.Fatalf("members of missing key: want none, got= %v", got)
+			}
+		})
+	}
+}
+
+func TestSets(t *testing.T) {
+	u := New()
+	for _, key := range []int{0, 1, 2, 3, 4, 5} {
+		u.Add(key)
+	}
+	u.Union(4, 0)
+	u.Union(2, 5)
+	u.Union(0, 2)
+
+	var sets [][]containers.Value
+	for _, set := range u.Sets() {
+		sets = append(sets, sorted(set))
+	}
+	want := [][]containers.Value{{0, 2, 4, 5}, {1}, {3}}
+	if got := sets; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+// labels returns the set of each key as the smallest key in it, by brute force over edges.
+func labels(n int, edges [][2]int) []int {
+	label := make([]int, n)
+	for i := range label {
+		label[i] = i
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, e := range edges {
+			a, b := label[e[0]], label[e[1]]
+			if a == b {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			for i := range label {
+				if label[i] == b {
+					label[i] = a
+					changed = true
+				}
+			}
+		}
+	}
+
+	return label
+}
+
+func TestAgainstBruteForce(t *testing.T) {
+	const n = 200
+	rng := rand.New(rand.NewSource(1))
+	var edges [][2]int
+	for i := 0; i < 150; i++ {
+		edges = append(edges, [2]int{rng.Intn(n), rng.Intn(n)})
+	}
+	label := labels(n, edges)
+
+	for impl, newSets := range implementations {
+		t.Run(impl, func(t *testing.T) {
+			u := newSets()
+			for i := 0; i < n; i++ {
+				u.Add(i)
+			}
+			for _, e := range edges {
+				u.Union(e[0], e[1])
+			}
+
+			count := map[int]int{}
+			for _, l := range label {
+				count[l]++
+			}
+			if want, got := len(count), u.Count(); want != got {
+				t.Fatalf("count: want= %v, got= %v", want, got)
+			}
+			for i := 0; i < n; i++ {
+				if want, got := count[label[i]], u.Size(i); want != got {
+					t.Fatalf("size of %d: want= %v, got= %v", i, want, got)
+				}
+				if want, got := count[label[i]], len(u.Members(i)); want != got {
+					t.Fatalf("members of %d: want= %v, got= %v", i, want, got)
+				}
+				j := rng.Intn(n)
+				if want, got := label[i] == label[j], u.Connected(i, j); want != got {
+					t.Fatalf("connected(%d, %d): want= %v, got= %v", i, j, want, got)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnionFind(b *testing.B) {
+	const n = 1 << 16
+	rng := rand.New(rand.NewSource(1))
+	pairs := make([][2]int, 1<<12)
+	for i := range pairs {
+		pairs[i] = [2]int{rng.Intn(n), rng.Intn(n)}
+	}
+
+	for impl, newSets := range implementations {
+		b.Run(impl, func(b *testing.B) {
+			u := newSets()
+			for i := 0; i < n; i++ {
+				u.Add(i)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p := pairs[i&(len(pairs)-1)]
+				if i%4 == 0 {
+					u.Union(p[0], p[1])
+				} else {
+					u.Connected(p[0], p[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package workstealing provides a Chase-Lev work-stealing deque and a task scheduler built on it.
+package workstealing
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers"
+)
+
+// Stealable provides work-stealing deque APIs.
+// Only the goroutine owning the deque may call PushBottom and PopBottom, any goroutine may call Steal.
+type Stealable interface {
+	PushBottom(item containers.Value)
+	PopBottom() (containers.Value, error)
+	Steal() (containers.Value, error)
+	Size() int
+}
+
+var (
+	// ErrEmpty is returned when there is nothing to pop or steal.
+	ErrEmpty = errors.New("deque is empty")
+	// ErrAborted is returned by Steal when it lost a race with another thief or the owner. It may be retried.
+	ErrAborted = errors.New("steal aborted")
+)
+
+const (
+	cacheLineSize      = 64
+	initialArrayLogLen = 5
+)
+
+// circularArray is a power-of-two ring of slots. Each slot holds a *containers.Value.
+type circularArray struct {
+	mask  int64
+	slots []unsafe.Pointer
+}
+
+func newCircularArray(logLen uint) *circularArray {
+	n := int64(1) << logLen
+	return &circularArray{
+		mask:  n - 1,
+		slots: make([]unsafe.Pointer, n),
+	}
+}
+
+func (a *circularArray) len() int64 {
+	return a.mask + 1
+}
+
+func (a *circularArray) get(i int64) containers.Value {
+	return *(*containers.Value)(atomic.LoadPointer(&a.slots[i&a.mask]))
+}
+
+func (a *circularArray) put(i int64, item containers.Value) {
+	atomic.StorePointer(&a.slots[i&a.mask], unsafe.Pointer(&item))
+}
+
+// grow returns a copy of a with twice the length, holding items in [top, bottom).
+func (a *circularArray) grow(top, bottom int64) *circularArray {
+	bigger := &circularArray{
+		mask:  a.len()*2 - 1,
+		slots: make([]unsafe.Pointer, a.len()*2),
+	}
+	for i := top; i < bottom; i++ {
+		bigger.slots[i&bigger.mask] = atomic.LoadPointer(&a.slots[i&a.mask])
+	}
+
+	return bigger
+}
+
+// deque is a concrete implementation of Stealable, following
+// "Dynamic Circular Work-Stealing Deque" (Chase, Lev - SPAA 2005).
+// Items are in [top, bottom). The owner works at the bottom without locks,
+// thieves race for the top with compare-and-swap.
+//
+// Popped items stay referenced by the array until their slots are reused.
+type deque struct {
+	top    int64 // accessed atomically, first in struct for 64-bit alignment
+	_      [cacheLineSize - 8]byte
+	bottom int64 // accessed atomically
+	_      [cacheLineSize - 8]byte
+	array  unsafe.Pointer // *circularArray, replaced atomically when it grows
+}
+
+// NewDeque returns an empty Stealable.
+func NewDeque() *deque {
+	return &deque{
+		array: unsafe.Pointer(newCircularArray(initialArrayLogLen)),
+	}
+}
+
+// PushBottom adds a new containers.Value at the bottom. Only the owner may call it.
+func (d *deque) PushBottom(item containers.Value) {
+	b := atomic.LoadInt64(&d.bottom)
+	t := atomic.LoadInt64(&d.top)
+	a := (*circularArray)(atomic.LoadPointer(&d.array))
+	if b-t >= a.len()-1 {
+		a = a.grow(t, b)
+		atomic.StorePointer(&d.array, unsafe.Pointer(a))
+	}
+
+	a.put(b, item)
+	atomic.StoreInt64(&d.bottom, b+1)
+}
+
+// PopBottom removes the containers.Value at the bottom and returns it. Only the owner may call it.
+// It returns ErrEmpty if there is nothing left, including when a thief stole the last item.
+func (d *deque) PopBottom() (containers.Value, error) {
+	b := atomic.LoadInt64(&d.bottom) - 1
+	a := (*circularArray)(atomic.LoadPointer(&d.array))
+	atomic.StoreInt64(&d.bottom, b) // from now on, thieves cannot take the item at b unless it's the last one
+	t := atomic.LoadInt64(&d.top)
+
+	if t > b {
+		atomic.StoreInt64(&d.bottom, b+1)
+		return nil, ErrEmpty
+	}
+
+	item := a.get(b)
+	if t < b {
+		return item, nil
+	}
+
+	// last item: race with thieves for it
+	won := atomic.CompareAndSwapInt64(&d.top, t, t+1)
+	atomic.StoreInt64(&d.bottom, b+1)
+	if !won {
+		return nil, ErrEmpty
+	}
+
+	return item, nil
+}
+
+// Steal removes the containers.Value at the top and returns it. Any goroutine may call it.
+// It returns ErrEmpty if there is nothing to steal, or ErrAborted if another goroutine took the item first.
+func (d *deque) Steal() (containers.Value, error) {
+	t := atomic.LoadInt64(&d.top)
+	b := atomic.LoadInt64(&d.bottom)
+	if t >= b {
+		return nil, ErrEmpty
+	}
+
+	a := (*circularArray)(atomic.LoadPointer(&d.array))
+	item := a.get(t)
+	if !atomic.CompareAndSwapInt64(&d.top, t, t+1) {
+		return nil, ErrAborted
+	}
+
+	return item, nil
+}
+
+// Size returns the number of items in the deque. It may be stale when other goroutines use the deque.
+func (d *deque) Size() int {
+	n := atomic.LoadInt64(&d.bottom) - atomic.LoadInt64(&d.top)
+	if n < 0 {
+		return 0
+	}
+
+	return int(n)
+}
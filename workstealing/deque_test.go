@@ -0,0 +1,181 @@
+package workstealing
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitsgofer/containers"
+)
+
+var _ Stealable = (*deque)(nil)
+
+func TestDequeOwnerIsLIFO(t *testing.T) {
+	d := NewDeque()
+	for i := 0; i < 5; i++ {
+		d.PushBottom(i)
+	}
+
+	var popped []containers.Value
+	for {
+		val, err := d.PopBottom()
+		if err == ErrEmpty {
+			break
+		}
+		if err != nil {
+			t.Fatalf("want no error, got %q", err)
+		}
+		popped = append(popped, val)
+	}
+
+	if want, got := []containers.Value{4, 3, 2, 1, 0}, popped; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func TestDequeThiefIsFIFO(t *testing.T) {
+	d := NewDeque()
+	for i := 0; i < 5; i++ {
+		d.PushBottom(i)
+	}
+
+	var stolen []containers.Value
+	for {
+		val, err := d.Steal()
+		if err == ErrEmpty {
+			break
+		}
+		if err != nil {
+			t.Fatalf("want no error, got %q", err)
+		}
+		stolen = append(stolen, val)
+	}
+
+	if want, got := []containers.Value{0, 1, 2, 3, 4}, stolen; !cmp.Equal(want, got) {
+		t.Fatalf("want= %v, got= %v", want, got)
+	}
+}
+
+func TestDequeGrow(t *testing.T) {
+	var testCases = map[string]struct {
+		pushes int
+		steals int // steals before pushing more, so items wrap around the array
+	}{
+		"fitsInitialArray": {pushes: 10},
+		"grows":            {pushes: 1000},
+		"growsWrapped":     {pushes: 1000, steals: 20},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := NewDeque()
+			var want []containers.Value
+			for i := 0; i < tc.steals; i++ {
+				d.PushBottom(-1)
+				d.Steal()
+			}
+			for i := 0; i < tc.pushes; i++ {
+				d.PushBottom(i)
+				want = append(want, i)
+			}
+			if want, got := tc.pushes, d.Size(); want != got {
+				t.Fatalf("size: want= %v, got= %v", want, got)
+			}
+
+			var got []containers.Value
+			for d.Size() > 0 {
+				val, err := d.Steal()
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				got = append(got, val)
+			}
+			if !cmp.Equal(want, got) {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+		})
+	}
+}
+
+// TestDequeConcurrentSteal has thieves steal while the owner pushes and pops.
+// Every item must be taken exactly once. Run it with -race.
+func TestDequeConcurrentSteal(t *testing.T) {
+	const (
+		items   = 100000
+		thieves = 8
+	)
+
+	d := NewDeque()
+	taken := make([][]int, thieves+1) // taken[thieves] is for the owner
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(thieves)
+	for i := 0; i < thieves; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for {
+				val, err := d.Steal()
+				if err == nil {
+					taken[i] = append(taken[i], val.(int))
+					continue
+				}
+
+				select {
+				case <-done:
+					if d.Size() == 0 {
+						return
+					}
+				default:
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < items; i++ {
+		d.PushBottom(i)
+		if i%3 == 0 {
+			if val, err := d.PopBottom(); err == nil {
+				taken[thieves] = append(taken[thieves], val.(int))
+			}
+		}
+	}
+	for {
+		val, err := d.PopBottom()
+		if err != nil {
+			break
+		}
+		taken[thieves] = append(taken[thieves], val.(int))
+	}
+	close(done)
+	wg.Wait()
+
+	seen := make([]int, items)
+	stolen := 0
+	for i, vals := range taken {
+		if i < thieves {
+			stolen += len(vals)
+		}
+		for _, v := range vals {
+			seen[v]++
+		}
+	}
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("item %d: want taken once, got %d times", v, n)
+		}
+	}
+	t.Logf("stolen %d out of %d items", stolen, items)
+}
+
+func BenchmarkDequePushPop(b *testing.B) {
+	d := NewDeque()
+	for i := 0; i < b.N; i++ {
+		d.PushBottom(i)
+		if i%2 == 1 {
+			d.PopBottom()
+			d.PopBottom()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package workstealing
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/bitsgofer/containers/queue"
+)
+
+// Task is a unit of work run by a Worker. It may spawn more tasks with w.Spawn().
+type Task func(w *Worker)
+
+// Worker runs tasks from its own deque, stealing from other workers when it runs out.
+type Worker struct {
+	id        int
+	deque     *deque
+	scheduler *scheduler
+	rng       *rand.Rand
+}
+
+// ID returns the index of the worker in its scheduler.
+func (w *Worker) ID() int {
+	return w.id
+}
+
+// Spawn adds a task to the worker's own deque. It must only be called from a task run by w.
+func (w *Worker) Spawn(task Task) {
+	w.scheduler.pending.Add(1)
+	w.deque.PushBottom(task)
+	w.scheduler.wakeOne()
+}
+
+// next returns a task to run: from its own deque, then from submitted tasks, then stolen from others.
+func (w *Worker) next() Task {
+	if task, err := w.deque.PopBottom(); err == nil {
+		return task.(Task)
+	}
+	if task := w.scheduler.injected(); task != nil {
+		return task
+	}
+
+	workers := w.scheduler.workers
+	for attempts := 0; attempts < 2*len(workers); attempts++ {
+		victim := workers[w.rng.Intn(len(workers))]
+		if victim == w {
+			continue
+		}
+
+		task, err := victim.deque.Steal()
+		if err == nil {
+			atomic.AddInt64(&w.scheduler.steals, 1)
+			return task.(Task)
+		}
+	}
+
+	// a last full pass, so the worker never sleeps while others have tasks
+	for _, victim := range workers {
+		for victim != w {
+			task, err := victim.deque.Steal()
+			if err == ErrAborted {
+				continue
+			}
+			if err == nil {
+				atomic.AddInt64(&w.scheduler.steals, 1)
+				return task.(Task)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) run() {
+	defer w.scheduler.running.Done()
+
+	for {
+		if task := w.next(); task != nil {
+			task(w)
+			w.scheduler.pending.Done()
+			continue
+		}
+
+		select {
+		case <-w.scheduler.quit:
+			return
+		case <-w.scheduler.wake:
+		}
+	}
+}
+
+// scheduler runs tasks on a fixed number of workers, each with a work-stealing deque.
+type scheduler struct {
+	workers []*Worker
+
+	mu     sync.Mutex
+	inject queue.Queueable // tasks submitted from outside of workers
+
+	wake    chan struct{} // a token wakes up one idle worker
+	quit    chan struct{}
+	pending sync.WaitGroup // tasks not finished yet
+	running sync.WaitGroup // worker goroutines
+	steals  int64          // accessed atomically
+}
+
+// NewScheduler starts a scheduler with n workers. Close() must be called to stop them.
+func NewScheduler(n int) (*scheduler, error) {
+	if n <= 0 {
+		return nil, errors.Errorf("number of workers must be positive, got %d", n)
+	}
+
+	s := &scheduler{
+		inject: queue.New(),
+		wake:   make(chan struct{}, n),
+		quit:   make(chan struct{}),
+	}
+	for i := 0; i < n; i++ {
+		s.workers = append(s.workers, &Worker{
+			id:        i,
+			deque:     NewDeque(),
+			scheduler: s,
+			rng:       rand.New(rand.NewSource(int64(i))),
+		})
+	}
+
+	s.running.Add(n)
+	for _, w := range s.workers {
+		go w.run()
+	}
+
+	return s, nil
+}
+
+// Submit adds a task from outside of the scheduler's workers.
+func (s *scheduler) Submit(task Task) {
+	s.pending.Add(1)
+	s.mu.Lock()
+	s.inject.Enqueue(task)
+	s.mu.Unlock()
+	s.wakeOne()
+}
+
+func (s *scheduler) injected() Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, err := s.inject.Dequeue()
+	if err != nil {
+		return nil
+	}
+	return task.(Task)
+}
+
+// wakeOne wakes up an idle worker, if any.
+// If all tokens are taken, every worker is already going to look for tasks.
+func (s *scheduler) wakeOne() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until all submitted tasks, and the tasks they spawned, are finished.
+func (s *scheduler) Wait() {
+	s.pending.Wait()
+}
+
+// Steals returns the number of tasks stolen by workers so far.
+func (s *scheduler) Steals() int64 {
+	return atomic.LoadInt64(&s.steals)
+}
+
+// Close stops the workers once they finish their current tasks. Pending tasks are not run.
+func (s *scheduler) Close() {
+	close(s.quit)
+	s.running.Wait()
+}
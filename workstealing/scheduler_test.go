@@ -0,0 +1,226 @@
+package workstealing
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bitsgofer/containers/queue"
+)
+
+func TestNewScheduler(t *testing.T) {
+	var testCases = map[string]struct {
+		workers int
+		isErr   bool
+	}{
+		"one":      {workers: 1},
+		"many":     {workers: 8},
+		"zero":     {workers: 0, isErr: true},
+		"negative": {workers: -1, isErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewScheduler(tc.workers)
+
+			if tc.isErr && err == nil {
+				t.Fatalf("want error, got none")
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if s != nil {
+				s.Close()
+			}
+		})
+	}
+}
+
+// spawnTree spawns a binary tree of tasks with the given depth, counting its leaves.
+func spawnTree(depth int, leaves *int64) Task {
+	return func(w *Worker) {
+		if depth == 0 {
+			atomic.AddInt64(leaves, 1)
+			return
+		}
+
+		w.Spawn(spawnTree(depth-1, leaves))
+		w.Spawn(spawnTree(depth-1, leaves))
+	}
+}
+
+func TestSchedulerSpawnTree(t *testing.T) {
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			s, err := NewScheduler(workers)
+			if err != nil {
+				t.Fatalf("need a valid scheduler to test, got %q", err)
+			}
+			defer s.Close()
+
+			var leaves int64
+			s.Submit(spawnTree(14, &leaves))
+			s.Wait()
+
+			if want, got := int64(1<<14), atomic.LoadInt64(&leaves); want != got {
+				t.Fatalf("want= %v, got= %v", want, got)
+			}
+			if workers > 1 {
+				t.Logf("%d steals", s.Steals())
+			}
+		})
+	}
+}
+
+func TestSchedulerConcurrentSubmit(t *testing.T) {
+	const (
+		submitters = 4
+		tasks      = 1000
+	)
+
+	s, err := NewScheduler(4)
+	if err != nil {
+		t.Fatalf("need a valid scheduler to test, got %q", err)
+	}
+	defer s.Close()
+
+	var (
+		mu  sync.Mutex
+		ran = map[int]int{}
+		wg  sync.WaitGroup
+	)
+	wg.Add(submitters)
+	for i := 0; i < submitters; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < tasks; j++ {
+				id := i*tasks + j
+				s.Submit(func(w *Worker) {
+					mu.Lock()
+					ran[id]++
+					mu.Unlock()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	s.Wait()
+
+	if want, got := submitters*tasks, len(ran); want != got {
+		t.Fatalf("tasks run: want= %v, got= %v", want, got)
+	}
+	for id, n := range ran {
+		if n != 1 {
+			t.Fatalf("task %d: want run once, got %d times", id, n)
+		}
+	}
+}
+
+func TestSchedulerWorkerIDs(t *testing.T) {
+	s, _ := NewScheduler(3)
+	defer s.Close()
+
+	var bad int64
+	for i := 0; i < 100; i++ {
+		s.Submit(func(w *Worker) {
+			if id := w.ID(); id < 0 || id >= 3 {
+				atomic.AddInt64(&bad, 1)
+			}
+		})
+	}
+	s.Wait()
+
+	if bad != 0 {
+		t.Fatalf("want worker IDs in [0, 3), got %d bad ones", bad)
+	}
+}
+
+// sharedQueue is a single blocking queue shared by all workers, to compare with work-stealing.
+type sharedQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   queue.Queueable
+	pending sync.WaitGroup
+	closed  bool
+}
+
+func runShared(workers int, root func(spawn func(task func()))) {
+	s := &sharedQueue{items: queue.New()}
+	s.cond = sync.NewCond(&s.mu)
+
+	spawn := func(task func()) {
+		s.pending.Add(1)
+		s.mu.Lock()
+		s.items.Enqueue(task)
+		s.mu.Unlock()
+		s.cond.Signal()
+	}
+
+	var running sync.WaitGroup
+	running.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer running.Done()
+			for {
+				s.mu.Lock()
+				for s.items.Size() == 0 && !s.closed {
+					s.cond.Wait()
+				}
+				if s.closed {
+					s.mu.Unlock()
+					return
+				}
+				task, _ := s.items.Dequeue()
+				s.mu.Unlock()
+
+				task.(func())()
+				s.pending.Done()
+			}
+		}()
+	}
+
+	root(spawn)
+	s.pending.Wait()
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	running.Wait()
+}
+
+func BenchmarkSpawnTree(b *testing.B) {
+	const depth = 12
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workstealing/workers=%d", workers), func(b *testing.B) {
+			s, _ := NewScheduler(workers)
+			defer s.Close()
+
+			for i := 0; i < b.N; i++ {
+				var leaves int64
+				s.Submit(spawnTree(depth, &leaves))
+				s.Wait()
+			}
+		})
+		b.Run(fmt.Sprintf("sharedQueue/workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var leaves int64
+				runShared(workers, func(spawn func(task func())) {
+					var tree func(depth int) func()
+					tree = func(depth int) func() {
+						return func() {
+							if depth == 0 {
+								atomic.AddInt64(&leaves, 1)
+								return
+							}
+							spawn(tree(depth - 1))
+							spawn(tree(depth - 1))
+						}
+					}
+					spawn(tree(depth))
+				})
+			}
+		})
+	}
+}